@@ -0,0 +1,92 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gvdb/hnsw"
+	"gvdb/index"
+)
+
+// Params 为一组 HNSW 构建参数及需要测试的 efSearch 取值
+type Params struct {
+	M              int
+	EFConstruction int
+	EFSearch       []int
+}
+
+// Result 为一组参数下的测试结果
+type Result struct {
+	M              int
+	EFConstruction int
+	EFSearch       int
+	K              int
+	BuildTime      time.Duration
+	QPS            float64
+	Recall         float64
+}
+
+// Build 将数据集的基础向量插入新的 HNSW 索引，ID 为向量下标
+func Build(ds *Dataset, m, efConstruction int, metric hnsw.Metric) (*hnsw.HNSWIndex, time.Duration) {
+	idx := hnsw.NewHNSWIndex(ds.Dim(), m, efConstruction, efConstruction, metric)
+	start := time.Now()
+	for i, v := range ds.Base {
+		idx.Add(strconv.Itoa(i), v)
+	}
+	return idx, time.Since(start)
+}
+
+// SearchAll 顺序执行全部查询，返回结果下标和耗时
+func SearchAll(idx index.Index, queries [][]float32, k int) ([][]int, time.Duration) {
+	results := make([][]int, len(queries))
+	start := time.Now()
+	for qi, q := range queries {
+		neighbors := idx.Search(q, k)
+		ids := make([]int, len(neighbors))
+		for i, n := range neighbors {
+			ids[i], _ = strconv.Atoi(n.ID)
+		}
+		results[qi] = ids
+	}
+	return results, time.Since(start)
+}
+
+// Run 对每组参数构建索引并测量召回率、QPS 与构建时间；数据集缺少真值时先计算真值
+func Run(ds *Dataset, params []Params, k int, metric hnsw.Metric) ([]Result, error) {
+	if len(ds.Base) == 0 || len(ds.Queries) == 0 {
+		return nil, fmt.Errorf("dataset must contain base and query vectors")
+	}
+	if len(ds.GroundTruth) < len(ds.Queries) || len(ds.GroundTruth[0]) < k {
+		ds.GroundTruth = ComputeGroundTruth(ds.Base, ds.Queries, k, metric)
+	}
+
+	var results []Result
+	for _, p := range params {
+		idx, buildTime := Build(ds, p.M, p.EFConstruction, metric)
+		for _, ef := range p.EFSearch {
+			idx.SetEfSearch(ef)
+			found, elapsed := SearchAll(idx, ds.Queries, k)
+			results = append(results, Result{
+				M:              p.M,
+				EFConstruction: p.EFConstruction,
+				EFSearch:       ef,
+				K:              k,
+				BuildTime:      buildTime,
+				QPS:            float64(len(ds.Queries)) / elapsed.Seconds(),
+				Recall:         Recall(found, ds.GroundTruth, k),
+			})
+		}
+	}
+	return results, nil
+}
+
+// WriteResults 以表格形式输出测试结果
+func WriteResults(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "%-4s %-8s %-8s %-10s %-12s %-10s\n", "M", "efC", "efS", "recall@k", "QPS", "build")
+	for _, r := range results {
+		fmt.Fprintf(w, "%-4d %-8d %-8d %-10.4f %-12.1f %-10s\n",
+			r.M, r.EFConstruction, r.EFSearch, r.Recall, r.QPS, r.BuildTime.Round(time.Millisecond))
+	}
+}
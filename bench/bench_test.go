@@ -0,0 +1,76 @@
+package bench
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gvdb/hnsw"
+	"gvdb/index"
+)
+
+func TestRun(t *testing.T) {
+	ds := GenerateDataset(1000, 20, 8, 1)
+	results, err := Run(ds, []Params{{M: 8, EFConstruction: 64, EFSearch: []int{16, 64}}}, 10, hnsw.L2{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[1].Recall < results[0].Recall {
+		t.Errorf("Expected recall to grow with efSearch: %v", results)
+	}
+	if results[1].Recall < 0.9 {
+		t.Errorf("Expected recall >= 0.9 with efSearch 64, got %f", results[1].Recall)
+	}
+
+	var buf bytes.Buffer
+	WriteResults(&buf, results)
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("Expected header and 2 rows, got %q", buf.String())
+	}
+}
+
+func BenchmarkHNSWBuild(b *testing.B) {
+	ds := GenerateDataset(5000, 0, 32, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Build(ds, 16, 100, hnsw.L2{})
+	}
+}
+
+func BenchmarkHNSWSearch(b *testing.B) {
+	const k = 10
+	ds := GenerateDataset(10000, 100, 32, 1)
+	ds.GroundTruth = ComputeGroundTruth(ds.Base, ds.Queries, k, hnsw.L2{})
+	idx, _ := Build(ds, 16, 100, hnsw.L2{})
+
+	for _, ef := range []int{16, 64, 256} {
+		b.Run("ef="+strconv.Itoa(ef), func(b *testing.B) {
+			idx.SetEfSearch(ef)
+			var found [][]int
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				found, _ = SearchAll(idx, ds.Queries, k)
+			}
+			b.ReportMetric(float64(b.N*len(ds.Queries))/b.Elapsed().Seconds(), "qps")
+			b.ReportMetric(Recall(found, ds.GroundTruth, k), "recall")
+		})
+	}
+}
+
+func TestFlatIndexIsExact(t *testing.T) {
+	// 暴力索引的召回率应为 1，可用于校验 HNSW 结果
+	ds := GenerateDataset(500, 10, 8, 2)
+	truth := ComputeGroundTruth(ds.Base, ds.Queries, 5, hnsw.L2{})
+	flat := index.NewFlatIndex(8, hnsw.L2{}, 0)
+	for i, v := range ds.Base {
+		flat.Add(strconv.Itoa(i), v)
+	}
+	found, _ := SearchAll(flat, ds.Queries, 5)
+	if r := Recall(found, truth, 5); r < 0.999 {
+		t.Errorf("Expected exact recall from flat index, got %f", r)
+	}
+}
@@ -0,0 +1,143 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// Dataset 为基准测试数据集，GroundTruth[i] 为第 i 个查询的精确近邻在 Base 中的下标
+type Dataset struct {
+	Base        [][]float32
+	Queries     [][]float32
+	GroundTruth [][]int
+}
+
+// Dim 返回数据集向量维度
+func (ds *Dataset) Dim() int {
+	if len(ds.Base) == 0 {
+		return 0
+	}
+	return len(ds.Base[0])
+}
+
+// GenerateDataset 生成 [-1, 1) 均匀分布的随机数据集，不含真值
+func GenerateDataset(n, nq, dim int, seed int64) *Dataset {
+	rng := rand.New(rand.NewSource(seed))
+	gen := func(count int) [][]float32 {
+		vectors := make([][]float32, count)
+		for i := range vectors {
+			v := make([]float32, dim)
+			for j := range v {
+				v[j] = rng.Float32()*2 - 1
+			}
+			vectors[i] = v
+		}
+		return vectors
+	}
+	return &Dataset{Base: gen(n), Queries: gen(nq)}
+}
+
+// ReadFvecs 读取 fvecs 格式文件（每条记录为 int32 维度加 float32 分量），limit<=0 时读取全部
+func ReadFvecs(path string, limit int) ([][]float32, error) {
+	var vectors [][]float32
+	err := readVecs(path, limit, func(raw []uint32) {
+		v := make([]float32, len(raw))
+		for i, bits := range raw {
+			v[i] = math.Float32frombits(bits)
+		}
+		vectors = append(vectors, v)
+	})
+	return vectors, err
+}
+
+// ReadIvecs 读取 ivecs 格式文件（每条记录为 int32 维度加 int32 分量），limit<=0 时读取全部
+func ReadIvecs(path string, limit int) ([][]int, error) {
+	var vectors [][]int
+	err := readVecs(path, limit, func(raw []uint32) {
+		v := make([]int, len(raw))
+		for i, bits := range raw {
+			v[i] = int(int32(bits))
+		}
+		vectors = append(vectors, v)
+	})
+	return vectors, err
+}
+
+func readVecs(path string, limit int, fn func(raw []uint32)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for n := 0; limit <= 0 || n < limit; n++ {
+		var dim int32
+		if err := binary.Read(r, binary.LittleEndian, &dim); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if dim <= 0 {
+			return fmt.Errorf("%s: invalid dimension %d in record %d", path, dim, n)
+		}
+		raw := make([]uint32, dim)
+		if err := binary.Read(r, binary.LittleEndian, raw); err != nil {
+			return fmt.Errorf("%s: truncated record %d: %v", path, n, err)
+		}
+		fn(raw)
+	}
+	return nil
+}
+
+// WriteFvecs 以 fvecs 格式写出向量
+func WriteFvecs(path string, vectors [][]float32) error {
+	return writeVecs(path, len(vectors), func(i int) []uint32 {
+		raw := make([]uint32, len(vectors[i]))
+		for j, x := range vectors[i] {
+			raw[j] = math.Float32bits(x)
+		}
+		return raw
+	})
+}
+
+// WriteIvecs 以 ivecs 格式写出整数向量
+func WriteIvecs(path string, vectors [][]int) error {
+	return writeVecs(path, len(vectors), func(i int) []uint32 {
+		raw := make([]uint32, len(vectors[i]))
+		for j, x := range vectors[i] {
+			raw[j] = uint32(int32(x))
+		}
+		return raw
+	})
+}
+
+func writeVecs(path string, n int, record func(i int) []uint32) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for i := 0; i < n; i++ {
+		raw := record(i)
+		if err := binary.Write(w, binary.LittleEndian, int32(len(raw))); err != nil {
+			f.Close()
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, raw); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
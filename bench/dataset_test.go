@@ -0,0 +1,60 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFvecsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	ds := GenerateDataset(10, 0, 4, 1)
+	path := filepath.Join(dir, "base.fvecs")
+	if err := WriteFvecs(path, ds.Base); err != nil {
+		t.Fatalf("WriteFvecs failed: %v", err)
+	}
+
+	got, err := ReadFvecs(path, 0)
+	if err != nil {
+		t.Fatalf("ReadFvecs failed: %v", err)
+	}
+	if len(got) != 10 || len(got[0]) != 4 {
+		t.Fatalf("Unexpected shape %dx%d", len(got), len(got[0]))
+	}
+	if !reflect.DeepEqual(got, ds.Base) {
+		t.Errorf("Vectors changed after round trip")
+	}
+
+	// 限制读取条数
+	got, err = ReadFvecs(path, 3)
+	if err != nil || len(got) != 3 {
+		t.Errorf("Expected 3 vectors with limit, got %d (%v)", len(got), err)
+	}
+}
+
+func TestIvecsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gt.ivecs")
+	want := [][]int{{1, 2, 3}, {4, 5, 6}}
+	if err := WriteIvecs(path, want); err != nil {
+		t.Fatalf("WriteIvecs failed: %v", err)
+	}
+	got, err := ReadIvecs(path, 0)
+	if err != nil {
+		t.Fatalf("ReadIvecs failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestReadVecsTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.fvecs")
+	// 声明 4 维但只有 1 个分量
+	if err := os.WriteFile(path, []byte{4, 0, 0, 0, 0, 0, 128, 63}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFvecs(path, 0); err == nil {
+		t.Error("Expected error for truncated record")
+	}
+}
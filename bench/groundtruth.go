@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+
+	"gvdb/hnsw"
+)
+
+// ComputeGroundTruth 用暴力搜索计算每个查询的前 k 个精确近邻下标，按查询并行
+func ComputeGroundTruth(base, queries [][]float32, k int, metric hnsw.Metric) [][]int {
+	truth := make([][]int, len(queries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for qi := range jobs {
+				truth[qi] = exactNeighbors(base, queries[qi], k, metric)
+			}
+		}()
+	}
+	for qi := range queries {
+		jobs <- qi
+	}
+	close(jobs)
+	wg.Wait()
+	return truth
+}
+
+func exactNeighbors(base [][]float32, query []float32, k int, metric hnsw.Metric) []int {
+	type scored struct {
+		i     int
+		score float32
+	}
+	all := make([]scored, len(base))
+	for i, v := range base {
+		all[i] = scored{i: i, score: metric.Score(query, v)}
+	}
+	sort.Slice(all, func(a, b int) bool { return hnsw.Better(metric, all[a].score, all[b].score) })
+	if k > len(all) {
+		k = len(all)
+	}
+	ids := make([]int, k)
+	for i := range ids {
+		ids[i] = all[i].i
+	}
+	return ids
+}
+
+// Recall 计算 recall@k：返回结果中属于前 k 个真值的比例
+func Recall(results [][]int, truth [][]int, k int) float64 {
+	hits, total := 0, 0
+	for qi, res := range results {
+		if qi >= len(truth) {
+			break
+		}
+		gt := truth[qi]
+		if len(gt) > k {
+			gt = gt[:k]
+		}
+		set := make(map[int]bool, len(gt))
+		for _, id := range gt {
+			set[id] = true
+		}
+		for i, id := range res {
+			if i >= k {
+				break
+			}
+			if set[id] {
+				hits++
+			}
+		}
+		total += len(gt)
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
@@ -0,0 +1,26 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func TestComputeGroundTruth(t *testing.T) {
+	base := [][]float32{{0, 0}, {1, 1}, {5, 5}, {2, 2}}
+	queries := [][]float32{{0.9, 0.9}}
+
+	truth := ComputeGroundTruth(base, queries, 3, hnsw.L2{})
+	if want := [][]int{{1, 0, 3}}; !reflect.DeepEqual(truth, want) {
+		t.Errorf("Expected %v, got %v", want, truth)
+	}
+}
+
+func TestRecall(t *testing.T) {
+	truth := [][]int{{1, 2}, {3, 4}}
+	results := [][]int{{2, 1}, {3, 9}}
+	if got := Recall(results, truth, 2); got != 0.75 {
+		t.Errorf("Expected recall 0.75, got %f", got)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gvdb/config"
+	"gvdb/vectordb"
+)
+
+// stringList 为可重复的字符串参数
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// options 为各子命令共用的参数
+type options struct {
+	config     string
+	overrides  stringList
+	collection string
+	format     string
+}
+
+// newFlagSet 创建子命令的参数集并注册共用参数；collection 为 -collection 的默认值，为空表示所有集合
+func newFlagSet(name, collection string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	o := &options{}
+	fs.StringVar(&o.config, "config", "config.yaml", "path to the configuration file")
+	fs.Var(&o.overrides, "set", "override a config value, e.g. -set storage.type=duckdb (repeatable)")
+	usage := "collection name"
+	if collection == "" {
+		usage = "collection name (all collections when empty)"
+	}
+	fs.StringVar(&o.collection, "collection", collection, usage)
+	fs.StringVar(&o.format, "format", "human", "output format: human or json")
+	return fs, o
+}
+
+func (o *options) loadConfig() (config.Config, error) {
+	if o.format != "human" && o.format != "json" {
+		return config.Config{}, fmt.Errorf("unknown output format: %s", o.format)
+	}
+	return config.LoadConfigOverride(o.config, o.overrides)
+}
+
+func (o *options) open() (*vectordb.VectorDB, error) {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return vectordb.NewVectorDB(cfg)
+}
+
+// collections 返回 -collection 指定的集合，未指定时返回所有集合
+func (o *options) collections(db *vectordb.VectorDB) ([]*vectordb.Collection, error) {
+	names := db.ListCollections()
+	if o.collection != "" {
+		names = []string{o.collection}
+	}
+	out := make([]*vectordb.Collection, 0, len(names))
+	for _, name := range names {
+		c, err := db.GetCollection(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// output 以 JSON 输出 v，或调用 human 输出表格形式
+func (o *options) output(v interface{}, human func(w *tabwriter.Writer)) error {
+	if o.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	human(w)
+	return w.Flush()
+}
+
+// openOutput 打开输出文件，"-" 或空表示标准输出
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// openInput 打开输入文件，"-" 表示标准输入
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// closeDB 关闭数据库，保留先发生的错误
+func closeDB(db *vectordb.VectorDB, err *error) {
+	if closeErr := db.Close(); *err == nil {
+		*err = closeErr
+	}
+}
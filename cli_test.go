@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportExport(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	config := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "` + filepath.Join(dir, "vectors.json") + `"
+hnsw:
+  dim: 2
+  metric: "l2"
+catalog: "` + filepath.Join(dir, "collections.yaml") + `"
+`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := `{"id": "b", "vector": [0, 1], "payload": {"lang": "go"}}
+{"id": "a", "vector": [1, 0], "meta": "plain"}
+`
+	inPath := filepath.Join(dir, "in.jsonl")
+	if err := os.WriteFile(inPath, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runImport([]string{"-config", configPath, "-batch", "1", "-format", "json", inPath}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	outPath := filepath.Join(dir, "out.jsonl")
+	if err := runExport([]string{"-config", configPath, "-o", outPath}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	got, _ := os.ReadFile(outPath)
+	want := `{"id":"a","vector":[1,0],"meta":"plain","payload":{"meta":"plain"}}
+{"id":"b","vector":[0,1],"payload":{"lang":"go"}}
+`
+	if string(got) != want {
+		t.Errorf("Unexpected export:\n%s\nwant:\n%s", got, want)
+	}
+
+	// 以 .npy 加旁路文件导出，删除后重新导入
+	npyPath := filepath.Join(dir, "out.npy")
+	if err := runExport([]string{"-config", configPath, "-o", npyPath}); err != nil {
+		t.Fatalf("export npy: %v", err)
+	}
+	if err := runDelete([]string{"-config", configPath, "-format", "json", "a"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := runImport([]string{"-config", configPath, "-format", "json", "-resume", npyPath}); err != nil {
+		t.Fatalf("import npy: %v", err)
+	}
+	if err := runGet([]string{"-config", configPath, "-format", "json", "a"}); err != nil {
+		t.Errorf("get after npy import: %v", err)
+	}
+
+	// -set 覆盖配置中的维度后，已有数据的维度不一致
+	if err := runImport([]string{"-config", configPath, "-set", "hnsw.dim=3", inPath}); err == nil {
+		t.Error("Expected dimension mismatch after overriding hnsw.dim")
+	}
+	if err := runDelete([]string{"-config", configPath, "-format", "json", "a", "missing"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := runGet([]string{"-config", configPath, "-format", "json", "a"}); err == nil {
+		t.Error("Expected get of a deleted point to fail")
+	}
+}
+
+func TestParseVector(t *testing.T) {
+	v, err := parseVector("0.5, 1,-2")
+	if err != nil || !reflect.DeepEqual(v, []float32{0.5, 1, -2}) {
+		t.Errorf("parseVector: %v, %v", v, err)
+	}
+	if _, err := parseVector("1,x"); err == nil {
+		t.Error("Expected error for invalid component")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	source := write("source.yaml", `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "`+filepath.Join(dir, "vectors.json")+`"
+hnsw:
+  dim: 2
+catalog: "`+filepath.Join(dir, "collections.yaml")+`"
+`)
+	target := write("target.yaml", `
+storage:
+  type: "duckdb"
+  duckdb:
+    enable: true
+    path: "`+filepath.Join(dir, "vectors.db")+`"
+hnsw:
+  dim: 2
+catalog: "`+filepath.Join(dir, "restored.yaml")+`"
+`)
+	points := write("in.jsonl", `{"id": "a", "vector": [1, 0], "payload": {"lang": "go"}}
+{"id": "b", "vector": [0, 1]}
+`)
+	if err := runImport([]string{"-config", source, "-format", "json", points}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	backup := filepath.Join(dir, "backup.zip")
+	if err := runSnapshot([]string{"-config", source, "-o", backup, "-format", "json"}); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := runRestore([]string{"-config", target, "-format", "json", backup}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if err := runGet([]string{"-config", target, "-format", "json", "a"}); err != nil {
+		t.Errorf("get after restore: %v", err)
+	}
+	if err := runRestore([]string{"-config", target, backup}); err == nil {
+		t.Error("Expected restore into a non-empty database to fail without -overwrite")
+	}
+	if err := runRestore([]string{"-config", target, "-overwrite", "-collection", "default", backup}); err != nil {
+		t.Errorf("restore -overwrite: %v", err)
+	}
+	if err := runSnapshot([]string{"-config", source}); err == nil {
+		t.Error("Expected snapshot without -o to fail")
+	}
+}
+
+func TestMigrateStorage(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	config := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "` + filepath.Join(dir, "vectors.json") + `"
+hnsw:
+  dim: 2
+catalog: "` + filepath.Join(dir, "collections.yaml") + `"
+`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inPath := filepath.Join(dir, "in.jsonl")
+	if err := os.WriteFile(inPath, []byte(`{"id": "a", "vector": [1, 0]}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runImport([]string{"-config", configPath, "-format", "json", inPath}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	target := []string{"-to-set", "storage.type=duckdb", "-to-set", "storage.duckdb.enable=true", "-to-set", "storage.duckdb.path=" + filepath.Join(dir, "vectors.db")}
+	checkpoint := filepath.Join(dir, "migrate.checkpoint")
+	args := append([]string{"-config", configPath, "-checkpoint", checkpoint, "-format", "json"}, target...)
+	if err := runMigrateStorage(append(args, "-dry-run")); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if err := runMigrateStorage(args); err != nil {
+		t.Fatalf("migrate-storage: %v", err)
+	}
+	get := []string{"-config", configPath, "-format", "json", "-set", "storage.type=duckdb", "-set", "storage.duckdb.enable=true", "-set", "storage.duckdb.path=" + filepath.Join(dir, "vectors.db"), "a"}
+	if err := runGet(get); err != nil {
+		t.Errorf("get from the target: %v", err)
+	}
+	if err := runMigrateStorage([]string{"-config", configPath}); err == nil {
+		t.Error("Expected migrating a storage onto itself to fail")
+	}
+}
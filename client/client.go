@@ -0,0 +1,196 @@
+// Package client 提供与 vectordb.VectorDB 对应的方法，可以嵌入进程内使用（Open），
+// 也可以通过 REST 接口访问远程的 gvdb serve 进程（Dial），两种模式下调用代码相同。
+//
+// 所有方法都接收 context 用于超时和取消；返回的错误为 *service.Error，用 service.CodeOf 获取类别
+package client
+
+import (
+	"context"
+
+	"gvdb/config"
+	"gvdb/filter"
+	"gvdb/service"
+	"gvdb/storage"
+	"gvdb/vector"
+	"gvdb/vectordb"
+)
+
+// backend 为嵌入模式和远程模式的传输层，参数和返回值使用 service 层的类型
+type backend interface {
+	listCollections(ctx context.Context) ([]string, error)
+	createCollection(ctx context.Context, cc config.CollectionConfig) (service.CollectionInfo, error)
+	describeCollection(ctx context.Context, name string) (service.CollectionInfo, error)
+	dropCollection(ctx context.Context, name string) error
+	upsert(ctx context.Context, collection string, points []service.Point) (int, error)
+	get(ctx context.Context, collection, id string) (service.Point, error)
+	delete(ctx context.Context, collection string, ids []string) (int, error)
+	search(ctx context.Context, collection string, req service.SearchRequest) ([]service.Hit, error)
+	query(ctx context.Context, collection string, req service.QueryRequest) ([]service.Hit, error)
+	close() error
+}
+
+// DB 与 vectordb.VectorDB 一样内嵌默认集合，其他集合通过 GetCollection 获取
+type DB struct {
+	*Collection
+	b backend
+}
+
+// Open 在进程内打开数据库，cfg 需由 config.LoadConfig 读取；Close 时关闭数据库
+func Open(cfg config.Config) (*DB, error) {
+	db, err := vectordb.NewVectorDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newDB(&embedded{db: db, svc: service.New(db)}), nil
+}
+
+// Dial 返回访问远程 gvdb serve 进程的客户端，baseURL 形如 http://localhost:8080；不会立即建立连接
+func Dial(baseURL string, opts Options) *DB {
+	return newDB(newRemote(baseURL, opts))
+}
+
+func newDB(b backend) *DB {
+	return &DB{Collection: &Collection{name: config.DefaultCollection, b: b}, b: b}
+}
+
+// GetCollection 返回指定集合的句柄，集合不存在时返回 NotFound 错误
+func (db *DB) GetCollection(ctx context.Context, name string) (*Collection, error) {
+	if _, err := db.b.describeCollection(ctx, name); err != nil {
+		return nil, err
+	}
+	return &Collection{name: name, b: db.b}, nil
+}
+
+func (db *DB) CreateCollection(ctx context.Context, cc config.CollectionConfig) (vectordb.CollectionInfo, error) {
+	info, err := db.b.createCollection(ctx, cc)
+	return collectionInfo(info), err
+}
+
+func (db *DB) DropCollection(ctx context.Context, name string) error {
+	return db.b.dropCollection(ctx, name)
+}
+
+func (db *DB) ListCollections(ctx context.Context) ([]string, error) {
+	return db.b.listCollections(ctx)
+}
+
+func (db *DB) DescribeCollection(ctx context.Context, name string) (vectordb.CollectionInfo, error) {
+	info, err := db.b.describeCollection(ctx, name)
+	return collectionInfo(info), err
+}
+
+// Close 在嵌入模式下关闭数据库，在远程模式下关闭空闲连接
+func (db *DB) Close() error {
+	return db.b.close()
+}
+
+// Collection 是一个集合的句柄，方法与 vectordb.Collection 对应
+type Collection struct {
+	name string
+	b    backend
+}
+
+func (c *Collection) Name() string { return c.name }
+
+// InsertFromModel 接收模型输出的 float64 向量，Payload 由 meta 生成
+func (c *Collection) InsertFromModel(ctx context.Context, id string, embedding []float64, meta string) error {
+	return c.InsertVector(ctx, id, vector.FromFloat64(embedding), meta)
+}
+
+func (c *Collection) InsertVector(ctx context.Context, id string, vec []float32, meta string) error {
+	return c.InsertDoc(ctx, id, storage.VectorDoc{Vector: vec, Meta: meta})
+}
+
+func (c *Collection) InsertFromModelPayload(ctx context.Context, id string, embedding []float64, payload storage.Payload) error {
+	return c.InsertVectorPayload(ctx, id, vector.FromFloat64(embedding), payload)
+}
+
+func (c *Collection) InsertVectorPayload(ctx context.Context, id string, vec []float32, payload storage.Payload) error {
+	return c.InsertDoc(ctx, id, storage.VectorDoc{Vector: vec, Payload: payload})
+}
+
+// InsertDoc 写入或覆盖文档，Payload 为空时由 Meta 生成
+func (c *Collection) InsertDoc(ctx context.Context, id string, doc storage.VectorDoc) error {
+	_, err := c.b.upsert(ctx, c.name, []service.Point{{ID: id, Vector: doc.Vector, Meta: doc.Meta, Payload: doc.Payload}})
+	return err
+}
+
+// Get 返回文档，不存在时返回 NotFound 错误
+func (c *Collection) Get(ctx context.Context, id string) (storage.VectorDoc, error) {
+	p, err := c.b.get(ctx, c.name, id)
+	if err != nil {
+		return storage.VectorDoc{}, err
+	}
+	return storage.VectorDoc{Vector: p.Vector, Meta: p.Meta, Payload: p.Payload}, nil
+}
+
+// Delete 删除文档，文档不存在时不报错
+func (c *Collection) Delete(ctx context.Context, id string) error {
+	_, err := c.b.delete(ctx, c.name, []string{id})
+	return err
+}
+
+func (c *Collection) SearchFromModel(ctx context.Context, queryEmbedding []float64, limit int) ([]vectordb.SearchResult, error) {
+	return c.SearchVectorFilter(ctx, vector.FromFloat64(queryEmbedding), limit, nil)
+}
+
+func (c *Collection) SearchVector(ctx context.Context, query []float32, limit int) ([]vectordb.SearchResult, error) {
+	return c.SearchVectorFilter(ctx, query, limit, nil)
+}
+
+func (c *Collection) SearchFromModelFilter(ctx context.Context, queryEmbedding []float64, limit int, f filter.Filter) ([]vectordb.SearchResult, error) {
+	return c.SearchVectorFilter(ctx, vector.FromFloat64(queryEmbedding), limit, f)
+}
+
+// SearchVectorFilter 只返回元数据匹配 f 的结果，f 为 nil 时不过滤
+func (c *Collection) SearchVectorFilter(ctx context.Context, query []float32, limit int, f filter.Filter) ([]vectordb.SearchResult, error) {
+	raw, err := encodeFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := c.b.search(ctx, c.name, service.SearchRequest{Vector: query, Limit: limit, Filter: raw})
+	return results(hits), err
+}
+
+// Query 按 ID 顺序返回元数据匹配 f 的文档，limit 为 0 时最多返回 service.MaxLimit 条
+func (c *Collection) Query(ctx context.Context, f filter.Filter, limit int) ([]vectordb.SearchResult, error) {
+	raw, err := encodeFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := c.b.query(ctx, c.name, service.QueryRequest{Filter: raw, Limit: limit})
+	return results(hits), err
+}
+
+func encodeFilter(f filter.Filter) ([]byte, error) {
+	if f == nil {
+		return nil, nil
+	}
+	data, err := filter.Marshal(f)
+	if err != nil {
+		return nil, &service.Error{Code: service.InvalidArgument, Message: "invalid filter: " + err.Error(), Err: err}
+	}
+	return data, nil
+}
+
+func results(hits []service.Hit) []vectordb.SearchResult {
+	if hits == nil {
+		return nil
+	}
+	out := make([]vectordb.SearchResult, len(hits))
+	for i, h := range hits {
+		out[i] = vectordb.SearchResult{ID: h.ID, Score: h.Score, Meta: h.Meta, Payload: h.Payload}
+	}
+	return out
+}
+
+func collectionInfo(info service.CollectionInfo) vectordb.CollectionInfo {
+	return vectordb.CollectionInfo{
+		Name:      info.Name,
+		Dim:       info.Dim,
+		Metric:    info.Metric,
+		IndexType: info.IndexType,
+		Count:     info.Count,
+		Config:    info.Config,
+	}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvdb/config"
+	"gvdb/filter"
+	"gvdb/server"
+	"gvdb/service"
+	"gvdb/storage"
+	"gvdb/vectordb"
+)
+
+func testConfig(t *testing.T) config.Config {
+	dir := t.TempDir()
+	var cfg config.Config
+	cfg.Storage.Type = "file"
+	cfg.Storage.File.Enable = true
+	cfg.Storage.File.Path = filepath.Join(dir, "vectors.json")
+	cfg.HNSW = config.HNSWConfig{Dim: 3, M: 16, EF: 64, EFConstruction: 64, EFSearch: 64, Metric: "l2"}
+	cfg.Index.Type = "hnsw"
+	cfg.Search.BruteForceRatio = 0.01
+	cfg.Catalog = filepath.Join(dir, "collections.yaml")
+	return cfg
+}
+
+// openBoth 返回嵌入模式和通过 httptest 访问的远程模式客户端，两者使用各自独立的数据库
+func openBoth(t *testing.T) map[string]*DB {
+	embeddedDB, err := Open(testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { embeddedDB.Close() })
+
+	vdb, err := vectordb.NewVectorDB(testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.New(service.New(vdb)))
+	remoteDB := Dial(ts.URL, Options{})
+	t.Cleanup(func() {
+		remoteDB.Close()
+		ts.Close()
+		vdb.Close()
+	})
+	return map[string]*DB{"embedded": embeddedDB, "remote": remoteDB}
+}
+
+func TestClient(t *testing.T) {
+	for mode, db := range openBoth(t) {
+		t.Run(mode, func(t *testing.T) {
+			ctx := context.Background()
+			if err := db.InsertFromModel(ctx, "doc1", []float64{1, 0, 0}, "Hello world"); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.InsertVectorPayload(ctx, "doc2", []float32{0, 1, 0}, storage.Payload{"lang": "go", "year": 2024}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.InsertVectorPayload(ctx, "a/b", []float32{0, 0, 1}, storage.Payload{"lang": "rust"}); err != nil {
+				t.Fatal(err)
+			}
+
+			doc, err := db.Get(ctx, "doc1")
+			if err != nil || doc.Meta != "Hello world" || doc.Payload["meta"] != "Hello world" {
+				t.Errorf("Get doc1: %+v, %v", doc, err)
+			}
+			if doc, err := db.Get(ctx, "a/b"); err != nil || doc.Payload["lang"] != "rust" {
+				t.Errorf("Get a/b: %+v, %v", doc, err)
+			}
+			if _, err := db.Get(ctx, "missing"); service.CodeOf(err) != service.NotFound {
+				t.Errorf("Expected NotFound, got %v", err)
+			}
+
+			results, err := db.SearchFromModel(ctx, []float64{0, 1, 0}, 1)
+			if err != nil || len(results) != 1 || results[0].ID != "doc2" || fmt.Sprint(results[0].Payload["year"]) != "2024" {
+				t.Errorf("SearchFromModel: %+v, %v", results, err)
+			}
+			results, err = db.SearchVectorFilter(ctx, []float32{0, 1, 0}, 3, filter.In{Field: "lang", Values: []interface{}{"rust"}})
+			if err != nil || len(results) != 1 || results[0].ID != "a/b" {
+				t.Errorf("SearchVectorFilter: %+v, %v", results, err)
+			}
+			results, err = db.Query(ctx, filter.Gte("year", 2020), 0)
+			if err != nil || len(results) != 1 || results[0].ID != "doc2" {
+				t.Errorf("Query: %+v, %v", results, err)
+			}
+			if _, err := db.SearchVector(ctx, []float32{1, 0}, 1); service.CodeOf(err) != service.InvalidArgument {
+				t.Errorf("Expected InvalidArgument for wrong dimension, got %v", err)
+			}
+
+			if err := db.Delete(ctx, "doc1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.Get(ctx, "doc1"); service.CodeOf(err) != service.NotFound {
+				t.Errorf("Expected NotFound after delete, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClientCollections(t *testing.T) {
+	for mode, db := range openBoth(t) {
+		t.Run(mode, func(t *testing.T) {
+			ctx := context.Background()
+			cc := config.CollectionConfig{Name: "images", HNSW: config.HNSWConfig{Dim: 2, Metric: "cosine"}}
+			info, err := db.CreateCollection(ctx, cc)
+			if err != nil || info.Dim != 2 || info.Config.HNSW.M != 16 {
+				t.Fatalf("CreateCollection: %+v, %v", info, err)
+			}
+			if _, err := db.CreateCollection(ctx, cc); service.CodeOf(err) != service.AlreadyExists {
+				t.Errorf("Expected AlreadyExists, got %v", err)
+			}
+			names, err := db.ListCollections(ctx)
+			if err != nil || strings.Join(names, ",") != "default,images" {
+				t.Errorf("ListCollections: %v, %v", names, err)
+			}
+
+			images, err := db.GetCollection(ctx, "images")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := images.InsertVector(ctx, "x", []float32{1, 1}, ""); err != nil {
+				t.Fatal(err)
+			}
+			if info, err := db.DescribeCollection(ctx, "images"); err != nil || info.Count != 1 || info.Metric != "cosine" {
+				t.Errorf("DescribeCollection: %+v, %v", info, err)
+			}
+
+			if err := db.DropCollection(ctx, "images"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.GetCollection(ctx, "images"); service.CodeOf(err) != service.NotFound {
+				t.Errorf("Expected NotFound after drop, got %v", err)
+			}
+			if err := db.DropCollection(ctx, "default"); service.CodeOf(err) != service.FailedPrecondition {
+				t.Errorf("Expected FailedPrecondition, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedContext(t *testing.T) {
+	db, err := Open(testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = db.InsertVector(ctx, "a", []float32{1, 0, 0}, "")
+	if !errors.Is(err, context.Canceled) || service.CodeOf(err) != service.Unavailable {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+
+	"gvdb/config"
+	"gvdb/service"
+	"gvdb/vectordb"
+)
+
+// embedded 在进程内直接调用 service 层，校验规则和错误与远程模式相同
+type embedded struct {
+	db  *vectordb.VectorDB
+	svc *service.Service
+}
+
+// checkContext 在调用前检查 ctx；进程内的调用一旦开始不会被中断
+func checkContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &service.Error{Code: service.Unavailable, Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+func (e *embedded) listCollections(ctx context.Context) ([]string, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	return e.svc.ListCollections(), nil
+}
+
+func (e *embedded) createCollection(ctx context.Context, cc config.CollectionConfig) (service.CollectionInfo, error) {
+	if err := checkContext(ctx); err != nil {
+		return service.CollectionInfo{}, err
+	}
+	return e.svc.CreateCollection(cc)
+}
+
+func (e *embedded) describeCollection(ctx context.Context, name string) (service.CollectionInfo, error) {
+	if err := checkContext(ctx); err != nil {
+		return service.CollectionInfo{}, err
+	}
+	return e.svc.DescribeCollection(name)
+}
+
+func (e *embedded) dropCollection(ctx context.Context, name string) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	return e.svc.DropCollection(name)
+}
+
+func (e *embedded) upsert(ctx context.Context, collection string, points []service.Point) (int, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	return e.svc.Upsert(collection, points)
+}
+
+func (e *embedded) get(ctx context.Context, collection, id string) (service.Point, error) {
+	if err := checkContext(ctx); err != nil {
+		return service.Point{}, err
+	}
+	return e.svc.Get(collection, id)
+}
+
+func (e *embedded) delete(ctx context.Context, collection string, ids []string) (int, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	return e.svc.Delete(collection, ids)
+}
+
+func (e *embedded) search(ctx context.Context, collection string, req service.SearchRequest) ([]service.Hit, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	return e.svc.Search(collection, req)
+}
+
+func (e *embedded) query(ctx context.Context, collection string, req service.QueryRequest) ([]service.Hit, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	return e.svc.Query(collection, req)
+}
+
+func (e *embedded) close() error {
+	return e.db.Close()
+}
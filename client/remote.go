@@ -0,0 +1,240 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gvdb/config"
+	"gvdb/service"
+)
+
+// Options 为远程客户端的参数，零值使用默认值
+type Options struct {
+	Timeout    time.Duration // 单次请求的超时时间，默认 30s；整体超时由 ctx 控制
+	Retries    int           // 可重试错误的最大重试次数，默认 3，小于 0 表示不重试
+	MinBackoff time.Duration // 第一次重试前的等待时间，之后每次加倍，默认 100ms
+	MaxBackoff time.Duration // 重试等待时间的上限，默认 2s
+	MaxConns   int           // 连接池中保留的空闲连接数，默认 16
+}
+
+func (o *Options) normalize() {
+	if o.Timeout == 0 {
+		o.Timeout = 30 * time.Second
+	}
+	if o.Retries == 0 {
+		o.Retries = 3
+	}
+	if o.MinBackoff == 0 {
+		o.MinBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = 2 * time.Second
+	}
+	if o.MaxConns == 0 {
+		o.MaxConns = 16
+	}
+}
+
+// remote 通过 REST 接口（doc/rest-api.md）访问 gvdb serve
+type remote struct {
+	baseURL string
+	opts    Options
+	http    *http.Client
+}
+
+func newRemote(baseURL string, opts Options) *remote {
+	opts.normalize()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.MaxConns
+	transport.MaxIdleConnsPerHost = opts.MaxConns
+	return &remote{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		opts:    opts,
+		http:    &http.Client{Transport: transport},
+	}
+}
+
+func (r *remote) listCollections(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Collections []string `json:"collections"`
+	}
+	err := r.do(ctx, "GET", "/collections", nil, &resp, true)
+	return resp.Collections, err
+}
+
+// createCollection 不自动重试，避免第一次请求已生效时重试返回 AlreadyExists
+func (r *remote) createCollection(ctx context.Context, cc config.CollectionConfig) (service.CollectionInfo, error) {
+	var info service.CollectionInfo
+	err := r.do(ctx, "POST", "/collections", cc, &info, false)
+	return info, err
+}
+
+func (r *remote) describeCollection(ctx context.Context, name string) (service.CollectionInfo, error) {
+	var info service.CollectionInfo
+	err := r.do(ctx, "GET", collectionPath(name), nil, &info, true)
+	return info, err
+}
+
+func (r *remote) dropCollection(ctx context.Context, name string) error {
+	return r.do(ctx, "DELETE", collectionPath(name), nil, nil, false)
+}
+
+func (r *remote) upsert(ctx context.Context, collection string, points []service.Point) (int, error) {
+	var resp struct {
+		Upserted int `json:"upserted"`
+	}
+	err := r.do(ctx, "PUT", collectionPath(collection)+"/points", map[string][]service.Point{"points": points}, &resp, true)
+	return resp.Upserted, err
+}
+
+func (r *remote) get(ctx context.Context, collection, id string) (service.Point, error) {
+	var p service.Point
+	err := r.do(ctx, "GET", collectionPath(collection)+"/points/"+url.PathEscape(id), nil, &p, true)
+	return p, err
+}
+
+func (r *remote) delete(ctx context.Context, collection string, ids []string) (int, error) {
+	var resp struct {
+		Deleted int `json:"deleted"`
+	}
+	err := r.do(ctx, "POST", collectionPath(collection)+"/points/delete", map[string][]string{"ids": ids}, &resp, true)
+	return resp.Deleted, err
+}
+
+func (r *remote) search(ctx context.Context, collection string, req service.SearchRequest) ([]service.Hit, error) {
+	var resp struct {
+		Hits []service.Hit `json:"hits"`
+	}
+	err := r.do(ctx, "POST", collectionPath(collection)+"/search", req, &resp, true)
+	return resp.Hits, err
+}
+
+func (r *remote) query(ctx context.Context, collection string, req service.QueryRequest) ([]service.Hit, error) {
+	var resp struct {
+		Hits []service.Hit `json:"hits"`
+	}
+	err := r.do(ctx, "POST", collectionPath(collection)+"/query", req, &resp, true)
+	return resp.Hits, err
+}
+
+func (r *remote) close() error {
+	r.http.CloseIdleConnections()
+	return nil
+}
+
+func collectionPath(name string) string {
+	return "/collections/" + url.PathEscape(name)
+}
+
+// do 发送请求并把响应解码到 out。idempotent 的请求在连接失败、503 等可重试错误时按指数退避重试
+func (r *remote) do(ctx context.Context, method, path string, body, out interface{}, idempotent bool) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return &service.Error{Code: service.InvalidArgument, Message: err.Error(), Err: err}
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		retryable, err := r.attempt(ctx, method, path, data, out)
+		if err == nil {
+			return nil
+		}
+		if !idempotent || !retryable || attempt >= r.opts.Retries || ctx.Err() != nil {
+			return err
+		}
+		if waitErr := r.backoff(ctx, attempt); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// attempt 发送一次请求，返回失败时能否重试以及错误
+func (r *remote) attempt(ctx context.Context, method, path string, data []byte, out interface{}) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, body)
+	if err != nil {
+		return false, &service.Error{Code: service.InvalidArgument, Message: err.Error(), Err: err}
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		// 连接失败或单次请求超时可以重试；调用方的 ctx 结束后 backoff 会停止重试
+		return true, &service.Error{Code: service.Unavailable, Message: err.Error(), Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		e := responseError(resp)
+		return e.Code == service.Unavailable, e
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, &service.Error{Code: service.Internal, Message: "decoding response: " + err.Error(), Err: err}
+	}
+	return false, nil
+}
+
+// responseError 把错误响应 {"error": {"code", "message"}} 还原为服务层错误
+func responseError(resp *http.Response) *service.Error {
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+	code, ok := service.ParseCode(body.Error.Code)
+	if !ok {
+		// 不是 gvdb 的错误响应，例如代理返回的 502/503/504
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			code = service.Unavailable
+		case http.StatusNotFound:
+			code = service.NotFound
+		case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+			code = service.InvalidArgument
+		}
+	}
+	message := body.Error.Message
+	if message == "" {
+		message = fmt.Sprintf("unexpected status %s", resp.Status)
+	}
+	return &service.Error{Code: code, Message: message}
+}
+
+// backoff 等待第 attempt 次重试前的退避时间，带随机抖动；ctx 结束时返回错误
+func (r *remote) backoff(ctx context.Context, attempt int) error {
+	d := r.opts.MinBackoff << attempt
+	if d <= 0 || d > r.opts.MaxBackoff {
+		d = r.opts.MaxBackoff
+	}
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gvdb/config"
+	"gvdb/service"
+)
+
+// flakyServer 在前 failures 次请求返回 status，之后返回空的集合列表
+func flakyServer(t *testing.T, failures int32, status int, body string) (*httptest.Server, *int32) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"collections": ["default"]}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &calls
+}
+
+var fastRetry = Options{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+func TestRemoteRetry(t *testing.T) {
+	ts, calls := flakyServer(t, 2, http.StatusServiceUnavailable, `{"error": {"code": "unavailable", "message": "connection lost"}}`)
+	db := Dial(ts.URL, fastRetry)
+	names, err := db.ListCollections(context.Background())
+	if err != nil || len(names) != 1 || *calls != 3 {
+		t.Fatalf("ListCollections: %v, %v after %d calls", names, err, *calls)
+	}
+
+	// 重试次数用尽后返回最后一次的错误
+	ts, calls = flakyServer(t, 10, http.StatusBadGateway, "bad gateway")
+	opts := fastRetry
+	opts.Retries = 2
+	_, err = Dial(ts.URL, opts).ListCollections(context.Background())
+	if service.CodeOf(err) != service.Unavailable || *calls != 3 {
+		t.Errorf("Expected Unavailable after 3 calls, got %v after %d", err, *calls)
+	}
+}
+
+func TestRemoteNoRetry(t *testing.T) {
+	// 非可重试错误不重试
+	ts, calls := flakyServer(t, 1, http.StatusNotFound, `{"error": {"code": "not_found", "message": "collection not found: x"}}`)
+	_, err := Dial(ts.URL, fastRetry).DescribeCollection(context.Background(), "x")
+	var e *service.Error
+	if !errors.As(err, &e) || e.Code != service.NotFound || e.Message != "collection not found: x" || *calls != 1 {
+		t.Errorf("Expected NotFound without retry, got %v after %d calls", err, *calls)
+	}
+
+	// 创建集合不是幂等操作，不重试
+	ts, calls = flakyServer(t, 1, http.StatusServiceUnavailable, `{"error": {"code": "unavailable", "message": "connection lost"}}`)
+	cc := config.CollectionConfig{Name: "x", HNSW: config.HNSWConfig{Dim: 2}}
+	if _, err := Dial(ts.URL, fastRetry).CreateCollection(context.Background(), cc); service.CodeOf(err) != service.Unavailable || *calls != 1 {
+		t.Errorf("Expected Unavailable without retry, got %v after %d calls", err, *calls)
+	}
+
+	ts, calls = flakyServer(t, 10, http.StatusServiceUnavailable, "")
+	opts := fastRetry
+	opts.Retries = -1
+	if _, err := Dial(ts.URL, opts).ListCollections(context.Background()); err == nil || *calls != 1 {
+		t.Errorf("Expected a single call with retries disabled, got %v after %d calls", err, *calls)
+	}
+}
+
+func TestRemoteTimeout(t *testing.T) {
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := Dial(ts.URL, Options{MinBackoff: time.Second}).ListCollections(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || service.CodeOf(err) != service.Unavailable {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Request took %v, expected it to stop at the context deadline", elapsed)
+	}
+
+	// 单次请求超时后重试
+	_, err = Dial(ts.URL, Options{Timeout: 10 * time.Millisecond, Retries: 1, MinBackoff: time.Millisecond}).ListCollections(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected per-request timeout, got %v", err)
+	}
+}
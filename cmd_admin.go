@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gvdb/config"
+	"gvdb/service"
+	"gvdb/storage"
+	"gvdb/vectordb"
+)
+
+// collectionStats 为 stats 子命令输出的一个集合
+type collectionStats struct {
+	service.CollectionInfo
+	Storage    string           `json:"storage"`
+	IndexFiles map[string]int64 `json:"index_files,omitempty"` // 索引文件路径及大小（字节）
+}
+
+// runStats 执行 stats 子命令：输出集合的维度、度量、索引类型、文档数和索引文件大小
+func runStats(args []string) (err error) {
+	fs, o := newFlagSet("stats", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	db, err := vectordb.NewVectorDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+	collections, err := o.collections(db)
+	if err != nil {
+		return err
+	}
+
+	stats := make([]collectionStats, 0, len(collections))
+	for _, c := range collections {
+		info, err := service.New(db).DescribeCollection(c.Name())
+		if err != nil {
+			return err
+		}
+		s := collectionStats{CollectionInfo: info, Storage: cfg.Storage.Type, IndexFiles: make(map[string]int64)}
+		for _, path := range []string{info.Config.HNSW.IndexPath, info.Config.Index.Disk.Path} {
+			if fi, err := os.Stat(path); path != "" && err == nil {
+				s.IndexFiles[path] = fi.Size()
+			}
+		}
+		stats = append(stats, s)
+	}
+	return o.output(stats, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "COLLECTION\tDIM\tMETRIC\tINDEX\tSTORAGE\tCOUNT\tINDEX FILES")
+		for _, s := range stats {
+			var files []string
+			for _, path := range []string{s.Config.HNSW.IndexPath, s.Config.Index.Disk.Path} {
+				if size, ok := s.IndexFiles[path]; ok {
+					files = append(files, fmt.Sprintf("%s (%s)", path, humanBytes(size)))
+				}
+			}
+			if files == nil {
+				files = []string{"-"}
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%d\t%s\n", s.Name, s.Dim, s.Metric, s.IndexType, s.Storage, s.Count, strings.Join(files, ", "))
+		}
+	})
+}
+
+// maintenanceResult 为维护类子命令对一个集合的执行结果
+type maintenanceResult struct {
+	Collection string  `json:"collection"`
+	Documents  int     `json:"documents"`
+	Seconds    float64 `json:"seconds"`
+}
+
+// runMaintenance 对 -collection 指定的集合（默认全部）依次执行 op，op 返回处理的文档数
+func runMaintenance(name, verb string, args []string, op func(c *vectordb.Collection) (int, error)) (err error) {
+	fs, o := newFlagSet(name, "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+	collections, err := o.collections(db)
+	if err != nil {
+		return err
+	}
+
+	results := make([]maintenanceResult, 0, len(collections))
+	for _, c := range collections {
+		start := time.Now()
+		n, err := op(c)
+		if err != nil {
+			return fmt.Errorf("collection %s: %v", c.Name(), err)
+		}
+		results = append(results, maintenanceResult{Collection: c.Name(), Documents: n, Seconds: time.Since(start).Seconds()})
+	}
+	return o.output(results, func(w *tabwriter.Writer) {
+		for _, r := range results {
+			fmt.Fprintf(w, "%s %s: %d documents in %.2fs\n", verb, r.Collection, r.Documents, r.Seconds)
+		}
+	})
+}
+
+// runRebuildIndex 执行 rebuild-index 子命令：按当前配置从存储重建向量索引和二级索引，例如修改 hnsw.m 或量化方式之后
+func runRebuildIndex(args []string) error {
+	return runMaintenance("rebuild-index", "Rebuilt index of", args, func(c *vectordb.Collection) (int, error) {
+		if err := c.RebuildIndex(); err != nil {
+			return 0, err
+		}
+		return c.Info().Count, nil
+	})
+}
+
+// runCompact 执行 compact 子命令：回收存储空间（SQL 存储执行 VACUUM，文件存储重写为单个段）并合并磁盘索引的增量缓冲区
+func runCompact(args []string) error {
+	return runMaintenance("compact", "Compacted", args, func(c *vectordb.Collection) (int, error) {
+		if err := c.Compact(); err != nil {
+			return 0, err
+		}
+		return c.Info().Count, nil
+	})
+}
+
+// runMigrate 执行 migrate 子命令：把旧格式的文档（只有 Meta、JSON 编码的向量）按当前格式重写
+func runMigrate(args []string) error {
+	return runMaintenance("migrate", "Migrated", args, func(c *vectordb.Collection) (int, error) {
+		return c.Migrate()
+	})
+}
+
+// runMigrateStorage 执行 migrate-storage 子命令：把 -config 的存储中各集合的文档复制到 -to 和 -to-set 描述的存储，
+// 分批写入、记录进度并核对文档数和校验和
+func runMigrateStorage(args []string) error {
+	fs, o := newFlagSet("migrate-storage", "")
+	toPath := fs.String("to", "", "configuration file of the target (default: the -config file)")
+	var toOverrides stringList
+	fs.Var(&toOverrides, "to-set", "override a target config value, e.g. -to-set storage.type=duckdb (repeatable)")
+	batch := fs.Int("batch", 1000, "documents per batch")
+	dryRun := fs.Bool("dry-run", false, "read the source and report counts and checksums without writing")
+	checkpoint := fs.String("checkpoint", "migrate-storage.checkpoint", "progress file for -resume, empty to disable")
+	resume := fs.Bool("resume", false, "continue an interrupted migration from the checkpoint")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	from, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	if *toPath == "" {
+		*toPath = o.config
+	}
+	to, err := config.LoadConfigOverride(*toPath, toOverrides)
+	if err != nil {
+		return err
+	}
+
+	opts := vectordb.MigrateOptions{BatchSize: *batch, DryRun: *dryRun, Checkpoint: *checkpoint, Resume: *resume}
+	if o.collection != "" {
+		opts.Collections = []string{o.collection}
+	}
+	start := time.Now()
+	opts.Progress = func(collection string, p storage.MigrateProgress) {
+		fmt.Fprintf(os.Stderr, "%s: %d documents (%.0f/s)\n", collection, p.Count, float64(p.Count)/time.Since(start).Seconds())
+	}
+	results, err := vectordb.MigrateStorage(from, to, opts)
+	if err != nil {
+		return err
+	}
+	return o.output(results, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "COLLECTION\tFROM\tTO\tCOPIED\tCOUNT\tCHECKSUM")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%016x\n", r.Collection, r.From, r.To, r.Copied, r.Count, r.Checksum)
+		}
+		if *dryRun {
+			fmt.Fprintln(w, "Dry run: nothing was written")
+		} else {
+			fmt.Fprintf(w, "Migrated %d collections in %.1fs, counts and checksums verified\n", len(results), time.Since(start).Seconds())
+		}
+	})
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
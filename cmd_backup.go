@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"gvdb/vectordb"
+)
+
+// runSnapshot 执行 snapshot 子命令：通过 -server 从运行中的服务下载快照，或打开数据库生成快照。
+// 先写入临时文件，校验 manifest 后再重命名为输出文件
+func runSnapshot(args []string) (err error) {
+	fs, o := newFlagSet("snapshot", "")
+	outPath := fs.String("o", "", "output file (required)")
+	serverURL := fs.String("server", "", "download the snapshot from a running server at this URL, e.g. http://localhost:8080")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *outPath == "" {
+		return errors.New("-o is required")
+	}
+	if o.collection != "" {
+		return errors.New("a snapshot always contains all collections; use -collection with restore")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(*outPath), filepath.Base(*outPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if *serverURL != "" {
+		err = downloadSnapshot(strings.TrimSuffix(*serverURL, "/")+"/snapshot", tmp)
+	} else {
+		err = writeSnapshot(o, tmp)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	m, err := vectordb.ReadSnapshotManifest(tmp, info.Size())
+	if err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), *outPath); err != nil {
+		return err
+	}
+	return o.output(m, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Snapshot of %s storage written to %s (%s)\n", m.Storage, *outPath, humanBytes(info.Size()))
+		printSnapshotCollections(w, m)
+	})
+}
+
+func writeSnapshot(o *options, w io.Writer) (err error) {
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+	_, err = db.Snapshot(w)
+	return err
+}
+
+func downloadSnapshot(url string, w io.Writer) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("GET %s: %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// runRestore 执行 restore 子命令：把快照恢复到 -config 描述的数据库，目标的存储类型可以与源不同。
+// 目标数据库不能在运行
+func runRestore(args []string) error {
+	fs, o := newFlagSet("restore", "")
+	overwrite := fs.Bool("overwrite", false, "replace the documents of target collections that are not empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: gvdb restore [flags] SNAPSHOT")
+	}
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	opts := vectordb.RestoreOptions{Overwrite: *overwrite}
+	if o.collection != "" {
+		opts.Collections = []string{o.collection}
+	}
+	m, err := vectordb.Restore(cfg, f, info.Size(), opts)
+	if err != nil {
+		return err
+	}
+	return o.output(m, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Restored %s snapshot from %s into %s storage\n", m.Storage, m.Created.Local().Format("2006-01-02 15:04:05"), cfg.Storage.Type)
+		printSnapshotCollections(w, m)
+	})
+}
+
+func printSnapshotCollections(w *tabwriter.Writer, m vectordb.SnapshotManifest) {
+	fmt.Fprintln(w, "COLLECTION\tDIM\tMETRIC\tINDEX\tCOUNT")
+	for _, c := range m.Collections {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%d\n", c.Name, c.Config.HNSW.Dim, c.Config.HNSW.Metric, c.Config.Index.Type, c.Count)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gvdb/bench"
+	"gvdb/hnsw"
+)
+
+// runBench 执行 bench 子命令：加载或生成数据集，按参数组合测量召回率、QPS 和构建时间
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	base := fs.String("base", "", "base vectors in fvecs format (random data when empty)")
+	query := fs.String("query", "", "query vectors in fvecs format")
+	gt := fs.String("gt", "", "ground truth neighbors in ivecs format (computed when empty)")
+	limit := fs.Int("limit", 0, "maximum number of base vectors to read")
+	n := fs.Int("n", 10000, "number of random base vectors")
+	nq := fs.Int("nq", 100, "number of random queries")
+	dim := fs.Int("dim", 64, "dimension of random vectors")
+	seed := fs.Int64("seed", 1, "random seed")
+	k := fs.Int("k", 10, "number of neighbors per query")
+	metricName := fs.String("metric", "l2", "distance metric")
+	ms := fs.String("m", "16", "comma separated list of M values")
+	efc := fs.Int("ef-construction", 200, "efConstruction")
+	efs := fs.String("ef-search", "16,32,64,128,256", "comma separated list of efSearch values")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	metric, err := hnsw.MetricByName(*metricName)
+	if err != nil {
+		return err
+	}
+	mValues, err := parseInts(*ms)
+	if err != nil {
+		return err
+	}
+	efValues, err := parseInts(*efs)
+	if err != nil {
+		return err
+	}
+
+	var ds *bench.Dataset
+	if *base != "" {
+		if *query == "" {
+			return fmt.Errorf("-query is required with -base")
+		}
+		ds = &bench.Dataset{}
+		if ds.Base, err = bench.ReadFvecs(*base, *limit); err != nil {
+			return err
+		}
+		if ds.Queries, err = bench.ReadFvecs(*query, *nq); err != nil {
+			return err
+		}
+		// 截断基础向量后原有真值不再有效
+		if *gt != "" && *limit <= 0 {
+			if ds.GroundTruth, err = bench.ReadIvecs(*gt, *nq); err != nil {
+				return err
+			}
+		}
+	} else {
+		ds = bench.GenerateDataset(*n, *nq, *dim, *seed)
+	}
+
+	params := make([]bench.Params, len(mValues))
+	for i, m := range mValues {
+		params[i] = bench.Params{M: m, EFConstruction: *efc, EFSearch: efValues}
+	}
+	fmt.Printf("dataset: %d base, %d queries, dim %d, metric %s, k %d\n",
+		len(ds.Base), len(ds.Queries), ds.Dim(), metric.Name(), *k)
+	results, err := bench.Run(ds, params, *k, metric)
+	if err != nil {
+		return err
+	}
+	bench.WriteResults(os.Stdout, results)
+	return nil
+}
+
+func parseInts(s string) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer list %q: %v", s, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
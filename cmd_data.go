@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gvdb/dataio"
+	"gvdb/service"
+)
+
+// runImport 执行 import 子命令：从 JSONL、CSV、Parquet 或 .npy/.npz（加旁路的 ID 和元数据文件）流式读取文档，按批写入集合。
+// 导入文件时每批写入后更新检查点，中断后用 -resume 从检查点继续
+func runImport(args []string) (err error) {
+	fs, o := newFlagSet("import", "default")
+	batch := fs.Int("batch", 1000, "number of points per upsert")
+	fileFormat := fs.String("file-format", "", "input format: jsonl, csv, parquet, npy or npz (by extension when empty, jsonl for stdin)")
+	sidecar := fs.String("sidecar", "", "id/metadata JSONL for npy/npz input (default FILE with extension .ids.jsonl)")
+	checkpoint := fs.String("checkpoint", "", "checkpoint file (default FILE.checkpoint)")
+	resume := fs.Bool("resume", false, "resume an interrupted import from its checkpoint")
+	progress := fs.Bool("progress", true, "report progress on stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gvdb import [flags] FILE|-")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one input file")
+	}
+	if *batch <= 0 {
+		return fmt.Errorf("-batch must be positive")
+	}
+	path := fs.Arg(0)
+	if path == "-" && *resume {
+		return fmt.Errorf("-resume requires an input file")
+	}
+	if *checkpoint == "" && path != "-" {
+		*checkpoint = path + ".checkpoint"
+	}
+	in, err := dataio.Open(path, *fileFormat, *sidecar)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+	svc := service.New(db)
+
+	opts := dataio.ImportOptions{BatchSize: *batch, Source: path, Checkpoint: *checkpoint, Resume: *resume}
+	start := time.Now()
+	if *progress {
+		last := start
+		opts.Progress = func(res dataio.ImportResult, fraction float64) {
+			if now := time.Now(); now.Sub(last) >= time.Second {
+				last = now
+				reportProgress(res, fraction, now.Sub(start))
+			}
+		}
+	}
+	res, err := dataio.Import(in, func(records []dataio.Record) error {
+		points := make([]service.Point, len(records))
+		for i, r := range records {
+			points[i] = service.Point(r)
+		}
+		_, err := svc.Upsert(o.collection, points)
+		return err
+	}, opts)
+	if err != nil {
+		if *checkpoint != "" && res.Skipped+res.Imported > 0 {
+			return fmt.Errorf("%v (rerun with -resume to continue after %d records)", err, res.Skipped+res.Imported)
+		}
+		return err
+	}
+	return o.output(map[string]interface{}{"collection": o.collection, "imported": res.Imported, "skipped": res.Skipped}, func(w *tabwriter.Writer) {
+		if res.Skipped > 0 {
+			fmt.Fprintf(w, "Resumed after %d points\n", res.Skipped)
+		}
+		fmt.Fprintf(w, "Imported %d points into %s in %.1fs\n", res.Imported, o.collection, time.Since(start).Seconds())
+	})
+}
+
+// runExport 执行 export 子命令：按 ID 顺序把集合中的文档流式写出，格式与 import 相同
+func runExport(args []string) (err error) {
+	fs, o := newFlagSet("export", "default")
+	outPath := fs.String("o", "-", "output file, - for stdout")
+	fileFormat := fs.String("file-format", "", "output format: jsonl, csv, parquet, npy or npz (by extension when empty, jsonl for stdout)")
+	sidecar := fs.String("sidecar", "", "id/metadata JSONL for npy/npz output (default FILE with extension .ids.jsonl)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+	c, err := db.GetCollection(o.collection)
+	if err != nil {
+		return err
+	}
+
+	out, err := dataio.Create(*outPath, *fileFormat, *sidecar)
+	if err != nil {
+		return err
+	}
+	exported := 0
+	for _, r := range c.Query(nil, 0) {
+		doc, ok := c.Get(r.ID)
+		if !ok {
+			continue
+		}
+		if err := out.Write(dataio.Record{ID: r.ID, Vector: doc.Vector, Meta: doc.Meta, Payload: doc.Payload}); err != nil {
+			out.Close()
+			return err
+		}
+		exported++
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// 导出到标准输出时摘要写到标准错误，避免混入数据
+	fmt.Fprintf(os.Stderr, "Exported %d points from %s\n", exported, o.collection)
+	return nil
+}
+
+// reportProgress 在标准错误输出导入进度，fraction 未知（小于 0）时只输出条数
+func reportProgress(res dataio.ImportResult, fraction float64, elapsed time.Duration) {
+	records, rate := res.Skipped+res.Imported, float64(res.Imported)/elapsed.Seconds()
+	if fraction < 0 {
+		fmt.Fprintf(os.Stderr, "Imported %d points (%.0f/s)\n", records, rate)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d points, %.1f%% (%.0f/s)\n", records, fraction*100, rate)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gvdb/service"
+)
+
+// runSearch 执行 search 子命令：给出 -vector 时做向量搜索，只给出 -filter 时按元数据过滤查询
+func runSearch(args []string) (err error) {
+	fs, o := newFlagSet("search", "default")
+	vec := fs.String("vector", "", "comma separated query vector, e.g. 0.1,0.2,0.3")
+	filterJSON := fs.String("filter", "", `JSON filter, e.g. '{"lang": "go", "year": {"$gte": 2020}}'`)
+	limit := fs.Int("limit", 10, "maximum number of results")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *vec == "" && *filterJSON == "" {
+		return fmt.Errorf("-vector or -filter is required")
+	}
+	var query []float32
+	if *vec != "" {
+		if query, err = parseVector(*vec); err != nil {
+			return err
+		}
+	}
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+	svc := service.New(db)
+
+	var hits []service.Hit
+	if query != nil {
+		hits, err = svc.Search(o.collection, service.SearchRequest{Vector: query, Limit: *limit, Filter: json.RawMessage(*filterJSON)})
+	} else {
+		hits, err = svc.Query(o.collection, service.QueryRequest{Filter: json.RawMessage(*filterJSON), Limit: *limit})
+	}
+	if err != nil {
+		return err
+	}
+	return o.output(hits, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tSCORE\tPAYLOAD")
+		for _, h := range hits {
+			fmt.Fprintf(w, "%s\t%.4f\t%s\n", h.ID, h.Score, compactJSON(h.Payload))
+		}
+	})
+}
+
+// runGet 执行 get 子命令：输出指定 ID 的文档，有 ID 不存在时返回错误
+func runGet(args []string) (err error) {
+	fs, o := newFlagSet("get", "default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gvdb get [flags] ID...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected at least one ID")
+	}
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+	svc := service.New(db)
+
+	points := make([]service.Point, 0, fs.NArg())
+	var missing []string
+	for _, id := range fs.Args() {
+		p, err := svc.Get(o.collection, id)
+		if service.CodeOf(err) == service.NotFound {
+			missing = append(missing, id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		points = append(points, p)
+	}
+	err = o.output(points, func(w *tabwriter.Writer) {
+		for _, p := range points {
+			fmt.Fprintf(w, "id:\t%s\n", p.ID)
+			fmt.Fprintf(w, "vector:\t%v\n", p.Vector)
+			if p.Meta != "" {
+				fmt.Fprintf(w, "meta:\t%s\n", p.Meta)
+			}
+			fmt.Fprintf(w, "payload:\t%s\n\n", compactJSON(p.Payload))
+		}
+	})
+	if err == nil && len(missing) > 0 {
+		err = fmt.Errorf("not found in %s: %s", o.collection, strings.Join(missing, ", "))
+	}
+	return err
+}
+
+// runDelete 执行 delete 子命令，不存在的 ID 会被忽略
+func runDelete(args []string) (err error) {
+	fs, o := newFlagSet("delete", "default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gvdb delete [flags] ID...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected at least one ID")
+	}
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer closeDB(db, &err)
+
+	n, err := service.New(db).Delete(o.collection, fs.Args())
+	if err != nil {
+		return err
+	}
+	return o.output(map[string]interface{}{"collection": o.collection, "deleted": n}, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Deleted %d of %d points from %s\n", n, fs.NArg(), o.collection)
+	})
+}
+
+func parseVector(s string) ([]float32, error) {
+	parts := strings.Split(s, ",")
+	vec := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q", p)
+		}
+		vec[i] = float32(f)
+	}
+	return vec, nil
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"gvdb/config"
+	"gvdb/grpcserver"
+	"gvdb/server"
+	"gvdb/service"
+	"gvdb/vectordb"
+)
+
+// runServe 执行 serve 子命令：启动 REST 服务和可选的 gRPC 服务，收到 SIGINT/SIGTERM 后等待进行中的请求结束，再保存索引并关闭存储
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := fs.String("config", "config.yaml", "path to the configuration file")
+	var overrides stringList
+	fs.Var(&overrides, "set", "override a config value, e.g. -set storage.type=duckdb (repeatable)")
+	addr := fs.String("addr", "", "listen address (overrides server.addr)")
+	grpcAddr := fs.String("grpc-addr", "", "gRPC listen address (overrides server.grpc_addr)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfigOverride(*configPath, overrides)
+	if err != nil {
+		return err
+	}
+	if *addr != "" {
+		cfg.Server.Addr = *addr
+	}
+	if *grpcAddr != "" {
+		cfg.Server.GRPCAddr = *grpcAddr
+	}
+
+	db, err := vectordb.NewVectorDB(cfg)
+	if err != nil {
+		return err
+	}
+	svc := service.New(db)
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           server.New(svc),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	var grpcSrv *grpc.Server
+	var grpcLis net.Listener
+	if cfg.Server.GRPCAddr != "" {
+		if grpcLis, err = net.Listen("tcp", cfg.Server.GRPCAddr); err != nil {
+			db.Close()
+			return err
+		}
+		grpcSrv = grpc.NewServer()
+		grpcserver.Register(grpcSrv, svc)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 2)
+	go func() {
+		fmt.Println("Listening on", cfg.Server.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+	if grpcSrv != nil {
+		go func() {
+			fmt.Println("gRPC listening on", cfg.Server.GRPCAddr)
+			serveErr <- grpcSrv.Serve(grpcLis)
+		}()
+	}
+
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		fmt.Println("Shutting down")
+	}
+	// 任一服务退出或收到信号后关闭两个服务，gRPC 服务在超时后强制断开
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if shutdownErr := srv.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+	if grpcSrv != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcSrv.Stop()
+		}
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
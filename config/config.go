@@ -2,7 +2,10 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
@@ -26,27 +29,177 @@ type Config struct {
 			User     string `yaml:"user"`
 			Password string `yaml:"password"`
 			Database string `yaml:"database"`
+			Pgvector struct {
+				Enable         bool   `yaml:"enable"`          // 向量保存在 pgvector 的 vector(dim) 列中，而不是 JSONB
+				Index          string `yaml:"index"`           // 在 PostgreSQL 中建立的向量索引：hnsw（默认）、ivfflat 或 none
+				M              int    `yaml:"m"`               // hnsw 每层最大连接数，默认 16
+				EFConstruction int    `yaml:"ef_construction"` // hnsw 构建时的候选集大小，默认 64
+				EFSearch       int    `yaml:"ef_search"`       // hnsw 搜索时的候选集大小，默认 40
+				Lists          int    `yaml:"lists"`           // ivfflat 的簇数量，默认 100
+				Probes         int    `yaml:"probes"`          // ivfflat 搜索时扫描的簇数量，默认 10
+			} `yaml:"pgvector"`
 		} `yaml:"postgres"`
 	} `yaml:"storage"`
-	HNSW struct {
-		Dim int `yaml:"dim"`
-		M   int `yaml:"m"`
-		EF  int `yaml:"ef"`
-	} `yaml:"hnsw"`
+	HNSW     HNSWConfig     `yaml:"hnsw"`
+	Index    IndexConfig    `yaml:"index"`
+	Metadata MetadataConfig `yaml:"metadata"`
+	Search   struct {
+		BruteForceRatio float64 `yaml:"brute_force_ratio"` // 带过滤条件的搜索中匹配文档占比不超过该值时改为暴力搜索，默认 0.01
+	} `yaml:"search"`
+	Server struct {
+		Addr            string `yaml:"addr"`             // REST 服务监听地址，默认 :8080
+		GRPCAddr        string `yaml:"grpc_addr"`        // gRPC 服务监听地址，留空则不启动
+		ShutdownTimeout int    `yaml:"shutdown_timeout"` // 优雅关闭时等待进行中请求的秒数，默认 10
+	} `yaml:"server"`
+	WAL struct {
+		Enable         bool   `yaml:"enable"`
+		Dir            string `yaml:"dir"`             // 日志目录，每个集合一个 <集合名>.wal 文件，默认 wal
+		Sync           string `yaml:"sync"`            // always（默认，每次写入 fsync）、interval（定期 fsync）或 none
+		SyncInterval   int    `yaml:"sync_interval"`   // sync 为 interval 时的 fsync 间隔毫秒数，默认 1000
+		CheckpointSize int64  `yaml:"checkpoint_size"` // 日志超过该字节数时保存索引快照并截断日志，默认 64MB
+	} `yaml:"wal"`
+	// 以上 hnsw、index、metadata 定义默认集合；collections 声明其他集合，各自有独立的维度、度量、索引参数和存储表/文件
+	Collections []CollectionConfig `yaml:"collections"`
+	Catalog     string             `yaml:"catalog"` // 通过 API 创建的集合定义保存的文件，默认 collections.yaml
+}
+
+type HNSWConfig struct {
+	Dim            int    `yaml:"dim" json:"dim"`
+	M              int    `yaml:"m" json:"m"`                             // 每层最大连接数，默认 16
+	EF             int    `yaml:"ef" json:"ef"`                           // ef_construction 和 ef_search 未设置时的默认值，默认 200
+	EFConstruction int    `yaml:"ef_construction" json:"ef_construction"` // 构建时的候选集大小
+	EFSearch       int    `yaml:"ef_search" json:"ef_search"`             // 搜索时的候选集大小
+	Metric         string `yaml:"metric" json:"metric"`                   // cosine、l2、ip、manhattan、hamming，默认 cosine
+	Quantization   string `yaml:"quantization" json:"quantization"`       // none（默认）、int8 或 binary
+	TrainSize      int    `yaml:"train_size" json:"train_size"`           // 训练量化器所需的向量数，默认 1000
+	Rescore        int    `yaml:"rescore" json:"rescore"`                 // 大于 0 时用存储中的全精度向量对前 k*rescore 个结果重新打分
+	IndexPath      string `yaml:"index_path" json:"index_path"`           // 图快照文件路径，为空时每次启动从存储重建索引
+}
+
+type IndexConfig struct {
+	Type string `yaml:"type" json:"type"` // 索引类型：hnsw（默认）、flat、ivf、ivfpq、disk 或 pgvector（由 PostgreSQL 搜索）
+	Flat struct {
+		Workers int `yaml:"workers" json:"workers"` // 并行扫描的 goroutine 数，默认 CPU 核数
+	} `yaml:"flat" json:"flat"`
+	IVF struct {
+		Nlist     int `yaml:"nlist" json:"nlist"`           // 簇数量
+		Nprobe    int `yaml:"nprobe" json:"nprobe"`         // 搜索时扫描的簇数量
+		TrainSize int `yaml:"train_size" json:"train_size"` // 自动训练所需的样本数，默认 nlist*39
+	} `yaml:"ivf" json:"ivf"` // ivf 与 ivfpq 共用
+	PQ struct {
+		M      int `yaml:"m" json:"m"`           // 子空间数量，需整除 hnsw.dim
+		Nbits  int `yaml:"nbits" json:"nbits"`   // 每个子空间的编码位数，默认 8
+		Rerank int `yaml:"rerank" json:"rerank"` // 大于 1 时取 k*rerank 个候选并用存储中的原始向量重排序
+	} `yaml:"pq" json:"pq"`
+	Disk struct {
+		Path           string  `yaml:"path" json:"path"`                       // 内存映射的索引文件路径
+		MaxDegree      int     `yaml:"max_degree" json:"max_degree"`           // 图的最大出度，默认 64
+		BuildList      int     `yaml:"build_list" json:"build_list"`           // 构建时的候选列表长度，默认 100
+		SearchList     int     `yaml:"search_list" json:"search_list"`         // 搜索时的候选列表长度，默认 64
+		Alpha          float64 `yaml:"alpha" json:"alpha"`                     // 剪枝参数，默认 1.2
+		MergeThreshold int     `yaml:"merge_threshold" json:"merge_threshold"` // 增量缓冲区达到该数量时合并进文件，默认 10000
+	} `yaml:"disk" json:"disk"`
+}
+
+type MetadataConfig struct {
+	Indexes []MetadataIndex `yaml:"indexes" json:"indexes"` // 建立二级索引的 Payload 字段
+}
+
+// MetadataIndex 声明一个 Payload 字段上的二级索引
+type MetadataIndex struct {
+	Field string `yaml:"field" json:"field"` // 字段名，嵌套字段用 "a.b"
+	Type  string `yaml:"type" json:"type"`   // keyword、int、float 或 text
+}
+
+// DefaultCollection 为顶层 hnsw、index、metadata 配置对应的集合名
+const DefaultCollection = "default"
+
+// CollectionConfig 定义一个集合。文件存储的集合保存在 <文件名>.<集合名>.json，SQL 存储保存在 vectors_<集合名> 表
+type CollectionConfig struct {
+	Name     string         `yaml:"name" json:"name"` // 小写字母开头，只含小写字母、数字和下划线
+	HNSW     HNSWConfig     `yaml:"hnsw" json:"hnsw"` // dim 必填
+	Index    IndexConfig    `yaml:"index" json:"index"`
+	Metadata MetadataConfig `yaml:"metadata" json:"metadata"`
+}
+
+var collectionName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,47}$`)
+
+// Normalize 填充集合参数的默认值并验证，通过 API 创建的集合也使用同样的规则
+func (c *CollectionConfig) Normalize() error {
+	if !collectionName.MatchString(c.Name) {
+		return fmt.Errorf("invalid collection name %q: use lowercase letters, digits and underscores", c.Name)
+	}
+	if c.HNSW.Dim <= 0 {
+		return fmt.Errorf("collection %s requires hnsw.dim", c.Name)
+	}
+	return normalize(&c.HNSW, &c.Index, c.Metadata)
+}
+
+// Default 返回默认集合的配置
+func (cfg Config) Default() CollectionConfig {
+	return CollectionConfig{Name: DefaultCollection, HNSW: cfg.HNSW, Index: cfg.Index, Metadata: cfg.Metadata}
 }
 
 // LoadConfig 读取配置文件并验证
 func LoadConfig(path string) (Config, error) {
+	return LoadConfigOverride(path, nil)
+}
+
+// LoadConfigOverride 读取配置文件，并在补全默认值和校验之前应用 key=value 形式的覆盖项，
+// key 为点号分隔的 YAML 路径（如 storage.type=duckdb），value 按 YAML 标量解析
+func LoadConfigOverride(path string, overrides []string) (Config, error) {
 	var cfg Config
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return cfg, err
 	}
+	if len(overrides) > 0 {
+		if data, err = override(data, overrides); err != nil {
+			return cfg, err
+		}
+	}
 	err = yaml.Unmarshal(data, &cfg)
 	if err != nil {
 		return cfg, err
 	}
 
+	if cfg.Search.BruteForceRatio == 0 {
+		cfg.Search.BruteForceRatio = 0.01
+	}
+	if cfg.Server.Addr == "" {
+		cfg.Server.Addr = ":8080"
+	}
+	if cfg.Server.ShutdownTimeout == 0 {
+		cfg.Server.ShutdownTimeout = 10
+	}
+	if cfg.Catalog == "" {
+		cfg.Catalog = "collections.yaml"
+	}
+	if cfg.WAL.Dir == "" {
+		cfg.WAL.Dir = "wal"
+	}
+	switch cfg.WAL.Sync {
+	case "":
+		cfg.WAL.Sync = "always"
+	case "always", "interval", "none":
+	default:
+		return cfg, errors.New("unknown wal sync policy: " + cfg.WAL.Sync)
+	}
+	if err := normalize(&cfg.HNSW, &cfg.Index, cfg.Metadata); err != nil {
+		return cfg, err
+	}
+	seen := map[string]bool{DefaultCollection: true}
+	for i := range cfg.Collections {
+		c := &cfg.Collections[i]
+		if err := c.Normalize(); err != nil {
+			return cfg, err
+		}
+		if seen[c.Name] {
+			return cfg, errors.New("duplicate collection: " + c.Name)
+		}
+		seen[c.Name] = true
+	}
+
 	// 验证指定的存储类型是否启用
 	switch cfg.Storage.Type {
 	case "file":
@@ -64,5 +217,123 @@ func LoadConfig(path string) (Config, error) {
 	default:
 		return cfg, errors.New("unknown storage type: " + cfg.Storage.Type)
 	}
+
+	pgvector := cfg.Storage.Type == "postgres" && cfg.Storage.Postgres.Pgvector.Enable
+	if pgvector {
+		switch cfg.Storage.Postgres.Pgvector.Index {
+		case "":
+			cfg.Storage.Postgres.Pgvector.Index = "hnsw"
+		case "hnsw", "ivfflat", "none":
+		default:
+			return cfg, errors.New("unknown pgvector index type: " + cfg.Storage.Postgres.Pgvector.Index)
+		}
+	}
+	// pgvector 索引把搜索交给数据库，只能用于启用了 pgvector 的 PostgreSQL 存储
+	for _, c := range append([]CollectionConfig{cfg.Default()}, cfg.Collections...) {
+		if c.Index.Type == "pgvector" && !pgvector {
+			return cfg, fmt.Errorf("collection %s: index type pgvector requires postgres storage with storage.postgres.pgvector.enable", c.Name)
+		}
+	}
 	return cfg, nil
 }
+
+// normalize 填充 hnsw 和 index 参数的默认值并验证，默认集合与其他集合共用
+func normalize(h *HNSWConfig, idx *IndexConfig, meta MetadataConfig) error {
+	if h.M == 0 {
+		h.M = 16
+	}
+	if h.EF == 0 {
+		h.EF = 200
+	}
+	if idx.PQ.Nbits == 0 {
+		idx.PQ.Nbits = 8
+	}
+	if h.EFConstruction == 0 {
+		h.EFConstruction = h.EF
+	}
+	if h.EFSearch == 0 {
+		h.EFSearch = h.EF
+	}
+
+	switch h.Quantization {
+	case "", "none", "int8", "binary":
+	default:
+		return errors.New("unknown hnsw quantization: " + h.Quantization)
+	}
+
+	switch idx.Type {
+	case "":
+		idx.Type = "hnsw"
+	case "hnsw", "flat", "ivf", "ivfpq", "disk", "pgvector":
+	default:
+		return errors.New("unknown index type: " + idx.Type)
+	}
+	if idx.Type == "disk" && idx.Disk.Path == "" {
+		return errors.New("disk index requires index.disk.path")
+	}
+
+	for _, mi := range meta.Indexes {
+		if mi.Field == "" {
+			return errors.New("metadata index requires a field")
+		}
+		switch mi.Type {
+		case "keyword", "int", "float", "text":
+		default:
+			return errors.New("unknown metadata index type: " + mi.Type)
+		}
+	}
+	return nil
+}
+
+func override(data []byte, overrides []string) ([]byte, error) {
+	doc := yaml.MapSlice{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		eq := strings.IndexByte(o, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid override %q, expected key=value", o)
+		}
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(o[eq+1:]), &value); err != nil {
+			return nil, fmt.Errorf("invalid override %q: %v", o, err)
+		}
+		var err error
+		if doc, err = setPath(doc, strings.Split(o[:eq], "."), value); err != nil {
+			return nil, fmt.Errorf("invalid override %q: %v", o, err)
+		}
+	}
+	return yaml.Marshal(doc)
+}
+
+// setPath 在 YAML 映射中按路径设置值，缺少的中间层级会被创建
+func setPath(m yaml.MapSlice, path []string, value interface{}) (yaml.MapSlice, error) {
+	for i := range m {
+		if m[i].Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			m[i].Value = value
+			return m, nil
+		}
+		sub, ok := m[i].Value.(yaml.MapSlice)
+		if !ok && m[i].Value != nil {
+			return nil, fmt.Errorf("%s is not a mapping", path[0])
+		}
+		sub, err := setPath(sub, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		m[i].Value = sub
+		return m, nil
+	}
+	if len(path) == 1 {
+		return append(m, yaml.MapItem{Key: path[0], Value: value}), nil
+	}
+	sub, err := setPath(nil, path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	return append(m, yaml.MapItem{Key: path[0], Value: sub}), nil
+}
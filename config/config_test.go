@@ -27,6 +27,22 @@ hnsw:
   dim: 3
   m: 16
   ef: 200
+  ef_search: 64
+  metric: "l2"
+  quantization: "int8"
+  rescore: 4
+  index_path: "test_vectors.hnsw"
+index:
+  type: "ivf"
+  ivf:
+    nlist: 32
+    nprobe: 4
+metadata:
+  indexes:
+    - field: "lang"
+      type: "keyword"
+    - field: "info.year"
+      type: "int"
 `
 	err := os.WriteFile("test_config.yaml", []byte(configContent), 0644)
 	if err != nil {
@@ -56,6 +72,189 @@ hnsw:
 	if cfg.HNSW.Dim != 3 {
 		t.Errorf("Expected HNSW dim 3, got %d", cfg.HNSW.Dim)
 	}
+	if cfg.HNSW.EFConstruction != 200 {
+		t.Errorf("Expected HNSW ef_construction to default to ef, got %d", cfg.HNSW.EFConstruction)
+	}
+	if cfg.HNSW.EFSearch != 64 {
+		t.Errorf("Expected HNSW ef_search 64, got %d", cfg.HNSW.EFSearch)
+	}
+	if cfg.HNSW.Metric != "l2" {
+		t.Errorf("Expected HNSW metric 'l2', got %s", cfg.HNSW.Metric)
+	}
+	if cfg.HNSW.Quantization != "int8" || cfg.HNSW.Rescore != 4 {
+		t.Errorf("Unexpected quantization config: %s, rescore %d", cfg.HNSW.Quantization, cfg.HNSW.Rescore)
+	}
+	if cfg.Search.BruteForceRatio != 0.01 {
+		t.Errorf("Expected search.brute_force_ratio to default to 0.01, got %f", cfg.Search.BruteForceRatio)
+	}
+	if cfg.HNSW.IndexPath != "test_vectors.hnsw" {
+		t.Errorf("Expected HNSW index_path 'test_vectors.hnsw', got %s", cfg.HNSW.IndexPath)
+	}
+	if cfg.Index.Type != "ivf" || cfg.Index.IVF.Nlist != 32 || cfg.Index.IVF.Nprobe != 4 {
+		t.Errorf("Unexpected index config: %+v", cfg.Index)
+	}
+	if len(cfg.Metadata.Indexes) != 2 || cfg.Metadata.Indexes[1] != (MetadataIndex{Field: "info.year", Type: "int"}) {
+		t.Errorf("Unexpected metadata indexes: %+v", cfg.Metadata.Indexes)
+	}
+}
+
+func TestLoadConfigInvalidIndexType(t *testing.T) {
+	configContent := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "test_vectors.json"
+index:
+  type: "annoy"
+`
+	err := os.WriteFile("test_config_index.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("test_config_index.yaml")
+
+	_, err = LoadConfig("test_config_index.yaml")
+	if err == nil {
+		t.Error("Expected error for unknown index type, got nil")
+	}
+}
+
+func TestLoadConfigWAL(t *testing.T) {
+	configContent := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "test_vectors.json"
+wal:
+  enable: true
+`
+	if err := os.WriteFile("test_config_wal.yaml", []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("test_config_wal.yaml")
+
+	cfg, err := LoadConfig("test_config_wal.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.WAL.Enable || cfg.WAL.Dir != "wal" || cfg.WAL.Sync != "always" {
+		t.Errorf("Unexpected wal defaults: %+v", cfg.WAL)
+	}
+	if _, err := LoadConfigOverride("test_config_wal.yaml", []string{"wal.sync=sometimes"}); err == nil {
+		t.Error("Expected error for unknown wal sync policy, got nil")
+	}
+}
+
+func TestLoadConfigDiskIndexRequiresPath(t *testing.T) {
+	configContent := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "test_vectors.json"
+index:
+  type: "disk"
+  disk:
+    max_degree: 32
+`
+	err := os.WriteFile("test_config_disk.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("test_config_disk.yaml")
+
+	_, err = LoadConfig("test_config_disk.yaml")
+	if err == nil {
+		t.Error("Expected error for disk index without path, got nil")
+	}
+}
+
+func TestLoadConfigInvalidMetadataIndex(t *testing.T) {
+	configContent := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "test_vectors.json"
+metadata:
+  indexes:
+    - field: "location"
+      type: "geo"
+`
+	err := os.WriteFile("test_config_metadata.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("test_config_metadata.yaml")
+
+	_, err = LoadConfig("test_config_metadata.yaml")
+	if err == nil {
+		t.Error("Expected error for unknown metadata index type, got nil")
+	}
+}
+
+func TestLoadConfigCollections(t *testing.T) {
+	configContent := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "test_vectors.json"
+hnsw:
+  dim: 3
+collections:
+  - name: "images"
+    hnsw:
+      dim: 512
+      metric: "l2"
+    index:
+      type: "flat"
+`
+	err := os.WriteFile("test_config_collections.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("test_config_collections.yaml")
+
+	cfg, err := LoadConfig("test_config_collections.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Collections) != 1 {
+		t.Fatalf("Expected 1 collection, got %d", len(cfg.Collections))
+	}
+	images := cfg.Collections[0]
+	if images.Name != "images" || images.HNSW.Dim != 512 || images.HNSW.Metric != "l2" || images.Index.Type != "flat" {
+		t.Errorf("Unexpected collection config: %+v", images)
+	}
+	if images.HNSW.M != 16 || images.HNSW.EFSearch != 200 {
+		t.Errorf("Expected collection defaults to be applied, got %+v", images.HNSW)
+	}
+	if cfg.Server.Addr != ":8080" || cfg.Server.ShutdownTimeout != 10 {
+		t.Errorf("Unexpected server defaults: %+v", cfg.Server)
+	}
+	if cfg.Catalog != "collections.yaml" {
+		t.Errorf("Expected catalog to default to collections.yaml, got %s", cfg.Catalog)
+	}
+	if d := cfg.Default(); d.Name != DefaultCollection || d.HNSW.Dim != 3 {
+		t.Errorf("Unexpected default collection: %+v", d)
+	}
+}
+
+func TestCollectionConfigNormalize(t *testing.T) {
+	for _, c := range []CollectionConfig{
+		{Name: "Images", HNSW: HNSWConfig{Dim: 3}},
+		{Name: "1st", HNSW: HNSWConfig{Dim: 3}},
+		{Name: "docs; drop", HNSW: HNSWConfig{Dim: 3}},
+		{Name: "docs"},
+		{Name: "docs", HNSW: HNSWConfig{Dim: 3}, Index: IndexConfig{Type: "disk"}},
+	} {
+		if err := c.Normalize(); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
 }
 
 func TestLoadConfigInvalidType(t *testing.T) {
@@ -84,3 +283,86 @@ hnsw:
 		t.Error("Expected error for disabled storage type, got nil")
 	}
 }
+
+func TestLoadConfigOverride(t *testing.T) {
+	configContent := `
+storage:
+  type: "file"
+  file:
+    enable: true
+    path: "test_vectors.json"
+hnsw:
+  dim: 3
+`
+	err := os.WriteFile("test_config_override.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("test_config_override.yaml")
+
+	cfg, err := LoadConfigOverride("test_config_override.yaml", []string{
+		"storage.type=duckdb",
+		"storage.duckdb.enable=true",
+		"storage.duckdb.path=other.db",
+		"hnsw.dim=8",
+		"index.type=flat",
+	})
+	if err != nil {
+		t.Fatalf("LoadConfigOverride failed: %v", err)
+	}
+	if cfg.Storage.Type != "duckdb" || !cfg.Storage.DuckDB.Enable || cfg.Storage.DuckDB.Path != "other.db" {
+		t.Errorf("Unexpected storage config: %+v", cfg.Storage)
+	}
+	if cfg.HNSW.Dim != 8 || cfg.Index.Type != "flat" || cfg.Storage.File.Path != "test_vectors.json" {
+		t.Errorf("Unexpected overridden config: %+v %+v", cfg.HNSW, cfg.Index)
+	}
+
+	for _, bad := range []string{"hnsw", "=3", "hnsw.dim.x=1", "index.type=bogus"} {
+		if _, err := LoadConfigOverride("test_config_override.yaml", []string{bad}); err == nil {
+			t.Errorf("Expected error for override %q", bad)
+		}
+	}
+}
+
+func TestLoadConfigPgvector(t *testing.T) {
+	configContent := `
+storage:
+  type: "postgres"
+  postgres:
+    enable: true
+    pgvector:
+      enable: true
+hnsw:
+  dim: 3
+index:
+  type: "pgvector"
+collections:
+  - name: "images"
+    hnsw:
+      dim: 2
+`
+	err := os.WriteFile("test_config_pgvector.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("test_config_pgvector.yaml")
+
+	cfg, err := LoadConfig("test_config_pgvector.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Index.Type != "pgvector" || cfg.Storage.Postgres.Pgvector.Index != "hnsw" || cfg.Collections[0].Index.Type != "hnsw" {
+		t.Errorf("Unexpected pgvector config: %+v %+v", cfg.Index, cfg.Storage.Postgres.Pgvector)
+	}
+
+	// 数据库搜索需要启用 pgvector 的 PostgreSQL 存储
+	for _, bad := range [][]string{
+		{"storage.postgres.pgvector.enable=false"},
+		{"storage.postgres.pgvector.index=btree"},
+		{"index.type=hnsw", "storage.postgres.pgvector.enable=false", "collections=[{name: images, hnsw: {dim: 2}, index: {type: pgvector}}]"},
+	} {
+		if _, err := LoadConfigOverride("test_config_pgvector.yaml", bad); err == nil {
+			t.Errorf("Expected error for overrides %q", bad)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package dataio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gvdb/storage"
+)
+
+// CSVReader 读取带表头的 CSV：id 列为 ID，meta 列为 Meta，payload 列为 JSON 形式的 Payload，
+// 其余各列按表头顺序组成向量，例如 id,v0,v1,v2,payload
+type CSVReader struct {
+	in      *countingReader
+	r       *csv.Reader
+	id      int
+	meta    int
+	payload int
+	vector  []int
+}
+
+// NewCSVReader 读取表头并创建 CSV 读取器，size 的含义与 NewJSONLReader 相同
+func NewCSVReader(r io.Reader, size int64) (*CSVReader, error) {
+	in := newCountingReader(r, size)
+	cr := csv.NewReader(in)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv: missing header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csv header: %v", err)
+	}
+	c := &CSVReader{in: in, r: cr, id: -1, meta: -1, payload: -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "id":
+			c.id = i
+		case "meta":
+			c.meta = i
+		case "payload":
+			c.payload = i
+		default:
+			c.vector = append(c.vector, i)
+		}
+	}
+	if c.id < 0 {
+		return nil, fmt.Errorf("csv: header has no id column")
+	}
+	return c, nil
+}
+
+func (c *CSVReader) Read() (Record, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	line, _ := c.r.FieldPos(0)
+	if len(c.vector) == 0 {
+		return Record{}, fmt.Errorf("line %d: header has no vector columns", line)
+	}
+	rec := Record{ID: row[c.id], Vector: make([]float32, len(c.vector))}
+	for i, col := range c.vector {
+		f, err := strconv.ParseFloat(strings.TrimSpace(row[col]), 32)
+		if err != nil {
+			return Record{}, fmt.Errorf("line %d: invalid vector component %q", line, row[col])
+		}
+		rec.Vector[i] = float32(f)
+	}
+	if c.meta >= 0 {
+		rec.Meta = row[c.meta]
+	}
+	if c.payload >= 0 {
+		if rec.Payload, err = storage.DecodePayload([]byte(row[c.payload])); err != nil {
+			return Record{}, fmt.Errorf("line %d: payload: %v", line, err)
+		}
+	}
+	return rec, nil
+}
+
+// Skip 跳过 n 行，不解析向量和 Payload
+func (c *CSVReader) Skip(n int64) error {
+	for ; n > 0; n-- {
+		if _, err := c.r.Read(); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CSVReader) Progress() float64 { return c.in.progress() }
+
+func (c *CSVReader) Close() error { return nil }
+
+// CSVWriter 写出 id,v0..vN,meta,payload 形式的 CSV，表头在写第一条记录时根据向量维度生成，
+// 所有记录的维度必须相同
+type CSVWriter struct {
+	w   *csv.Writer
+	dim int
+	row []string
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), dim: -1}
+}
+
+func (c *CSVWriter) writeHeader(dim int) error {
+	c.dim = dim
+	header := make([]string, 0, dim+3)
+	header = append(header, "id")
+	for i := 0; i < dim; i++ {
+		header = append(header, "v"+strconv.Itoa(i))
+	}
+	header = append(header, "meta", "payload")
+	c.row = make([]string, len(header))
+	return c.w.Write(header)
+}
+
+func (c *CSVWriter) Write(r Record) error {
+	if c.dim < 0 {
+		if err := c.writeHeader(len(r.Vector)); err != nil {
+			return err
+		}
+	}
+	if len(r.Vector) != c.dim {
+		return fmt.Errorf("csv: record %s has dimension %d, expected %d", r.ID, len(r.Vector), c.dim)
+	}
+	c.row[0] = r.ID
+	for i, f := range r.Vector {
+		c.row[i+1] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+	}
+	c.row[c.dim+1] = r.Meta
+	c.row[c.dim+2] = ""
+	if r.Payload != nil {
+		data, err := json.Marshal(r.Payload)
+		if err != nil {
+			return err
+		}
+		c.row[c.dim+2] = string(data)
+	}
+	return c.w.Write(c.row)
+}
+
+// Close 写出缓冲区，没有写过记录时只写表头 id,meta,payload
+func (c *CSVWriter) Close() error {
+	if c.dim < 0 {
+		if err := c.w.Write([]string{"id", "meta", "payload"}); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
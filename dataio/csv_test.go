@@ -0,0 +1,41 @@
+package dataio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gvdb/storage"
+)
+
+func TestCSVColumns(t *testing.T) {
+	// 向量列可以任意命名，按表头顺序组成向量
+	input := "x,ID,y,payload\n1,a,2,\"{\"\"n\"\": 1}\"\n3,b,4,\n"
+	r, err := NewCSVReader(strings.NewReader(input), int64(len(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readAll(t, r)
+	want := []Record{
+		{ID: "a", Vector: []float32{1, 2}, Payload: storage.Payload{"n": int64(1)}},
+		{ID: "b", Vector: []float32{3, 4}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected records %+v", got)
+	}
+
+	if _, err := NewCSVReader(strings.NewReader("v0,v1\n1,2\n"), -1); err == nil {
+		t.Error("Expected error for missing id column")
+	}
+	r, _ = NewCSVReader(strings.NewReader("id,v0\na,x\n"), -1)
+	if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected invalid component error on line 2, got %v", err)
+	}
+
+	var b strings.Builder
+	w := NewCSVWriter(&b)
+	w.Write(Record{ID: "a", Vector: []float32{1, 2}})
+	if err := w.Write(Record{ID: "b", Vector: []float32{1}}); err == nil {
+		t.Error("Expected dimension mismatch")
+	}
+}
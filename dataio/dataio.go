@@ -0,0 +1,216 @@
+package dataio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gvdb/storage"
+)
+
+// 支持的文件格式
+const (
+	JSONL   = "jsonl"
+	CSV     = "csv"
+	Parquet = "parquet"
+	NPY     = "npy"
+	NPZ     = "npz"
+)
+
+// Record 为导入导出的一条文档，JSON 形式与 REST API 的 point 相同
+type Record struct {
+	ID      string          `json:"id"`
+	Vector  []float32       `json:"vector"`
+	Meta    string          `json:"meta,omitempty"`
+	Payload storage.Payload `json:"payload,omitempty"`
+}
+
+// Reader 逐条读取记录，不会把整个文件读入内存
+type Reader interface {
+	// Read 返回下一条记录，读完时返回 io.EOF
+	Read() (Record, error)
+	// Skip 跳过接下来的 n 条记录，用于从检查点继续导入
+	Skip(n int64) error
+	// Progress 返回已读取的比例（0 到 1），无法估计时返回 -1
+	Progress() float64
+	Close() error
+}
+
+// Writer 逐条写出记录，Close 时写完文件尾部
+type Writer interface {
+	Write(r Record) error
+	Close() error
+}
+
+// DetectFormat 根据扩展名判断文件格式，"-" 和无法识别的扩展名返回错误
+func DetectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return JSONL, nil
+	case ".csv":
+		return CSV, nil
+	case ".parquet":
+		return Parquet, nil
+	case ".npy":
+		return NPY, nil
+	case ".npz":
+		return NPZ, nil
+	}
+	return "", fmt.Errorf("cannot detect file format of %s, specify one of jsonl, csv, parquet, npy, npz", path)
+}
+
+// SidecarPath 返回 .npy/.npz 文件默认的 ID 和元数据文件：同名的 .ids.jsonl
+func SidecarPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".ids.jsonl"
+}
+
+// Open 打开 path 读取记录，format 为空时按扩展名判断；"-" 表示标准输入，只支持 JSONL 和 CSV。
+// sidecar 为 .npy/.npz 的 ID 和元数据文件，为空时使用 SidecarPath
+func Open(path, format, sidecar string) (Reader, error) {
+	format, err := resolveFormat(path, format)
+	if err != nil {
+		return nil, err
+	}
+	if path == "-" {
+		switch format {
+		case JSONL:
+			return NewJSONLReader(os.Stdin, -1), nil
+		case CSV:
+			return NewCSVReader(os.Stdin, -1)
+		}
+		return nil, fmt.Errorf("%s cannot be read from stdin", format)
+	}
+	switch format {
+	case NPY, NPZ:
+		return OpenNumPy(path, sidecar, format == NPZ)
+	case Parquet:
+		return OpenParquet(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(-1)
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+		size = fi.Size()
+	}
+	var r Reader
+	if format == JSONL {
+		r = NewJSONLReader(f, size)
+	} else if r, err = NewCSVReader(f, size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return fileReader{r, f}, nil
+}
+
+// Create 创建 path 写出记录，参数含义与 Open 相同；"-" 表示标准输出
+func Create(path, format, sidecar string) (Writer, error) {
+	format, err := resolveFormat(path, format)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case NPY, NPZ:
+		if path == "-" {
+			return nil, fmt.Errorf("%s cannot be written to stdout", format)
+		}
+		return CreateNumPy(path, sidecar, format == NPZ)
+	}
+
+	var f io.WriteCloser = nopCloser{os.Stdout}
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+	switch format {
+	case JSONL:
+		return fileWriter{NewJSONLWriter(f), f}, nil
+	case CSV:
+		return fileWriter{NewCSVWriter(f), f}, nil
+	}
+	return fileWriter{NewParquetWriter(f), f}, nil
+}
+
+func resolveFormat(path, format string) (string, error) {
+	switch format {
+	case "":
+		if path == "-" {
+			return JSONL, nil
+		}
+		return DetectFormat(path)
+	case JSONL, CSV, Parquet, NPY, NPZ:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown file format: %s", format)
+}
+
+// fileReader 在关闭 Reader 之后关闭底层文件
+type fileReader struct {
+	Reader
+	f io.Closer
+}
+
+func (r fileReader) Close() error {
+	err := r.Reader.Close()
+	if closeErr := r.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// fileWriter 在关闭 Writer（写出缓冲区和文件尾部）之后关闭底层文件
+type fileWriter struct {
+	Writer
+	f io.Closer
+}
+
+func (w fileWriter) Close() error {
+	err := w.Writer.Close()
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// countingReader 为带缓冲的输入，记录已读取的字节数用于估计进度
+type countingReader struct {
+	*bufio.Reader
+	src  *countingSource
+	size int64
+}
+
+type countingSource struct {
+	r io.Reader
+	n int64
+}
+
+func (s *countingSource) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.n += int64(n)
+	return n, err
+}
+
+func newCountingReader(r io.Reader, size int64) *countingReader {
+	src := &countingSource{r: r}
+	return &countingReader{Reader: bufio.NewReaderSize(src, 1<<20), src: src, size: size}
+}
+
+// progress 返回已消费的字节占总大小的比例，扣除缓冲区中尚未处理的部分
+func (c *countingReader) progress() float64 {
+	if c.size <= 0 {
+		return -1
+	}
+	return float64(c.src.n-int64(c.Buffered())) / float64(c.size)
+}
@@ -0,0 +1,124 @@
+package dataio
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gvdb/storage"
+)
+
+func testRecords() []Record {
+	return []Record{
+		{ID: "a", Vector: []float32{0.5, -1, 2}, Payload: storage.Payload{"lang": "go", "year": int64(2021), "score": 0.75}},
+		{ID: "b,c", Vector: []float32{1, 0, 0}, Meta: "plain \"quoted\""},
+		{ID: "d", Vector: []float32{0, 0, 1e-7}, Payload: storage.Payload{"tags": []interface{}{"x", "y"}}},
+	}
+}
+
+func readAll(t *testing.T, r Reader) []Record {
+	t.Helper()
+	var out []Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, rec)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := testRecords()
+	for _, format := range []string{JSONL, CSV, Parquet, NPY, NPZ} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "points."+format)
+			w, err := Create(path, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, rec := range want {
+				if err := w.Write(rec); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := Open(path, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got := readAll(t, r)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Round trip mismatch:\n got %+v\nwant %+v", got, want)
+			}
+			if p := r.Progress(); p != 1 {
+				t.Errorf("Expected progress 1 at end, got %v", p)
+			}
+
+			// 续传时跳过前两条
+			r2, err := Open(path, format, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r2.Close()
+			if err := r2.Skip(2); err != nil {
+				t.Fatal(err)
+			}
+			if got := readAll(t, r2); len(got) != 1 || got[0].ID != "d" {
+				t.Errorf("Expected only d after skipping 2, got %+v", got)
+			}
+			if err := r2.Skip(1); err == nil {
+				t.Error("Expected error skipping past the end")
+			}
+		})
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	for _, format := range []string{JSONL, CSV, Parquet, NPY, NPZ} {
+		path := filepath.Join(t.TempDir(), "empty."+format)
+		w, err := Create(path, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		r, err := Open(path, "", "")
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got := readAll(t, r); len(got) != 0 {
+			t.Errorf("%s: expected no records, got %+v", format, got)
+		}
+		r.Close()
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	for path, want := range map[string]string{"a.jsonl": JSONL, "b.NDJSON": JSONL, "c.csv": CSV, "d.parquet": Parquet, "e.npy": NPY, "f.npz": NPZ} {
+		if got, err := DetectFormat(path); err != nil || got != want {
+			t.Errorf("DetectFormat(%s) = %s, %v", path, got, err)
+		}
+	}
+	if _, err := DetectFormat("g.txt"); err == nil {
+		t.Error("Expected error for unknown extension")
+	}
+	if _, err := Open("x.parquet", "bogus", ""); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := Open("-", NPY, ""); err == nil {
+		t.Error("Expected npy from stdin to be rejected")
+	}
+	if got := SidecarPath("/data/vectors.npz"); got != "/data/vectors.ids.jsonl" {
+		t.Errorf("Unexpected sidecar path %s", got)
+	}
+}
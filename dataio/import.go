@@ -0,0 +1,153 @@
+package dataio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Checkpoint 记录一次导入已写入的记录数，导入中断后可以从这里继续。
+// Size 和 ModTime 用于确认输入文件没有变化
+type Checkpoint struct {
+	Source  string    `json:"source"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Records int64     `json:"records"`
+}
+
+// LoadCheckpoint 读取检查点文件，文件不存在时返回 nil
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %v", path, err)
+	}
+	return &cp, nil
+}
+
+// Save 先写临时文件再重命名，中途崩溃不会留下损坏的检查点
+func (cp *Checkpoint) Save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// matches 判断检查点是否属于 source 的当前内容
+func (cp *Checkpoint) matches(source Checkpoint) bool {
+	return cp.Source == source.Source && cp.Size == source.Size && cp.ModTime.Equal(source.ModTime)
+}
+
+// ImportOptions 为 Import 的参数，零值使用默认值
+type ImportOptions struct {
+	BatchSize  int    // 每批写入的记录数，默认 1000
+	Source     string // 输入文件路径，用于校验检查点
+	Checkpoint string // 检查点文件路径，为空时不记录检查点
+	Resume     bool   // 从检查点记录的位置继续，没有检查点时从头开始
+	// Progress 在每批写入后调用，fraction 为已读取的比例，同 Reader.Progress
+	Progress func(res ImportResult, fraction float64)
+}
+
+func (o *ImportOptions) normalize() {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 1000
+	}
+}
+
+// ImportResult 为 Import 的结果
+type ImportResult struct {
+	Skipped  int64 `json:"skipped"`  // 续传时跳过的、之前已导入的记录数
+	Imported int64 `json:"imported"` // 本次写入的记录数
+}
+
+// Import 从 r 读取记录，每 BatchSize 条调用一次 write。每批写入成功后更新检查点，
+// 全部导入后删除检查点。write 应当是幂等的（如 upsert）：中断时最后一批可能已部分写入，续传时会重新写入
+func Import(r Reader, write func([]Record) error, opts ImportOptions) (ImportResult, error) {
+	opts.normalize()
+	var res ImportResult
+	var cp Checkpoint
+	if opts.Checkpoint != "" {
+		cp.Source = opts.Source
+		if abs, err := filepath.Abs(opts.Source); err == nil {
+			cp.Source = abs
+		}
+		if fi, err := os.Stat(opts.Source); err == nil {
+			cp.Size, cp.ModTime = fi.Size(), fi.ModTime()
+		}
+		if opts.Resume {
+			saved, err := LoadCheckpoint(opts.Checkpoint)
+			if err != nil {
+				return res, err
+			}
+			if saved != nil {
+				if !saved.matches(cp) {
+					return res, fmt.Errorf("checkpoint %s does not match %s, remove it to start over", opts.Checkpoint, opts.Source)
+				}
+				if err := r.Skip(saved.Records); err != nil {
+					return res, fmt.Errorf("resume after %d records: %v", saved.Records, err)
+				}
+				res.Skipped = saved.Records
+			}
+		}
+	}
+
+	batch := make([]Record, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := write(batch); err != nil {
+			return fmt.Errorf("batch ending at record %d: %v", res.Skipped+res.Imported+int64(len(batch)), err)
+		}
+		res.Imported += int64(len(batch))
+		batch = batch[:0]
+		if opts.Checkpoint != "" {
+			cp.Records = res.Skipped + res.Imported
+			if err := cp.Save(opts.Checkpoint); err != nil {
+				return err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(res, r.Progress())
+		}
+		return nil
+	}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+		batch = append(batch, rec)
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return res, err
+	}
+	if opts.Checkpoint != "" {
+		if err := os.Remove(opts.Checkpoint); err != nil && !os.IsNotExist(err) {
+			return res, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,83 @@
+package dataio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "points.jsonl")
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf(`{"id": "p%d", "vector": [%d]}`, i, i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint := path + ".checkpoint"
+	opts := ImportOptions{BatchSize: 3, Source: path, Checkpoint: checkpoint, Resume: true}
+
+	// 第三批写入失败，前两批（6 条）已记录在检查点中
+	var written []string
+	write := func(fail bool) func([]Record) error {
+		return func(batch []Record) error {
+			if fail && len(written) == 6 {
+				return fmt.Errorf("disk full")
+			}
+			for _, r := range batch {
+				written = append(written, r.ID)
+			}
+			return nil
+		}
+	}
+	r, _ := Open(path, "", "")
+	res, err := Import(r, write(true), opts)
+	r.Close()
+	if err == nil || !strings.Contains(err.Error(), "batch ending at record 9") {
+		t.Fatalf("Expected failing batch, got %v", err)
+	}
+	if res.Imported != 6 {
+		t.Errorf("Expected 6 imported before failure, got %+v", res)
+	}
+	if cp, err := LoadCheckpoint(checkpoint); err != nil || cp == nil || cp.Records != 6 {
+		t.Fatalf("Unexpected checkpoint %+v, %v", cp, err)
+	}
+
+	var progress []int64
+	opts.Progress = func(res ImportResult, fraction float64) {
+		progress = append(progress, res.Skipped+res.Imported)
+	}
+	r, _ = Open(path, "", "")
+	res, err = Import(r, write(false), opts)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Skipped != 6 || res.Imported != 4 {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	if got := strings.Join(written, ","); got != "p0,p1,p2,p3,p4,p5,p6,p7,p8,p9" {
+		t.Errorf("Unexpected writes %s", got)
+	}
+	if fmt.Sprint(progress) != "[9 10]" {
+		t.Errorf("Unexpected progress %v", progress)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Error("Expected checkpoint to be removed after a complete import")
+	}
+
+	// 输入文件变化后检查点失效
+	stale := Checkpoint{Source: path, Records: 3}
+	if err := stale.Save(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = Open(path, "", "")
+	defer r.Close()
+	if _, err := Import(r, write(false), opts); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected stale checkpoint to be rejected, got %v", err)
+	}
+}
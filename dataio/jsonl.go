@@ -0,0 +1,82 @@
+package dataio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONLReader 读取每行一个 {"id", "vector", "meta", "payload"} 的 JSONL，空行会被忽略
+type JSONLReader struct {
+	in   *countingReader
+	line int
+}
+
+// NewJSONLReader 创建 JSONL 读取器，size 为输入的总字节数（用于估计进度），未知时传 -1
+func NewJSONLReader(r io.Reader, size int64) *JSONLReader {
+	return &JSONLReader{in: newCountingReader(r, size)}
+}
+
+// next 返回下一个非空行，行没有长度限制
+func (r *JSONLReader) next() ([]byte, error) {
+	for {
+		line, err := r.in.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return nil, err
+		}
+		r.line++
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *JSONLReader) Read() (Record, error) {
+	line, err := r.next()
+	if err != nil {
+		return Record{}, err
+	}
+	var rec Record
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return Record{}, fmt.Errorf("line %d: %v", r.line, err)
+	}
+	return rec, nil
+}
+
+// Skip 跳过 n 个非空行，不解析内容
+func (r *JSONLReader) Skip(n int64) error {
+	for ; n > 0; n-- {
+		if _, err := r.next(); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *JSONLReader) Progress() float64 { return r.in.progress() }
+
+func (r *JSONLReader) Close() error { return nil }
+
+// JSONLWriter 把记录写成 JSONL，格式与 JSONLReader 相同
+type JSONLWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	bw := bufio.NewWriter(w)
+	return &JSONLWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (w *JSONLWriter) Write(r Record) error { return w.enc.Encode(r) }
+
+// Close 写出缓冲区，不关闭底层的 io.Writer
+func (w *JSONLWriter) Close() error { return w.w.Flush() }
@@ -0,0 +1,423 @@
+package dataio
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gvdb/storage"
+)
+
+// NumPy 格式：.npy 为 (N, D) 的 float32 或 float64 矩阵，.npz 为包含这样一个 .npy 的 zip（如 np.savez(f, vectors=...)）。
+// ID 和元数据保存在旁路的 JSONL 文件中，每行 {"id", "meta", "payload"}，行序与矩阵的行相同
+
+// npzEntry 为 .npz 中向量矩阵的默认名称
+const npzEntry = "vectors.npy"
+
+// npyMagic 为 .npy 文件的魔数
+const npyMagic = "\x93NUMPY"
+
+// npyHeaderSize 为写出的 .npy 文件头的固定长度，预留足够的空间以便 Close 时回填行数
+const npyHeaderSize = 128
+
+// npyMaxDim 为读取时接受的最大向量维度，防止损坏的文件头导致分配过大的行缓冲区
+const npyMaxDim = 1 << 16
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// sidecarRow 为旁路文件的一行
+type sidecarRow struct {
+	ID      string          `json:"id"`
+	Meta    string          `json:"meta,omitempty"`
+	Payload storage.Payload `json:"payload,omitempty"`
+}
+
+// NumPyReader 逐行读取 .npy/.npz 中的向量，并从旁路文件读取对应的 ID 和元数据
+type NumPyReader struct {
+	closers []io.Closer
+	in      *bufio.Reader
+	order   binary.ByteOrder
+	word    int // 每个元素的字节数，4 或 8
+	rows    int64
+	dim     int
+	read    int64
+	buf     []byte
+	sidecar *JSONLReader // 为 nil 时以行号作为 ID
+}
+
+// OpenNumPy 打开 .npy 文件，npz 为 true 时打开 .npz 文件。sidecar 为空时使用 SidecarPath，该文件不存在时以行号（从 0 开始）作为 ID；
+// 显式指定的 sidecar 必须存在
+func OpenNumPy(path, sidecar string, npz bool) (_ *NumPyReader, err error) {
+	r := &NumPyReader{}
+	defer func() {
+		if err != nil {
+			r.Close()
+		}
+	}()
+
+	var in io.Reader
+	if npz {
+		z, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		r.closers = append(r.closers, z)
+		entry, err := findNPZEntry(&z.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		r.closers = append(r.closers, rc)
+		in = rc
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r.closers = append(r.closers, f)
+		in = f
+	}
+	r.in = bufio.NewReaderSize(in, 1<<20)
+	if err := r.readHeader(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	optional := sidecar == ""
+	if optional {
+		sidecar = SidecarPath(path)
+	}
+	f, err := os.Open(sidecar)
+	if os.IsNotExist(err) && optional {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.closers = append(r.closers, f)
+	r.sidecar = NewJSONLReader(f, -1)
+	return r, nil
+}
+
+// findNPZEntry 返回 .npz 中名为 vectors.npy 的数组，没有时返回唯一的一个 .npy 数组
+func findNPZEntry(z *zip.Reader) (*zip.File, error) {
+	var arrays []*zip.File
+	for _, f := range z.File {
+		if f.Name == npzEntry {
+			return f, nil
+		}
+		if strings.HasSuffix(f.Name, ".npy") {
+			arrays = append(arrays, f)
+		}
+	}
+	if len(arrays) != 1 {
+		return nil, fmt.Errorf("expected a %s array or exactly one array, found %d", npzEntry, len(arrays))
+	}
+	return arrays[0], nil
+}
+
+// readHeader 解析 .npy 文件头，只接受二维、C 顺序的 float32/float64 矩阵
+func (r *NumPyReader) readHeader() error {
+	prefix := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r.in, prefix); err != nil || string(prefix[:len(npyMagic)]) != npyMagic {
+		return fmt.Errorf("not a .npy file")
+	}
+	var size int
+	switch major := prefix[len(npyMagic)]; major {
+	case 1:
+		var n uint16
+		if err := binary.Read(r.in, binary.LittleEndian, &n); err != nil {
+			return err
+		}
+		size = int(n)
+	case 2, 3:
+		var n uint32
+		if err := binary.Read(r.in, binary.LittleEndian, &n); err != nil {
+			return err
+		}
+		size = int(n)
+	default:
+		return fmt.Errorf("unsupported .npy version %d", major)
+	}
+	header := make([]byte, size)
+	if _, err := io.ReadFull(r.in, header); err != nil {
+		return err
+	}
+
+	descr := npyDescr.FindSubmatch(header)
+	fortran := npyFortran.FindSubmatch(header)
+	shape := npyShape.FindSubmatch(header)
+	if descr == nil || fortran == nil || shape == nil {
+		return fmt.Errorf("invalid .npy header %q", header)
+	}
+	switch d := string(descr[1]); d {
+	case "<f4", "=f4", "|f4", ">f4", "<f8", "=f8", "|f8", ">f8":
+		r.order = binary.ByteOrder(binary.LittleEndian)
+		if d[0] == '>' {
+			r.order = binary.BigEndian
+		}
+		r.word = int(d[2] - '0')
+	default:
+		return fmt.Errorf("unsupported dtype %s, expected float32 or float64", d)
+	}
+	if string(fortran[1]) == "True" {
+		return fmt.Errorf("fortran order arrays are not supported")
+	}
+	var dims []int64
+	for _, s := range strings.Split(string(shape[1]), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid shape %q", shape[1])
+		}
+		dims = append(dims, n)
+	}
+	if len(dims) != 2 {
+		return fmt.Errorf("expected a 2-d array, got shape (%s)", shape[1])
+	}
+	// 空矩阵的形状为 (0, 0)，与 Writer 没有写入任何行时一致
+	if dims[0] < 0 || dims[1] < 0 || dims[1] > npyMaxDim || (dims[1] == 0 && dims[0] > 0) {
+		return fmt.Errorf("invalid shape (%s): need rows >= 0 and 1 <= dim <= %d", shape[1], npyMaxDim)
+	}
+	r.rows, r.dim = dims[0], int(dims[1])
+	r.buf = make([]byte, r.dim*r.word)
+	return nil
+}
+
+func (r *NumPyReader) Read() (Record, error) {
+	if r.read == r.rows {
+		if r.sidecar != nil {
+			if _, err := r.sidecar.next(); err != io.EOF {
+				return Record{}, fmt.Errorf("sidecar has more rows than the %d vectors", r.rows)
+			}
+		}
+		return Record{}, io.EOF
+	}
+	if _, err := io.ReadFull(r.in, r.buf); err != nil {
+		return Record{}, fmt.Errorf("row %d: %v", r.read, err)
+	}
+	vec := make([]float32, r.dim)
+	for i := range vec {
+		if r.word == 4 {
+			vec[i] = math.Float32frombits(r.order.Uint32(r.buf[i*4:]))
+		} else {
+			vec[i] = float32(math.Float64frombits(r.order.Uint64(r.buf[i*8:])))
+		}
+	}
+	rec := Record{ID: strconv.FormatInt(r.read, 10), Vector: vec}
+	if r.sidecar != nil {
+		side, err := r.sidecar.Read()
+		if err == io.EOF {
+			return Record{}, fmt.Errorf("sidecar has fewer rows than the %d vectors", r.rows)
+		}
+		if err != nil {
+			return Record{}, fmt.Errorf("sidecar: %v", err)
+		}
+		rec.ID, rec.Meta, rec.Payload = side.ID, side.Meta, side.Payload
+	}
+	r.read++
+	return rec, nil
+}
+
+func (r *NumPyReader) Skip(n int64) error {
+	if r.read+n > r.rows {
+		return io.ErrUnexpectedEOF
+	}
+	if _, err := r.in.Discard(int(n) * len(r.buf)); err != nil {
+		return err
+	}
+	if r.sidecar != nil {
+		if err := r.sidecar.Skip(n); err != nil {
+			return fmt.Errorf("sidecar: %v", err)
+		}
+	}
+	r.read += n
+	return nil
+}
+
+func (r *NumPyReader) Progress() float64 {
+	if r.rows == 0 {
+		return 1
+	}
+	return float64(r.read) / float64(r.rows)
+}
+
+func (r *NumPyReader) Close() error {
+	var err error
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		if closeErr := r.closers[i].Close(); err == nil {
+			err = closeErr
+		}
+	}
+	r.closers = nil
+	return err
+}
+
+// NumPyWriter 把向量写成 float32 的 .npy/.npz，ID 和元数据写入旁路文件；所有记录的维度必须相同
+type NumPyWriter struct {
+	path    string
+	npz     bool
+	f       *os.File // .npy 文件，写 .npz 时为临时文件，Close 时再打包
+	w       *bufio.Writer
+	dim     int
+	rows    int64
+	buf     []byte
+	side    *os.File
+	sideW   *bufio.Writer
+	sideEnc *json.Encoder
+}
+
+// CreateNumPy 创建 .npy 文件（npz 为 true 时为 .npz 文件）和旁路文件，sidecar 为空时使用 SidecarPath
+func CreateNumPy(path, sidecar string, npz bool) (*NumPyWriter, error) {
+	if sidecar == "" {
+		sidecar = SidecarPath(path)
+	}
+	w := &NumPyWriter{path: path, npz: npz, dim: -1}
+	var err error
+	if w.npz {
+		w.f, err = os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.npy")
+	} else {
+		w.f, err = os.Create(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if w.side, err = os.Create(sidecar); err != nil {
+		w.abort()
+		return nil, err
+	}
+	w.w = bufio.NewWriterSize(w.f, 1<<20)
+	w.sideW = bufio.NewWriter(w.side)
+	w.sideEnc = json.NewEncoder(w.sideW)
+	// 先写占位的文件头，行数在 Close 时回填
+	if _, err := w.w.Write(npyHeader(0, 0)); err != nil {
+		w.abort()
+		return nil, err
+	}
+	return w, nil
+}
+
+// npyHeader 返回 (rows, dim) 的 float32 矩阵的 .npy 1.0 文件头，长度固定为 npyHeaderSize
+func npyHeader(rows int64, dim int) []byte {
+	var b bytes.Buffer
+	b.WriteString(npyMagic)
+	b.Write([]byte{1, 0})
+	binary.Write(&b, binary.LittleEndian, uint16(npyHeaderSize-b.Len()-2))
+	fmt.Fprintf(&b, "{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", rows, dim)
+	for b.Len() < npyHeaderSize-1 {
+		b.WriteByte(' ')
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+func (w *NumPyWriter) Write(r Record) error {
+	if w.dim < 0 {
+		w.dim = len(r.Vector)
+		w.buf = make([]byte, 4*w.dim)
+	}
+	if len(r.Vector) != w.dim {
+		return fmt.Errorf("npy: record %s has dimension %d, expected %d", r.ID, len(r.Vector), w.dim)
+	}
+	for i, f := range r.Vector {
+		binary.LittleEndian.PutUint32(w.buf[i*4:], math.Float32bits(f))
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+	w.rows++
+	return w.sideEnc.Encode(sidecarRow{ID: r.ID, Meta: r.Meta, Payload: r.Payload})
+}
+
+func (w *NumPyWriter) Close() error {
+	err := w.finish()
+	if err != nil {
+		w.abort()
+	}
+	return err
+}
+
+func (w *NumPyWriter) finish() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.f.WriteAt(npyHeader(w.rows, max(w.dim, 0)), 0); err != nil {
+		return err
+	}
+	if err := w.sideW.Flush(); err != nil {
+		return err
+	}
+	if err := w.side.Close(); err != nil {
+		return err
+	}
+	w.side = nil
+	if w.npz {
+		return w.pack()
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// pack 把临时的 .npy 文件以不压缩的方式打包为 .npz，与 np.savez 相同
+func (w *NumPyWriter) pack() (err error) {
+	defer func() {
+		w.f.Close()
+		os.Remove(w.f.Name())
+		w.f = nil
+	}()
+	out, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	z := zip.NewWriter(out)
+	entry, err := z.CreateHeader(&zip.FileHeader{Name: npzEntry, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(entry, w.f); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// abort 在出错时关闭文件，并删除写 .npz 用的临时文件
+func (w *NumPyWriter) abort() {
+	if w.f != nil {
+		w.f.Close()
+		if w.npz {
+			os.Remove(w.f.Name())
+		}
+		w.f = nil
+	}
+	if w.side != nil {
+		w.side.Close()
+		w.side = nil
+	}
+}
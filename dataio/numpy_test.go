@@ -0,0 +1,126 @@
+package dataio
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// npyFloat64 按 np.save 的方式编码 float64 矩阵
+func npyFloat64(rows [][]float64) []byte {
+	header := "{'descr': '<f8', 'fortran_order': False, 'shape': (" + strconv.Itoa(len(rows)) + ", " + strconv.Itoa(len(rows[0])) + "), }"
+	for (10+len(header)+1)%64 != 0 {
+		header += " "
+	}
+	header += "\n"
+	var b bytes.Buffer
+	b.WriteString(npyMagic)
+	b.Write([]byte{1, 0})
+	binary.Write(&b, binary.LittleEndian, uint16(len(header)))
+	b.WriteString(header)
+	for _, row := range rows {
+		binary.Write(&b, binary.LittleEndian, row)
+	}
+	return b.Bytes()
+}
+
+func TestNumPyFloat64(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "emb.npy")
+	if err := os.WriteFile(path, npyFloat64([][]float64{{1, 2}, {3, 4}, {5, 6}}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有旁路文件时以行号作为 ID
+	r, err := Open(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readAll(t, r)
+	r.Close()
+	want := []Record{{ID: "0", Vector: []float32{1, 2}}, {ID: "1", Vector: []float32{3, 4}}, {ID: "2", Vector: []float32{5, 6}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected records %+v", got)
+	}
+
+	// 显式指定的旁路文件必须存在
+	if _, err := Open(path, "", filepath.Join(dir, "missing.jsonl")); err == nil {
+		t.Error("Expected error for missing sidecar")
+	}
+
+	// 旁路文件的行数与矩阵不一致
+	if err := os.WriteFile(SidecarPath(path), []byte(`{"id": "x"}`+"\n"+`{"id": "y", "payload": {"n": 1}}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err = Open(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i, id := range []string{"x", "y"} {
+		rec, err := r.Read()
+		if err != nil || rec.ID != id {
+			t.Fatalf("Row %d: %+v, %v", i, rec, err)
+		}
+	}
+	if _, err := r.Read(); err == nil {
+		t.Error("Expected error when the sidecar has fewer rows")
+	}
+}
+
+func TestNumPyNPZ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emb.npz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	// np.savez_compressed(f, emb=...) 会压缩数组，名称也不一定是 vectors
+	w, _ := z.Create("emb.npy")
+	w.Write(npyFloat64([][]float64{{0.5}, {1.5}}))
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	r, err := Open(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.Skip(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, r); len(got) != 1 || got[0].ID != "1" || got[0].Vector[0] != 1.5 {
+		t.Errorf("Unexpected records %+v", got)
+	}
+}
+
+func TestNumPyHeader(t *testing.T) {
+	// Close 时回填的文件头长度不变，且满足 numpy 的 64 字节对齐
+	for _, h := range [][]byte{npyHeader(0, 0), npyHeader(1<<40, 4096)} {
+		if len(h) != npyHeaderSize || h[len(h)-1] != '\n' {
+			t.Errorf("Unexpected header %q", h)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "bad.npy")
+	bad := bytes.Replace(npyFloat64([][]float64{{1}}), []byte("False"), []byte("True "), 1)
+	os.WriteFile(path, bad, 0644)
+	if _, err := Open(path, "", ""); err == nil {
+		t.Error("Expected fortran order array to be rejected")
+	}
+
+	// 负数、零或过大的维度以及负的行数返回错误，而不是在分配行缓冲区时崩溃
+	for _, shape := range []string{"(1, -1)", "(1, 0)", "(-1, 1)", "(1, 9999999999)"} {
+		bad := bytes.Replace(npyFloat64([][]float64{{1}}), []byte("(1, 1)"), []byte(shape), 1)
+		os.WriteFile(path, bad, 0644)
+		if _, err := Open(path, "", ""); err == nil {
+			t.Errorf("Expected shape %s to be rejected", shape)
+		}
+	}
+}
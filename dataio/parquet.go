@@ -0,0 +1,177 @@
+package dataio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/parquet-go/parquet-go"
+
+	"gvdb/storage"
+)
+
+// parquetRow 为 Parquet 文件的一行：vector 为 float 列表，payload 为 JSON 文本
+type parquetRow struct {
+	ID      string    `parquet:"id"`
+	Vector  []float32 `parquet:"vector,list"`
+	Meta    string    `parquet:"meta,optional"`
+	Payload string    `parquet:"payload,optional"`
+}
+
+// parquetBatch 为每次从文件读取或向文件写入的行数
+const parquetBatch = 1024
+
+// parquetRowGroup 为每个行组的行数，写满一个行组就落盘，写大文件时内存占用有上限
+const parquetRowGroup = 64 * 1024
+
+// ParquetReader 按批读取 Parquet 文件的行
+type ParquetReader struct {
+	f    *os.File
+	r    *parquet.GenericReader[parquetRow]
+	rows []parquetRow
+	pos  int   // rows 中下一行的下标
+	read int64 // 已返回或跳过的行数
+}
+
+// OpenParquet 打开 Parquet 文件，文件必须有 id 和 vector 列，meta 和 payload 列可选
+func OpenParquet(path string) (_ *ParquetReader, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	pf, err := parquet.OpenFile(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("parquet: %v", err)
+	}
+	for _, name := range []string{"id", "vector"} {
+		if !hasField(pf.Schema(), name) {
+			f.Close()
+			return nil, fmt.Errorf("parquet: %s has no %s column", path, name)
+		}
+	}
+	// 文件的 schema 与 parquetRow 不兼容时 parquet-go 会 panic
+	defer func() {
+		if p := recover(); p != nil {
+			f.Close()
+			err = fmt.Errorf("parquet: %v", p)
+		}
+	}()
+	return &ParquetReader{f: f, r: parquet.NewGenericReader[parquetRow](pf)}, nil
+}
+
+func hasField(schema *parquet.Schema, name string) bool {
+	for _, field := range schema.Fields() {
+		if field.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ParquetReader) Read() (Record, error) {
+	if p.pos == len(p.rows) {
+		if p.rows == nil {
+			p.rows = make([]parquetRow, parquetBatch)
+		}
+		// 清空上一批的行，避免 parquet-go 复用其中的向量切片
+		p.rows = p.rows[:cap(p.rows)]
+		clear(p.rows)
+		n, err := p.r.Read(p.rows)
+		p.rows, p.pos = p.rows[:n], 0
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return Record{}, err
+		}
+	}
+	row := p.rows[p.pos]
+	p.pos++
+	p.read++
+	rec := Record{ID: row.ID, Vector: row.Vector, Meta: row.Meta}
+	var err error
+	if rec.Payload, err = storage.DecodePayload([]byte(row.Payload)); err != nil {
+		return Record{}, fmt.Errorf("row %d: payload: %v", p.read, err)
+	}
+	return rec, nil
+}
+
+// Skip 直接定位到第 n 行之后，不读取被跳过的行
+func (p *ParquetReader) Skip(n int64) error {
+	if p.read+n > p.r.NumRows() {
+		return io.ErrUnexpectedEOF
+	}
+	if buffered := int64(len(p.rows) - p.pos); n <= buffered {
+		p.pos += int(n)
+		p.read += n
+		return nil
+	}
+	p.read += n
+	p.rows, p.pos = p.rows[:0], 0
+	return p.r.SeekToRow(p.read)
+}
+
+func (p *ParquetReader) Progress() float64 {
+	if p.r.NumRows() == 0 {
+		return 1
+	}
+	return float64(p.read) / float64(p.r.NumRows())
+}
+
+func (p *ParquetReader) Close() error {
+	err := p.r.Close()
+	if closeErr := p.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ParquetWriter 按批写出 Parquet 行
+type ParquetWriter struct {
+	w    *parquet.GenericWriter[parquetRow]
+	rows []parquetRow
+}
+
+// NewParquetWriter 创建写入 w 的 Parquet 写入器，Close 时写出文件尾部但不关闭 w
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	return &ParquetWriter{
+		w:    parquet.NewGenericWriter[parquetRow](w, parquet.MaxRowsPerRowGroup(parquetRowGroup)),
+		rows: make([]parquetRow, 0, parquetBatch),
+	}
+}
+
+func (p *ParquetWriter) Write(r Record) error {
+	row := parquetRow{ID: r.ID, Vector: r.Vector, Meta: r.Meta}
+	if r.Payload != nil {
+		data, err := json.Marshal(r.Payload)
+		if err != nil {
+			return err
+		}
+		row.Payload = string(data)
+	}
+	p.rows = append(p.rows, row)
+	if len(p.rows) == cap(p.rows) {
+		return p.flush()
+	}
+	return nil
+}
+
+func (p *ParquetWriter) flush() error {
+	_, err := p.w.Write(p.rows)
+	p.rows = p.rows[:0]
+	return err
+}
+
+func (p *ParquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
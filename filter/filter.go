@@ -0,0 +1,211 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Document 为解析后的元数据，字段可以嵌套，用点号路径访问（如 "author.name"）
+type Document map[string]interface{}
+
+// Filter 为元数据谓词
+type Filter interface {
+	Match(doc Document) bool
+}
+
+// ParseMeta 把 JSON 对象形式的元数据解析为 Document，不是 JSON 对象时返回 nil
+func ParseMeta(meta string) Document {
+	meta = strings.TrimSpace(meta)
+	if !strings.HasPrefix(meta, "{") {
+		return nil
+	}
+	var doc Document
+	if err := json.Unmarshal([]byte(meta), &doc); err != nil {
+		return nil
+	}
+	return doc
+}
+
+// Lookup 按点号路径读取字段
+func (d Document) Lookup(field string) (interface{}, bool) {
+	var cur interface{} = map[string]interface{}(d)
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Eq 匹配字段等于 Value 的文档；字段为数组时任一元素相等即匹配
+type Eq struct {
+	Field string
+	Value interface{}
+}
+
+func (f Eq) Match(doc Document) bool {
+	return anyValue(doc, f.Field, func(v interface{}) bool { return equal(v, f.Value) })
+}
+
+// In 匹配字段等于 Values 中任一值的文档
+type In struct {
+	Field  string
+	Values []interface{}
+}
+
+func (f In) Match(doc Document) bool {
+	return anyValue(doc, f.Field, func(v interface{}) bool {
+		for _, want := range f.Values {
+			if equal(v, want) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Range 匹配字段落在区间内的文档，Min/Max 为 nil 表示该侧不设限；数字和字符串均可比较
+type Range struct {
+	Field        string
+	Min, Max     interface{}
+	MinExclusive bool
+	MaxExclusive bool
+}
+
+func (f Range) Match(doc Document) bool {
+	return anyValue(doc, f.Field, func(v interface{}) bool {
+		if f.Min != nil {
+			c, ok := compare(v, f.Min)
+			if !ok || c < 0 || (c == 0 && f.MinExclusive) {
+				return false
+			}
+		}
+		if f.Max != nil {
+			c, ok := compare(v, f.Max)
+			if !ok || c > 0 || (c == 0 && f.MaxExclusive) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Gt、Gte、Lt、Lte 和 Between 为常用区间的简写，Between 两端都包含
+func Gt(field string, v interface{}) Range  { return Range{Field: field, Min: v, MinExclusive: true} }
+func Gte(field string, v interface{}) Range { return Range{Field: field, Min: v} }
+func Lt(field string, v interface{}) Range  { return Range{Field: field, Max: v, MaxExclusive: true} }
+func Lte(field string, v interface{}) Range { return Range{Field: field, Max: v} }
+func Between(field string, min, max interface{}) Range {
+	return Range{Field: field, Min: min, Max: max}
+}
+
+// And 在所有子条件都匹配时匹配，空 And 匹配所有文档
+type And []Filter
+
+func (f And) Match(doc Document) bool {
+	for _, sub := range f {
+		if !sub.Match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// Or 在任一子条件匹配时匹配，空 Or 不匹配任何文档
+type Or []Filter
+
+func (f Or) Match(doc Document) bool {
+	for _, sub := range f {
+		if sub.Match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+// Not 对子条件取反
+type Not struct {
+	Filter Filter
+}
+
+func (f Not) Match(doc Document) bool {
+	return !f.Filter.Match(doc)
+}
+
+func anyValue(doc Document, field string, pred func(interface{}) bool) bool {
+	v, ok := doc.Lookup(field)
+	if !ok {
+		return false
+	}
+	if arr, ok := v.([]interface{}); ok {
+		for _, e := range arr {
+			if pred(e) {
+				return true
+			}
+		}
+		return false
+	}
+	return pred(v)
+}
+
+func equal(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	ab, aok := a.(bool)
+	bb, bok := b.(bool)
+	return aok && bok && ab == bb
+}
+
+// compare 比较两个数字或两个字符串，类型不可比较时 ok 为 false
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := Number(a); ok {
+		y, ok := Number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, aok := a.(string)
+	y, bok := b.(string)
+	if !aok || !bok {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// Number 把 JSON 解码或 Go 代码中常见的数字类型转为 float64，非数字时 ok 为 false
+func Number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,55 @@
+package filter
+
+import "testing"
+
+func TestParseMeta(t *testing.T) {
+	doc := ParseMeta(`{"lang": "go", "year": 2021, "author": {"name": "ljq"}, "tags": ["db", "ann"]}`)
+	if doc == nil {
+		t.Fatal("Expected JSON object meta to be parsed")
+	}
+	if v, ok := doc.Lookup("author.name"); !ok || v != "ljq" {
+		t.Errorf("Expected nested field author.name, got %v", v)
+	}
+	if _, ok := doc.Lookup("author.email"); ok {
+		t.Error("Expected missing nested field")
+	}
+	// 非 JSON 对象的元数据没有字段
+	if ParseMeta("Hello world") != nil || ParseMeta("[1,2]") != nil {
+		t.Error("Expected plain string meta to yield nil document")
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	doc := ParseMeta(`{"lang": "go", "year": 2021, "score": 0.5, "draft": false, "tags": ["db", "ann"]}`)
+	cases := []struct {
+		name string
+		f    Filter
+		want bool
+	}{
+		{"eq string", Eq{Field: "lang", Value: "go"}, true},
+		{"eq int against float64", Eq{Field: "year", Value: 2021}, true},
+		{"eq bool", Eq{Field: "draft", Value: false}, true},
+		{"eq mismatch", Eq{Field: "lang", Value: "rust"}, false},
+		{"eq missing field", Eq{Field: "missing", Value: 1}, false},
+		{"eq array element", Eq{Field: "tags", Value: "ann"}, true},
+		{"in", In{Field: "lang", Values: []interface{}{"rust", "go"}}, true},
+		{"in miss", In{Field: "lang", Values: []interface{}{"rust", "c"}}, false},
+		{"gte", Gte("year", 2021), true},
+		{"gt", Gt("year", 2021), false},
+		{"lt", Lt("score", 0.6), true},
+		{"between", Between("year", 2020, 2022), true},
+		{"string range", Between("lang", "a", "h"), true},
+		{"range type mismatch", Gt("lang", 1), false},
+		{"and", And{Eq{Field: "lang", Value: "go"}, Gte("year", 2020)}, true},
+		{"and short", And{Eq{Field: "lang", Value: "go"}, Gte("year", 2022)}, false},
+		{"or", Or{Eq{Field: "lang", Value: "rust"}, Gte("year", 2020)}, true},
+		{"not", Not{Filter: Eq{Field: "lang", Value: "go"}}, false},
+		{"empty and", And{}, true},
+		{"empty or", Or{}, false},
+	}
+	for _, c := range cases {
+		if got := c.f.Match(doc); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
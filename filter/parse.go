@@ -0,0 +1,210 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Parse 解析 JSON 形式的过滤条件，语法与 MongoDB 查询类似：
+//
+//	{"lang": "go"}                             等值
+//	{"year": {"$gte": 2020, "$lt": 2024}}      区间（$gt、$gte、$lt、$lte）
+//	{"tag": {"$in": ["a", "b"]}}               IN（$nin 为 NOT IN），$eq、$ne 同理
+//	{"title": {"$match": "vector database"}}   全文匹配
+//	{"$and": [...]}、{"$or": [...]}、{"$not": {...}}
+//
+// 同一对象中的多个键按 AND 组合
+func Parse(data []byte) (Filter, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return parseValue(raw)
+}
+
+func parseValue(raw interface{}) (Filter, error) {
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("filter must be a JSON object, got %T", raw)
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var all And
+	for _, key := range keys {
+		val := obj[key]
+		switch key {
+		case "$and", "$or":
+			arr, ok := val.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s expects an array", key)
+			}
+			subs := make([]Filter, 0, len(arr))
+			for _, e := range arr {
+				sub, err := parseValue(e)
+				if err != nil {
+					return nil, err
+				}
+				subs = append(subs, sub)
+			}
+			if key == "$and" {
+				all = append(all, And(subs))
+			} else {
+				all = append(all, Or(subs))
+			}
+		case "$not":
+			sub, err := parseValue(val)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, Not{Filter: sub})
+		default:
+			if len(key) > 0 && key[0] == '$' {
+				return nil, fmt.Errorf("unknown filter operator: %s", key)
+			}
+			f, err := parseField(key, val)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, f)
+		}
+	}
+	if len(all) == 1 {
+		return all[0], nil
+	}
+	return all, nil
+}
+
+func parseField(field string, val interface{}) (Filter, error) {
+	ops, ok := val.(map[string]interface{})
+	if !ok || !hasOperator(ops) {
+		return Eq{Field: field, Value: val}, nil
+	}
+
+	var all And
+	r := Range{Field: field}
+	hasRange := false
+	opNames := make([]string, 0, len(ops))
+	for op := range ops {
+		opNames = append(opNames, op)
+	}
+	sort.Strings(opNames)
+	for _, op := range opNames {
+		arg := ops[op]
+		switch op {
+		case "$eq":
+			all = append(all, Eq{Field: field, Value: arg})
+		case "$ne":
+			all = append(all, Not{Filter: Eq{Field: field, Value: arg}})
+		case "$in", "$nin":
+			values, ok := arg.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s on %s expects an array", op, field)
+			}
+			if op == "$in" {
+				all = append(all, In{Field: field, Values: values})
+			} else {
+				all = append(all, Not{Filter: In{Field: field, Values: values}})
+			}
+		case "$match":
+			text, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("$match on %s expects a string", field)
+			}
+			all = append(all, Match{Field: field, Text: text})
+		case "$gt", "$gte":
+			r.Min, r.MinExclusive, hasRange = arg, op == "$gt", true
+		case "$lt", "$lte":
+			r.Max, r.MaxExclusive, hasRange = arg, op == "$lt", true
+		default:
+			return nil, fmt.Errorf("unknown filter operator: %s", op)
+		}
+	}
+	if hasRange {
+		all = append(all, r)
+	}
+	if len(all) == 1 {
+		return all[0], nil
+	}
+	return all, nil
+}
+
+func hasOperator(obj map[string]interface{}) bool {
+	for k := range obj {
+		if len(k) > 0 && k[0] == '$' {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshal 把 Filter 编码为 Parse 的 JSON 语法，用于通过网络传递过滤条件
+func Marshal(f Filter) ([]byte, error) {
+	v, err := encode(f)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func encode(f Filter) (map[string]interface{}, error) {
+	switch f := f.(type) {
+	case Eq:
+		return field(f.Field, "$eq", f.Value), nil
+	case In:
+		values := f.Values
+		if values == nil {
+			values = []interface{}{}
+		}
+		return field(f.Field, "$in", values), nil
+	case Match:
+		return field(f.Field, "$match", f.Text), nil
+	case Range:
+		ops := make(map[string]interface{})
+		if f.Min != nil && f.MinExclusive {
+			ops["$gt"] = f.Min
+		} else if f.Min != nil {
+			ops["$gte"] = f.Min
+		}
+		if f.Max != nil && f.MaxExclusive {
+			ops["$lt"] = f.Max
+		} else if f.Max != nil {
+			ops["$lte"] = f.Max
+		}
+		if len(ops) == 0 {
+			return nil, fmt.Errorf("range on %s has no bounds", f.Field)
+		}
+		return map[string]interface{}{f.Field: ops}, nil
+	case And:
+		return encodeAll("$and", f)
+	case Or:
+		return encodeAll("$or", f)
+	case Not:
+		sub, err := encode(f.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$not": sub}, nil
+	}
+	return nil, fmt.Errorf("filter %T cannot be encoded", f)
+}
+
+func field(name, op string, arg interface{}) map[string]interface{} {
+	return map[string]interface{}{name: map[string]interface{}{op: arg}}
+}
+
+func encodeAll(op string, subs []Filter) (map[string]interface{}, error) {
+	arr := make([]interface{}, len(subs))
+	for i, sub := range subs {
+		v, err := encode(sub)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return map[string]interface{}{op: arr}, nil
+}
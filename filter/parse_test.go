@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Filter
+	}{
+		{`{"lang": "go"}`, Eq{Field: "lang", Value: "go"}},
+		{`{"year": {"$gte": 2020, "$lt": 2024}}`, Range{Field: "year", Min: 2020.0, Max: 2024.0, MaxExclusive: true}},
+		{`{"tag": {"$in": ["a", "b"]}}`, In{Field: "tag", Values: []interface{}{"a", "b"}}},
+		{`{"tag": {"$nin": ["a"]}}`, Not{Filter: In{Field: "tag", Values: []interface{}{"a"}}}},
+		{`{"lang": {"$ne": "go"}}`, Not{Filter: Eq{Field: "lang", Value: "go"}}},
+		{`{"lang": "go", "year": 2021}`, And{Eq{Field: "lang", Value: "go"}, Eq{Field: "year", Value: 2021.0}}},
+		{`{"$or": [{"lang": "go"}, {"$not": {"year": 2021}}]}`, Or{
+			Eq{Field: "lang", Value: "go"},
+			Not{Filter: Eq{Field: "year", Value: 2021.0}},
+		}},
+		{`{"title": {"$match": "Vector DB"}}`, Match{Field: "title", Text: "Vector DB"}},
+		// 不含操作符的对象按等值比较
+		{`{"author": {"name": "ljq"}}`, Eq{Field: "author", Value: map[string]interface{}{"name": "ljq"}}},
+	}
+	for _, c := range cases {
+		got, err := Parse([]byte(c.input))
+		if err != nil {
+			t.Errorf("Parse(%s): %v", c.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Parse(%s) = %#v, want %#v", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		`[1, 2]`,
+		`{"$and": {"a": 1}}`,
+		`{"$xor": []}`,
+		`{"year": {"$between": [1, 2]}}`,
+		`{"tag": {"$in": "a"}}`,
+		`{"title": {"$match": 1}}`,
+		`{not json`,
+	} {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	filters := []Filter{
+		Eq{Field: "lang", Value: "go"},
+		Eq{Field: "author", Value: map[string]interface{}{"$name": "ljq"}},
+		In{Field: "tag", Values: []interface{}{"a", "b"}},
+		Range{Field: "year", Min: 2020.0, Max: 2024.0, MaxExclusive: true},
+		Gt("score", 0.5),
+		Match{Field: "title", Text: "Vector DB"},
+		Or{
+			Eq{Field: "lang", Value: "go"},
+			Not{Filter: And{Eq{Field: "year", Value: 2021.0}, Lte("stars", 10.0)}},
+		},
+	}
+	for _, f := range filters {
+		data, err := Marshal(f)
+		if err != nil {
+			t.Errorf("Marshal(%#v): %v", f, err)
+			continue
+		}
+		got, err := Parse(data)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, f) {
+			t.Errorf("Round trip of %s: got %#v, want %#v", data, got, f)
+		}
+	}
+
+	if _, err := Marshal(Range{Field: "year"}); err == nil {
+		t.Error("Expected error for range without bounds")
+	}
+}
@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Match 为全文匹配：字段文本分词后包含 Text 的全部词元时匹配；字段为数组时任一元素满足即可
+type Match struct {
+	Field string
+	Text  string
+}
+
+func (f Match) Match(doc Document) bool {
+	want := Tokenize(f.Text)
+	return anyValue(doc, f.Field, func(v interface{}) bool {
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		have := make(map[string]bool)
+		for _, tok := range Tokenize(s) {
+			have[tok] = true
+		}
+		for _, tok := range want {
+			if !have[tok] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Tokenize 把文本转为小写词元：按非字母数字字符切分，汉字等表意文字每个字单独成词
+func Tokenize(text string) []string {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,40 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	cases := map[string][]string{
+		"Hello, World!":     {"hello", "world"},
+		"go1.22 HNSW-index": {"go1", "22", "hnsw", "index"},
+		"向量数据库 gvdb":        {"向", "量", "数", "据", "库", "gvdb"},
+		"   ":               nil,
+		"Café déjà-vu":      {"café", "déjà", "vu"},
+	}
+	for text, want := range cases {
+		if got := Tokenize(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	doc := Document{"title": "A Minimal Vector Database in Go", "tags": []interface{}{"ann search", "hnsw"}, "n": 1}
+	cases := []struct {
+		f    Match
+		want bool
+	}{
+		{Match{Field: "title", Text: "vector go"}, true},
+		{Match{Field: "title", Text: "database rust"}, false},
+		{Match{Field: "tags", Text: "search"}, true},
+		{Match{Field: "n", Text: "1"}, false},
+		{Match{Field: "missing", Text: "x"}, false},
+	}
+	for _, c := range cases {
+		if got := c.f.Match(doc); got != c.want {
+			t.Errorf("%+v: expected %v, got %v", c.f, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package grpcserver 以 gRPC 接口（gvdbpb/gvdb.proto）提供 service 层的功能，语义与 REST 接口相同
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"gvdb/config"
+	"gvdb/gvdbpb"
+	"gvdb/service"
+	"gvdb/storage"
+)
+
+type Server struct {
+	gvdbpb.UnimplementedVectorDBServer
+	svc *service.Service
+}
+
+func New(svc *service.Service) *Server {
+	return &Server{svc: svc}
+}
+
+// Register 把服务注册到 gRPC 服务器
+func Register(s *grpc.Server, svc *service.Service) {
+	gvdbpb.RegisterVectorDBServer(s, New(svc))
+}
+
+func (s *Server) ListCollections(ctx context.Context, req *gvdbpb.ListCollectionsRequest) (*gvdbpb.ListCollectionsResponse, error) {
+	return &gvdbpb.ListCollectionsResponse{Collections: s.svc.ListCollections()}, nil
+}
+
+func (s *Server) CreateCollection(ctx context.Context, req *gvdbpb.CreateCollectionRequest) (*gvdbpb.CollectionInfo, error) {
+	var cc config.CollectionConfig
+	if err := fromStruct(req.GetSpec(), &cc); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid collection spec: %v", err)
+	}
+	info, err := s.svc.CreateCollection(cc)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return collectionInfo(info)
+}
+
+func (s *Server) DescribeCollection(ctx context.Context, req *gvdbpb.DescribeCollectionRequest) (*gvdbpb.CollectionInfo, error) {
+	info, err := s.svc.DescribeCollection(req.GetCollection())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return collectionInfo(info)
+}
+
+func (s *Server) DropCollection(ctx context.Context, req *gvdbpb.DropCollectionRequest) (*gvdbpb.DropCollectionResponse, error) {
+	if err := s.svc.DropCollection(req.GetCollection()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &gvdbpb.DropCollectionResponse{}, nil
+}
+
+func (s *Server) Upsert(ctx context.Context, req *gvdbpb.UpsertRequest) (*gvdbpb.UpsertResponse, error) {
+	n, err := s.upsert(req)
+	if err != nil {
+		return nil, err
+	}
+	return &gvdbpb.UpsertResponse{Upserted: int64(n)}, nil
+}
+
+// BulkUpsert 每收到一条消息就写入，出错时停止并返回错误，此前的消息已经写入
+func (s *Server) BulkUpsert(stream grpc.ClientStreamingServer[gvdbpb.UpsertRequest, gvdbpb.UpsertResponse]) error {
+	var total int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&gvdbpb.UpsertResponse{Upserted: total})
+		}
+		if err != nil {
+			return err
+		}
+		n, err := s.upsert(req)
+		total += int64(n)
+		if err != nil {
+			st := status.Convert(err)
+			return status.Errorf(st.Code(), "after %d points: %s", total, st.Message())
+		}
+	}
+}
+
+func (s *Server) upsert(req *gvdbpb.UpsertRequest) (int, error) {
+	points := make([]service.Point, len(req.GetPoints()))
+	for i, p := range req.GetPoints() {
+		payload, err := toPayload(p.GetPayload())
+		if err != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "points[%d]: invalid payload: %v", i, err)
+		}
+		points[i] = service.Point{ID: p.GetId(), Vector: p.GetVector(), Meta: p.GetMeta(), Payload: payload}
+	}
+	n, err := s.svc.Upsert(req.GetCollection(), points)
+	if err != nil {
+		return n, toStatus(err)
+	}
+	return n, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *gvdbpb.GetRequest) (*gvdbpb.Point, error) {
+	p, err := s.svc.Get(req.GetCollection(), req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	payload, err := fromPayload(p.Payload)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &gvdbpb.Point{Id: p.ID, Vector: p.Vector, Meta: p.Meta, Payload: payload}, nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *gvdbpb.DeleteRequest) (*gvdbpb.DeleteResponse, error) {
+	n, err := s.svc.Delete(req.GetCollection(), req.GetIds())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &gvdbpb.DeleteResponse{Deleted: int64(n)}, nil
+}
+
+func (s *Server) Search(ctx context.Context, req *gvdbpb.SearchRequest) (*gvdbpb.SearchResponse, error) {
+	r, err := searchRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.svc.Search(req.GetCollection(), r)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return searchResponse(hits)
+}
+
+func (s *Server) SearchBatch(ctx context.Context, req *gvdbpb.SearchBatchRequest) (*gvdbpb.SearchBatchResponse, error) {
+	reqs := make([]service.SearchRequest, len(req.GetSearches()))
+	for i, sr := range req.GetSearches() {
+		r, err := searchRequest(sr)
+		if err != nil {
+			st := status.Convert(err)
+			return nil, status.Errorf(st.Code(), "searches[%d]: %s", i, st.Message())
+		}
+		reqs[i] = r
+	}
+	results, err := s.svc.SearchBatch(req.GetCollection(), reqs)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &gvdbpb.SearchBatchResponse{Results: make([]*gvdbpb.SearchResponse, len(results))}
+	for i, hits := range results {
+		if resp.Results[i], err = searchResponse(hits); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) Query(ctx context.Context, req *gvdbpb.QueryRequest) (*gvdbpb.SearchResponse, error) {
+	f, err := filterJSON(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.svc.Query(req.GetCollection(), service.QueryRequest{Filter: f, Limit: int(req.GetLimit())})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return searchResponse(hits)
+}
+
+func searchRequest(req *gvdbpb.SearchRequest) (service.SearchRequest, error) {
+	f, err := filterJSON(req.GetFilter())
+	if err != nil {
+		return service.SearchRequest{}, err
+	}
+	return service.SearchRequest{Vector: req.GetVector(), Limit: int(req.GetLimit()), Filter: f}, nil
+}
+
+func searchResponse(hits []service.Hit) (*gvdbpb.SearchResponse, error) {
+	resp := &gvdbpb.SearchResponse{Hits: make([]*gvdbpb.Hit, len(hits))}
+	for i, h := range hits {
+		payload, err := fromPayload(h.Payload)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Hits[i] = &gvdbpb.Hit{Id: h.ID, Score: h.Score, Meta: h.Meta, Payload: payload}
+	}
+	return resp, nil
+}
+
+func collectionInfo(info service.CollectionInfo) (*gvdbpb.CollectionInfo, error) {
+	cfg, err := toStruct(info.Config)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &gvdbpb.CollectionInfo{
+		Name:      info.Name,
+		Dim:       int32(info.Dim),
+		Metric:    info.Metric,
+		IndexType: info.IndexType,
+		Count:     int64(info.Count),
+		Config:    cfg,
+	}, nil
+}
+
+// filterJSON 把 Struct 形式的过滤条件转为 filter.Parse 的 JSON 语法，nil 表示不过滤
+func filterJSON(f *structpb.Struct) (json.RawMessage, error) {
+	if f == nil {
+		return nil, nil
+	}
+	data, err := protojson.Marshal(f)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid filter: %v", err)
+	}
+	return data, nil
+}
+
+// toPayload 经 JSON 转换，使数值与 REST 接口一样归一化为 int64 或 float64
+func toPayload(s *structpb.Struct) (storage.Payload, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := protojson.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return storage.DecodePayload(data)
+}
+
+func fromPayload(p storage.Payload) (*structpb.Struct, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return toStruct(p)
+}
+
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// fromStruct 把 Struct 按 JSON 标签解码到 v，拒绝未知字段
+func fromStruct(s *structpb.Struct, v interface{}) error {
+	if s == nil {
+		return errors.New("missing spec")
+	}
+	data, err := protojson.Marshal(s)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+var grpcCodes = map[service.Code]codes.Code{
+	service.Internal:           codes.Internal,
+	service.InvalidArgument:    codes.InvalidArgument,
+	service.NotFound:           codes.NotFound,
+	service.AlreadyExists:      codes.AlreadyExists,
+	service.FailedPrecondition: codes.FailedPrecondition,
+	service.Unavailable:        codes.Unavailable,
+}
+
+// toStatus 把服务层错误转为 gRPC 状态
+func toStatus(err error) error {
+	return status.Error(grpcCodes[service.CodeOf(err)], err.Error())
+}
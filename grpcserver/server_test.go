@@ -0,0 +1,201 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"gvdb/config"
+	"gvdb/gvdbpb"
+	"gvdb/service"
+	"gvdb/vectordb"
+)
+
+// newTestClient 通过 bufconn 在进程内启动服务器并返回客户端
+func newTestClient(t *testing.T) gvdbpb.VectorDBClient {
+	dir := t.TempDir()
+	var cfg config.Config
+	cfg.Storage.Type = "file"
+	cfg.Storage.File.Enable = true
+	cfg.Storage.File.Path = filepath.Join(dir, "vectors.json")
+	cfg.HNSW = config.HNSWConfig{Dim: 3, M: 16, EF: 64, EFConstruction: 64, EFSearch: 64, Metric: "l2"}
+	cfg.Index.Type = "hnsw"
+	cfg.Search.BruteForceRatio = 0.01
+	cfg.Catalog = filepath.Join(dir, "collections.yaml")
+	db, err := vectordb.NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	Register(srv, service.New(db))
+	go srv.Serve(lis)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+		db.Close()
+	})
+	return gvdbpb.NewVectorDBClient(conn)
+}
+
+func mustStruct(t *testing.T, m map[string]interface{}) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCollections(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	spec := mustStruct(t, map[string]interface{}{
+		"name":  "images",
+		"hnsw":  map[string]interface{}{"dim": 2, "metric": "cosine"},
+		"index": map[string]interface{}{"type": "flat"},
+	})
+	info, err := client.CreateCollection(ctx, &gvdbpb.CreateCollectionRequest{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Dim != 2 || info.IndexType != "flat" || info.Config.Fields["hnsw"].GetStructValue().Fields["m"].GetNumberValue() != 16 {
+		t.Errorf("Unexpected collection info: %v", info)
+	}
+	_, err = client.CreateCollection(ctx, &gvdbpb.CreateCollectionRequest{Spec: spec})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+	bad := mustStruct(t, map[string]interface{}{"name": "x", "hnsw": map[string]interface{}{"dim": 2}, "bogus": true})
+	if _, err := client.CreateCollection(ctx, &gvdbpb.CreateCollectionRequest{Spec: bad}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for unknown field, got %v", err)
+	}
+
+	list, err := client.ListCollections(ctx, &gvdbpb.ListCollectionsRequest{})
+	if err != nil || strings.Join(list.Collections, ",") != "default,images" {
+		t.Fatalf("ListCollections: %v, %v", list, err)
+	}
+	if _, err := client.DropCollection(ctx, &gvdbpb.DropCollectionRequest{Collection: "default"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition, got %v", err)
+	}
+	if _, err := client.DropCollection(ctx, &gvdbpb.DropCollectionRequest{Collection: "images"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DescribeCollection(ctx, &gvdbpb.DescribeCollectionRequest{Collection: "images"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestPoints(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	stream, err := client.BulkUpsert(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	langs := []string{"go", "rust", "go"}
+	for i, lang := range langs {
+		v := []float32{0, 0, 0}
+		v[i] = 1
+		err := stream.Send(&gvdbpb.UpsertRequest{Collection: "default", Points: []*gvdbpb.Point{{
+			Id:      string(rune('a' + i)),
+			Vector:  v,
+			Payload: mustStruct(t, map[string]interface{}{"lang": lang, "year": 2020 + i}),
+		}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil || resp.Upserted != 3 {
+		t.Fatalf("BulkUpsert: %v, %v", resp, err)
+	}
+
+	p, err := client.Get(ctx, &gvdbpb.GetRequest{Collection: "default", Id: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Payload.Fields["lang"].GetStringValue() != "rust" || p.Payload.Fields["year"].GetNumberValue() != 2021 {
+		t.Errorf("Unexpected point: %v", p)
+	}
+
+	res, err := client.Search(ctx, &gvdbpb.SearchRequest{
+		Collection: "default",
+		Vector:     []float32{0, 1, 0},
+		Limit:      3,
+		Filter:     mustStruct(t, map[string]interface{}{"lang": "go"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Hits) != 2 || res.Hits[0].Id == "b" {
+		t.Errorf("Unexpected filtered hits: %v", res.Hits)
+	}
+
+	batch, err := client.SearchBatch(ctx, &gvdbpb.SearchBatchRequest{Collection: "default", Searches: []*gvdbpb.SearchRequest{
+		{Vector: []float32{1, 0, 0}, Limit: 1},
+		{Vector: []float32{0, 0, 1}, Limit: 1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Results) != 2 || batch.Results[0].Hits[0].Id != "a" || batch.Results[1].Hits[0].Id != "c" {
+		t.Errorf("Unexpected batch results: %v", batch.Results)
+	}
+	_, err = client.SearchBatch(ctx, &gvdbpb.SearchBatchRequest{Collection: "default", Searches: []*gvdbpb.SearchRequest{
+		{Vector: []float32{1, 0, 0}},
+		{Vector: []float32{1, 0}},
+	}})
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "searches[1]") {
+		t.Errorf("Expected InvalidArgument for searches[1], got %v", err)
+	}
+
+	q, err := client.Query(ctx, &gvdbpb.QueryRequest{
+		Collection: "default",
+		Filter:     mustStruct(t, map[string]interface{}{"year": map[string]interface{}{"$gte": 2021}}),
+	})
+	if err != nil || len(q.Hits) != 2 {
+		t.Fatalf("Query: %v, %v", q, err)
+	}
+
+	del, err := client.Delete(ctx, &gvdbpb.DeleteRequest{Collection: "default", Ids: []string{"a", "missing"}})
+	if err != nil || del.Deleted != 1 {
+		t.Fatalf("Delete: %v, %v", del, err)
+	}
+	if _, err := client.Get(ctx, &gvdbpb.GetRequest{Collection: "default", Id: "a"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound after delete, got %v", err)
+	}
+}
+
+func TestBulkUpsertError(t *testing.T) {
+	client := newTestClient(t)
+	stream, err := client.BulkUpsert(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&gvdbpb.UpsertRequest{Collection: "default", Points: []*gvdbpb.Point{{Id: "a", Vector: []float32{1, 0, 0}}}})
+	stream.Send(&gvdbpb.UpsertRequest{Collection: "default", Points: []*gvdbpb.Point{{Id: "b", Vector: []float32{1, 0}}}})
+	_, err = stream.CloseAndRecv()
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "after 1 points") {
+		t.Errorf("Expected InvalidArgument after 1 points, got %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package gvdbpb 包含 gvdb.proto 生成的 protobuf 消息和 gRPC 服务代码
+package gvdbpb
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gvdb.proto
@@ -0,0 +1,502 @@
+// gvdb 的 gRPC 接口，与 REST 接口（doc/rest-api.md）共用 service 层，语义相同。
+// 修改后在本目录执行 go generate 重新生成 Go 代码。
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: gvdb.proto
+
+package gvdbpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VectorDB_ListCollections_FullMethodName    = "/gvdb.v1.VectorDB/ListCollections"
+	VectorDB_CreateCollection_FullMethodName   = "/gvdb.v1.VectorDB/CreateCollection"
+	VectorDB_DescribeCollection_FullMethodName = "/gvdb.v1.VectorDB/DescribeCollection"
+	VectorDB_DropCollection_FullMethodName     = "/gvdb.v1.VectorDB/DropCollection"
+	VectorDB_Upsert_FullMethodName             = "/gvdb.v1.VectorDB/Upsert"
+	VectorDB_BulkUpsert_FullMethodName         = "/gvdb.v1.VectorDB/BulkUpsert"
+	VectorDB_Get_FullMethodName                = "/gvdb.v1.VectorDB/Get"
+	VectorDB_Delete_FullMethodName             = "/gvdb.v1.VectorDB/Delete"
+	VectorDB_Search_FullMethodName             = "/gvdb.v1.VectorDB/Search"
+	VectorDB_SearchBatch_FullMethodName        = "/gvdb.v1.VectorDB/SearchBatch"
+	VectorDB_Query_FullMethodName              = "/gvdb.v1.VectorDB/Query"
+)
+
+// VectorDBClient is the client API for VectorDB service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VectorDBClient interface {
+	ListCollections(ctx context.Context, in *ListCollectionsRequest, opts ...grpc.CallOption) (*ListCollectionsResponse, error)
+	CreateCollection(ctx context.Context, in *CreateCollectionRequest, opts ...grpc.CallOption) (*CollectionInfo, error)
+	DescribeCollection(ctx context.Context, in *DescribeCollectionRequest, opts ...grpc.CallOption) (*CollectionInfo, error)
+	DropCollection(ctx context.Context, in *DropCollectionRequest, opts ...grpc.CallOption) (*DropCollectionResponse, error)
+	Upsert(ctx context.Context, in *UpsertRequest, opts ...grpc.CallOption) (*UpsertResponse, error)
+	// BulkUpsert 逐条处理客户端流中的消息，适合大批量导入；每条消息可以指定不同的集合
+	BulkUpsert(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UpsertRequest, UpsertResponse], error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Point, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// SearchBatch 在同一集合中执行多个查询，结果与 searches 一一对应
+	SearchBatch(ctx context.Context, in *SearchBatchRequest, opts ...grpc.CallOption) (*SearchBatchResponse, error)
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+}
+
+type vectorDBClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVectorDBClient(cc grpc.ClientConnInterface) VectorDBClient {
+	return &vectorDBClient{cc}
+}
+
+func (c *vectorDBClient) ListCollections(ctx context.Context, in *ListCollectionsRequest, opts ...grpc.CallOption) (*ListCollectionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCollectionsResponse)
+	err := c.cc.Invoke(ctx, VectorDB_ListCollections_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) CreateCollection(ctx context.Context, in *CreateCollectionRequest, opts ...grpc.CallOption) (*CollectionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CollectionInfo)
+	err := c.cc.Invoke(ctx, VectorDB_CreateCollection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) DescribeCollection(ctx context.Context, in *DescribeCollectionRequest, opts ...grpc.CallOption) (*CollectionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CollectionInfo)
+	err := c.cc.Invoke(ctx, VectorDB_DescribeCollection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) DropCollection(ctx context.Context, in *DropCollectionRequest, opts ...grpc.CallOption) (*DropCollectionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DropCollectionResponse)
+	err := c.cc.Invoke(ctx, VectorDB_DropCollection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) Upsert(ctx context.Context, in *UpsertRequest, opts ...grpc.CallOption) (*UpsertResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpsertResponse)
+	err := c.cc.Invoke(ctx, VectorDB_Upsert_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) BulkUpsert(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UpsertRequest, UpsertResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VectorDB_ServiceDesc.Streams[0], VectorDB_BulkUpsert_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UpsertRequest, UpsertResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VectorDB_BulkUpsertClient = grpc.ClientStreamingClient[UpsertRequest, UpsertResponse]
+
+func (c *vectorDBClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Point, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Point)
+	err := c.cc.Invoke(ctx, VectorDB_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, VectorDB_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, VectorDB_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) SearchBatch(ctx context.Context, in *SearchBatchRequest, opts ...grpc.CallOption) (*SearchBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchBatchResponse)
+	err := c.cc.Invoke(ctx, VectorDB_SearchBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorDBClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, VectorDB_Query_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VectorDBServer is the server API for VectorDB service.
+// All implementations must embed UnimplementedVectorDBServer
+// for forward compatibility.
+type VectorDBServer interface {
+	ListCollections(context.Context, *ListCollectionsRequest) (*ListCollectionsResponse, error)
+	CreateCollection(context.Context, *CreateCollectionRequest) (*CollectionInfo, error)
+	DescribeCollection(context.Context, *DescribeCollectionRequest) (*CollectionInfo, error)
+	DropCollection(context.Context, *DropCollectionRequest) (*DropCollectionResponse, error)
+	Upsert(context.Context, *UpsertRequest) (*UpsertResponse, error)
+	// BulkUpsert 逐条处理客户端流中的消息，适合大批量导入；每条消息可以指定不同的集合
+	BulkUpsert(grpc.ClientStreamingServer[UpsertRequest, UpsertResponse]) error
+	Get(context.Context, *GetRequest) (*Point, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// SearchBatch 在同一集合中执行多个查询，结果与 searches 一一对应
+	SearchBatch(context.Context, *SearchBatchRequest) (*SearchBatchResponse, error)
+	Query(context.Context, *QueryRequest) (*SearchResponse, error)
+	mustEmbedUnimplementedVectorDBServer()
+}
+
+// UnimplementedVectorDBServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVectorDBServer struct{}
+
+func (UnimplementedVectorDBServer) ListCollections(context.Context, *ListCollectionsRequest) (*ListCollectionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListCollections not implemented")
+}
+func (UnimplementedVectorDBServer) CreateCollection(context.Context, *CreateCollectionRequest) (*CollectionInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateCollection not implemented")
+}
+func (UnimplementedVectorDBServer) DescribeCollection(context.Context, *DescribeCollectionRequest) (*CollectionInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method DescribeCollection not implemented")
+}
+func (UnimplementedVectorDBServer) DropCollection(context.Context, *DropCollectionRequest) (*DropCollectionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DropCollection not implemented")
+}
+func (UnimplementedVectorDBServer) Upsert(context.Context, *UpsertRequest) (*UpsertResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Upsert not implemented")
+}
+func (UnimplementedVectorDBServer) BulkUpsert(grpc.ClientStreamingServer[UpsertRequest, UpsertResponse]) error {
+	return status.Error(codes.Unimplemented, "method BulkUpsert not implemented")
+}
+func (UnimplementedVectorDBServer) Get(context.Context, *GetRequest) (*Point, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedVectorDBServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedVectorDBServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedVectorDBServer) SearchBatch(context.Context, *SearchBatchRequest) (*SearchBatchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SearchBatch not implemented")
+}
+func (UnimplementedVectorDBServer) Query(context.Context, *QueryRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Query not implemented")
+}
+func (UnimplementedVectorDBServer) mustEmbedUnimplementedVectorDBServer() {}
+func (UnimplementedVectorDBServer) testEmbeddedByValue()                  {}
+
+// UnsafeVectorDBServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VectorDBServer will
+// result in compilation errors.
+type UnsafeVectorDBServer interface {
+	mustEmbedUnimplementedVectorDBServer()
+}
+
+func RegisterVectorDBServer(s grpc.ServiceRegistrar, srv VectorDBServer) {
+	// If the following call panics, it indicates UnimplementedVectorDBServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VectorDB_ServiceDesc, srv)
+}
+
+func _VectorDB_ListCollections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCollectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).ListCollections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_ListCollections_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).ListCollections(ctx, req.(*ListCollectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_CreateCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).CreateCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_CreateCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).CreateCollection(ctx, req.(*CreateCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_DescribeCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).DescribeCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_DescribeCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).DescribeCollection(ctx, req.(*DescribeCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_DropCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DropCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).DropCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_DropCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).DropCollection(ctx, req.(*DropCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_Upsert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).Upsert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_Upsert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).Upsert(ctx, req.(*UpsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_BulkUpsert_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(VectorDBServer).BulkUpsert(&grpc.GenericServerStream[UpsertRequest, UpsertResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VectorDB_BulkUpsertServer = grpc.ClientStreamingServer[UpsertRequest, UpsertResponse]
+
+func _VectorDB_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_SearchBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).SearchBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_SearchBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).SearchBatch(ctx, req.(*SearchBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorDB_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorDBServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorDB_Query_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorDBServer).Query(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VectorDB_ServiceDesc is the grpc.ServiceDesc for VectorDB service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VectorDB_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gvdb.v1.VectorDB",
+	HandlerType: (*VectorDBServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListCollections",
+			Handler:    _VectorDB_ListCollections_Handler,
+		},
+		{
+			MethodName: "CreateCollection",
+			Handler:    _VectorDB_CreateCollection_Handler,
+		},
+		{
+			MethodName: "DescribeCollection",
+			Handler:    _VectorDB_DescribeCollection_Handler,
+		},
+		{
+			MethodName: "DropCollection",
+			Handler:    _VectorDB_DropCollection_Handler,
+		},
+		{
+			MethodName: "Upsert",
+			Handler:    _VectorDB_Upsert_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _VectorDB_Get_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _VectorDB_Delete_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _VectorDB_Search_Handler,
+		},
+		{
+			MethodName: "SearchBatch",
+			Handler:    _VectorDB_SearchBatch_Handler,
+		},
+		{
+			MethodName: "Query",
+			Handler:    _VectorDB_Query_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BulkUpsert",
+			Handler:       _VectorDB_BulkUpsert_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "gvdb.proto",
+}
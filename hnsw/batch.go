@@ -0,0 +1,136 @@
+package hnsw
+
+import (
+	"runtime"
+	"sync"
+)
+
+// insertPlan 为待插入节点在并行阶段找到的候选邻居，found[l] 为第 l 层的候选
+type insertPlan struct {
+	node   *HNSWNode
+	vector []float32
+	found  [][]candidate
+}
+
+// AddBatch 批量插入向量，ids 与 vectors 一一对应，已存在的 ID 会被替换。
+// 已存在的节点一次性删除；新节点按块处理：先在多个 goroutine 中并行搜索每个节点的候选邻居（只读），
+// 再串行建立连接，同一块中先插入的节点会作为后插入节点的候选，因此图的质量与逐个 Add 相当
+func (idx *HNSWIndex) AddBatch(ids []string, vectors [][]float32) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	// 同一 ID 出现多次时保留最后一次
+	last := make(map[string]int, len(ids))
+	for i, id := range ids {
+		last[id] = i
+	}
+	var replaced []string
+	for id := range last {
+		if _, exists := idx.nodes[id]; exists {
+			replaced = append(replaced, id)
+		}
+	}
+	idx.removeAll(replaced)
+
+	pending := make([]int, 0, len(last))
+	for i, id := range ids {
+		if last[id] == i {
+			pending = append(pending, i)
+		}
+	}
+	// 图为空时先串行插入一个节点作为入口
+	if idx.entryPoint == nil && len(pending) > 0 {
+		i := pending[0]
+		idx.add(idx.newNode(ids[i], vectors[i]), vectors[i])
+		pending = pending[1:]
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	chunk := 4 * workers
+	plans := make([]insertPlan, 0, chunk)
+	for len(pending) > 0 {
+		n := min(chunk, len(pending))
+		plans = plans[:n]
+		for j, i := range pending[:n] {
+			plans[j] = insertPlan{node: idx.newNode(ids[i], vectors[i]), vector: vectors[i]}
+		}
+		var wg sync.WaitGroup
+		next := make(chan int, n)
+		for j := 0; j < n; j++ {
+			next <- j
+		}
+		close(next)
+		for w := 0; w < min(workers, n); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range next {
+					idx.plan(&plans[j])
+				}
+			}()
+		}
+		wg.Wait()
+		for j := range plans {
+			idx.link(&plans[j], plans[:j])
+		}
+		pending = pending[n:]
+	}
+}
+
+// RemoveBatch 删除一组向量，一次修复所有指向它们的连接，不存在的 ID 会被忽略
+func (idx *HNSWIndex) RemoveBatch(ids []string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.removeAll(ids)
+}
+
+// plan 在当前图中搜索节点各层的候选邻居，只读取图，可以并发执行
+func (idx *HNSWIndex) plan(p *insertPlan) {
+	layer := p.node.Layer
+	dist := idx.distanceFunc(p.vector)
+	ep := []candidate{{node: idx.entryPoint, dist: dist(idx.entryPoint)}}
+	for l := idx.maxLayer; l > layer; l-- {
+		ep = idx.searchLayer(dist, ep, 1, l, nil)
+	}
+	p.found = make([][]candidate, min(layer, idx.maxLayer)+1)
+	for l := len(p.found) - 1; l >= 0; l-- {
+		p.found[l] = idx.searchLayer(dist, ep, idx.efConstruction, l, nil)
+		ep = p.found[l]
+	}
+}
+
+// link 把节点接入图中：候选为并行阶段找到的邻居加上同一块中已插入的节点
+func (idx *HNSWIndex) link(p *insertPlan, inserted []insertPlan) {
+	node := p.node
+	for l := node.Layer; l >= 0; l-- {
+		var cands []candidate
+		if l < len(p.found) {
+			cands = p.found[l]
+		}
+		for i := range inserted {
+			if other := inserted[i].node; other.Layer >= l {
+				cands = append(cands, candidate{node: other, dist: idx.nodeDistance(node, other)})
+			}
+		}
+		if len(cands) == 0 {
+			continue
+		}
+		selected := idx.selectNeighbors(cands, idx.m)
+		idx.setNeighbors(node, l, candidateIDs(selected))
+		for _, c := range selected {
+			idx.connect(c.node, node, l)
+		}
+	}
+
+	// 同一块中前面的节点可能刚触发了量化器训练
+	if idx.quantized() && node.Code == nil {
+		node.Code = idx.quantizer.Encode(node.Vector)
+		node.Vector = nil
+	}
+	idx.nodes[node.ID] = node
+	if node.Layer > idx.maxLayer {
+		idx.maxLayer = node.Layer
+		idx.entryPoint = node
+	}
+	idx.maybeTrain()
+}
@@ -0,0 +1,77 @@
+package hnsw
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHNSWAddBatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	dim, n, k := 16, 2000, 10
+	vectors := randomVectors(n, dim, rng)
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("id%d", i)
+	}
+
+	idx := NewHNSWIndex(dim, 16, 200, 100, L2{})
+	// 先逐个插入一部分，再批量插入全部：前一部分被替换
+	for i := 0; i < 100; i++ {
+		idx.Add(ids[i], randomVectors(1, dim, rng)[0])
+	}
+	idx.AddBatch(ids, vectors)
+	if idx.Len() != n {
+		t.Fatalf("Expected %d nodes, got %d", n, idx.Len())
+	}
+
+	queries := randomVectors(50, dim, rng)
+	hits := 0
+	for _, q := range queries {
+		exact := make([]Neighbor, 0, n)
+		for i, v := range vectors {
+			exact = append(exact, Neighbor{ID: ids[i], Score: euclideanDistance(q, v)})
+		}
+		sort.Slice(exact, func(i, j int) bool { return exact[i].Score < exact[j].Score })
+		truth := make(map[string]bool, k)
+		for _, e := range exact[:k] {
+			truth[e.ID] = true
+		}
+		for _, r := range idx.Search(q, k) {
+			if truth[r.ID] {
+				hits++
+			}
+		}
+	}
+	if recall := float64(hits) / float64(len(queries)*k); recall < 0.9 {
+		t.Errorf("Expected recall@%d >= 0.9 after AddBatch, got %.3f", k, recall)
+	}
+
+	for _, node := range idx.nodes {
+		for l, neighbors := range node.Neighbors {
+			if len(neighbors) > idx.maxConnections(l) {
+				t.Errorf("Node %s has %d neighbors on layer %d", node.ID, len(neighbors), l)
+			}
+			for _, nid := range neighbors {
+				if _, ok := idx.nodes[nid]; !ok || nid == node.ID {
+					t.Errorf("Node %s has invalid neighbor %s", node.ID, nid)
+				}
+			}
+		}
+	}
+	if idx.entryPoint.Layer != idx.maxLayer {
+		t.Error("Entry point is not on the top layer")
+	}
+}
+
+func TestHNSWAddBatchDuplicates(t *testing.T) {
+	idx := NewHNSWIndex(2, 4, 16, 16, L2{})
+	idx.AddBatch([]string{"a", "b", "a"}, [][]float32{{0, 0}, {5, 5}, {1, 1}})
+	if idx.Len() != 2 {
+		t.Fatalf("Expected 2 nodes, got %d", idx.Len())
+	}
+	if r := idx.Search([]float32{1, 1}, 1); len(r) != 1 || r[0].ID != "a" || r[0].Score != 0 {
+		t.Errorf("Expected the last vector of a to win, got %+v", r)
+	}
+}
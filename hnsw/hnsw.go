@@ -1,199 +1,580 @@
 package hnsw
 
 import (
+	"container/heap"
 	"math"
 	"math/rand"
+	"sort"
 	"sync"
 )
 
 type HNSWIndex struct {
-	nodes    map[string]*HNSWNode
-	dim      int
-	m        int
-	ef       int
-	maxLayer int
-	mutex    sync.RWMutex
+	nodes          map[string]*HNSWNode
+	dim            int
+	m              int
+	mMax0          int
+	efConstruction int
+	efSearch       int
+	levelMult      float64
+	maxLayer       int
+	entryPoint     *HNSWNode
+	metric         Metric
+	quantizer      Quantizer
+	trainSize      int
+	source         VectorSource
+	rescore        int
+	// inbound 为反向连接：指向某节点的节点 ID 及其在几层上指向它，删除节点时只需修复这些节点
+	inbound map[string]map[string]int
+	mutex   sync.RWMutex
 }
 
 type HNSWNode struct {
 	ID        string
-	Vector    []float64
-	Neighbors map[int][]Neighbor
+	Vector    []float32  // 量化器训练完成后为 nil
+	Code      []byte     // 量化编码，未启用量化时为 nil
+	Neighbors [][]string // 第 l 层的邻居 ID，长度为 Layer+1
 	Layer     int
 }
 
+// Neighbor 为搜索结果，Score 的含义与排序方向由索引的 Metric 决定
 type Neighbor struct {
-	ID         string
-	Similarity float64
+	ID    string
+	Score float32
 }
 
-func NewHNSWIndex(dim, m, ef int) *HNSWIndex {
+// NewHNSWIndex 创建 HNSW 索引，metric 为 nil 时使用余弦相似度。
+// m 为每层最大连接数（第 0 层为 2*m），efConstruction 和 efSearch 分别为构建和搜索时的候选集大小。
+func NewHNSWIndex(dim, m, efConstruction, efSearch int, metric Metric) *HNSWIndex {
+	if metric == nil {
+		metric = Cosine{}
+	}
+	if m < 2 {
+		m = 2
+	}
+	if efConstruction < m {
+		efConstruction = m
+	}
+	if efSearch < 1 {
+		efSearch = efConstruction
+	}
 	return &HNSWIndex{
-		nodes:    make(map[string]*HNSWNode),
-		dim:      dim,
-		m:        m,
-		ef:       ef,
-		maxLayer: 0,
+		nodes:          make(map[string]*HNSWNode),
+		inbound:        make(map[string]map[string]int),
+		dim:            dim,
+		m:              m,
+		mMax0:          2 * m,
+		efConstruction: efConstruction,
+		efSearch:       efSearch,
+		levelMult:      1 / math.Log(float64(m)),
+		maxLayer:       0,
+		metric:         metric,
 	}
 }
 
-func (idx *HNSWIndex) Add(id string, vector []float64) {
+// Metric 返回索引使用的度量
+func (idx *HNSWIndex) Metric() Metric {
+	return idx.metric
+}
+
+// SetEfSearch 调整搜索时的候选集大小
+func (idx *HNSWIndex) SetEfSearch(ef int) {
 	idx.mutex.Lock()
 	defer idx.mutex.Unlock()
+	if ef > 0 {
+		idx.efSearch = ef
+	}
+}
 
-	layer := int(math.Floor(-math.Log(rand.Float64()) * float64(idx.m)))
-	if layer > idx.maxLayer {
-		idx.maxLayer = layer
+// SetQuantizer 启用量化：节点数达到 trainSize 时训练量化器，之后节点只保存编码
+func (idx *HNSWIndex) SetQuantizer(q Quantizer, trainSize int) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if trainSize <= 0 {
+		trainSize = 1000
+	}
+	idx.quantizer = q
+	idx.trainSize = trainSize
+	idx.maybeTrain()
+}
+
+// SetRescore 设置重新打分使用的原始向量来源，搜索时对前 k*factor 个近似结果用全精度向量重新计算得分
+func (idx *HNSWIndex) SetRescore(source VectorSource, factor int) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.source = source
+	idx.rescore = factor
+}
+
+// Len 返回索引中的向量数量
+func (idx *HNSWIndex) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.nodes)
+}
+
+func (idx *HNSWIndex) Add(id string, vector []float32) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if _, exists := idx.nodes[id]; exists {
+		idx.remove(id)
 	}
+	idx.add(idx.newNode(id, vector), vector)
+}
 
+// newNode 创建随机层数的新节点，已训练量化器时只保存编码
+func (idx *HNSWIndex) newNode(id string, vector []float32) *HNSWNode {
+	layer := int(math.Floor(-math.Log(1-rand.Float64()) * idx.levelMult))
 	node := &HNSWNode{
 		ID:        id,
 		Vector:    vector,
-		Neighbors: make(map[int][]Neighbor),
+		Neighbors: make([][]string, layer+1),
 		Layer:     layer,
 	}
-	idx.nodes[id] = node
+	if idx.quantized() {
+		node.Code = idx.quantizer.Encode(vector)
+		node.Vector = nil
+	}
+	return node
+}
 
-	if len(idx.nodes) == 1 {
+// add 把新节点插入图中，vector 为节点的全精度向量
+func (idx *HNSWIndex) add(node *HNSWNode, vector []float32) {
+	id, layer := node.ID, node.Layer
+
+	if idx.entryPoint == nil {
+		idx.nodes[id] = node
+		idx.entryPoint = node
+		idx.maxLayer = layer
+		idx.maybeTrain()
 		return
 	}
 
-	for l := idx.maxLayer; l >= 0; l-- {
-		if l > layer {
+	dist := idx.distanceFunc(vector)
+	ep := []candidate{{node: idx.entryPoint, dist: dist(idx.entryPoint)}}
+	for l := idx.maxLayer; l > layer; l-- {
+		ep = idx.searchLayer(dist, ep, 1, l, nil)
+	}
+	for l := min(layer, idx.maxLayer); l >= 0; l-- {
+		found := idx.searchLayer(dist, ep, idx.efConstruction, l, nil)
+		selected := idx.selectNeighbors(found, idx.m)
+		idx.setNeighbors(node, l, candidateIDs(selected))
+		for _, c := range selected {
+			idx.connect(c.node, node, l)
+		}
+		ep = found
+	}
+
+	idx.nodes[id] = node
+	if layer > idx.maxLayer {
+		idx.maxLayer = layer
+		idx.entryPoint = node
+	}
+	idx.maybeTrain()
+}
+
+// connect 为 node 在第 l 层添加指向 added 的反向连接，超出上限时用启发式裁剪
+func (idx *HNSWIndex) connect(node, added *HNSWNode, l int) {
+	node.Neighbors[l] = append(node.Neighbors[l], added.ID)
+	idx.linkRef(node.ID, added.ID)
+	if len(node.Neighbors[l]) <= idx.maxConnections(l) {
+		return
+	}
+	cands := make([]candidate, 0, len(node.Neighbors[l]))
+	for _, nid := range node.Neighbors[l] {
+		other, ok := idx.nodes[nid]
+		if nid == added.ID {
+			other = added
+		} else if !ok {
 			continue
 		}
-		neighbors := idx.searchLayer(vector, l, idx.ef)
-		node.Neighbors[l] = neighbors
-		for _, n := range neighbors {
-			neighborNode := idx.nodes[n.ID]
-			neighborNode.Neighbors[l] = append(neighborNode.Neighbors[l], Neighbor{ID: id, Similarity: n.Similarity})
-			if len(neighborNode.Neighbors[l]) > idx.m {
-				neighborNode.Neighbors[l] = neighborNode.Neighbors[l][:idx.m]
-			}
+		cands = append(cands, candidate{node: other, dist: idx.nodeDistance(node, other)})
+	}
+	idx.setNeighbors(node, l, candidateIDs(idx.selectNeighbors(cands, idx.maxConnections(l))))
+}
+
+// setNeighbors 替换 node 在第 l 层的邻居，并同步反向连接
+func (idx *HNSWIndex) setNeighbors(node *HNSWNode, l int, ids []string) {
+	for _, nid := range node.Neighbors[l] {
+		idx.unlinkRef(node.ID, nid)
+	}
+	node.Neighbors[l] = ids
+	for _, nid := range ids {
+		idx.linkRef(node.ID, nid)
+	}
+}
+
+// linkRef 记录 from 在某一层指向 to
+func (idx *HNSWIndex) linkRef(from, to string) {
+	refs := idx.inbound[to]
+	if refs == nil {
+		refs = make(map[string]int)
+		idx.inbound[to] = refs
+	}
+	refs[from]++
+}
+
+func (idx *HNSWIndex) unlinkRef(from, to string) {
+	refs := idx.inbound[to]
+	if refs[from] <= 1 {
+		delete(refs, from)
+		if len(refs) == 0 {
+			delete(idx.inbound, to)
 		}
+		return
+	}
+	refs[from]--
+}
+
+func (idx *HNSWIndex) maxConnections(l int) int {
+	if l == 0 {
+		return idx.mMax0
 	}
+	return idx.m
 }
 
 func (idx *HNSWIndex) Remove(id string) {
 	idx.mutex.Lock()
 	defer idx.mutex.Unlock()
+	idx.remove(id)
+}
+
+func (idx *HNSWIndex) remove(id string) {
+	idx.removeAll([]string{id})
+}
 
-	node, exists := idx.nodes[id]
-	if !exists {
+// removeAll 删除一组节点，通过反向连接找到指向它们的节点并修复其连接，不需要遍历整个图
+func (idx *HNSWIndex) removeAll(ids []string) {
+	removed := make(map[string]*HNSWNode, len(ids))
+	for _, id := range ids {
+		if node, exists := idx.nodes[id]; exists {
+			removed[id] = node
+			delete(idx.nodes, id)
+		}
+	}
+	if len(removed) == 0 {
 		return
 	}
 
-	for l := 0; l <= node.Layer; l++ {
-		for _, n := range node.Neighbors[l] {
-			neighbor := idx.nodes[n.ID]
-			for i, nb := range neighbor.Neighbors[l] {
-				if nb.ID == id {
-					neighbor.Neighbors[l] = append(neighbor.Neighbors[l][:i], neighbor.Neighbors[l][i+1:]...)
-					break
+	// 连接经过裁剪后不一定双向，需要在每层修复所有指向被删节点的节点，用被删节点的邻居补充它们的连接
+	affected := make(map[string]*HNSWNode)
+	for id := range removed {
+		for from := range idx.inbound[id] {
+			if other, ok := idx.nodes[from]; ok {
+				affected[from] = other
+			}
+		}
+	}
+	for _, other := range affected {
+		for l := 0; l <= other.Layer; l++ {
+			var extra []string
+			kept := other.Neighbors[l]
+			for pos := 0; pos < len(kept); {
+				node, ok := removed[kept[pos]]
+				if !ok {
+					pos++
+					continue
 				}
+				kept = append(kept[:pos:pos], kept[pos+1:]...)
+				if l <= node.Layer {
+					extra = append(extra, node.Neighbors[l]...)
+				}
+			}
+			if len(kept) == len(other.Neighbors[l]) {
+				continue
+			}
+			idx.setNeighbors(other, l, kept)
+			idx.repair(other, extra, l)
+		}
+	}
+	for id, node := range removed {
+		for _, ids := range node.Neighbors {
+			for _, nid := range ids {
+				idx.unlinkRef(id, nid)
+			}
+		}
+	}
+	for id := range removed {
+		delete(idx.inbound, id)
+	}
+
+	if idx.entryPoint != nil && removed[idx.entryPoint.ID] == idx.entryPoint {
+		idx.entryPoint = nil
+		idx.maxLayer = 0
+		for _, other := range idx.nodes {
+			if idx.entryPoint == nil || other.Layer > idx.maxLayer {
+				idx.entryPoint = other
+				idx.maxLayer = other.Layer
+			}
+		}
+	}
+}
+
+// repair 用被删节点的邻居补充 node 在第 l 层的连接
+func (idx *HNSWIndex) repair(node *HNSWNode, extra []string, l int) {
+	seen := map[string]bool{node.ID: true}
+	cands := make([]candidate, 0, len(node.Neighbors[l])+len(extra))
+	for _, ids := range [][]string{node.Neighbors[l], extra} {
+		for _, nid := range ids {
+			other, ok := idx.nodes[nid]
+			if !ok || seen[nid] || other.Layer < l {
+				continue
 			}
+			seen[nid] = true
+			cands = append(cands, candidate{node: other, dist: idx.nodeDistance(node, other)})
 		}
 	}
-	delete(idx.nodes, id)
+	idx.setNeighbors(node, l, candidateIDs(idx.selectNeighbors(cands, idx.maxConnections(l))))
+}
+
+// FilterFunc 判断 ID 是否允许出现在搜索结果中
+type FilterFunc func(id string) bool
+
+func (idx *HNSWIndex) Search(query []float32, k int) []Neighbor {
+	return idx.SearchFilter(query, k, nil)
 }
 
-func (idx *HNSWIndex) Search(query []float64, k int) []Neighbor {
+// SearchFilter 在第 0 层遍历时只把 allow 通过的节点放入结果集，不通过的节点仍用于导航，
+// 因此过滤条件不太严格时仍能返回 k 个结果；allow 为 nil 时等同于 Search
+func (idx *HNSWIndex) SearchFilter(query []float32, k int, allow FilterFunc) []Neighbor {
 	idx.mutex.RLock()
 	defer idx.mutex.RUnlock()
 
-	if len(idx.nodes) == 0 {
+	if idx.entryPoint == nil || k <= 0 {
 		return nil
 	}
 
-	var entryPoint *HNSWNode
-	for _, node := range idx.nodes {
-		entryPoint = node
-		break
+	dist := idx.distanceFunc(query)
+	ep := []candidate{{node: idx.entryPoint, dist: dist(idx.entryPoint)}}
+	for l := idx.maxLayer; l > 0; l-- {
+		ep = idx.searchLayer(dist, ep, 1, l, nil)
+	}
+	ef := max(idx.efSearch, k)
+	if idx.quantized() && idx.source != nil && idx.rescore > 1 {
+		ef = max(ef, k*idx.rescore)
+	}
+	found := idx.searchLayer(dist, ep, ef, 0, allow)
+	if idx.quantized() {
+		found = idx.rescoreCandidates(query, found, k)
+	}
+	if len(found) > k {
+		found = found[:k]
+	}
+
+	results := make([]Neighbor, len(found))
+	for i, c := range found {
+		results[i] = Neighbor{ID: c.node.ID, Score: idx.score(c.dist)}
 	}
+	return results
+}
 
-	for l := idx.maxLayer; l > 0; l-- {
-		entryPoint = idx.searchLayerEntry(query, entryPoint, l)
+// searchLayer 从入口集合出发在第 layer 层做贪心搜索，返回按距离升序排列的至多 ef 个候选；
+// allow 不为 nil 时只有通过的节点进入结果集
+func (idx *HNSWIndex) searchLayer(dist func(*HNSWNode) float32, entries []candidate, ef, layer int, allow FilterFunc) []candidate {
+	visited := make(map[string]bool, ef*4)
+	candidates := &candidateHeap{}
+	results := &candidateHeap{farthest: true}
+	accept := func(c candidate) {
+		if allow != nil && !allow(c.node.ID) {
+			return
+		}
+		heap.Push(results, c)
+		if results.Len() > ef {
+			heap.Pop(results)
+		}
+	}
+	for _, e := range entries {
+		visited[e.node.ID] = true
+		heap.Push(candidates, e)
+		accept(e)
+	}
+
+	for candidates.Len() > 0 {
+		closest := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && closest.dist > results.top().dist {
+			break
+		}
+		if layer >= len(closest.node.Neighbors) {
+			continue
+		}
+		for _, nid := range closest.node.Neighbors[layer] {
+			if visited[nid] {
+				continue
+			}
+			visited[nid] = true
+			neighbor, ok := idx.nodes[nid]
+			if !ok {
+				continue
+			}
+			c := candidate{node: neighbor, dist: dist(neighbor)}
+			if results.Len() < ef || c.dist < results.top().dist {
+				heap.Push(candidates, c)
+				accept(c)
+			}
+		}
 	}
 
-	return idx.searchLayer(query, 0, k)
+	sorted := make([]candidate, results.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(results).(candidate)
+	}
+	return sorted
 }
 
-func (idx *HNSWIndex) searchLayerEntry(query []float64, entry *HNSWNode, layer int) *HNSWNode {
-	current := entry
-	for {
-		best := current
-		bestSim := cosineSimilarity(query, current.Vector)
-		for _, n := range current.Neighbors[layer] {
-			sim := cosineSimilarity(query, idx.nodes[n.ID].Vector)
-			if sim > bestSim {
-				best = idx.nodes[n.ID]
-				bestSim = sim
+// selectNeighbors 实现论文中的启发式邻居选择：只保留比已选邻居更接近基准点的候选，
+// 不足 m 个时再用被丢弃的最近候选补齐（keepPrunedConnections）
+func (idx *HNSWIndex) selectNeighbors(cands []candidate, m int) []candidate {
+	if len(cands) <= m {
+		return cands
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+
+	selected := make([]candidate, 0, m)
+	pruned := make([]candidate, 0, len(cands))
+	for _, c := range cands {
+		if len(selected) >= m {
+			break
+		}
+		good := true
+		for _, s := range selected {
+			if idx.nodeDistance(c.node, s.node) < c.dist {
+				good = false
+				break
 			}
 		}
-		if best == current {
+		if good {
+			selected = append(selected, c)
+		} else {
+			pruned = append(pruned, c)
+		}
+	}
+	for _, c := range pruned {
+		if len(selected) >= m {
 			break
 		}
-		current = best
+		selected = append(selected, c)
 	}
-	return current
+	return selected
 }
 
-func (idx *HNSWIndex) searchLayer(query []float64, layer, k int) []Neighbor {
-	visited := make(map[string]bool)
-	candidates := make([]Neighbor, 0)
-	result := make([]Neighbor, 0, k)
-
-	var entry *HNSWNode
-	for _, node := range idx.nodes {
-		entry = node
-		break
+// distance 把度量得分统一转换为越小越相似的距离
+func (idx *HNSWIndex) distance(v1, v2 []float32) float32 {
+	s := idx.metric.Score(v1, v2)
+	if idx.metric.HigherIsBetter() {
+		return -s
 	}
-	candidates = append(candidates, Neighbor{ID: entry.ID, Similarity: cosineSimilarity(query, entry.Vector)})
-	visited[entry.ID] = true
+	return s
+}
 
-	for len(candidates) > 0 && len(result) < k {
-		closest := candidates[0]
-		candidates = candidates[1:]
+func (idx *HNSWIndex) score(dist float32) float32 {
+	if idx.metric.HigherIsBetter() {
+		return -dist
+	}
+	return dist
+}
 
-		if len(result) > 0 && closest.Similarity < result[len(result)-1].Similarity {
-			break
+// distanceFunc 返回查询到节点的距离函数，已量化的节点使用量化器计算近似距离
+func (idx *HNSWIndex) distanceFunc(query []float32) func(*HNSWNode) float32 {
+	var approx func([]byte) float32
+	if idx.quantized() {
+		approx = idx.quantizer.Query(query)
+	}
+	return func(n *HNSWNode) float32 {
+		if n.Code != nil && approx != nil {
+			return approx(n.Code)
 		}
+		return idx.distance(query, n.Vector)
+	}
+}
 
-		result = insertSorted(result, closest, k)
-		for _, n := range idx.nodes[closest.ID].Neighbors[layer] {
-			if !visited[n.ID] {
-				visited[n.ID] = true
-				sim := cosineSimilarity(query, idx.nodes[n.ID].Vector)
-				candidates = append(candidates, Neighbor{ID: n.ID, Similarity: sim})
-			}
-		}
+// nodeDistance 计算两个节点之间的距离，用于邻居选择
+func (idx *HNSWIndex) nodeDistance(a, b *HNSWNode) float32 {
+	if a.Code != nil && b.Code != nil && idx.quantized() {
+		return idx.quantizer.Distance(a.Code, b.Code)
 	}
-	return result
+	return idx.distance(idx.vectorOf(a), idx.vectorOf(b))
 }
 
-func insertSorted(list []Neighbor, n Neighbor, k int) []Neighbor {
-	list = append(list, n)
-	for i := len(list) - 1; i > 0 && list[i].Similarity > list[i-1].Similarity; i-- {
-		list[i], list[i-1] = list[i-1], list[i]
+func (idx *HNSWIndex) vectorOf(n *HNSWNode) []float32 {
+	if n.Vector == nil && n.Code != nil {
+		return idx.quantizer.Decode(n.Code)
 	}
-	if len(list) > k {
-		return list[:k]
+	return n.Vector
+}
+
+func (idx *HNSWIndex) quantized() bool {
+	return idx.quantizer != nil && idx.quantizer.Trained()
+}
+
+// maybeTrain 在节点数达到 trainSize 时训练量化器，并把所有节点替换为编码
+func (idx *HNSWIndex) maybeTrain() {
+	if idx.quantizer == nil || idx.quantizer.Trained() || len(idx.nodes) < idx.trainSize {
+		return
+	}
+	vectors := make([][]float32, 0, len(idx.nodes))
+	for _, n := range idx.nodes {
+		vectors = append(vectors, n.Vector)
+	}
+	idx.quantizer.Train(vectors)
+	for _, n := range idx.nodes {
+		n.Code = idx.quantizer.Encode(n.Vector)
+		n.Vector = nil
 	}
-	return list
 }
 
-func cosineSimilarity(v1, v2 []float64) float64 {
-	if len(v1) != len(v2) {
-		return 0
+// rescoreCandidates 用全精度向量（或无法读取时用重建向量）重新计算候选得分并排序
+func (idx *HNSWIndex) rescoreCandidates(query []float32, found []candidate, k int) []candidate {
+	limit := len(found)
+	if idx.source != nil && idx.rescore > 0 && k*idx.rescore < limit {
+		limit = k * idx.rescore
 	}
-	var dotProduct, norm1, norm2 float64
-	for i := range v1 {
-		dotProduct += v1[i] * v2[i]
-		norm1 += v1[i] * v1[i]
-		norm2 += v2[i] * v2[i]
+	rescored := make([]candidate, 0, limit)
+	for _, c := range found[:limit] {
+		v := c.node.Vector
+		if v == nil && idx.source != nil && idx.rescore > 0 {
+			v, _ = idx.source(c.node.ID)
+		}
+		if v == nil {
+			v = idx.vectorOf(c.node)
+		}
+		rescored = append(rescored, candidate{node: c.node, dist: idx.distance(query, v)})
 	}
-	if norm1 == 0 || norm2 == 0 {
-		return 0
+	sort.Slice(rescored, func(i, j int) bool { return rescored[i].dist < rescored[j].dist })
+	return rescored
+}
+
+type candidate struct {
+	node *HNSWNode
+	dist float32
+}
+
+// candidateHeap 默认是最小堆，farthest 为 true 时为最大堆
+type candidateHeap struct {
+	items    []candidate
+	farthest bool
+}
+
+func (h candidateHeap) Len() int { return len(h.items) }
+func (h candidateHeap) Less(i, j int) bool {
+	if h.farthest {
+		return h.items[i].dist > h.items[j].dist
+	}
+	return h.items[i].dist < h.items[j].dist
+}
+func (h candidateHeap) Swap(i, j int)       { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x interface{}) { h.items = append(h.items, x.(candidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	n := len(h.items)
+	x := h.items[n-1]
+	h.items = h.items[:n-1]
+	return x
+}
+func (h candidateHeap) top() candidate { return h.items[0] }
+
+func candidateIDs(cands []candidate) []string {
+	ids := make([]string, len(cands))
+	for i, c := range cands {
+		ids[i] = c.node.ID
 	}
-	return dotProduct / (math.Sqrt(norm1) * math.Sqrt(norm2))
+	return ids
 }
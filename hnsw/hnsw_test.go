@@ -1,20 +1,24 @@
 package hnsw
 
 import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
 	"testing"
 )
 
 func TestHNSWIndex(t *testing.T) {
 	// 初始化 HNSW 索引
-	idx := NewHNSWIndex(3, 16, 200)
+	idx := NewHNSWIndex(3, 16, 200, 200, nil)
 
 	// 添加向量
-	idx.Add("id1", []float64{1.0, 0.0, 0.0})
-	idx.Add("id2", []float64{0.0, 1.0, 0.0})
-	idx.Add("id3", []float64{1.0, 1.0, 0.0})
+	idx.Add("id1", []float32{1.0, 0.0, 0.0})
+	idx.Add("id2", []float32{0.0, 1.0, 0.0})
+	idx.Add("id3", []float32{1.0, 1.0, 0.0})
 
 	// 测试搜索
-	query := []float64{1.0, 0.0, 0.0}
+	query := []float32{1.0, 0.0, 0.0}
 	results := idx.Search(query, 2)
 	if len(results) != 2 {
 		t.Errorf("Expected 2 results, got %d", len(results))
@@ -24,8 +28,8 @@ func TestHNSWIndex(t *testing.T) {
 	if results[0].ID != "id1" {
 		t.Errorf("Expected id1 as top result, got %s", results[0].ID)
 	}
-	if results[0].Similarity < 0.99 { // 余弦相似度应接近 1
-		t.Errorf("Expected high similarity for id1, got %f", results[0].Similarity)
+	if results[0].Score < 0.99 { // 余弦相似度应接近 1
+		t.Errorf("Expected high similarity for id1, got %f", results[0].Score)
 	}
 
 	// 测试删除
@@ -39,17 +43,159 @@ func TestHNSWIndex(t *testing.T) {
 	}
 }
 
-func TestCosineSimilarity(t *testing.T) {
-	v1 := []float64{1.0, 0.0}
-	v2 := []float64{1.0, 0.0}
-	sim := cosineSimilarity(v1, v2)
-	if sim != 1.0 {
-		t.Errorf("Expected similarity 1.0, got %f", sim)
+func randomVectors(n, dim int, rng *rand.Rand) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rng.Float32()*2 - 1
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+func TestHNSWRecall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dim, n, k := 16, 2000, 10
+	vectors := randomVectors(n, dim, rng)
+
+	idx := NewHNSWIndex(dim, 16, 200, 100, L2{})
+	for i, v := range vectors {
+		idx.Add(fmt.Sprintf("id%d", i), v)
+	}
+
+	// 与暴力搜索结果比较召回率
+	queries := randomVectors(50, dim, rng)
+	hits := 0
+	for _, q := range queries {
+		exact := make([]Neighbor, 0, n)
+		for i, v := range vectors {
+			exact = append(exact, Neighbor{ID: fmt.Sprintf("id%d", i), Score: euclideanDistance(q, v)})
+		}
+		sort.Slice(exact, func(i, j int) bool { return exact[i].Score < exact[j].Score })
+		truth := make(map[string]bool, k)
+		for _, e := range exact[:k] {
+			truth[e.ID] = true
+		}
+		for _, r := range idx.Search(q, k) {
+			if truth[r.ID] {
+				hits++
+			}
+		}
+	}
+	recall := float64(hits) / float64(len(queries)*k)
+	if recall < 0.9 {
+		t.Errorf("Expected recall@%d >= 0.9, got %.3f", k, recall)
+	}
+}
+
+func TestHNSWSearchFilter(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	dim, n, k := 16, 2000, 10
+	vectors := randomVectors(n, dim, rng)
+	idx := NewHNSWIndex(dim, 16, 200, 64, L2{})
+	for i, v := range vectors {
+		idx.Add(fmt.Sprintf("id%d", i), v)
+	}
+
+	// 分别只允许 1/2 和 1/20 的节点
+	for _, mod := range []int{2, 20} {
+		allowed := make(map[string]bool)
+		for i := 0; i < n; i += mod {
+			allowed[fmt.Sprintf("id%d", i)] = true
+		}
+		allow := func(id string) bool { return allowed[id] }
+
+		hits, total := 0, 0
+		for _, q := range randomVectors(20, dim, rng) {
+			exact := make([]Neighbor, 0, n)
+			for i, v := range vectors {
+				if i%mod == 0 {
+					exact = append(exact, Neighbor{ID: fmt.Sprintf("id%d", i), Score: euclideanDistance(q, v)})
+				}
+			}
+			sort.Slice(exact, func(i, j int) bool { return exact[i].Score < exact[j].Score })
+			truth := make(map[string]bool, k)
+			for _, e := range exact[:k] {
+				truth[e.ID] = true
+			}
+			results := idx.SearchFilter(q, k, allow)
+			if len(results) != k {
+				t.Fatalf("Expected %d filtered results with 1/%d allowed, got %d", k, mod, len(results))
+			}
+			for _, r := range results {
+				if !allowed[r.ID] {
+					t.Fatalf("Result %s does not pass the filter", r.ID)
+				}
+				if truth[r.ID] {
+					hits++
+				}
+			}
+			total += k
+		}
+		if recall := float64(hits) / float64(total); recall < 0.9 {
+			t.Errorf("Expected filtered recall >= 0.9 with 1/%d allowed, got %.3f", mod, recall)
+		}
 	}
 
-	v3 := []float64{0.0, 1.0}
-	sim = cosineSimilarity(v1, v3)
-	if sim != 0.0 {
-		t.Errorf("Expected similarity 0.0, got %f", sim)
+	if got := idx.SearchFilter(vectors[0], k, func(string) bool { return false }); len(got) != 0 {
+		t.Errorf("Expected no results when nothing is allowed, got %d", len(got))
+	}
+}
+
+func TestHNSWGraphInvariants(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	idx := NewHNSWIndex(8, 4, 32, 32, nil)
+	for i, v := range randomVectors(500, 8, rng) {
+		idx.Add(fmt.Sprintf("id%d", i), v)
+	}
+
+	// 删除入口点后应重新选出最高层的节点作为入口
+	idx.Remove(idx.entryPoint.ID)
+	for i := 0; i < 500; i += 3 {
+		idx.Remove(fmt.Sprintf("id%d", i))
+	}
+	// 替换已有的 ID 同样经过删除
+	for i, v := range randomVectors(50, 8, rng) {
+		idx.Add(fmt.Sprintf("id%d", 4*i+1), v)
+	}
+	idx.RemoveBatch([]string{"id2", "id5", "id8"})
+	if idx.entryPoint == nil || idx.entryPoint.Layer != idx.maxLayer {
+		t.Fatalf("Entry point is not on the top layer")
+	}
+	for _, node := range idx.nodes {
+		if node.Layer > idx.maxLayer {
+			t.Errorf("Node %s above max layer", node.ID)
+		}
+		for l, neighbors := range node.Neighbors {
+			if len(neighbors) > idx.maxConnections(l) {
+				t.Errorf("Node %s has %d neighbors on layer %d", node.ID, len(neighbors), l)
+			}
+			for _, nid := range neighbors {
+				if _, ok := idx.nodes[nid]; !ok {
+					t.Errorf("Node %s links to removed node %s", node.ID, nid)
+				}
+			}
+		}
+	}
+	if got := len(idx.Search(randomVectors(1, 8, rng)[0], 10)); got != 10 {
+		t.Errorf("Expected 10 results, got %d", got)
+	}
+
+	// 反向连接与各节点的邻居一致
+	want := make(map[string]map[string]int)
+	for _, node := range idx.nodes {
+		for _, neighbors := range node.Neighbors {
+			for _, nid := range neighbors {
+				if want[nid] == nil {
+					want[nid] = make(map[string]int)
+				}
+				want[nid][node.ID]++
+			}
+		}
+	}
+	if !reflect.DeepEqual(idx.inbound, want) {
+		t.Errorf("Reverse links out of sync: %d targets tracked, %d expected", len(idx.inbound), len(want))
 	}
 }
@@ -0,0 +1,125 @@
+package hnsw
+
+import (
+	"fmt"
+	"math"
+
+	"gvdb/vector"
+)
+
+// Metric 定义向量间的距离/相似度度量
+type Metric interface {
+	// Name 返回度量名称，与配置中的 hnsw.metric 对应
+	Name() string
+	// Score 计算两个向量的得分
+	Score(v1, v2 []float32) float32
+	// HigherIsBetter 为 true 时得分越大越相似（相似度），否则越小越相似（距离）
+	HigherIsBetter() bool
+}
+
+// Better 判断在给定度量下得分 a 是否优于得分 b
+func Better(m Metric, a, b float32) bool {
+	if m.HigherIsBetter() {
+		return a > b
+	}
+	return a < b
+}
+
+// MetricByName 根据名称返回度量，空名称返回余弦相似度
+func MetricByName(name string) (Metric, error) {
+	switch name {
+	case "", "cosine":
+		return Cosine{}, nil
+	case "l2", "euclidean":
+		return L2{}, nil
+	case "ip", "inner_product", "dot":
+		return InnerProduct{}, nil
+	case "manhattan", "l1":
+		return Manhattan{}, nil
+	case "hamming":
+		return Hamming{}, nil
+	default:
+		return nil, fmt.Errorf("unknown metric: %s", name)
+	}
+}
+
+// Cosine 余弦相似度，越大越相似
+type Cosine struct{}
+
+func (Cosine) Name() string                   { return "cosine" }
+func (Cosine) Score(v1, v2 []float32) float32 { return cosineSimilarity(v1, v2) }
+func (Cosine) HigherIsBetter() bool           { return true }
+
+// L2 欧氏距离，越小越相似
+type L2 struct{}
+
+func (L2) Name() string                   { return "l2" }
+func (L2) Score(v1, v2 []float32) float32 { return euclideanDistance(v1, v2) }
+func (L2) HigherIsBetter() bool           { return false }
+
+// InnerProduct 内积，越大越相似
+type InnerProduct struct{}
+
+func (InnerProduct) Name() string                   { return "ip" }
+func (InnerProduct) Score(v1, v2 []float32) float32 { return innerProduct(v1, v2) }
+func (InnerProduct) HigherIsBetter() bool           { return true }
+
+// Manhattan 曼哈顿距离，越小越相似
+type Manhattan struct{}
+
+func (Manhattan) Name() string                   { return "manhattan" }
+func (Manhattan) Score(v1, v2 []float32) float32 { return manhattanDistance(v1, v2) }
+func (Manhattan) HigherIsBetter() bool           { return false }
+
+// Hamming 汉明距离（取值不同的维度数），越小越相似
+type Hamming struct{}
+
+func (Hamming) Name() string                   { return "hamming" }
+func (Hamming) Score(v1, v2 []float32) float32 { return hammingDistance(v1, v2) }
+func (Hamming) HigherIsBetter() bool           { return false }
+
+func cosineSimilarity(v1, v2 []float32) float32 {
+	// 维度不一致时与其他度量一样返回最差的得分，不能被当作中等相似的结果
+	if len(v1) != len(v2) {
+		return float32(math.Inf(-1))
+	}
+	n1, n2 := vector.Norm(v1), vector.Norm(v2)
+	if n1 == 0 || n2 == 0 {
+		return 0
+	}
+	return vector.Dot(v1, v2) / (n1 * n2)
+}
+
+func euclideanDistance(v1, v2 []float32) float32 {
+	if len(v1) != len(v2) {
+		return float32(math.Inf(1))
+	}
+	return float32(math.Sqrt(float64(vector.L2Squared(v1, v2))))
+}
+
+func innerProduct(v1, v2 []float32) float32 {
+	if len(v1) != len(v2) {
+		return float32(math.Inf(-1))
+	}
+	return vector.Dot(v1, v2)
+}
+
+func manhattanDistance(v1, v2 []float32) float32 {
+	if len(v1) != len(v2) {
+		return float32(math.Inf(1))
+	}
+	return vector.L1(v1, v2)
+}
+
+func hammingDistance(v1, v2 []float32) float32 {
+	if len(v1) != len(v2) {
+		return float32(math.Inf(1))
+	}
+	var diff float32
+	for i := range v1 {
+		if v1[i] != v2[i] {
+			diff++
+		}
+	}
+	return diff
+}
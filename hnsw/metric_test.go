@@ -0,0 +1,86 @@
+package hnsw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	v1 := []float32{1.0, 0.0}
+	v2 := []float32{1.0, 0.0}
+	sim := cosineSimilarity(v1, v2)
+	if sim != 1.0 {
+		t.Errorf("Expected similarity 1.0, got %f", sim)
+	}
+
+	v3 := []float32{0.0, 1.0}
+	sim = cosineSimilarity(v1, v3)
+	if sim != 0.0 {
+		t.Errorf("Expected similarity 0.0, got %f", sim)
+	}
+}
+
+func TestMetricScores(t *testing.T) {
+	v1 := []float32{1.0, 2.0, 3.0}
+	v2 := []float32{4.0, 2.0, 1.0}
+
+	cases := []struct {
+		name   string
+		want   float64
+		higher bool
+	}{
+		{"l2", math.Sqrt(13), false},
+		{"ip", 11, true},
+		{"manhattan", 5, false},
+		{"hamming", 2, false},
+		{"cosine", 11 / math.Sqrt(14*21), true},
+	}
+	for _, c := range cases {
+		m, err := MetricByName(c.name)
+		if err != nil {
+			t.Fatalf("MetricByName(%s) failed: %v", c.name, err)
+		}
+		if got := float64(m.Score(v1, v2)); math.Abs(got-c.want) > 1e-5 {
+			t.Errorf("%s: expected %f, got %f", c.name, c.want, got)
+		}
+		if m.HigherIsBetter() != c.higher {
+			t.Errorf("%s: unexpected ordering direction", c.name)
+		}
+		// 维度不一致的向量得分比任何正常结果都差
+		if mismatch := m.Score(v1, v2[:2]); !Better(m, m.Score(v1, v2), mismatch) || !Better(m, m.Score(v1, []float32{-1, -2, -3}), mismatch) {
+			t.Errorf("%s: mismatched dimensions scored %f", c.name, mismatch)
+		}
+	}
+
+	if _, err := MetricByName("unknown"); err == nil {
+		t.Error("Expected error for unknown metric")
+	}
+}
+
+func TestSearchWithDistanceMetric(t *testing.T) {
+	// 距离度量下结果应按距离升序排列
+	idx := NewHNSWIndex(2, 16, 200, 200, L2{})
+	idx.Add("near", []float32{1.0, 1.0})
+	idx.Add("mid", []float32{3.0, 3.0})
+	idx.Add("far", []float32{10.0, 10.0})
+
+	results := idx.Search([]float32{0.0, 0.0}, 3)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].ID != "near" || results[2].ID != "far" {
+		t.Errorf("Unexpected order: %v", results)
+	}
+	if results[0].Score > results[1].Score {
+		t.Errorf("Expected ascending distances, got %v", results)
+	}
+
+	// 内积：越大越好
+	idx = NewHNSWIndex(2, 16, 200, 200, InnerProduct{})
+	idx.Add("small", []float32{1.0, 0.0})
+	idx.Add("large", []float32{5.0, 0.0})
+	results = idx.Search([]float32{1.0, 0.0}, 2)
+	if len(results) != 2 || results[0].ID != "large" {
+		t.Errorf("Expected large first for inner product, got %v", results)
+	}
+}
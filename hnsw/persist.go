@@ -0,0 +1,380 @@
+package hnsw
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// 快照文件格式：magic、版本号、参数、量化器状态、节点（向量或编码、各层邻居的节点序号），末尾为 CRC32 校验和
+const (
+	snapshotMagic   = "GVHNSW"
+	snapshotVersion = 1
+)
+
+// ErrSnapshotCorrupt 表示快照文件损坏或版本不受支持
+var ErrSnapshotCorrupt = errors.New("hnsw: corrupt or unsupported snapshot")
+
+// Save 把索引的参数、图结构和入口点写入 w
+func (idx *HNSWIndex) Save(w io.Writer) error {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	enc := &snapshotWriter{w: bw, crc: crc32.NewIEEE()}
+	enc.bytes([]byte(snapshotMagic))
+	enc.u32(snapshotVersion)
+	enc.u32(uint32(idx.dim))
+	enc.u32(uint32(idx.m))
+	enc.u32(uint32(idx.mMax0))
+	enc.u32(uint32(idx.efConstruction))
+	enc.u32(uint32(idx.efSearch))
+	enc.str(idx.metric.Name())
+	if err := enc.quantizer(idx.quantizer); err != nil {
+		return err
+	}
+	enc.u32(uint32(idx.trainSize))
+
+	// 节点按序号编号，邻居以序号保存
+	order := make(map[string]uint32, len(idx.nodes))
+	nodes := make([]*HNSWNode, 0, len(idx.nodes))
+	for id, n := range idx.nodes {
+		order[id] = uint32(len(nodes))
+		nodes = append(nodes, n)
+	}
+	enc.u32(uint32(len(nodes)))
+	entry := int64(-1)
+	if idx.entryPoint != nil {
+		entry = int64(order[idx.entryPoint.ID])
+	}
+	enc.u64(uint64(entry))
+	enc.u32(uint32(idx.maxLayer))
+	for _, n := range nodes {
+		enc.str(n.ID)
+		enc.u32(uint32(n.Layer))
+		if n.Code != nil {
+			enc.u8(1)
+			enc.bytes32(n.Code)
+		} else {
+			enc.u8(0)
+			enc.floats(n.Vector)
+		}
+		for l := 0; l <= n.Layer; l++ {
+			enc.u32(uint32(len(n.Neighbors[l])))
+			for _, nid := range n.Neighbors[l] {
+				enc.u32(order[nid])
+			}
+		}
+	}
+	if enc.err != nil {
+		return enc.err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, enc.crc.Sum32()); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Load 从 r 读取 Save 写出的快照并重建索引
+func Load(r io.Reader) (*HNSWIndex, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(snapshotMagic)+8 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, ErrSnapshotCorrupt
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	dec := &snapshotReader{r: bytes.NewReader(body[len(snapshotMagic):])}
+	if v := dec.u32(); v != snapshotVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrSnapshotCorrupt, v)
+	}
+	dim, m, mMax0 := int(dec.u32()), int(dec.u32()), int(dec.u32())
+	efConstruction, efSearch := int(dec.u32()), int(dec.u32())
+	metric, err := MetricByName(dec.str())
+	if err != nil {
+		return nil, err
+	}
+	idx := NewHNSWIndex(dim, m, efConstruction, efSearch, metric)
+	idx.mMax0 = mMax0
+	idx.quantizer = dec.quantizer(metric)
+	idx.trainSize = int(dec.u32())
+
+	count := int(dec.u32())
+	entry := int64(dec.u64())
+	idx.maxLayer = int(dec.u32())
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	nodes := make([]*HNSWNode, count)
+	links := make([][][]uint32, count)
+	for i := range nodes {
+		n := &HNSWNode{ID: dec.str(), Layer: int(dec.u32())}
+		if dec.u8() == 1 {
+			n.Code = dec.bytes32()
+		} else {
+			n.Vector = dec.floats()
+		}
+		if dec.err != nil || n.Layer > idx.maxLayer {
+			return nil, ErrSnapshotCorrupt
+		}
+		links[i] = make([][]uint32, n.Layer+1)
+		for l := range links[i] {
+			links[i][l] = make([]uint32, dec.u32())
+			for j := range links[i][l] {
+				links[i][l][j] = dec.u32()
+			}
+			if dec.err != nil {
+				return nil, dec.err
+			}
+		}
+		nodes[i] = n
+	}
+	for i, n := range nodes {
+		n.Neighbors = make([][]string, n.Layer+1)
+		for l, ids := range links[i] {
+			n.Neighbors[l] = make([]string, len(ids))
+			for j, o := range ids {
+				if int(o) >= count {
+					return nil, ErrSnapshotCorrupt
+				}
+				n.Neighbors[l][j] = nodes[o].ID
+				idx.linkRef(n.ID, nodes[o].ID)
+			}
+		}
+		idx.nodes[n.ID] = n
+	}
+	if entry >= int64(count) || (entry < 0 && count > 0) {
+		return nil, ErrSnapshotCorrupt
+	}
+	if entry >= 0 {
+		idx.entryPoint = nodes[entry]
+	}
+	return idx, nil
+}
+
+// SaveFile 把快照写入临时文件后原子地重命名为 path
+func (idx *HNSWIndex) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := idx.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile 读取 SaveFile 写出的快照
+func LoadFile(path string) (*HNSWIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Compatible 判断加载的索引是否与给定参数一致，quantizer 只比较类型
+func (idx *HNSWIndex) Compatible(dim, m int, metric Metric, quantizer Quantizer) bool {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	if idx.dim != dim || idx.m != m || idx.metric.Name() != metric.Name() {
+		return false
+	}
+	if idx.quantizer == nil || quantizer == nil {
+		return idx.quantizer == nil && quantizer == nil
+	}
+	return idx.quantizer.Name() == quantizer.Name()
+}
+
+// Contains 判断索引中是否存在 id 且其向量（或量化编码）与 v 一致，用于校验快照与存储是否同步
+func (idx *HNSWIndex) Contains(id string, v []float32) bool {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	n, ok := idx.nodes[id]
+	if !ok {
+		return false
+	}
+	if n.Code != nil {
+		return idx.quantizer != nil && bytes.Equal(n.Code, idx.quantizer.Encode(v))
+	}
+	if len(n.Vector) != len(v) {
+		return false
+	}
+	for i := range v {
+		if n.Vector[i] != v[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type snapshotWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	err error
+	buf [8]byte
+}
+
+func (e *snapshotWriter) bytes(b []byte) {
+	if e.err != nil {
+		return
+	}
+	e.crc.Write(b)
+	_, e.err = e.w.Write(b)
+}
+
+func (e *snapshotWriter) u8(v uint8) { e.bytes([]byte{v}) }
+
+func (e *snapshotWriter) u32(v uint32) {
+	binary.LittleEndian.PutUint32(e.buf[:4], v)
+	e.bytes(e.buf[:4])
+}
+
+func (e *snapshotWriter) u64(v uint64) {
+	binary.LittleEndian.PutUint64(e.buf[:8], v)
+	e.bytes(e.buf[:8])
+}
+
+func (e *snapshotWriter) str(s string) { e.bytes32([]byte(s)) }
+
+func (e *snapshotWriter) bytes32(b []byte) {
+	e.u32(uint32(len(b)))
+	e.bytes(b)
+}
+
+func (e *snapshotWriter) floats(v []float32) {
+	e.u32(uint32(len(v)))
+	for _, x := range v {
+		e.u32(math.Float32bits(x))
+	}
+}
+
+func (e *snapshotWriter) quantizer(q Quantizer) error {
+	switch q := q.(type) {
+	case nil:
+		e.str("none")
+	case *ScalarQuantizer:
+		e.str(q.Name())
+		e.floats(q.min)
+		e.floats(q.scale)
+	case *BinaryQuantizer:
+		e.str(q.Name())
+		e.u32(uint32(q.dim))
+		e.floats(q.threshold)
+		e.floats(q.lo)
+		e.floats(q.hi)
+	default:
+		return fmt.Errorf("hnsw: cannot save quantizer %s", q.Name())
+	}
+	return nil
+}
+
+type snapshotReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *snapshotReader) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > d.r.Len() {
+		d.err = ErrSnapshotCorrupt
+		return nil
+	}
+	b := make([]byte, n)
+	d.r.Read(b)
+	return b
+}
+
+func (d *snapshotReader) u8() uint8 {
+	if b := d.read(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *snapshotReader) u32() uint32 {
+	if b := d.read(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *snapshotReader) u64() uint64 {
+	if b := d.read(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *snapshotReader) bytes32() []byte { return d.read(int(d.u32())) }
+
+func (d *snapshotReader) str() string { return string(d.bytes32()) }
+
+func (d *snapshotReader) floats() []float32 {
+	n := int(d.u32())
+	if d.err != nil || n > d.r.Len()/4 {
+		d.err = ErrSnapshotCorrupt
+		return nil
+	}
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = math.Float32frombits(d.u32())
+	}
+	return v
+}
+
+func (d *snapshotReader) quantizer(metric Metric) Quantizer {
+	switch name := d.str(); name {
+	case "none":
+		return nil
+	case "int8":
+		sq := NewScalarQuantizer(metric)
+		sq.min, sq.scale = d.floats(), d.floats()
+		if len(sq.min) == 0 {
+			sq.min, sq.scale = nil, nil
+		}
+		return sq
+	case "binary":
+		bq := NewBinaryQuantizer()
+		bq.dim = int(d.u32())
+		bq.threshold, bq.lo, bq.hi = d.floats(), d.floats(), d.floats()
+		if len(bq.threshold) == 0 {
+			bq.threshold = nil
+		}
+		return bq
+	default:
+		d.err = fmt.Errorf("%w: unknown quantizer %q", ErrSnapshotCorrupt, name)
+		return nil
+	}
+}
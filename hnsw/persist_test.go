@@ -0,0 +1,104 @@
+package hnsw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func buildIndex(t *testing.T, q Quantizer) (*HNSWIndex, [][]float32) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(3))
+	vectors := randomVectors(500, 8, rng)
+	idx := NewHNSWIndex(8, 8, 64, 32, L2{})
+	if q != nil {
+		idx.SetQuantizer(q, 100)
+	}
+	for i, v := range vectors {
+		idx.Add(fmt.Sprintf("v%d", i), v)
+	}
+	idx.Remove("v7")
+	return idx, vectors
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	for _, q := range []Quantizer{nil, NewScalarQuantizer(L2{}), NewBinaryQuantizer()} {
+		idx, vectors := buildIndex(t, q)
+		var buf bytes.Buffer
+		if err := idx.Save(&buf); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		loaded, err := Load(&buf)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if loaded.Len() != idx.Len() || loaded.Metric().Name() != "l2" {
+			t.Fatalf("Expected %d l2 nodes, got %d %s", idx.Len(), loaded.Len(), loaded.Metric().Name())
+		}
+		if loaded.entryPoint.ID != idx.entryPoint.ID || loaded.maxLayer != idx.maxLayer {
+			t.Errorf("Entry point mismatch: %s/%d vs %s/%d", loaded.entryPoint.ID, loaded.maxLayer, idx.entryPoint.ID, idx.maxLayer)
+		}
+		for id, n := range idx.nodes {
+			got := loaded.nodes[id].Neighbors
+			if len(got) != len(n.Neighbors) {
+				t.Fatalf("Layers of %s differ after reload", id)
+			}
+			for l := range got {
+				if fmt.Sprint(got[l]) != fmt.Sprint(n.Neighbors[l]) {
+					t.Fatalf("Neighbors of %s differ on layer %d after reload", id, l)
+				}
+			}
+		}
+		if !reflect.DeepEqual(loaded.inbound, idx.inbound) {
+			t.Fatal("Reverse links differ after reload")
+		}
+		// 重新加载的图搜索结果应与原图一致
+		for _, query := range vectors[:20] {
+			if !reflect.DeepEqual(idx.Search(query, 5), loaded.Search(query, 5)) {
+				t.Fatalf("Search results differ after reload")
+			}
+		}
+		if !loaded.Contains("v1", vectors[1]) || loaded.Contains("v7", vectors[7]) || loaded.Contains("v1", vectors[2]) {
+			t.Error("Contains does not reflect stored vectors")
+		}
+	}
+}
+
+func TestSaveFileLoadFile(t *testing.T) {
+	idx, _ := buildIndex(t, nil)
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := idx.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if loaded.Len() != idx.Len() {
+		t.Errorf("Expected %d nodes, got %d", idx.Len(), loaded.Len())
+	}
+}
+
+func TestLoadRejectsCorruptSnapshot(t *testing.T) {
+	idx, _ := buildIndex(t, nil)
+	var buf bytes.Buffer
+	idx.Save(&buf)
+	data := buf.Bytes()
+
+	// 翻转一个字节，校验和应失败
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)/2] ^= 0xff
+	if _, err := Load(bytes.NewReader(flipped)); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("Expected ErrSnapshotCorrupt for flipped byte, got %v", err)
+	}
+	if _, err := Load(bytes.NewReader(data[:len(data)/3])); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("Expected ErrSnapshotCorrupt for truncated data, got %v", err)
+	}
+	if _, err := Load(bytes.NewReader([]byte("not a snapshot"))); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("Expected ErrSnapshotCorrupt for bad magic, got %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package hnsw
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"gvdb/vector"
+)
+
+// Quantizer 把节点向量压缩为字节编码，训练完成后 HNSWNode 只保留编码
+type Quantizer interface {
+	Name() string
+	Train(vectors [][]float32)
+	Trained() bool
+	Encode(v []float32) []byte
+	// Decode 由编码重建近似向量
+	Decode(code []byte) []float32
+	// Query 为查询预处理后返回计算其与编码之间越小越相似的距离的函数
+	Query(q []float32) func(code []byte) float32
+	// Distance 直接比较两个编码，返回与 Query 同样越小越相似的距离，用于构图时的邻居选择
+	Distance(a, b []byte) float32
+}
+
+// VectorSource 按 ID 读取原始向量，用于对量化结果重新打分
+type VectorSource func(id string) ([]float32, bool)
+
+// NewQuantizer 根据名称创建量化器，none 或空名称返回 nil
+func NewQuantizer(name string, metric Metric) (Quantizer, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "int8", "scalar":
+		return NewScalarQuantizer(metric), nil
+	case "binary":
+		return NewBinaryQuantizer(), nil
+	default:
+		return nil, fmt.Errorf("unknown quantization: %s", name)
+	}
+}
+
+// ScalarQuantizer 把每个维度按训练得到的最小/最大值线性映射到 0~255
+type ScalarQuantizer struct {
+	metric Metric
+	min    []float32
+	scale  []float32
+}
+
+// NewScalarQuantizer 创建 int8 标量量化器，距离在重建向量上按 metric 计算
+func NewScalarQuantizer(metric Metric) *ScalarQuantizer {
+	if metric == nil {
+		metric = Cosine{}
+	}
+	return &ScalarQuantizer{metric: metric}
+}
+
+func (sq *ScalarQuantizer) Name() string  { return "int8" }
+func (sq *ScalarQuantizer) Trained() bool { return sq.min != nil }
+
+func (sq *ScalarQuantizer) Train(vectors [][]float32) {
+	if len(vectors) == 0 {
+		return
+	}
+	dim := len(vectors[0])
+	lo := make([]float32, dim)
+	hi := make([]float32, dim)
+	for i := range lo {
+		lo[i], hi[i] = math.MaxFloat32, -math.MaxFloat32
+	}
+	for _, v := range vectors {
+		for i := 0; i < dim && i < len(v); i++ {
+			lo[i] = min(lo[i], v[i])
+			hi[i] = max(hi[i], v[i])
+		}
+	}
+	scale := make([]float32, dim)
+	for i := range scale {
+		scale[i] = (hi[i] - lo[i]) / 255
+	}
+	sq.min, sq.scale = lo, scale
+}
+
+func (sq *ScalarQuantizer) Encode(v []float32) []byte {
+	code := make([]byte, len(sq.min))
+	for i := range code {
+		if i >= len(v) || sq.scale[i] == 0 {
+			continue
+		}
+		x := math.Round(float64((v[i] - sq.min[i]) / sq.scale[i]))
+		// 超出训练范围的值截断
+		code[i] = byte(max(0, min(255, x)))
+	}
+	return code
+}
+
+func (sq *ScalarQuantizer) Decode(code []byte) []float32 {
+	v := make([]float32, len(code))
+	for i, c := range code {
+		v[i] = sq.min[i] + float32(c)*sq.scale[i]
+	}
+	return v
+}
+
+// Query 预先把查询换算到各维度的 min/scale 空间，距离直接在 uint8 编码上计算，比较时不重建向量、不分配内存。
+// 编码 c 在第 i 维的重建值为 min[i]+c*scale[i]。自定义度量的函数复用一个重建缓冲区，不能被多个 goroutine 同时调用
+func (sq *ScalarQuantizer) Query(q []float32) func(code []byte) float32 {
+	if len(q) != len(sq.min) {
+		// 与度量一样把维度不一致的查询排在最后
+		worst := float32(math.Inf(1))
+		return func([]byte) float32 { return worst }
+	}
+	lo, scale := sq.min, sq.scale
+	switch sq.metric.(type) {
+	case L2:
+		diff := sq.offset(q)
+		return func(code []byte) float32 {
+			var sum float32
+			for i, c := range code {
+				d := diff[i] - float32(c)*scale[i]
+				sum += d * d
+			}
+			return float32(math.Sqrt(float64(sum)))
+		}
+	case Manhattan:
+		diff := sq.offset(q)
+		return func(code []byte) float32 {
+			var sum float32
+			for i, c := range code {
+				sum += float32(math.Abs(float64(diff[i] - float32(c)*scale[i])))
+			}
+			return sum
+		}
+	case InnerProduct:
+		// q·x = Σq[i]*min[i] + Σc[i]*(q[i]*scale[i])
+		var base float32
+		weight := make([]float32, len(q))
+		for i := range q {
+			base += q[i] * lo[i]
+			weight[i] = q[i] * scale[i]
+		}
+		return func(code []byte) float32 {
+			dot := base
+			for i, c := range code {
+				dot += float32(c) * weight[i]
+			}
+			return -dot
+		}
+	case Cosine:
+		qn := vector.Norm(q)
+		return func(code []byte) float32 {
+			var dot, xn float32
+			for i, c := range code {
+				x := lo[i] + float32(c)*scale[i]
+				dot += q[i] * x
+				xn += x * x
+			}
+			if qn == 0 || xn == 0 {
+				return 0
+			}
+			return -dot / (qn * float32(math.Sqrt(float64(xn))))
+		}
+	case Hamming:
+		return func(code []byte) float32 {
+			var diff float32
+			for i, c := range code {
+				if q[i] != lo[i]+float32(c)*scale[i] {
+					diff++
+				}
+			}
+			return diff
+		}
+	}
+	buf := make([]float32, len(q))
+	return func(code []byte) float32 {
+		for i, c := range code {
+			buf[i] = lo[i] + float32(c)*scale[i]
+		}
+		s := sq.metric.Score(q, buf)
+		if sq.metric.HigherIsBetter() {
+			return -s
+		}
+		return s
+	}
+}
+
+// Distance 在两个 uint8 编码上按度量计算距离，常用度量不重建向量、不分配内存。长度不一致时返回 +Inf
+func (sq *ScalarQuantizer) Distance(a, b []byte) float32 {
+	if len(a) != len(sq.min) || len(b) != len(sq.min) {
+		return float32(math.Inf(1))
+	}
+	lo, scale := sq.min, sq.scale
+	switch sq.metric.(type) {
+	case L2:
+		var sum float32
+		for i := range a {
+			d := (float32(a[i]) - float32(b[i])) * scale[i]
+			sum += d * d
+		}
+		return float32(math.Sqrt(float64(sum)))
+	case Manhattan:
+		var sum float32
+		for i := range a {
+			sum += float32(math.Abs(float64(a[i])-float64(b[i]))) * scale[i]
+		}
+		return sum
+	case InnerProduct:
+		var dot float32
+		for i := range a {
+			dot += (lo[i] + float32(a[i])*scale[i]) * (lo[i] + float32(b[i])*scale[i])
+		}
+		return -dot
+	case Cosine:
+		var dot, an, bn float32
+		for i := range a {
+			x, y := lo[i]+float32(a[i])*scale[i], lo[i]+float32(b[i])*scale[i]
+			dot += x * y
+			an += x * x
+			bn += y * y
+		}
+		if an == 0 || bn == 0 {
+			return 0
+		}
+		return -dot / float32(math.Sqrt(float64(an))*math.Sqrt(float64(bn)))
+	case Hamming:
+		var diff float32
+		for i := range a {
+			if lo[i]+float32(a[i])*scale[i] != lo[i]+float32(b[i])*scale[i] {
+				diff++
+			}
+		}
+		return diff
+	}
+	s := sq.metric.Score(sq.Decode(a), sq.Decode(b))
+	if sq.metric.HigherIsBetter() {
+		return -s
+	}
+	return s
+}
+
+// offset 返回查询与各维度最小值的差
+func (sq *ScalarQuantizer) offset(q []float32) []float32 {
+	diff := make([]float32, len(q))
+	for i := range q {
+		diff[i] = q[i] - sq.min[i]
+	}
+	return diff
+}
+
+// BinaryQuantizer 每个维度用 1 位表示是否大于训练得到的均值，距离为汉明距离
+type BinaryQuantizer struct {
+	dim       int
+	threshold []float32
+	lo        []float32 // 位为 0 时的重建值
+	hi        []float32 // 位为 1 时的重建值
+}
+
+// NewBinaryQuantizer 创建 1 位二值量化器
+func NewBinaryQuantizer() *BinaryQuantizer {
+	return &BinaryQuantizer{}
+}
+
+func (bq *BinaryQuantizer) Name() string  { return "binary" }
+func (bq *BinaryQuantizer) Trained() bool { return bq.threshold != nil }
+
+func (bq *BinaryQuantizer) Train(vectors [][]float32) {
+	if len(vectors) == 0 {
+		return
+	}
+	dim := len(vectors[0])
+	threshold := make([]float32, dim)
+	for _, v := range vectors {
+		for i := 0; i < dim && i < len(v); i++ {
+			threshold[i] += v[i] / float32(len(vectors))
+		}
+	}
+	// 重建值取阈值两侧样本的均值
+	lo, hi := make([]float32, dim), make([]float32, dim)
+	nlo, nhi := make([]int, dim), make([]int, dim)
+	for _, v := range vectors {
+		for i := 0; i < dim && i < len(v); i++ {
+			if v[i] > threshold[i] {
+				hi[i] += v[i]
+				nhi[i]++
+			} else {
+				lo[i] += v[i]
+				nlo[i]++
+			}
+		}
+	}
+	for i := 0; i < dim; i++ {
+		if nlo[i] > 0 {
+			lo[i] /= float32(nlo[i])
+		} else {
+			lo[i] = threshold[i]
+		}
+		if nhi[i] > 0 {
+			hi[i] /= float32(nhi[i])
+		} else {
+			hi[i] = threshold[i]
+		}
+	}
+	bq.dim, bq.threshold, bq.lo, bq.hi = dim, threshold, lo, hi
+}
+
+func (bq *BinaryQuantizer) Encode(v []float32) []byte {
+	code := make([]byte, (bq.dim+7)/8)
+	for i := 0; i < bq.dim && i < len(v); i++ {
+		if v[i] > bq.threshold[i] {
+			code[i/8] |= 1 << (i % 8)
+		}
+	}
+	return code
+}
+
+func (bq *BinaryQuantizer) Decode(code []byte) []float32 {
+	v := make([]float32, bq.dim)
+	for i := range v {
+		if code[i/8]&(1<<(i%8)) != 0 {
+			v[i] = bq.hi[i]
+		} else {
+			v[i] = bq.lo[i]
+		}
+	}
+	return v
+}
+
+func (bq *BinaryQuantizer) Query(q []float32) func(code []byte) float32 {
+	qc := bq.Encode(q)
+	return func(code []byte) float32 {
+		return float32(hammingBits(qc, code))
+	}
+}
+
+// Distance 返回两个编码之间的汉明距离
+func (bq *BinaryQuantizer) Distance(a, b []byte) float32 {
+	return float32(hammingBits(a, b))
+}
+
+func hammingBits(a, b []byte) int {
+	n := 0
+	for i := range a {
+		n += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return n
+}
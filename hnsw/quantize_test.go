@@ -0,0 +1,156 @@
+package hnsw
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestScalarQuantizer(t *testing.T) {
+	sq := NewScalarQuantizer(L2{})
+	sq.Train([][]float32{{0, -1}, {10, 1}})
+	if !sq.Trained() {
+		t.Fatal("Expected quantizer to be trained")
+	}
+
+	code := sq.Encode([]float32{5, 0})
+	if len(code) != 2 {
+		t.Fatalf("Expected 1 byte per dimension, got %d", len(code))
+	}
+	v := sq.Decode(code)
+	if math.Abs(float64(v[0])-5) > 10.0/255 || math.Abs(float64(v[1])) > 2.0/255 {
+		t.Errorf("Reconstruction too far from original: %v", v)
+	}
+
+	// 超出训练范围的值被截断
+	if v := sq.Decode(sq.Encode([]float32{100, -5})); v[0] != 10 || v[1] != -1 {
+		t.Errorf("Expected clamped values, got %v", v)
+	}
+
+	// 直接在编码上计算的距离与在重建向量上按度量计算的结果一致，且不分配内存
+	rng := rand.New(rand.NewSource(4))
+	samples := make([][]float32, 100)
+	for i := range samples {
+		samples[i] = []float32{rng.Float32()*4 - 2, rng.Float32(), rng.Float32()*10 - 5, rng.Float32()}
+	}
+	query := []float32{0.5, -0.2, 3, 0.9}
+	for _, metric := range []Metric{L2{}, Manhattan{}, InnerProduct{}, Cosine{}, Hamming{}} {
+		sq := NewScalarQuantizer(metric)
+		sq.Train(samples)
+		dist := sq.Query(query)
+		for _, v := range samples[:10] {
+			code := sq.Encode(v)
+			want := metric.Score(query, sq.Decode(code))
+			if metric.HigherIsBetter() {
+				want = -want
+			}
+			if got := dist(code); math.Abs(float64(got-want)) > 1e-4*math.Max(1, math.Abs(float64(want))) {
+				t.Errorf("%s: distance %v, want %v", metric.Name(), got, want)
+			}
+		}
+		// 两个编码之间的距离与在重建向量上按度量计算的结果一致
+		for i, v := range samples[:10] {
+			a, b := sq.Encode(v), sq.Encode(samples[i+10])
+			want := metric.Score(sq.Decode(a), sq.Decode(b))
+			if metric.HigherIsBetter() {
+				want = -want
+			}
+			if got := sq.Distance(a, b); math.Abs(float64(got-want)) > 1e-4*math.Max(1, math.Abs(float64(want))) {
+				t.Errorf("%s: code distance %v, want %v", metric.Name(), got, want)
+			}
+		}
+		code, other := sq.Encode(samples[0]), sq.Encode(samples[1])
+		if allocs := testing.AllocsPerRun(100, func() { dist(code); sq.Distance(code, other) }); allocs != 0 {
+			t.Errorf("%s: expected no allocations per comparison, got %v", metric.Name(), allocs)
+		}
+		if got := sq.Query(query[:2])(code); !math.IsInf(float64(got), 1) {
+			t.Errorf("%s: expected a mismatched query to rank last, got %v", metric.Name(), got)
+		}
+	}
+}
+
+func TestBinaryQuantizer(t *testing.T) {
+	bq := NewBinaryQuantizer()
+	bq.Train([][]float32{{1, 1, 1, 1, 1, 1, 1, 1, 1}, {-1, -1, -1, -1, -1, -1, -1, -1, -1}})
+
+	a := bq.Encode([]float32{1, 1, 1, 1, 1, 1, 1, 1, 1})
+	if len(a) != 2 {
+		t.Fatalf("Expected 2 bytes for 9 dimensions, got %d", len(a))
+	}
+	b := bq.Encode([]float32{1, -1, 1, -1, 1, 1, 1, 1, -1})
+	if d := bq.Query([]float32{1, 1, 1, 1, 1, 1, 1, 1, 1})(b); d != 3 {
+		t.Errorf("Expected hamming distance 3, got %f", d)
+	}
+	if d := bq.Distance(a, b); d != 3 {
+		t.Errorf("Expected hamming distance 3 between codes, got %f", d)
+	}
+	if v := bq.Decode(a); v[0] != 1 || v[8] != 1 {
+		t.Errorf("Expected decoded levels of 1, got %v", v)
+	}
+}
+
+func quantizedRecall(t *testing.T, q Quantizer, rescore bool) float64 {
+	rng := rand.New(rand.NewSource(3))
+	dim, n, k := 32, 2000, 10
+	vectors := randomVectors(n, dim, rng)
+	byID := make(map[string][]float32, n)
+
+	idx := NewHNSWIndex(dim, 16, 100, 100, Cosine{})
+	idx.SetQuantizer(q, 500)
+	if rescore {
+		idx.SetRescore(func(id string) ([]float32, bool) {
+			v, ok := byID[id]
+			return v, ok
+		}, 10)
+	}
+	for i, v := range vectors {
+		id := fmt.Sprintf("id%d", i)
+		byID[id] = v
+		idx.Add(id, v)
+	}
+
+	// 训练后节点只保留编码
+	for _, node := range idx.nodes {
+		if node.Vector != nil || node.Code == nil {
+			t.Fatalf("Expected node %s to hold only its code", node.ID)
+		}
+	}
+
+	hits := 0
+	queries := randomVectors(30, dim, rng)
+	for _, query := range queries {
+		exact := make([]Neighbor, 0, n)
+		for i, v := range vectors {
+			exact = append(exact, Neighbor{ID: fmt.Sprintf("id%d", i), Score: cosineSimilarity(query, v)})
+		}
+		sort.Slice(exact, func(i, j int) bool { return exact[i].Score > exact[j].Score })
+		truth := make(map[string]bool, k)
+		for _, e := range exact[:k] {
+			truth[e.ID] = true
+		}
+		results := idx.Search(query, k)
+		for i, r := range results {
+			if truth[r.ID] {
+				hits++
+			}
+			if i > 0 && r.Score > results[i-1].Score {
+				t.Fatalf("Results not ordered by cosine similarity: %v", results)
+			}
+		}
+	}
+	return float64(hits) / float64(len(queries)*k)
+}
+
+func TestHNSWScalarQuantization(t *testing.T) {
+	if recall := quantizedRecall(t, NewScalarQuantizer(Cosine{}), false); recall < 0.85 {
+		t.Errorf("Expected recall >= 0.85 with int8 quantization, got %.3f", recall)
+	}
+}
+
+func TestHNSWBinaryQuantizationRescore(t *testing.T) {
+	if recall := quantizedRecall(t, NewBinaryQuantizer(), true); recall < 0.7 {
+		t.Errorf("Expected recall >= 0.7 with binary quantization and rescoring, got %.3f", recall)
+	}
+}
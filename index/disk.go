@@ -0,0 +1,628 @@
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gvdb/hnsw"
+)
+
+// 磁盘索引文件格式（小端）：
+//
+//	header  64 字节：magic、版本、dim、r、n、medoid、度量名称
+//	records n 条定长记录：dim 个 float32 向量、uint32 出度、r 个 uint32 邻居序号
+//	ids     n+1 个 uint64 偏移量，随后是拼接的 ID 字节
+const (
+	diskMagic      = "GVDISK\x00\x00"
+	diskVersion    = 1
+	diskHeaderSize = 64
+	maxMetricName  = diskHeaderSize - 32
+)
+
+// ErrDiskIndexCorrupt 表示磁盘索引文件损坏或版本不受支持
+var ErrDiskIndexCorrupt = errors.New("disk index: corrupt or unsupported file")
+
+// DiskIndex 为 DiskANN 风格的磁盘索引：Vamana 图和向量保存在内存映射文件中，
+// 搜索时只有访问到的页面会被换入，因此可以搜索远大于内存的数据集。
+// 新插入的向量先进入内存中的增量缓冲区，删除只记录墓碑，
+// 缓冲区达到 mergeThreshold 或调用 Merge/Close 时把它们合并进新文件。
+// 有未合并的修改时文件旁存在 <path>.dirty 标记，打开时据此判断文件是否包含上次的全部修改。
+type DiskIndex struct {
+	path           string
+	dim            int
+	r              int
+	buildL         int
+	searchL        int
+	alpha          float32
+	mergeThreshold int
+	metric         hnsw.Metric
+	dist           kernel // 搜索用距离
+	graphDist      kernel // 构图用非负距离
+	file           *diskFile
+	ord            map[string]uint32 // 文件中未删除节点的序号
+	deleted        map[uint32]bool
+	delta          *flatList
+	dirty          bool // 已写入 <path>.dirty 标记
+	clean          bool // 打开时文件包含上次的全部修改
+	mutex          sync.RWMutex
+}
+
+// NewDiskIndex 打开或创建 path 处的磁盘索引。r 为图的最大出度（默认 64），buildL/searchL 为构建和搜索时的候选列表长度
+// （默认 100/64），alpha 为剪枝参数（默认 1.2），mergeThreshold 为触发合并的增量缓冲区大小（默认 10000）。
+// 已有文件的维度或度量与参数不一致时忽略该文件，下次合并时覆盖。
+func NewDiskIndex(path string, dim, r, buildL, searchL int, alpha float32, mergeThreshold int, metric hnsw.Metric) (*DiskIndex, error) {
+	if metric == nil {
+		metric = hnsw.Cosine{}
+	}
+	if r <= 0 {
+		r = 64
+	}
+	if buildL <= 0 {
+		buildL = 100
+	}
+	if searchL <= 0 {
+		searchL = 64
+	}
+	if alpha < 1 {
+		alpha = 1.2
+	}
+	if mergeThreshold <= 0 {
+		mergeThreshold = 10000
+	}
+	idx := &DiskIndex{
+		path:           path,
+		dim:            dim,
+		r:              r,
+		buildL:         buildL,
+		searchL:        searchL,
+		alpha:          alpha,
+		mergeThreshold: mergeThreshold,
+		metric:         metric,
+		dist:           kernelFor(metric),
+		graphDist:      graphKernel(metric),
+		ord:            make(map[string]uint32),
+		deleted:        make(map[uint32]bool),
+		delta:          newFlatList(),
+	}
+
+	// 没有可用的文件时残留的标记也视为已写入，下次合并后删除
+	_, err := os.Stat(idx.markerPath())
+	idx.dirty = err == nil
+	f, err := openDiskFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.dim != dim || f.metric != metric.Name() {
+		f.close()
+		return idx, nil
+	}
+	idx.setFile(f)
+	idx.clean = !idx.dirty
+	return idx, nil
+}
+
+func (idx *DiskIndex) markerPath() string {
+	return idx.path + ".dirty"
+}
+
+// MarkDirty 写入并同步 .dirty 标记。调用方在写入与索引对应的存储之前调用，
+// 这样存储写入之后、合并之前崩溃时，下次打开不会把落后于存储的文件当作最新
+func (idx *DiskIndex) MarkDirty() error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	return idx.writeMarker()
+}
+
+func (idx *DiskIndex) writeMarker() error {
+	if idx.dirty {
+		return nil
+	}
+	f, err := os.Create(idx.markerPath())
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(idx.path))
+	idx.dirty = true
+	return nil
+}
+
+// markDirty 在第一次出现未合并的修改时写入标记。写入失败时下次修改重试
+func (idx *DiskIndex) markDirty() {
+	idx.writeMarker()
+}
+
+// clearDirty 在全部修改合并进文件后删除标记
+func (idx *DiskIndex) clearDirty() {
+	if !idx.dirty {
+		return
+	}
+	if err := os.Remove(idx.markerPath()); err == nil || os.IsNotExist(err) {
+		idx.dirty = false
+	}
+}
+
+func (idx *DiskIndex) setFile(f *diskFile) {
+	idx.file = f
+	idx.r = f.r
+	idx.ord = make(map[string]uint32, f.n)
+	for i, id := range f.ids {
+		idx.ord[id] = uint32(i)
+	}
+	idx.deleted = make(map[uint32]bool)
+	idx.delta = newFlatList()
+}
+
+func (idx *DiskIndex) Metric() hnsw.Metric {
+	return idx.metric
+}
+
+func (idx *DiskIndex) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.ord) + len(idx.delta.ids)
+}
+
+// Contains 报告 id 是否在索引中，不读取映射文件中的向量
+func (idx *DiskIndex) Contains(id string) bool {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	if _, ok := idx.ord[id]; ok {
+		return true
+	}
+	_, ok := idx.delta.pos[id]
+	return ok
+}
+
+// Clean 报告打开时文件是否包含上次的全部修改。为 false 时上次可能在合并之前退出，
+// 文件中的向量可能落后于存储，需要用 Add 逐个比较
+func (idx *DiskIndex) Clean() bool {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return idx.clean
+}
+
+// IDs 返回索引中的所有 ID
+func (idx *DiskIndex) IDs() []string {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	ids := make([]string, 0, len(idx.ord)+len(idx.delta.ids))
+	for id := range idx.ord {
+		ids = append(ids, id)
+	}
+	return append(ids, idx.delta.ids...)
+}
+
+// Add 插入向量，文件中已有相同向量时不做任何操作；维度不一致的向量被跳过，需要错误时使用 Insert
+func (idx *DiskIndex) Add(id string, vector []float32) {
+	idx.Insert(id, vector)
+}
+
+// Insert 插入向量，维度与索引不一致时返回 ErrDimension，原有的向量保持不变
+func (idx *DiskIndex) Insert(id string, vector []float32) error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if idx.dim == 0 {
+		idx.dim = len(vector)
+	}
+	if len(vector) != idx.dim {
+		return fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+	if o, ok := idx.ord[id]; ok {
+		if equalVectors(idx.file.vector(o), vector) {
+			return nil
+		}
+		idx.deleted[o] = true
+		delete(idx.ord, id)
+	}
+	idx.markDirty()
+	idx.delta.remove(id, idx.dim)
+	idx.delta.add(id, vector)
+	if len(idx.delta.ids) >= idx.mergeThreshold {
+		// 合并失败时向量仍保留在增量缓冲区中，可以正常搜索
+		idx.merge()
+	}
+	return nil
+}
+
+func (idx *DiskIndex) Remove(id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if o, ok := idx.ord[id]; ok {
+		idx.markDirty()
+		idx.deleted[o] = true
+		delete(idx.ord, id)
+	}
+	idx.delta.remove(id, idx.dim)
+}
+
+func (idx *DiskIndex) Search(query []float32, k int) []hnsw.Neighbor {
+	return idx.SearchFilter(query, k, nil)
+}
+
+// SearchFilter 中被过滤或已删除的节点仍参与导航；候选列表中通过的节点不足 k 个时加倍列表长度重新搜索
+func (idx *DiskIndex) SearchFilter(query []float32, k int, allow hnsw.FilterFunc) []hnsw.Neighbor {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	if k <= 0 {
+		return nil
+	}
+	t := newTopK(k)
+	if f := idx.file; f != nil && f.n > 0 {
+		l := idx.searchL
+		if l < k {
+			l = k
+		}
+		// 墓碑节点不计入结果，适当放宽候选列表
+		if extra := len(idx.deleted); extra > 0 {
+			if extra > l {
+				extra = l
+			}
+			l += extra
+		}
+		dist := func(i uint32) float32 { return idx.dist(query, f.vector(i)) }
+		for {
+			t = newTopK(k)
+			list, _ := greedySearch(f.medoid, l, dist, f.neighbors)
+			for _, c := range list {
+				if !idx.deleted[c.id] && (allow == nil || allow(f.ids[c.id])) {
+					t.offer(f.ids[c.id], c.dist)
+				}
+			}
+			if allow == nil || t.full() || l >= f.n {
+				break
+			}
+			l *= 2
+		}
+	}
+	idx.delta.scan(query, idx.dim, idx.dist, allow, t)
+	return t.neighbors(idx.metric)
+}
+
+// Merge 把增量缓冲区和墓碑合并进新的索引文件，并原子地替换旧文件
+func (idx *DiskIndex) Merge() error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	return idx.merge()
+}
+
+// Close 合并未落盘的修改并解除文件映射
+func (idx *DiskIndex) Close() error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	err := idx.merge()
+	if idx.file != nil {
+		if cerr := idx.file.close(); err == nil {
+			err = cerr
+		}
+		idx.file = nil
+	}
+	return err
+}
+
+func (idx *DiskIndex) merge() error {
+	if len(idx.delta.ids) == 0 && len(idx.deleted) == 0 {
+		idx.clearDirty()
+		return nil
+	}
+	f := idx.file
+
+	// 新序号：先是文件中未删除的节点，再是增量缓冲区中的向量
+	var live []uint32
+	remap := make(map[uint32]uint32)
+	if f != nil {
+		for o := 0; o < f.n; o++ {
+			if !idx.deleted[uint32(o)] {
+				remap[uint32(o)] = uint32(len(live))
+				live = append(live, uint32(o))
+			}
+		}
+	}
+	ids := make([]string, 0, len(live)+len(idx.delta.ids))
+	for _, o := range live {
+		ids = append(ids, f.ids[o])
+	}
+	ids = append(ids, idx.delta.ids...)
+	n, dim := len(ids), idx.dim
+	vec := func(i uint32) []float32 {
+		if int(i) < len(live) {
+			return f.vector(live[i])
+		}
+		j := int(i) - len(live)
+		return idx.delta.data[j*dim : (j+1)*dim]
+	}
+
+	g := &vamana{vec: vec, dist: idx.graphDist, r: idx.r, l: idx.buildL, alpha: idx.alpha}
+	if len(live) == 0 {
+		g.build(n, int64(n))
+	} else {
+		g.graph = make([][]uint32, n)
+		for i, o := range live {
+			g.graph[i] = idx.consolidate(g, uint32(i), o, remap)
+		}
+		g.medoid = idx.newMedoid(remap)
+		for i := len(live); i < n; i++ {
+			g.insert(uint32(i))
+		}
+	}
+
+	tmp := idx.path + ".tmp"
+	if err := writeDiskFile(tmp, dim, idx.r, idx.metric.Name(), ids, vec, g.graph, g.medoid); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// 先替换文件再解除旧映射，重命名失败时索引保持原状
+	if err := os.Rename(tmp, idx.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if f != nil {
+		f.close()
+	}
+	nf, err := openDiskFile(idx.path)
+	if err != nil {
+		idx.file, idx.ord, idx.deleted = nil, make(map[string]uint32), make(map[uint32]bool)
+		return err
+	}
+	idx.setFile(nf)
+	idx.clearDirty()
+	return nil
+}
+
+// consolidate 为文件中节点 o（新序号 p）重新计算出边：被删除的邻居由其未删除的邻居替代，再做剪枝
+func (idx *DiskIndex) consolidate(g *vamana, p, o uint32, remap map[uint32]uint32) []uint32 {
+	f := idx.file
+	nbs := f.neighbors(o)
+	dirty := false
+	for _, nb := range nbs {
+		if idx.deleted[nb] {
+			dirty = true
+			break
+		}
+	}
+	if !dirty {
+		out := make([]uint32, len(nbs))
+		for i, nb := range nbs {
+			out[i] = remap[nb]
+		}
+		return out
+	}
+
+	seen := map[uint32]bool{o: true}
+	var cands []vcand
+	pv := f.vector(o)
+	offer := func(x uint32) {
+		if seen[x] || idx.deleted[x] {
+			return
+		}
+		seen[x] = true
+		cands = append(cands, vcand{id: remap[x], dist: idx.graphDist(pv, f.vector(x))})
+	}
+	for _, nb := range nbs {
+		if idx.deleted[nb] {
+			for _, nb2 := range f.neighbors(nb) {
+				offer(nb2)
+			}
+		} else {
+			offer(nb)
+		}
+	}
+	return g.prune(p, cands)
+}
+
+// newMedoid 返回合并后的入口：旧入口未删除时沿用，否则取它的第一个未删除邻居
+func (idx *DiskIndex) newMedoid(remap map[uint32]uint32) uint32 {
+	f := idx.file
+	if p, ok := remap[f.medoid]; ok {
+		return p
+	}
+	for _, nb := range f.neighbors(f.medoid) {
+		if p, ok := remap[nb]; ok {
+			return p
+		}
+	}
+	return 0
+}
+
+// diskFile 为内存映射的索引文件，ID 表常驻内存，向量和邻接表按需读取
+type diskFile struct {
+	file   *os.File
+	data   []byte
+	dim    int
+	r      int
+	n      int
+	medoid uint32
+	metric string
+	record int
+	ids    []string
+}
+
+func openDiskFile(path string) (*diskFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	data, err := mapFile(file, int(info.Size()))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	f := &diskFile{file: file, data: data}
+	if err := f.parse(); err != nil {
+		f.close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *diskFile) parse() error {
+	d := f.data
+	if len(d) < diskHeaderSize || string(d[:8]) != diskMagic {
+		return ErrDiskIndexCorrupt
+	}
+	le := binary.LittleEndian
+	if v := le.Uint32(d[8:]); v != diskVersion {
+		return fmt.Errorf("%w: version %d", ErrDiskIndexCorrupt, v)
+	}
+	f.dim, f.r, f.n = int(le.Uint32(d[12:])), int(le.Uint32(d[16:])), int(le.Uint32(d[20:]))
+	f.medoid = le.Uint32(d[24:])
+	nameLen := int(le.Uint32(d[28:]))
+	if nameLen > maxMetricName {
+		return ErrDiskIndexCorrupt
+	}
+	f.metric = string(d[32 : 32+nameLen])
+	f.record = 4*f.dim + 4 + 4*f.r
+
+	idStart := diskHeaderSize + f.n*f.record
+	bytesStart := idStart + 8*(f.n+1)
+	if bytesStart > len(d) || (f.n > 0 && int(f.medoid) >= f.n) {
+		return ErrDiskIndexCorrupt
+	}
+	f.ids = make([]string, f.n)
+	for i := range f.ids {
+		lo, hi := le.Uint64(d[idStart+8*i:]), le.Uint64(d[idStart+8*(i+1):])
+		if lo > hi || bytesStart+int(hi) > len(d) {
+			return ErrDiskIndexCorrupt
+		}
+		f.ids[i] = string(d[bytesStart+int(lo) : bytesStart+int(hi)])
+	}
+	return nil
+}
+
+func (f *diskFile) vector(i uint32) []float32 {
+	off := diskHeaderSize + int(i)*f.record
+	v := make([]float32, f.dim)
+	for j := range v {
+		v[j] = math.Float32frombits(binary.LittleEndian.Uint32(f.data[off+4*j:]))
+	}
+	return v
+}
+
+func (f *diskFile) neighbors(i uint32) []uint32 {
+	off := diskHeaderSize + int(i)*f.record + 4*f.dim
+	deg := int(binary.LittleEndian.Uint32(f.data[off:]))
+	if deg > f.r {
+		deg = f.r
+	}
+	nbs := make([]uint32, 0, deg)
+	for j := 0; j < deg; j++ {
+		if nb := binary.LittleEndian.Uint32(f.data[off+4+4*j:]); int(nb) < f.n {
+			nbs = append(nbs, nb)
+		}
+	}
+	return nbs
+}
+
+func (f *diskFile) close() error {
+	err := unmapFile(f.data)
+	f.data = nil
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeDiskFile(path string, dim, r int, metric string, ids []string, vec func(uint32) []float32, graph [][]uint32, medoid uint32) error {
+	if len(metric) > maxMetricName {
+		return fmt.Errorf("disk index: metric name too long: %s", metric)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(file, 1<<20)
+	le := binary.LittleEndian
+
+	header := make([]byte, diskHeaderSize)
+	copy(header, diskMagic)
+	le.PutUint32(header[8:], diskVersion)
+	le.PutUint32(header[12:], uint32(dim))
+	le.PutUint32(header[16:], uint32(r))
+	le.PutUint32(header[20:], uint32(len(ids)))
+	le.PutUint32(header[24:], medoid)
+	le.PutUint32(header[28:], uint32(len(metric)))
+	copy(header[32:], metric)
+	w.Write(header)
+
+	record := make([]byte, 4*dim+4+4*r)
+	for i := range ids {
+		for j := range record {
+			record[j] = 0
+		}
+		for j, x := range vec(uint32(i)) {
+			le.PutUint32(record[4*j:], math.Float32bits(x))
+		}
+		le.PutUint32(record[4*dim:], uint32(len(graph[i])))
+		for j, nb := range graph[i] {
+			le.PutUint32(record[4*dim+4+4*j:], nb)
+		}
+		w.Write(record)
+	}
+
+	var buf [8]byte
+	var off uint64
+	w.Write(buf[:])
+	for _, id := range ids {
+		off += uint64(len(id))
+		le.PutUint64(buf[:], off)
+		w.Write(buf[:])
+	}
+	for _, id := range ids {
+		w.WriteString(id)
+	}
+
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir 同步目录项，让新建的文件在崩溃后仍然存在；部分平台不支持同步目录，失败时忽略
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+func equalVectors(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func diskRecall(idx Index, flat *FlatIndex, queries [][]float32, k int) float64 {
+	hits := 0
+	for _, q := range queries {
+		truth := make(map[string]bool)
+		for _, n := range flat.Search(q, k) {
+			truth[n.ID] = true
+		}
+		for _, n := range idx.Search(q, k) {
+			if truth[n.ID] {
+				hits++
+			}
+		}
+	}
+	return float64(hits) / float64(len(queries)*k)
+}
+
+func TestDiskIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	dim, k := 16, 10
+	vectors := randomVectors(2000, dim, rng)
+	queries := randomVectors(30, dim, rng)
+	path := filepath.Join(t.TempDir(), "vectors.disk")
+
+	idx, err := NewDiskIndex(path, dim, 24, 64, 64, 1.2, 500, hnsw.L2{})
+	if err != nil {
+		t.Fatalf("NewDiskIndex: %v", err)
+	}
+	flat := NewFlatIndex(dim, hnsw.L2{}, 1)
+	for i, v := range vectors {
+		id := fmt.Sprintf("id%d", i)
+		idx.Add(id, v)
+		flat.Add(id, v)
+	}
+	if idx.Len() != len(vectors) {
+		t.Fatalf("Expected %d vectors, got %d", len(vectors), idx.Len())
+	}
+	// 达到 mergeThreshold 时自动合并进文件
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected index file after automatic merge: %v", err)
+	}
+	if recall := diskRecall(idx, flat, queries, k); recall < 0.9 {
+		t.Errorf("Expected recall >= 0.9, got %.3f", recall)
+	}
+
+	// 删除和更新先以墓碑/增量形式生效，合并后保持一致
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("id%d", i)
+		idx.Remove(id)
+		flat.Remove(id)
+	}
+	idx.Add("id300", vectors[0])
+	flat.Add("id300", vectors[0])
+	for _, n := range idx.Search(vectors[5], k) {
+		if n.ID == "id5" {
+			t.Fatal("Expected removed vector to be excluded from results")
+		}
+	}
+	if got := idx.Search(vectors[0], 1); len(got) == 0 || got[0].ID != "id300" {
+		t.Fatalf("Expected updated vector id300 as top result, got %v", got)
+	}
+	if err := idx.Merge(); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if idx.Len() != len(vectors)-200 {
+		t.Errorf("Expected %d vectors after merge, got %d", len(vectors)-200, idx.Len())
+	}
+	if recall := diskRecall(idx, flat, queries, k); recall < 0.9 {
+		t.Errorf("Expected recall >= 0.9 after merge, got %.3f", recall)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// 重新打开后无需重建即可搜索
+	reopened, err := NewDiskIndex(path, dim, 24, 64, 64, 1.2, 500, hnsw.L2{})
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer reopened.Close()
+	if reopened.Len() != len(vectors)-200 {
+		t.Errorf("Expected %d vectors after reopen, got %d", len(vectors)-200, reopened.Len())
+	}
+	if recall := diskRecall(reopened, flat, queries, k); recall < 0.9 {
+		t.Errorf("Expected recall >= 0.9 after reopen, got %.3f", recall)
+	}
+	// 重新插入相同向量不会进入增量缓冲区
+	reopened.Add("id1000", vectors[1000])
+	if len(reopened.delta.ids) != 0 {
+		t.Error("Expected unchanged vector not to be buffered")
+	}
+	// 正常关闭后文件包含全部修改，不读取向量也能判断 ID 是否存在
+	if !reopened.Clean() || !reopened.Contains("id1000") || reopened.Contains("id5") {
+		t.Errorf("Expected a clean file with id1000 and without id5, clean=%v", reopened.Clean())
+	}
+
+	// 未合并就退出时留下标记，下次打开时文件不再被当作最新
+	reopened.Add("id1000", vectors[1001])
+	if _, err := os.Stat(path + ".dirty"); err != nil {
+		t.Fatalf("Expected a dirty marker with unmerged changes: %v", err)
+	}
+	crashed, err := NewDiskIndex(path, dim, 24, 64, 64, 1.2, 500, hnsw.L2{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crashed.Clean() {
+		t.Error("Expected a file with unmerged changes not to be clean")
+	}
+	crashed.Add("id1000", vectors[1001])
+	if err := crashed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".dirty"); !os.IsNotExist(err) {
+		t.Errorf("Expected the marker to be removed after merging, got %v", err)
+	}
+}
+
+func TestDiskIndexIgnoresIncompatibleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.disk")
+	idx, _ := NewDiskIndex(path, 4, 8, 16, 16, 1.2, 10, hnsw.L2{})
+	idx.Add("a", []float32{1, 0, 0, 0})
+	idx.Close()
+
+	other, err := NewDiskIndex(path, 4, 8, 16, 16, 1.2, 10, hnsw.Cosine{})
+	if err != nil {
+		t.Fatalf("NewDiskIndex: %v", err)
+	}
+	if other.Len() != 0 {
+		t.Errorf("Expected file with different metric to be ignored, got %d vectors", other.Len())
+	}
+
+	// 维度不一致的向量被拒绝，文件中的原向量不变
+	if err := other.Insert("b", []float32{1, 0}); !errors.Is(err, ErrDimension) {
+		t.Errorf("Expected ErrDimension, got %v", err)
+	}
+	other.Add("b", []float32{1, 0, 0, 0, 0})
+	if other.Len() != 0 {
+		t.Errorf("Expected the wrong-dimension vector to be skipped, got %d vectors", other.Len())
+	}
+}
+
+func TestDiskIndexStaleMarker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.disk")
+	if err := os.WriteFile(path+".dirty", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 索引文件已被删除而标记残留时，即使没有任何修改，合并后也删除标记
+	idx, err := NewDiskIndex(path, 4, 8, 16, 16, 1.2, 10, hnsw.L2{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".dirty"); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale marker to be removed, got %v", err)
+	}
+}
+
+func TestDiskIndexCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.disk")
+	os.WriteFile(path, []byte("not an index file"), 0o644)
+	if _, err := NewDiskIndex(path, 4, 8, 16, 16, 1.2, 10, hnsw.L2{}); !errors.Is(err, ErrDiskIndexCorrupt) {
+		t.Errorf("Expected ErrDiskIndexCorrupt, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package index
+
+import (
+	"sync"
+
+	"gvdb/hnsw"
+)
+
+// SearchFunc 在进程外执行近邻搜索，返回至多 k 个结果，Score 的含义与索引的度量一致
+type SearchFunc func(query []float32, k int) ([]hnsw.Neighbor, error)
+
+// ExternalIndex 把搜索交给进程外的实现，例如 pgvector 在 PostgreSQL 中建立的索引。
+// 进程内只记录 ID 以便统计数量，Add 不保存向量，向量由存储负责写入
+type ExternalIndex struct {
+	metric hnsw.Metric
+	search SearchFunc
+	ids    map[string]struct{}
+	mutex  sync.RWMutex
+}
+
+// NewExternalIndex 创建外部索引，metric 为 nil 时使用余弦相似度
+func NewExternalIndex(metric hnsw.Metric, search SearchFunc) *ExternalIndex {
+	if metric == nil {
+		metric = hnsw.Cosine{}
+	}
+	return &ExternalIndex{metric: metric, search: search, ids: make(map[string]struct{})}
+}
+
+func (idx *ExternalIndex) Metric() hnsw.Metric {
+	return idx.metric
+}
+
+func (idx *ExternalIndex) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.ids)
+}
+
+func (idx *ExternalIndex) Add(id string, _ []float32) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.ids[id] = struct{}{}
+}
+
+func (idx *ExternalIndex) Remove(id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	delete(idx.ids, id)
+}
+
+// Search 出错时返回空结果，需要错误时使用 SearchErr
+func (idx *ExternalIndex) Search(query []float32, k int) []hnsw.Neighbor {
+	neighbors, _ := idx.SearchErr(query, k)
+	return neighbors
+}
+
+// SearchFilter 出错时返回空结果，需要错误时使用 SearchFilterErr
+func (idx *ExternalIndex) SearchFilter(query []float32, k int, allow hnsw.FilterFunc) []hnsw.Neighbor {
+	neighbors, _ := idx.SearchFilterErr(query, k, allow)
+	return neighbors
+}
+
+// SearchErr 执行外部搜索并返回其错误
+func (idx *ExternalIndex) SearchErr(query []float32, k int) ([]hnsw.Neighbor, error) {
+	if k <= 0 || idx.Len() == 0 {
+		return nil, nil
+	}
+	return idx.search(query, k)
+}
+
+// SearchFilterErr 先取 4k 个结果再过滤，不够 k 个时每次取回 4 倍的结果，直到取回全部文档或外部搜索不再返回更多
+func (idx *ExternalIndex) SearchFilterErr(query []float32, k int, allow hnsw.FilterFunc) ([]hnsw.Neighbor, error) {
+	if allow == nil {
+		return idx.SearchErr(query, k)
+	}
+	n := idx.Len()
+	if k <= 0 || n == 0 {
+		return nil, nil
+	}
+	for fetch := 4 * k; ; fetch *= 4 {
+		fetch = min(fetch, n)
+		neighbors, err := idx.search(query, fetch)
+		if err != nil {
+			return nil, err
+		}
+		allowed := make([]hnsw.Neighbor, 0, k)
+		for _, nb := range neighbors {
+			if allow(nb.ID) {
+				allowed = append(allowed, nb)
+				if len(allowed) == k {
+					return allowed, nil
+				}
+			}
+		}
+		if fetch == n || len(neighbors) < fetch {
+			return allowed, nil
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func TestExternalIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	dim, n := 4, 300
+	flat := NewFlatIndex(dim, hnsw.L2{}, 1)
+	var calls []int
+	idx := NewExternalIndex(hnsw.L2{}, func(query []float32, k int) ([]hnsw.Neighbor, error) {
+		calls = append(calls, k)
+		return flat.Search(query, k), nil
+	})
+	for i, v := range randomVectors(n, dim, rng) {
+		id := fmt.Sprintf("id%d", i)
+		flat.Add(id, v)
+		idx.Add(id, v)
+	}
+	idx.Remove("id0")
+	flat.Remove("id0")
+	if idx.Len() != n-1 || idx.Metric().Name() != "l2" {
+		t.Fatalf("Unexpected index: %d vectors, metric %s", idx.Len(), idx.Metric().Name())
+	}
+
+	query := randomVectors(1, dim, rng)[0]
+	if got, want := idx.Search(query, 5), flat.Search(query, 5); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Search = %v, want %v", got, want)
+	}
+
+	// 只允许两个 ID：4k 个结果中不够时逐步扩大，最后取回全部文档
+	calls = nil
+	allow := func(id string) bool { return id == "id7" || id == "id8" }
+	got := idx.SearchFilter(query, 2, allow)
+	if len(got) != 2 || !allow(got[0].ID) || !allow(got[1].ID) {
+		t.Errorf("Unexpected filtered results: %v", got)
+	}
+	if calls[0] != 8 || calls[len(calls)-1] > n-1 {
+		t.Errorf("Unexpected fetch sizes: %v", calls)
+	}
+	if got := idx.SearchFilter(query, 2, func(string) bool { return false }); len(got) != 0 {
+		t.Errorf("Expected no results, got %v", got)
+	}
+
+	failing := NewExternalIndex(nil, func([]float32, int) ([]hnsw.Neighbor, error) { return nil, errors.New("unavailable") })
+	failing.Add("a", nil)
+	if got := failing.Search(query, 1); got != nil {
+		t.Errorf("Expected no results from a failing search, got %v", got)
+	}
+	if _, err := failing.SearchErr(query, 1); err == nil {
+		t.Error("Expected SearchErr to return the search error")
+	}
+	if _, err := SearchFilter(failing, query, 1, allow); err == nil {
+		t.Error("Expected SearchFilter to return the search error")
+	}
+	if got, err := SearchFilter(flat, query, 2, allow); err != nil || len(got) != 2 {
+		t.Errorf("Unexpected filtered results from an in-process index: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,154 @@
+package index
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"gvdb/hnsw"
+)
+
+// parallelThreshold 以下的向量数量直接单线程扫描
+const parallelThreshold = 4096
+
+// FlatIndex 为暴力精确搜索索引，向量以 float32 连续存放以便顺序扫描
+type FlatIndex struct {
+	dim     int
+	data    []float32
+	ids     []string
+	pos     map[string]int
+	metric  hnsw.Metric
+	dist    kernel
+	workers int
+	mutex   sync.RWMutex
+}
+
+// NewFlatIndex 创建暴力索引，metric 为 nil 时使用余弦相似度，workers<=0 时使用 CPU 核数
+func NewFlatIndex(dim int, metric hnsw.Metric, workers int) *FlatIndex {
+	if metric == nil {
+		metric = hnsw.Cosine{}
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &FlatIndex{
+		dim:     dim,
+		pos:     make(map[string]int),
+		metric:  metric,
+		dist:    kernelFor(metric),
+		workers: workers,
+	}
+}
+
+func (idx *FlatIndex) Metric() hnsw.Metric {
+	return idx.metric
+}
+
+func (idx *FlatIndex) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.ids)
+}
+
+// Add 插入或替换向量，维度不一致的向量被跳过，需要错误时使用 Insert
+func (idx *FlatIndex) Add(id string, vector []float32) {
+	idx.Insert(id, vector)
+}
+
+// Insert 插入或替换向量，维度与索引不一致时返回 ErrDimension，原有的向量保持不变
+func (idx *FlatIndex) Insert(id string, vector []float32) error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if idx.dim == 0 {
+		idx.dim = len(vector)
+	}
+	if len(vector) != idx.dim {
+		return fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+	if p, exists := idx.pos[id]; exists {
+		copy(idx.row(p), vector)
+		return nil
+	}
+	idx.pos[id] = len(idx.ids)
+	idx.ids = append(idx.ids, id)
+	idx.data = append(idx.data, vector...)
+	return nil
+}
+
+// Remove 将最后一行移到被删除的位置，保持存储连续
+func (idx *FlatIndex) Remove(id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	p, exists := idx.pos[id]
+	if !exists {
+		return
+	}
+	last := len(idx.ids) - 1
+	if p != last {
+		copy(idx.row(p), idx.row(last))
+		idx.ids[p] = idx.ids[last]
+		idx.pos[idx.ids[p]] = p
+	}
+	idx.ids = idx.ids[:last]
+	idx.data = idx.data[:last*idx.dim]
+	delete(idx.pos, id)
+}
+
+func (idx *FlatIndex) Search(query []float32, k int) []hnsw.Neighbor {
+	return idx.SearchFilter(query, k, nil)
+}
+
+func (idx *FlatIndex) SearchFilter(query []float32, k int, allow hnsw.FilterFunc) []hnsw.Neighbor {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	n := len(idx.ids)
+	if n == 0 || k <= 0 {
+		return nil
+	}
+	q := query
+
+	workers := idx.workers
+	if n < parallelThreshold || workers == 1 {
+		workers = 1
+	}
+	chunk := (n + workers - 1) / workers
+	partials := make([]*topK, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := w*chunk, (w+1)*chunk
+		if end > n {
+			end = n
+		}
+		partials[w] = newTopK(k)
+		if start >= end {
+			continue
+		}
+		wg.Add(1)
+		go func(t *topK, start, end int) {
+			defer wg.Done()
+			idx.scan(q, start, end, allow, t)
+		}(partials[w], start, end)
+	}
+	wg.Wait()
+
+	result := partials[0]
+	for _, t := range partials[1:] {
+		result.merge(t)
+	}
+	return result.neighbors(idx.metric)
+}
+
+func (idx *FlatIndex) scan(q []float32, start, end int, allow hnsw.FilterFunc, t *topK) {
+	for i := start; i < end; i++ {
+		if allow == nil || allow(idx.ids[i]) {
+			t.offer(idx.ids[i], idx.dist(q, idx.row(i)))
+		}
+	}
+}
+
+func (idx *FlatIndex) row(i int) []float32 {
+	return idx.data[i*idx.dim : (i+1)*idx.dim]
+}
@@ -0,0 +1,94 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func TestFlatIndex(t *testing.T) {
+	idx := NewFlatIndex(3, nil, 1)
+
+	idx.Add("id1", []float32{1.0, 0.0, 0.0})
+	idx.Add("id2", []float32{0.0, 1.0, 0.0})
+	idx.Add("id3", []float32{1.0, 1.0, 0.0})
+
+	results := idx.Search([]float32{1.0, 0.0, 0.0}, 2)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].ID != "id1" || results[1].ID != "id3" {
+		t.Errorf("Unexpected results: %v", results)
+	}
+	if results[0].Score < 0.99 {
+		t.Errorf("Expected high similarity for id1, got %f", results[0].Score)
+	}
+
+	// 删除后最后一行被移动到空位
+	idx.Remove("id1")
+	if idx.Len() != 2 {
+		t.Errorf("Expected 2 vectors after delete, got %d", idx.Len())
+	}
+	results = idx.Search([]float32{1.0, 0.0, 0.0}, 3)
+	if len(results) != 2 || results[0].ID != "id3" {
+		t.Errorf("Unexpected results after delete: %v", results)
+	}
+
+	// 重复 ID 覆盖原向量
+	idx.Add("id2", []float32{1.0, 0.0, 0.0})
+	if results = idx.Search([]float32{1.0, 0.0, 0.0}, 1); results[0].ID != "id2" {
+		t.Errorf("Expected updated id2 first, got %v", results)
+	}
+
+	// 维度不一致的向量被拒绝，原向量不变
+	if err := idx.Insert("id2", []float32{0.0, 1.0}); !errors.Is(err, ErrDimension) {
+		t.Errorf("Expected ErrDimension, got %v", err)
+	}
+	idx.Add("id4", []float32{1.0, 0.0, 0.0, 0.0})
+	if idx.Len() != 2 {
+		t.Errorf("Expected the wrong-dimension vector to be skipped, got %d vectors", idx.Len())
+	}
+	if results = idx.Search([]float32{1.0, 0.0, 0.0}, 1); results[0].ID != "id2" || results[0].Score < 0.99 {
+		t.Errorf("Expected id2 to keep its vector, got %v", results)
+	}
+	err := AddBatch(idx, []string{"id5", "id6"}, [][]float32{{0.0, 0.0, 1.0}, {1.0}})
+	if !errors.Is(err, ErrDimension) || idx.Len() != 3 {
+		t.Errorf("Expected AddBatch to skip one vector and report it, got %v with %d vectors", err, idx.Len())
+	}
+}
+
+func TestFlatIndexParallelScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dim := 8
+	flat := NewFlatIndex(dim, hnsw.L2{}, 4)
+	serial := NewFlatIndex(dim, hnsw.L2{}, 1)
+	for i := 0; i < 2*parallelThreshold; i++ {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rng.Float32()
+		}
+		flat.Add(fmt.Sprintf("id%d", i), v)
+		serial.Add(fmt.Sprintf("id%d", i), v)
+	}
+
+	query := make([]float32, dim)
+	for j := range query {
+		query[j] = rng.Float32()
+	}
+	got := flat.Search(query, 10)
+	want := serial.Search(query, 10)
+	if len(got) != 10 {
+		t.Fatalf("Expected 10 results, got %d", len(got))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID {
+			t.Fatalf("Parallel scan differs at %d: %v vs %v", i, got[i], want[i])
+		}
+		if i > 0 && got[i].Score < got[i-1].Score {
+			t.Errorf("Expected ascending distances: %v", got)
+		}
+	}
+}
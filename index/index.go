@@ -0,0 +1,118 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+
+	"gvdb/hnsw"
+)
+
+// ErrDimension 表示插入的向量维度与索引不一致，这样的向量不会加入索引
+var ErrDimension = errors.New("index: vector dimension does not match index")
+
+// Index 定义向量索引的公共接口，VectorDB 通过它持有任意类型的索引
+type Index interface {
+	Add(id string, vector []float32)
+	Remove(id string)
+	Search(query []float32, k int) []hnsw.Neighbor
+	// SearchFilter 只返回 allow 通过的结果，allow 为 nil 时等同于 Search
+	SearchFilter(query []float32, k int, allow hnsw.FilterFunc) []hnsw.Neighbor
+	Len() int
+	Metric() hnsw.Metric
+}
+
+var (
+	_ Index = (*hnsw.HNSWIndex)(nil)
+	_ Index = (*FlatIndex)(nil)
+	_ Index = (*IVFIndex)(nil)
+	_ Index = (*IVFPQIndex)(nil)
+	_ Index = (*DiskIndex)(nil)
+	_ Index = (*ExternalIndex)(nil)
+)
+
+// ErrSearcher 由搜索可能失败的索引实现（如由数据库执行搜索的 ExternalIndex），
+// 这类索引的 Search 和 SearchFilter 出错时只能返回空结果，需要区分出错和没有结果时使用这两个方法
+type ErrSearcher interface {
+	SearchErr(query []float32, k int) ([]hnsw.Neighbor, error)
+	SearchFilterErr(query []float32, k int, allow hnsw.FilterFunc) ([]hnsw.Neighbor, error)
+}
+
+var _ ErrSearcher = (*ExternalIndex)(nil)
+
+// SearchFilter 按 allow 过滤搜索，allow 为 nil 时不过滤；索引实现了 ErrSearcher 时返回搜索的错误
+func SearchFilter(idx Index, query []float32, k int, allow hnsw.FilterFunc) ([]hnsw.Neighbor, error) {
+	if s, ok := idx.(ErrSearcher); ok {
+		return s.SearchFilterErr(query, k, allow)
+	}
+	return idx.SearchFilter(query, k, allow), nil
+}
+
+// Inserter 由会拒绝向量的索引实现（如维度不一致），Insert 与 Add 相同但返回拒绝的原因；
+// 这类索引的 Add 会跳过被拒绝的向量
+type Inserter interface {
+	Insert(id string, vector []float32) error
+}
+
+var (
+	_ Inserter = (*FlatIndex)(nil)
+	_ Inserter = (*IVFIndex)(nil)
+	_ Inserter = (*IVFPQIndex)(nil)
+	_ Inserter = (*DiskIndex)(nil)
+)
+
+// Insert 插入一个向量，索引实现了 Inserter 时返回其拒绝的原因
+func Insert(idx Index, id string, vector []float32) error {
+	if ins, ok := idx.(Inserter); ok {
+		return ins.Insert(id, vector)
+	}
+	idx.Add(id, vector)
+	return nil
+}
+
+// Batcher 由能够高效批量增删的索引实现（如 HNSW 并行插入）
+type Batcher interface {
+	AddBatch(ids []string, vectors [][]float32)
+	RemoveBatch(ids []string)
+}
+
+var _ Batcher = (*hnsw.HNSWIndex)(nil)
+
+// AddBatch 批量插入向量，索引实现了 Batcher 时使用其批量接口，否则逐个插入。
+// 被拒绝的向量会被跳过，其余向量照常插入，返回的错误包含被拒绝的数量和第一个原因
+func AddBatch(idx Index, ids []string, vectors [][]float32) error {
+	if b, ok := idx.(Batcher); ok {
+		b.AddBatch(ids, vectors)
+		return nil
+	}
+	var first error
+	rejected := 0
+	for i, id := range ids {
+		if err := Insert(idx, id, vectors[i]); err != nil {
+			if first == nil {
+				first = fmt.Errorf("%s: %w", id, err)
+			}
+			rejected++
+		}
+	}
+	if first != nil {
+		return fmt.Errorf("%d of %d vectors rejected: %w", rejected, len(ids), first)
+	}
+	return nil
+}
+
+// RemoveBatch 批量删除向量，索引实现了 Batcher 时使用其批量接口，否则逐个 Remove
+func RemoveBatch(idx Index, ids []string) {
+	if b, ok := idx.(Batcher); ok {
+		b.RemoveBatch(ids)
+		return
+	}
+	for _, id := range ids {
+		idx.Remove(id)
+	}
+}
+
+// Trainer 由需要先训练再使用的索引实现（如 IVF）
+type Trainer interface {
+	Train(samples [][]float32) error
+	Trained() bool
+}
@@ -0,0 +1,61 @@
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func TestSearchFilter(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	dim, n, k := 8, 1500, 10
+	vectors := randomVectors(n, dim, rng)
+
+	ivfpq, err := NewIVFPQIndex(dim, 16, 2, 4, 8, 500, hnsw.L2{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := NewDiskIndex(filepath.Join(t.TempDir(), "vectors.disk"), dim, 16, 48, 32, 1.2, 1000, hnsw.L2{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer disk.Close()
+	indexes := map[string]Index{
+		"hnsw":  hnsw.NewHNSWIndex(dim, 16, 100, 64, hnsw.L2{}),
+		"flat":  NewFlatIndex(dim, hnsw.L2{}, 2),
+		"ivf":   NewIVFIndex(dim, 16, 2, 500, hnsw.L2{}),
+		"ivfpq": ivfpq,
+		"disk":  disk,
+	}
+	for i, v := range vectors {
+		for _, idx := range indexes {
+			idx.Add(fmt.Sprintf("id%d", i), v)
+		}
+	}
+
+	// 只允许 1/25 的向量：nprobe 很小的 IVF 也必须继续扫描更远的簇凑够 k 个结果
+	allow := func(id string) bool {
+		var i int
+		fmt.Sscanf(id, "id%d", &i)
+		return i%25 == 0
+	}
+	for name, idx := range indexes {
+		for _, q := range randomVectors(5, dim, rng) {
+			results := idx.SearchFilter(q, k, allow)
+			if len(results) != k {
+				t.Errorf("%s: expected %d filtered results, got %d", name, k, len(results))
+			}
+			for _, r := range results {
+				if !allow(r.ID) {
+					t.Errorf("%s: result %s does not pass the filter", name, r.ID)
+				}
+			}
+		}
+		if got := idx.SearchFilter(vectors[0], k, nil); len(got) != k || got[0].ID != "id0" {
+			t.Errorf("%s: expected nil filter to behave like Search, got %v", name, got)
+		}
+	}
+}
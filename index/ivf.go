@@ -0,0 +1,293 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"gvdb/hnsw"
+)
+
+// maxSamplesPerList 为训练时每个簇最多使用的样本数
+const maxSamplesPerList = 256
+
+// IVFIndex 为倒排文件索引（IVF-Flat）：用 k-means 把向量划分到 nlist 个簇，搜索时只扫描最近的 nprobe 个簇。
+// 训练前插入的向量暂存在待训练缓冲区中并以暴力方式搜索，缓冲区达到 trainSize 时自动训练。
+type IVFIndex struct {
+	dim       int
+	nlist     int
+	nprobe    int
+	trainSize int
+	metric    hnsw.Metric
+	dist      kernel
+	centroids [][]float32
+	lists     []*flatList
+	where     map[string]int // 向量所在的簇，-1 表示待训练缓冲区
+	pending   *flatList
+	mutex     sync.RWMutex
+}
+
+// flatList 为连续存放的一组向量
+type flatList struct {
+	ids  []string
+	data []float32
+	pos  map[string]int
+}
+
+func newFlatList() *flatList {
+	return &flatList{pos: make(map[string]int)}
+}
+
+func (l *flatList) add(id string, v []float32) {
+	l.pos[id] = len(l.ids)
+	l.ids = append(l.ids, id)
+	l.data = append(l.data, v...)
+}
+
+func (l *flatList) remove(id string, dim int) {
+	p, ok := l.pos[id]
+	if !ok {
+		return
+	}
+	last := len(l.ids) - 1
+	if p != last {
+		copy(l.data[p*dim:(p+1)*dim], l.data[last*dim:(last+1)*dim])
+		l.ids[p] = l.ids[last]
+		l.pos[l.ids[p]] = p
+	}
+	l.ids = l.ids[:last]
+	l.data = l.data[:last*dim]
+	delete(l.pos, id)
+}
+
+func (l *flatList) scan(q []float32, dim int, dist kernel, allow hnsw.FilterFunc, t *topK) {
+	for i, id := range l.ids {
+		if allow == nil || allow(id) {
+			t.offer(id, dist(q, l.data[i*dim:(i+1)*dim]))
+		}
+	}
+}
+
+// NewIVFIndex 创建 IVF 索引；trainSize<=0 时默认为 nlist*39
+func NewIVFIndex(dim, nlist, nprobe, trainSize int, metric hnsw.Metric) *IVFIndex {
+	if metric == nil {
+		metric = hnsw.Cosine{}
+	}
+	if nlist < 1 {
+		nlist = 1
+	}
+	if nprobe < 1 {
+		nprobe = 1
+	}
+	if trainSize <= 0 {
+		trainSize = nlist * 39
+	}
+	return &IVFIndex{
+		dim:       dim,
+		nlist:     nlist,
+		nprobe:    nprobe,
+		trainSize: trainSize,
+		metric:    metric,
+		dist:      kernelFor(metric),
+		where:     make(map[string]int),
+		pending:   newFlatList(),
+	}
+}
+
+func (idx *IVFIndex) Metric() hnsw.Metric {
+	return idx.metric
+}
+
+func (idx *IVFIndex) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.where)
+}
+
+// Trained 返回索引是否已完成聚类训练
+func (idx *IVFIndex) Trained() bool {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return idx.centroids != nil
+}
+
+// SetNprobe 调整搜索时扫描的簇数量
+func (idx *IVFIndex) SetNprobe(nprobe int) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if nprobe > 0 {
+		idx.nprobe = nprobe
+	}
+}
+
+// Train 用给定样本训练质心，已插入的向量会被重新分配到各簇；维度与索引不一致的样本被忽略
+func (idx *IVFIndex) Train(samples [][]float32) error {
+	idx.mutex.RLock()
+	samples = sameDim(samples, idx.dim)
+	idx.mutex.RUnlock()
+	if len(samples) < idx.nlist {
+		return errors.New("ivf: not enough training samples for nlist")
+	}
+	samples = subsample(samples, idx.nlist*maxSamplesPerList)
+	data := make([][]float32, len(samples))
+	for i, v := range samples {
+		data[i] = v
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.train(data)
+	return nil
+}
+
+// sameDim 返回维度为 dim 的样本，dim 为 0 时以第一个样本的维度为准
+func sameDim(samples [][]float32, dim int) [][]float32 {
+	if dim == 0 && len(samples) > 0 {
+		dim = len(samples[0])
+	}
+	kept := make([][]float32, 0, len(samples))
+	for _, v := range samples {
+		if len(v) == dim {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// subsample 在样本多于 limit 时以固定种子随机抽取 limit 个，避免 k-means 耗时过长
+func subsample(samples [][]float32, limit int) [][]float32 {
+	if len(samples) <= limit {
+		return samples
+	}
+	rng := rand.New(rand.NewSource(1))
+	shuffled := append([][]float32(nil), samples...)
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return shuffled[:limit]
+}
+
+func (idx *IVFIndex) train(data [][]float32) {
+	if idx.dim == 0 && len(data) > 0 {
+		idx.dim = len(data[0])
+	}
+	_, spherical := idx.metric.(hnsw.Cosine)
+	idx.centroids = KMeans(data, idx.nlist, 25, idx.dist, spherical, 1)
+
+	old := idx.lists
+	idx.lists = make([]*flatList, len(idx.centroids))
+	for i := range idx.lists {
+		idx.lists[i] = newFlatList()
+	}
+	for _, l := range append(old, idx.pending) {
+		for i, id := range l.ids {
+			idx.assign(id, l.data[i*idx.dim:(i+1)*idx.dim])
+		}
+	}
+	idx.pending = newFlatList()
+}
+
+func (idx *IVFIndex) assign(id string, v []float32) {
+	c := nearestCentroid(v, idx.centroids, idx.dist)
+	idx.lists[c].add(id, v)
+	idx.where[id] = c
+}
+
+// Add 插入或替换向量，维度不一致的向量被跳过，需要错误时使用 Insert
+func (idx *IVFIndex) Add(id string, vector []float32) {
+	idx.Insert(id, vector)
+}
+
+// Insert 插入或替换向量，维度与索引不一致时返回 ErrDimension，原有的向量保持不变
+func (idx *IVFIndex) Insert(id string, vector []float32) error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if idx.dim == 0 {
+		idx.dim = len(vector)
+	}
+	if len(vector) != idx.dim {
+		return fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+	idx.remove(id)
+	if idx.centroids != nil {
+		idx.assign(id, vector)
+		return nil
+	}
+	idx.pending.add(id, vector)
+	idx.where[id] = -1
+	if len(idx.pending.ids) >= idx.trainSize {
+		data := make([][]float32, len(idx.pending.ids))
+		for i := range data {
+			data[i] = idx.pending.data[i*idx.dim : (i+1)*idx.dim]
+		}
+		idx.train(data)
+	}
+	return nil
+}
+
+func (idx *IVFIndex) Remove(id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.remove(id)
+}
+
+func (idx *IVFIndex) remove(id string) {
+	c, ok := idx.where[id]
+	if !ok {
+		return
+	}
+	if c < 0 {
+		idx.pending.remove(id, idx.dim)
+	} else {
+		idx.lists[c].remove(id, idx.dim)
+	}
+	delete(idx.where, id)
+}
+
+func (idx *IVFIndex) Search(query []float32, k int) []hnsw.Neighbor {
+	return idx.SearchFilter(query, k, nil)
+}
+
+// SearchFilter 带过滤条件时在扫描完 nprobe 个簇后仍不足 k 个结果的情况下继续扫描更远的簇
+func (idx *IVFIndex) SearchFilter(query []float32, k int, allow hnsw.FilterFunc) []hnsw.Neighbor {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	if len(idx.where) == 0 || k <= 0 {
+		return nil
+	}
+	q := query
+	t := newTopK(k)
+	idx.pending.scan(q, idx.dim, idx.dist, allow, t)
+	for i, c := range probeCentroids(q, idx.centroids, idx.dist, probeLimit(idx.nprobe, len(idx.centroids), allow)) {
+		if i >= idx.nprobe && t.full() {
+			break
+		}
+		idx.lists[c].scan(q, idx.dim, idx.dist, allow, t)
+	}
+	return t.neighbors(idx.metric)
+}
+
+// probeLimit 返回最多扫描的簇数：带过滤条件时可能需要扫描全部簇才能凑够 k 个结果
+func probeLimit(nprobe, nlist int, allow hnsw.FilterFunc) int {
+	if allow != nil {
+		return nlist
+	}
+	return nprobe
+}
+
+// probeCentroids 返回与查询最近的 nprobe 个簇
+func probeCentroids(q []float32, centroids [][]float32, dist kernel, nprobe int) []int {
+	order := make([]int, len(centroids))
+	dists := make([]float32, len(centroids))
+	for c, centroid := range centroids {
+		order[c] = c
+		dists[c] = dist(q, centroid)
+	}
+	sort.Slice(order, func(i, j int) bool { return dists[order[i]] < dists[order[j]] })
+	if len(order) > nprobe {
+		order = order[:nprobe]
+	}
+	return order
+}
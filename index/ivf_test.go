@@ -0,0 +1,137 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func randomVectors(n, dim int, rng *rand.Rand) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = rng.Float32()*2 - 1
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+func TestIVFIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	dim := 8
+	vectors := randomVectors(2000, dim, rng)
+
+	idx := NewIVFIndex(dim, 16, 4, 500, hnsw.L2{})
+	flat := NewFlatIndex(dim, hnsw.L2{}, 1)
+	for i, v := range vectors {
+		id := fmt.Sprintf("id%d", i)
+		idx.Add(id, v)
+		flat.Add(id, v)
+		// 达到 trainSize 前未训练
+		if i == 100 && idx.Trained() {
+			t.Fatal("Expected index to be untrained before trainSize")
+		}
+	}
+	if !idx.Trained() {
+		t.Fatal("Expected index to be trained automatically")
+	}
+	if idx.Len() != len(vectors) {
+		t.Errorf("Expected %d vectors, got %d", len(vectors), idx.Len())
+	}
+
+	hits, k := 0, 10
+	queries := randomVectors(20, dim, rng)
+	for _, q := range queries {
+		truth := make(map[string]bool)
+		for _, n := range flat.Search(q, k) {
+			truth[n.ID] = true
+		}
+		for _, n := range idx.Search(q, k) {
+			if truth[n.ID] {
+				hits++
+			}
+		}
+	}
+	if recall := float64(hits) / float64(len(queries)*k); recall < 0.7 {
+		t.Errorf("Expected recall >= 0.7 with nprobe 4, got %.3f", recall)
+	}
+
+	// nprobe 等于 nlist 时结果精确
+	idx.SetNprobe(16)
+	for _, q := range queries {
+		got, want := idx.Search(q, k), flat.Search(q, k)
+		for i := range want {
+			if got[i].ID != want[i].ID {
+				t.Fatalf("Expected exact results with full probe, got %v want %v", got, want)
+			}
+		}
+	}
+
+	// 删除
+	idx.Remove("id0")
+	if idx.Len() != len(vectors)-1 {
+		t.Errorf("Expected %d vectors after delete, got %d", len(vectors)-1, idx.Len())
+	}
+	for _, n := range idx.Search(vectors[0], 5) {
+		if n.ID == "id0" {
+			t.Error("Expected id0 to be deleted from results")
+		}
+	}
+}
+
+func TestIVFIndexUntrained(t *testing.T) {
+	idx := NewIVFIndex(3, 4, 1, 0, nil)
+	idx.Add("id1", []float32{1.0, 0.0, 0.0})
+	idx.Add("id2", []float32{0.0, 1.0, 0.0})
+	results := idx.Search([]float32{1.0, 0.0, 0.0}, 1)
+	if len(results) != 1 || results[0].ID != "id1" {
+		t.Errorf("Expected id1 from pending buffer, got %v", results)
+	}
+	if err := idx.Train([][]float32{{1, 0, 0}}); err == nil {
+		t.Error("Expected error with fewer samples than nlist")
+	}
+
+	// 维度不一致的向量被拒绝，原向量不变；训练时忽略这样的样本
+	if err := idx.Insert("id1", []float32{0.0, 1.0}); !errors.Is(err, ErrDimension) {
+		t.Errorf("Expected ErrDimension, got %v", err)
+	}
+	if results := idx.Search([]float32{1.0, 0.0, 0.0}, 1); len(results) != 1 || results[0].ID != "id1" {
+		t.Errorf("Expected id1 to keep its vector, got %v", results)
+	}
+	if err := idx.Train([][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {1, 1}}); err == nil {
+		t.Error("Expected the wrong-dimension sample to be ignored, leaving fewer samples than nlist")
+	}
+	if err := idx.Train([][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {1, 1, 0}, {1, 1, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	idx.Add("id3", []float32{1.0})
+	if idx.Len() != 2 {
+		t.Errorf("Expected the wrong-dimension vector to be skipped, got %d vectors", idx.Len())
+	}
+}
+
+func TestSubsample(t *testing.T) {
+	samples := randomVectors(100, 2, rand.New(rand.NewSource(1)))
+	if got := subsample(samples, 100); len(got) != 100 || &got[0] != &samples[0] {
+		t.Errorf("Expected samples under the limit to be returned as is, got %d", len(got))
+	}
+	first := samples[0]
+	a, b := subsample(samples, 10), subsample(samples, 10)
+	if len(a) != 10 {
+		t.Fatalf("Expected 10 samples, got %d", len(a))
+	}
+	// 固定种子，结果可重复；原切片不被打乱
+	for i := range a {
+		if &a[i][0] != &b[i][0] {
+			t.Fatal("Expected the same subsample on every call")
+		}
+	}
+	if &samples[0][0] != &first[0] {
+		t.Error("Expected the input order to be preserved")
+	}
+}
@@ -0,0 +1,290 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"gvdb/hnsw"
+)
+
+// VectorSource 按 ID 读取原始向量，用于对量化结果重排序
+type VectorSource = hnsw.VectorSource
+
+// IVFPQIndex 为倒排文件加乘积量化索引：向量只以 PQ 编码保存在各簇中，
+// 搜索时用 ADC 估算距离，可选地从 VectorSource 读取原始向量对前 k*rerank 个候选重新打分。
+// nlist 为 1 时等价于纯 PQ 扫描。
+type IVFPQIndex struct {
+	dim       int
+	nlist     int
+	nprobe    int
+	trainSize int
+	metric    hnsw.Metric
+	dist      kernel
+	pq        *ProductQuantizer
+	centroids [][]float32
+	lists     []*codeList
+	where     map[string]int
+	pending   *flatList
+	trainErr  error // 最近一次自动训练的错误，训练成功后清除
+	source    VectorSource
+	rerank    int
+	mutex     sync.RWMutex
+}
+
+// codeList 为连续存放的一组 PQ 编码
+type codeList struct {
+	ids   []string
+	codes []byte
+	pos   map[string]int
+}
+
+func newCodeList() *codeList {
+	return &codeList{pos: make(map[string]int)}
+}
+
+func (l *codeList) add(id string, code []byte) {
+	l.pos[id] = len(l.ids)
+	l.ids = append(l.ids, id)
+	l.codes = append(l.codes, code...)
+}
+
+func (l *codeList) remove(id string, size int) {
+	p, ok := l.pos[id]
+	if !ok {
+		return
+	}
+	last := len(l.ids) - 1
+	if p != last {
+		copy(l.codes[p*size:(p+1)*size], l.codes[last*size:(last+1)*size])
+		l.ids[p] = l.ids[last]
+		l.pos[l.ids[p]] = p
+	}
+	l.ids = l.ids[:last]
+	l.codes = l.codes[:last*size]
+	delete(l.pos, id)
+}
+
+// NewIVFPQIndex 创建 IVF-PQ 索引，m 和 nbits 为 PQ 参数；trainSize<=0 时默认为 max(nlist, 2^nbits)*39
+func NewIVFPQIndex(dim, nlist, nprobe, m, nbits, trainSize int, metric hnsw.Metric) (*IVFPQIndex, error) {
+	if metric == nil {
+		metric = hnsw.Cosine{}
+	}
+	pq, err := NewProductQuantizer(dim, m, nbits, metric)
+	if err != nil {
+		return nil, err
+	}
+	if nlist < 1 {
+		nlist = 1
+	}
+	if nprobe < 1 {
+		nprobe = 1
+	}
+	if trainSize <= 0 {
+		trainSize = nlist
+		if pq.ksub > trainSize {
+			trainSize = pq.ksub
+		}
+		trainSize *= 39
+	}
+	return &IVFPQIndex{
+		dim:       dim,
+		nlist:     nlist,
+		nprobe:    nprobe,
+		trainSize: trainSize,
+		metric:    metric,
+		dist:      kernelFor(metric),
+		pq:        pq,
+		where:     make(map[string]int),
+		pending:   newFlatList(),
+	}, nil
+}
+
+// SetRerank 设置重排序使用的原始向量来源和候选倍数，factor<=1 时关闭重排序
+func (idx *IVFPQIndex) SetRerank(source VectorSource, factor int) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.source = source
+	idx.rerank = factor
+}
+
+// SetNprobe 调整搜索时扫描的簇数量
+func (idx *IVFPQIndex) SetNprobe(nprobe int) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if nprobe > 0 {
+		idx.nprobe = nprobe
+	}
+}
+
+func (idx *IVFPQIndex) Metric() hnsw.Metric {
+	return idx.metric
+}
+
+func (idx *IVFPQIndex) Len() int {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return len(idx.where)
+}
+
+func (idx *IVFPQIndex) Trained() bool {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return idx.centroids != nil
+}
+
+// TrainErr 返回最近一次插入时自动训练的错误，训练成功后为 nil。
+// 训练失败时向量保留在待训练缓冲区中并以暴力方式搜索，之后的插入会重试训练
+func (idx *IVFPQIndex) TrainErr() error {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+	return idx.trainErr
+}
+
+// Train 用给定样本训练粗量化质心和 PQ 码本，已插入的向量会被编码并重新分配；维度与索引不一致的样本被忽略，
+// 样本过多时与 IVFIndex 一样每个簇最多抽取 maxSamplesPerList 个
+func (idx *IVFPQIndex) Train(samples [][]float32) error {
+	data := subsample(sameDim(samples, idx.dim), idx.nlist*maxSamplesPerList)
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.centroids != nil {
+		return errors.New("ivfpq: index is already trained")
+	}
+	return idx.train(data)
+}
+
+func (idx *IVFPQIndex) train(data [][]float32) error {
+	if len(data) < idx.nlist {
+		return errors.New("ivfpq: not enough training samples for nlist")
+	}
+	if err := idx.pq.Train(data); err != nil {
+		return err
+	}
+	_, spherical := idx.metric.(hnsw.Cosine)
+	idx.centroids = KMeans(data, idx.nlist, 25, idx.dist, spherical, 1)
+	idx.lists = make([]*codeList, len(idx.centroids))
+	for i := range idx.lists {
+		idx.lists[i] = newCodeList()
+	}
+	for i, id := range idx.pending.ids {
+		idx.assign(id, idx.pending.data[i*idx.dim:(i+1)*idx.dim])
+	}
+	// 训练完成后不再保留原始向量
+	idx.pending = newFlatList()
+	idx.trainErr = nil
+	return nil
+}
+
+func (idx *IVFPQIndex) assign(id string, v []float32) {
+	c := nearestCentroid(v, idx.centroids, idx.dist)
+	idx.lists[c].add(id, idx.pq.Encode(v))
+	idx.where[id] = c
+}
+
+// Add 插入或替换向量，维度不一致的向量被跳过，需要错误时使用 Insert
+func (idx *IVFPQIndex) Add(id string, vector []float32) {
+	idx.Insert(id, vector)
+}
+
+// Insert 插入或替换向量，维度与索引不一致时返回 ErrDimension，原有的向量保持不变。
+// 待训练缓冲区达到 trainSize 时自动训练，训练失败不影响本次插入，错误由 TrainErr 返回
+func (idx *IVFPQIndex) Insert(id string, vector []float32) error {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if len(vector) != idx.dim {
+		return fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), idx.dim)
+	}
+	idx.remove(id)
+	if idx.centroids != nil {
+		idx.assign(id, vector)
+		return nil
+	}
+	idx.pending.add(id, vector)
+	idx.where[id] = -1
+	if len(idx.pending.ids) >= idx.trainSize {
+		data := make([][]float32, len(idx.pending.ids))
+		for i := range data {
+			data[i] = idx.pending.data[i*idx.dim : (i+1)*idx.dim]
+		}
+		if err := idx.train(data); err != nil {
+			idx.trainErr = fmt.Errorf("ivfpq: training on %d vectors: %w", len(data), err)
+		}
+	}
+	return nil
+}
+
+func (idx *IVFPQIndex) Remove(id string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.remove(id)
+}
+
+func (idx *IVFPQIndex) remove(id string) {
+	c, ok := idx.where[id]
+	if !ok {
+		return
+	}
+	if c < 0 {
+		idx.pending.remove(id, idx.dim)
+	} else {
+		idx.lists[c].remove(id, idx.pq.CodeSize())
+	}
+	delete(idx.where, id)
+}
+
+func (idx *IVFPQIndex) Search(query []float32, k int) []hnsw.Neighbor {
+	return idx.SearchFilter(query, k, nil)
+}
+
+// SearchFilter 与 IVFIndex 相同，带过滤条件时会继续扫描更远的簇直到凑够候选
+func (idx *IVFPQIndex) SearchFilter(query []float32, k int, allow hnsw.FilterFunc) []hnsw.Neighbor {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	if len(idx.where) == 0 || k <= 0 {
+		return nil
+	}
+	q := query
+
+	fetch := k
+	if idx.source != nil && idx.rerank > 1 {
+		fetch = k * idx.rerank
+	}
+	t := newTopK(fetch)
+	idx.pending.scan(q, idx.dim, idx.dist, allow, t)
+	if idx.centroids != nil {
+		table := idx.pq.DistanceTable(q)
+		size := idx.pq.CodeSize()
+		for p, c := range probeCentroids(q, idx.centroids, idx.dist, probeLimit(idx.nprobe, len(idx.centroids), allow)) {
+			if p >= idx.nprobe && t.full() {
+				break
+			}
+			l := idx.lists[c]
+			for i, id := range l.ids {
+				if allow == nil || allow(id) {
+					t.offer(id, idx.pq.ADC(table, l.codes[i*size:(i+1)*size]))
+				}
+			}
+		}
+	}
+
+	if fetch > k {
+		t = idx.rescore(q, t, k)
+	}
+	return t.neighbors(idx.metric)
+}
+
+// rescore 用原始向量重新计算候选的精确距离；读取失败的候选保留近似距离
+func (idx *IVFPQIndex) rescore(q []float32, candidates *topK, k int) *topK {
+	t := newTopK(k)
+	for _, c := range candidates.items {
+		if v, ok := idx.source(c.id); ok {
+			t.offer(c.id, idx.dist(q, v))
+		} else {
+			t.offer(c.id, c.dist)
+		}
+	}
+	return t
+}
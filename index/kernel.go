@@ -0,0 +1,14 @@
+package index
+
+import "gvdb/hnsw"
+
+// kernel 计算 float32 向量间越小越相似的距离
+type kernel func(a, b []float32) float32
+
+// kernelFor 返回度量对应的距离核函数，相似度取负值以统一排序方向
+func kernelFor(metric hnsw.Metric) kernel {
+	if metric.HigherIsBetter() {
+		return func(a, b []float32) float32 { return -metric.Score(a, b) }
+	}
+	return metric.Score
+}
@@ -0,0 +1,19 @@
+package index
+
+import (
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func TestKernelOrdering(t *testing.T) {
+	a := []float32{1, 0}
+	near := []float32{2, 0}
+	far := []float32{-1, 0}
+	for _, m := range []hnsw.Metric{hnsw.Cosine{}, hnsw.L2{}, hnsw.InnerProduct{}, hnsw.Manhattan{}} {
+		dist := kernelFor(m)
+		if dist(a, near) >= dist(a, far) {
+			t.Errorf("%s: expected near vector to have smaller distance", m.Name())
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package index
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"gvdb/vector"
+)
+
+// KMeans 对 data 做 k-means 聚类（k-means++ 初始化），返回 k 个质心。
+// dist 用于样本分配，normalize 为 true 时每轮把质心归一化（用于余弦/内积的球面 k-means）。
+func KMeans(data [][]float32, k, iters int, dist kernel, normalize bool, seed int64) [][]float32 {
+	if len(data) == 0 || k <= 0 {
+		return nil
+	}
+	if k > len(data) {
+		k = len(data)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	centroids := kmeansPlusPlus(data, k, rng)
+	assign := make([]int, len(data))
+
+	for it := 0; it < iters; it++ {
+		changed := assignAll(data, centroids, assign, dist)
+
+		dim := len(data[0])
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for c := range sums {
+			sums[c] = make([]float64, dim)
+		}
+		for i, v := range data {
+			c := assign[i]
+			counts[c]++
+			for j, x := range v {
+				sums[c][j] += float64(x)
+			}
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				// 空簇重新随机选择一个样本作为质心
+				copy(centroids[c], data[rng.Intn(len(data))])
+				continue
+			}
+			for j := range centroids[c] {
+				centroids[c][j] = float32(sums[c][j] / float64(counts[c]))
+			}
+			if normalize {
+				vector.Normalize(centroids[c])
+			}
+		}
+		if it > 0 && changed == 0 {
+			break
+		}
+	}
+	return centroids
+}
+
+func kmeansPlusPlus(data [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := make([][]float32, 0, k)
+	first := data[rng.Intn(len(data))]
+	centroids = append(centroids, append([]float32(nil), first...))
+
+	// 按与最近质心距离的平方加权抽样
+	d2 := make([]float64, len(data))
+	for i, v := range data {
+		d2[i] = float64(vector.L2Squared(v, first))
+	}
+	for len(centroids) < k {
+		var total float64
+		for _, d := range d2 {
+			total += d
+		}
+		next := rng.Intn(len(data))
+		if total > 0 {
+			r := rng.Float64() * total
+			for i, d := range d2 {
+				r -= d
+				if r <= 0 {
+					next = i
+					break
+				}
+			}
+		}
+		c := append([]float32(nil), data[next]...)
+		centroids = append(centroids, c)
+		for i, v := range data {
+			d2[i] = math.Min(d2[i], float64(vector.L2Squared(v, c)))
+		}
+	}
+	return centroids
+}
+
+// assignAll 并行计算每个样本最近的质心，返回分配发生变化的样本数
+func assignAll(data [][]float32, centroids [][]float32, assign []int, dist kernel) int {
+	workers := runtime.NumCPU()
+	chunk := (len(data) + workers - 1) / workers
+	changed := make([]int, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := w*chunk, (w+1)*chunk
+		if end > len(data) {
+			end = len(data)
+		}
+		if start >= end {
+			continue
+		}
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				c := nearestCentroid(data[i], centroids, dist)
+				if c != assign[i] {
+					assign[i] = c
+					changed[w]++
+				}
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+	total := 0
+	for _, c := range changed {
+		total += c
+	}
+	return total
+}
+
+func nearestCentroid(v []float32, centroids [][]float32, dist kernel) int {
+	best, bestDist := 0, float32(math.MaxFloat32)
+	for c, centroid := range centroids {
+		if d := dist(v, centroid); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,26 @@
+package index
+
+import (
+	"testing"
+
+	"gvdb/vector"
+)
+
+func TestKMeans(t *testing.T) {
+	// 两个明显分离的簇
+	var data [][]float32
+	for i := 0; i < 50; i++ {
+		data = append(data, []float32{float32(i%5) * 0.01, 0}, []float32{10 + float32(i%5)*0.01, 10})
+	}
+	centroids := KMeans(data, 2, 10, vector.L2Squared, false, 1)
+	if len(centroids) != 2 {
+		t.Fatalf("Expected 2 centroids, got %d", len(centroids))
+	}
+	a, b := centroids[0], centroids[1]
+	if a[0] > b[0] {
+		a, b = b, a
+	}
+	if a[0] > 0.1 || b[0] < 9.9 {
+		t.Errorf("Unexpected centroids: %v", centroids)
+	}
+}
@@ -0,0 +1,27 @@
+package index
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile 以只读方式映射整个文件，页面按需由内核换入换出
+func mapFile(f *os.File, size int) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	// 图遍历是随机访问，关闭预读避免换入无关页面
+	syscall.Madvise(data, syscall.MADV_RANDOM)
+	return data, nil
+}
+
+func unmapFile(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package index
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile 在不支持 mmap 的平台上把文件整体读入内存，无法搜索超出内存的数据集
+func mapFile(f *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
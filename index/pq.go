@@ -0,0 +1,160 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gvdb/hnsw"
+	"gvdb/vector"
+)
+
+// ProductQuantizer 把 dim 维向量切分为 m 个子空间，每个子空间用 2^nbits 个质心的码本编码，
+// 一个向量压缩为 m 字节。距离按子空间累加，支持非对称距离计算（ADC）。
+type ProductQuantizer struct {
+	dim       int
+	m         int
+	nbits     int
+	ksub      int
+	dsub      int
+	metric    hnsw.Metric
+	partial   kernel
+	normalize bool
+	codebooks [][]float32 // 每个子空间 ksub*dsub 个分量
+}
+
+// NewProductQuantizer 创建乘积量化器，dim 必须能被 m 整除，nbits 取值 1~8
+func NewProductQuantizer(dim, m, nbits int, metric hnsw.Metric) (*ProductQuantizer, error) {
+	if metric == nil {
+		metric = hnsw.Cosine{}
+	}
+	if m <= 0 || dim <= 0 || dim%m != 0 {
+		return nil, fmt.Errorf("pq: dimension %d is not divisible by m=%d", dim, m)
+	}
+	if nbits < 1 || nbits > 8 {
+		return nil, fmt.Errorf("pq: nbits must be between 1 and 8, got %d", nbits)
+	}
+	pq := &ProductQuantizer{
+		dim:    dim,
+		m:      m,
+		nbits:  nbits,
+		ksub:   1 << nbits,
+		dsub:   dim / m,
+		metric: metric,
+	}
+	switch metric.(type) {
+	case hnsw.L2:
+		pq.partial = vector.L2Squared
+	case hnsw.InnerProduct:
+		pq.partial = func(a, b []float32) float32 { return -vector.Dot(a, b) }
+	case hnsw.Cosine:
+		// 余弦相似度等价于归一化向量的内积
+		pq.partial = func(a, b []float32) float32 { return -vector.Dot(a, b) }
+		pq.normalize = true
+	case hnsw.Manhattan:
+		pq.partial = vector.L1
+	default:
+		return nil, fmt.Errorf("pq: metric %s is not supported", metric.Name())
+	}
+	return pq, nil
+}
+
+// CodeSize 返回每个向量编码后的字节数
+func (pq *ProductQuantizer) CodeSize() int {
+	return pq.m
+}
+
+// Trained 返回码本是否已训练
+func (pq *ProductQuantizer) Trained() bool {
+	return pq.codebooks != nil
+}
+
+// Train 对每个子空间分别做 k-means 训练码本
+func (pq *ProductQuantizer) Train(data [][]float32) error {
+	if len(data) == 0 {
+		return errors.New("pq: no training data")
+	}
+	codebooks := make([][]float32, pq.m)
+	for s := 0; s < pq.m; s++ {
+		sub := make([][]float32, len(data))
+		for i, v := range data {
+			sub[i] = pq.prepare(v)[s*pq.dsub : (s+1)*pq.dsub]
+		}
+		centroids := KMeans(sub, pq.ksub, 20, vector.L2Squared, false, int64(s+1))
+		book := make([]float32, pq.ksub*pq.dsub)
+		for c, centroid := range centroids {
+			copy(book[c*pq.dsub:], centroid)
+		}
+		// 样本数少于 ksub 时，多余的码字保持为已有质心的副本
+		for c := len(centroids); c < pq.ksub; c++ {
+			copy(book[c*pq.dsub:], centroids[c%len(centroids)])
+		}
+		codebooks[s] = book
+	}
+	pq.codebooks = codebooks
+	return nil
+}
+
+// Encode 把向量编码为每个子空间最近码字的下标
+func (pq *ProductQuantizer) Encode(v []float32) []byte {
+	v = pq.prepare(v)
+	code := make([]byte, pq.m)
+	for s := 0; s < pq.m; s++ {
+		sub := v[s*pq.dsub : (s+1)*pq.dsub]
+		best, bestDist := 0, float32(math.MaxFloat32)
+		for c := 0; c < pq.ksub; c++ {
+			if d := vector.L2Squared(sub, pq.codeword(s, c)); d < bestDist {
+				best, bestDist = c, d
+			}
+		}
+		code[s] = byte(best)
+	}
+	return code
+}
+
+// Decode 由编码重建近似向量
+func (pq *ProductQuantizer) Decode(code []byte) []float32 {
+	v := make([]float32, pq.dim)
+	for s := 0; s < pq.m; s++ {
+		copy(v[s*pq.dsub:], pq.codeword(s, int(code[s])))
+	}
+	return v
+}
+
+// DistanceTable 预计算查询每个子向量到所有码字的部分距离，用于 ADC
+func (pq *ProductQuantizer) DistanceTable(query []float32) []float32 {
+	q := pq.prepare(query)
+	table := make([]float32, pq.m*pq.ksub)
+	for s := 0; s < pq.m; s++ {
+		sub := q[s*pq.dsub : (s+1)*pq.dsub]
+		for c := 0; c < pq.ksub; c++ {
+			table[s*pq.ksub+c] = pq.partial(sub, pq.codeword(s, c))
+		}
+	}
+	return table
+}
+
+// ADC 用距离表计算查询与编码向量之间越小越相似的距离
+func (pq *ProductQuantizer) ADC(table []float32, code []byte) float32 {
+	var d float32
+	for s, c := range code {
+		d += table[s*pq.ksub+int(c)]
+	}
+	if _, ok := pq.metric.(hnsw.L2); ok {
+		return float32(math.Sqrt(float64(d)))
+	}
+	return d
+}
+
+func (pq *ProductQuantizer) codeword(s, c int) []float32 {
+	return pq.codebooks[s][c*pq.dsub : (c+1)*pq.dsub]
+}
+
+func (pq *ProductQuantizer) prepare(v []float32) []float32 {
+	if !pq.normalize {
+		return v
+	}
+	n := append([]float32(nil), v...)
+	vector.Normalize(n)
+	return n
+}
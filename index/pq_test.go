@@ -0,0 +1,141 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gvdb/hnsw"
+	"gvdb/vector"
+)
+
+func TestProductQuantizer(t *testing.T) {
+	if _, err := NewProductQuantizer(10, 3, 8, hnsw.L2{}); err == nil {
+		t.Error("Expected error when dim is not divisible by m")
+	}
+	if _, err := NewProductQuantizer(8, 4, 8, hnsw.Hamming{}); err == nil {
+		t.Error("Expected error for unsupported metric")
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	dim := 16
+	var data [][]float32
+	for _, v := range randomVectors(2000, dim, rng) {
+		data = append(data, v)
+	}
+	pq, err := NewProductQuantizer(dim, 4, 8, hnsw.L2{})
+	if err != nil {
+		t.Fatalf("NewProductQuantizer failed: %v", err)
+	}
+	if err := pq.Train(data); err != nil {
+		t.Fatalf("Train failed: %v", err)
+	}
+
+	// 编码大小为 m 字节，重建误差应明显小于向量本身的范数
+	code := pq.Encode(data[0])
+	if len(code) != pq.CodeSize() || pq.CodeSize() != 4 {
+		t.Fatalf("Unexpected code size %d", len(code))
+	}
+	recon := pq.Decode(code)
+	if err := vector.L2Squared(recon, data[0]); err > vector.Dot(data[0], data[0])/2 {
+		t.Errorf("Reconstruction error too large: %f", err)
+	}
+
+	// ADC 与解码后向量的精确距离一致
+	q := data[1]
+	table := pq.DistanceTable(q)
+	adc := pq.ADC(table, code)
+	exact := kernelFor(hnsw.L2{})(q, recon)
+	if d := adc - exact; d > 1e-3 || d < -1e-3 {
+		t.Errorf("ADC %f differs from decoded distance %f", adc, exact)
+	}
+}
+
+func TestIVFPQIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	dim := 16
+	vectors := randomVectors(3000, dim, rng)
+	original := make(map[string][]float32)
+
+	idx, err := NewIVFPQIndex(dim, 8, 8, 8, 8, 2000, hnsw.L2{})
+	if err != nil {
+		t.Fatalf("NewIVFPQIndex failed: %v", err)
+	}
+	flat := NewFlatIndex(dim, hnsw.L2{}, 1)
+	for i, v := range vectors {
+		id := fmt.Sprintf("id%d", i)
+		original[id] = v
+		idx.Add(id, v)
+		flat.Add(id, v)
+	}
+	if !idx.Trained() || idx.Len() != len(vectors) {
+		t.Fatalf("Expected trained index with %d vectors, got trained=%v len=%d", len(vectors), idx.Trained(), idx.Len())
+	}
+
+	recall := func() float64 {
+		hits := 0
+		queries := randomVectors(20, dim, rand.New(rand.NewSource(3)))
+		for _, q := range queries {
+			truth := make(map[string]bool)
+			for _, n := range flat.Search(q, 10) {
+				truth[n.ID] = true
+			}
+			for _, n := range idx.Search(q, 10) {
+				if truth[n.ID] {
+					hits++
+				}
+			}
+		}
+		return float64(hits) / float64(len(queries)*10)
+	}
+
+	approx := recall()
+	// 使用原始向量重排序后召回率应提升
+	idx.SetRerank(func(id string) ([]float32, bool) {
+		v, ok := original[id]
+		return v, ok
+	}, 10)
+	reranked := recall()
+	if reranked < approx || reranked < 0.8 {
+		t.Errorf("Expected rerank to improve recall: approx %.3f, reranked %.3f", approx, reranked)
+	}
+
+	// 重排序后的得分为精确距离
+	q := vectors[5]
+	results := idx.Search(q, 1)
+	if len(results) != 1 || results[0].Score > 1e-4 {
+		t.Errorf("Expected exact match with zero distance, got %v", results)
+	}
+
+	idx.Remove(results[0].ID)
+	if idx.Len() != len(vectors)-1 {
+		t.Errorf("Expected %d vectors after delete, got %d", len(vectors)-1, idx.Len())
+	}
+}
+
+func TestIVFPQIndexTrainError(t *testing.T) {
+	// trainSize 小于 nlist，第一次自动训练样本不足
+	idx, err := NewIVFPQIndex(4, 4, 4, 2, 2, 2, hnsw.L2{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Add("a", []float32{1, 0, 0, 0})
+	idx.Add("b", []float32{0, 1, 0, 0})
+	if idx.TrainErr() == nil || idx.Trained() {
+		t.Fatalf("Expected a recorded training failure, got trained=%v err=%v", idx.Trained(), idx.TrainErr())
+	}
+	// 训练失败时向量仍在缓冲区中，可以搜索
+	if results := idx.Search([]float32{1, 0, 0, 0}, 1); idx.Len() != 2 || len(results) != 1 || results[0].ID != "a" {
+		t.Errorf("Expected buffered vectors to be searchable, got %v with %d vectors", results, idx.Len())
+	}
+
+	if err := idx.Insert("c", []float32{0, 0, 1}); !errors.Is(err, ErrDimension) {
+		t.Errorf("Expected ErrDimension, got %v", err)
+	}
+	idx.Add("c", []float32{0, 0, 1, 0})
+	idx.Add("d", []float32{0, 0, 0, 1})
+	if !idx.Trained() || idx.TrainErr() != nil || idx.Len() != 4 {
+		t.Errorf("Expected training to be retried and succeed, got trained=%v err=%v len=%d", idx.Trained(), idx.TrainErr(), idx.Len())
+	}
+}
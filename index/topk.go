@@ -0,0 +1,72 @@
+package index
+
+import (
+	"container/heap"
+	"sort"
+
+	"gvdb/hnsw"
+)
+
+type scored struct {
+	id   string
+	dist float32
+}
+
+// topK 用最大堆维护距离最小的 k 个结果
+type topK struct {
+	k     int
+	items []scored
+}
+
+func newTopK(k int) *topK {
+	return &topK{k: k, items: make([]scored, 0, k+1)}
+}
+
+func (t *topK) Len() int           { return len(t.items) }
+func (t *topK) Less(i, j int) bool { return t.items[i].dist > t.items[j].dist }
+func (t *topK) Swap(i, j int)      { t.items[i], t.items[j] = t.items[j], t.items[i] }
+func (t *topK) Push(x interface{}) { t.items = append(t.items, x.(scored)) }
+func (t *topK) Pop() interface{} {
+	n := len(t.items)
+	x := t.items[n-1]
+	t.items = t.items[:n-1]
+	return x
+}
+func (t *topK) worst() float32 { return t.items[0].dist }
+func (t *topK) full() bool     { return len(t.items) >= t.k }
+
+// offer 在结果更优或未满时加入候选
+func (t *topK) offer(id string, dist float32) {
+	if t.k <= 0 {
+		return
+	}
+	if !t.full() {
+		heap.Push(t, scored{id: id, dist: dist})
+		return
+	}
+	if dist < t.worst() {
+		t.items[0] = scored{id: id, dist: dist}
+		heap.Fix(t, 0)
+	}
+}
+
+func (t *topK) merge(other *topK) {
+	for _, s := range other.items {
+		t.offer(s.id, s.dist)
+	}
+}
+
+// neighbors 返回按相似程度排序的结果，并把距离还原为度量得分
+func (t *topK) neighbors(metric hnsw.Metric) []hnsw.Neighbor {
+	items := append([]scored(nil), t.items...)
+	sort.Slice(items, func(i, j int) bool { return items[i].dist < items[j].dist })
+	results := make([]hnsw.Neighbor, len(items))
+	for i, s := range items {
+		score := s.dist
+		if metric.HigherIsBetter() {
+			score = -score
+		}
+		results[i] = hnsw.Neighbor{ID: s.id, Score: score}
+	}
+	return results
+}
@@ -0,0 +1,201 @@
+package index
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"gvdb/hnsw"
+)
+
+// vcand 为 Vamana 图搜索中的候选节点
+type vcand struct {
+	id   uint32
+	dist float32
+}
+
+// vamana 在内存中维护邻接表，向量通过 vec 按需读取（可以来自内存映射文件），
+// 因此构建时常驻内存的只有 n*r 个邻居序号
+type vamana struct {
+	graph  [][]uint32
+	vec    func(i uint32) []float32
+	dist   kernel // 非负距离，供 alpha 剪枝使用
+	r      int
+	l      int
+	alpha  float32
+	medoid uint32
+}
+
+// graphKernel 返回构图用的非负距离：余弦使用 1-cos，内积退化为欧氏距离
+func graphKernel(metric hnsw.Metric) kernel {
+	switch metric.(type) {
+	case hnsw.Cosine:
+		return func(a, b []float32) float32 { return 1 - metric.Score(a, b) }
+	case hnsw.InnerProduct:
+		return hnsw.L2{}.Score
+	default:
+		if metric.HigherIsBetter() {
+			return hnsw.L2{}.Score
+		}
+		return metric.Score
+	}
+}
+
+// build 按 Vamana 算法构建 n 个点的图：随机 r 正则图初始化，再分别以 alpha=1 和 alpha 各遍历一轮
+func (g *vamana) build(n int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	g.graph = make([][]uint32, n)
+	if n == 0 {
+		return
+	}
+	for i := range g.graph {
+		deg := g.r
+		if deg > n-1 {
+			deg = n - 1
+		}
+		seen := map[uint32]bool{uint32(i): true}
+		for len(g.graph[i]) < deg {
+			j := uint32(rng.Intn(n))
+			if !seen[j] {
+				seen[j] = true
+				g.graph[i] = append(g.graph[i], j)
+			}
+		}
+	}
+	g.medoid = g.findMedoid(n)
+
+	alpha := g.alpha
+	for _, a := range []float32{1, alpha} {
+		g.alpha = a
+		for _, i := range rng.Perm(n) {
+			g.insert(uint32(i))
+		}
+	}
+	g.alpha = alpha
+}
+
+// findMedoid 返回离质心最近的点，作为搜索入口
+func (g *vamana) findMedoid(n int) uint32 {
+	var centroid []float64
+	for i := 0; i < n; i++ {
+		v := g.vec(uint32(i))
+		if centroid == nil {
+			centroid = make([]float64, len(v))
+		}
+		for j, x := range v {
+			centroid[j] += float64(x)
+		}
+	}
+	c := make([]float32, len(centroid))
+	for j := range centroid {
+		c[j] = float32(centroid[j] / float64(n))
+	}
+	best, bestDist := uint32(0), float32(math.Inf(1))
+	for i := 0; i < n; i++ {
+		if d := g.dist(c, g.vec(uint32(i))); d < bestDist {
+			best, bestDist = uint32(i), d
+		}
+	}
+	return best
+}
+
+// insert 把节点 p 接入图：从入口贪心搜索，对访问过的节点剪枝得到出边，再补充反向边
+func (g *vamana) insert(p uint32) {
+	pv := g.vec(p)
+	_, visited := greedySearch(g.medoid, g.l, func(i uint32) float32 { return g.dist(pv, g.vec(i)) },
+		func(i uint32) []uint32 { return g.graph[i] })
+	for _, nb := range g.graph[p] {
+		visited = append(visited, vcand{id: nb, dist: g.dist(pv, g.vec(nb))})
+	}
+	g.graph[p] = g.prune(p, visited)
+
+	for _, j := range g.graph[p] {
+		if containsID(g.graph[j], p) {
+			continue
+		}
+		if len(g.graph[j]) < g.r {
+			g.graph[j] = append(g.graph[j], p)
+			continue
+		}
+		jv := g.vec(j)
+		cands := make([]vcand, 0, len(g.graph[j])+1)
+		for _, nb := range append(g.graph[j], p) {
+			cands = append(cands, vcand{id: nb, dist: g.dist(jv, g.vec(nb))})
+		}
+		g.graph[j] = g.prune(j, cands)
+	}
+}
+
+// prune 为 RobustPrune：依次选取最近的候选，并丢弃被它以 alpha 倍距离“覆盖”的其余候选
+func (g *vamana) prune(p uint32, cands []vcand) []uint32 {
+	sort.Slice(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+	out := make([]uint32, 0, g.r)
+	seen := map[uint32]bool{p: true}
+	for len(cands) > 0 && len(out) < g.r {
+		best := cands[0]
+		cands = cands[1:]
+		if seen[best.id] {
+			continue
+		}
+		seen[best.id] = true
+		out = append(out, best.id)
+		bv := g.vec(best.id)
+		kept := cands[:0]
+		for _, c := range cands {
+			if !seen[c.id] && g.alpha*g.dist(bv, g.vec(c.id)) > c.dist {
+				kept = append(kept, c)
+			}
+		}
+		cands = kept
+	}
+	return out
+}
+
+// greedySearch 从 start 开始做宽度为 l 的贪心搜索，返回按距离排序的候选列表和所有展开过的节点
+func greedySearch(start uint32, l int, dist func(uint32) float32, neighbors func(uint32) []uint32) ([]vcand, []vcand) {
+	list := []vcand{{id: start, dist: dist(start)}}
+	seen := map[uint32]bool{start: true}
+	expanded := make(map[uint32]bool)
+	var visited []vcand
+	for {
+		next := -1
+		for i, c := range list {
+			if !expanded[c.id] {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return list, visited
+		}
+		cur := list[next]
+		expanded[cur.id] = true
+		visited = append(visited, cur)
+		for _, nb := range neighbors(cur.id) {
+			if seen[nb] {
+				continue
+			}
+			seen[nb] = true
+			c := vcand{id: nb, dist: dist(nb)}
+			if len(list) >= l && c.dist >= list[len(list)-1].dist {
+				continue
+			}
+			pos := sort.Search(len(list), func(i int) bool { return list[i].dist > c.dist })
+			list = append(list, vcand{})
+			copy(list[pos+1:], list[pos:])
+			list[pos] = c
+			if len(list) > l {
+				list = list[:l]
+			}
+		}
+	}
+}
+
+func containsID(ids []uint32, id uint32) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package index
+
+import (
+	"math/rand"
+	"testing"
+
+	"gvdb/hnsw"
+)
+
+func TestVamanaBuild(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	vectors := randomVectors(1000, 8, rng)
+	g := &vamana{
+		vec:   func(i uint32) []float32 { return vectors[i] },
+		dist:  graphKernel(hnsw.L2{}),
+		r:     16,
+		l:     32,
+		alpha: 1.2,
+	}
+	g.build(len(vectors), 1)
+
+	// 出度不超过 r，且没有自环
+	for i, nbs := range g.graph {
+		if len(nbs) > g.r {
+			t.Fatalf("Node %d has degree %d > %d", i, len(nbs), g.r)
+		}
+		if containsID(nbs, uint32(i)) {
+			t.Fatalf("Node %d links to itself", i)
+		}
+	}
+
+	// 从入口出发所有节点可达
+	seen := map[uint32]bool{g.medoid: true}
+	queue := []uint32{g.medoid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, nb := range g.graph[p] {
+			if !seen[nb] {
+				seen[nb] = true
+				queue = append(queue, nb)
+			}
+		}
+	}
+	if len(seen) != len(vectors) {
+		t.Errorf("Expected all %d nodes reachable from medoid, got %d", len(vectors), len(seen))
+	}
+
+	// 贪心搜索能找到查询点自身
+	for _, i := range []uint32{0, 123, 999} {
+		list, _ := greedySearch(g.medoid, 32, func(j uint32) float32 { return g.dist(vectors[i], vectors[j]) },
+			func(j uint32) []uint32 { return g.graph[j] })
+		if list[0].id != i {
+			t.Errorf("Expected greedy search to find node %d, got %d", i, list[0].id)
+		}
+	}
+}
+
+func TestGraphKernelNonNegative(t *testing.T) {
+	a, b := []float32{1, 2, 3}, []float32{-1, 0.5, 2}
+	for _, m := range []hnsw.Metric{hnsw.Cosine{}, hnsw.L2{}, hnsw.InnerProduct{}, hnsw.Manhattan{}} {
+		if d := graphKernel(m)(a, b); d < 0 {
+			t.Errorf("Expected non-negative graph distance for %s, got %f", m.Name(), d)
+		}
+		if d := graphKernel(m)(a, a); d > 1e-5 {
+			t.Errorf("Expected zero self distance for %s, got %f", m.Name(), d)
+		}
+	}
+}
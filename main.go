@@ -1,161 +1,65 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"sync"
-
-	"gvdb/config"
-	"gvdb/hnsw"
-	"gvdb/storage"
+	"os"
 )
 
-type VectorDB struct {
-	storage storage.Storage
-	index   *hnsw.HNSWIndex
-	mutex   sync.RWMutex
-}
-
-func NewVectorDB(cfg config.Config) (*VectorDB, error) {
-	var s storage.Storage
-	var err error
-
-	switch cfg.Storage.Type {
-	case "file":
-		if cfg.Storage.File.Enable {
-			s = storage.NewFileStorage(cfg.Storage.File.Path)
-		}
-	case "duckdb":
-		if cfg.Storage.DuckDB.Enable {
-			s, err = storage.NewDuckDBStorage(cfg.Storage.DuckDB.Path)
-		}
-	case "postgres":
-		if cfg.Storage.Postgres.Enable {
-			s, err = storage.NewPostgresStorage(
-				cfg.Storage.Postgres.Host,
-				cfg.Storage.Postgres.Port,
-				cfg.Storage.Postgres.User,
-				cfg.Storage.Postgres.Password,
-				cfg.Storage.Postgres.Database,
-			)
-		}
-	default:
-		return nil, fmt.Errorf("unknown or disabled storage type: %s", cfg.Storage.Type)
-	}
-	if err != nil {
-		return nil, err
-	}
-	if s == nil {
-		return nil, fmt.Errorf("no enabled storage backend selected")
-	}
-
-	data, err := s.Load()
-	if err != nil {
-		return nil, err
-	}
-
-	index := hnsw.NewHNSWIndex(cfg.HNSW.Dim, cfg.HNSW.M, cfg.HNSW.EF)
-	for id, doc := range data {
-		index.Add(id, doc.Vector)
-	}
-
-	return &VectorDB{storage: s, index: index}, nil
-}
-
-func (db *VectorDB) InsertFromModel(id string, embedding []float64, meta string) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
-
-	doc := storage.VectorDoc{Vector: embedding, Meta: meta}
-	if err := db.storage.Insert(id, doc); err != nil {
-		return err
-	}
-	db.index.Add(id, embedding)
-	return nil
-}
-
-func (db *VectorDB) Get(id string) (storage.VectorDoc, bool) {
-	db.mutex.RLock()
-	defer db.mutex.RUnlock()
-	return db.storage.Get(id)
+// command 为一个子命令，run 接收子命令之后的参数
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
 }
 
-func (db *VectorDB) Delete(id string) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
-
-	if err := db.storage.Delete(id); err != nil {
-		return err
-	}
-	db.index.Remove(id)
-	return nil
-}
-
-type SearchResult struct {
-	ID         string
-	Similarity float64
-	Meta       string
+var commands = []command{
+	{"serve", "start the REST (and optional gRPC) server", runServe},
+	{"import", "import points from a JSONL file", runImport},
+	{"export", "export points as JSONL", runExport},
+	{"search", "search by vector and/or filter", runSearch},
+	{"get", "print points by ID", runGet},
+	{"delete", "delete points by ID", runDelete},
+	{"stats", "show collection statistics", runStats},
+	{"rebuild-index", "rebuild vector and metadata indexes from storage", runRebuildIndex},
+	{"compact", "reclaim storage space and merge the disk index", runCompact},
+	{"migrate", "rewrite stored documents in the current format", runMigrate},
+	{"migrate-storage", "copy all documents to another storage backend", runMigrateStorage},
+	{"snapshot", "write a backup of all collections to a file", runSnapshot},
+	{"restore", "restore a snapshot, possibly into another storage backend", runRestore},
+	{"bench", "measure HNSW recall and QPS", runBench},
 }
 
-func (db *VectorDB) SearchFromModel(queryEmbedding []float64, limit int) []SearchResult {
-	db.mutex.RLock()
-	defer db.mutex.RUnlock()
-
-	neighbors := db.index.Search(queryEmbedding, limit)
-	results := make([]SearchResult, 0, len(neighbors))
-	for _, n := range neighbors {
-		if doc, exists := db.storage.Get(n.ID); exists {
-			results = append(results, SearchResult{
-				ID:         n.ID,
-				Similarity: n.Similarity,
-				Meta:       doc.Meta,
-			})
-		}
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: gvdb <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.summary)
 	}
-	return results
-}
-
-type MockEmbeddingModel struct{}
-
-func (m *MockEmbeddingModel) GenerateEmbedding(text string) []float64 {
-	return []float64{float64(len(text)), 2.0, 3.0}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'gvdb <command> -h' for the flags of a command.")
 }
 
 func main() {
-	cfg, err := config.LoadConfig("config.yaml")
-	if err != nil {
-		fmt.Println("Error loading config:", err)
-		return
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		usage()
+		os.Exit(2)
 	}
-
-	db, err := NewVectorDB(cfg)
-	if err != nil {
-		fmt.Println("Error initializing VectorDB:", err)
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+		if err := c.run(os.Args[2:]); err != nil {
+			if err == flag.ErrHelp {
+				os.Exit(2)
+			}
+			fmt.Fprintf(os.Stderr, "gvdb %s: %v\n", c.name, err)
+			os.Exit(1)
+		}
 		return
 	}
-	defer db.storage.Close()
-
-	model := &MockEmbeddingModel{}
-
-	db.InsertFromModel("doc1", model.GenerateEmbedding("Hello world"), "Hello world")
-	db.InsertFromModel("doc2", model.GenerateEmbedding("Hi there"), "Hi there")
-	db.InsertFromModel("doc3", model.GenerateEmbedding("Good day"), "Good day")
-
-	if doc, exists := db.Get("doc1"); exists {
-		fmt.Println("doc1 vector:", doc.Vector, "meta:", doc.Meta)
-	}
-
-	queryText := "Hello everyone"
-	queryEmbedding := model.GenerateEmbedding(queryText)
-	results := db.SearchFromModel(queryEmbedding, 2)
-	fmt.Println("Top 2 similar documents:")
-	for _, res := range results {
-		fmt.Printf("ID: %s, Similarity: %.4f, Meta: %s\n", res.ID, res.Similarity, res.Meta)
-	}
-
-	db.Delete("doc2")
-	fmt.Println("After deleting doc2, search results:")
-	results = db.SearchFromModel(queryEmbedding, 3)
-	for _, res := range results {
-		fmt.Printf("ID: %s, Similarity: %.4f, Meta: %s\n", res.ID, res.Similarity, res.Meta)
-	}
+	fmt.Fprintf(os.Stderr, "gvdb: unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
 }
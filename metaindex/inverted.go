@@ -0,0 +1,121 @@
+package metaindex
+
+import (
+	"strconv"
+
+	"gvdb/filter"
+)
+
+// inverted 是词项到文档 ID 的倒排索引。keyword 索引的词项是字段值本身，text 索引的词项是分词结果
+type inverted struct {
+	text     bool
+	postings map[string]map[string]bool
+	terms    map[string][]string // 文档已索引的词项，删除时使用
+}
+
+func newInverted(text bool) *inverted {
+	return &inverted{text: text, postings: make(map[string]map[string]bool), terms: make(map[string][]string)}
+}
+
+func (x *inverted) add(id string, values []interface{}) {
+	var terms []string
+	for _, v := range values {
+		if x.text {
+			if s, ok := v.(string); ok {
+				terms = append(terms, filter.Tokenize(s)...)
+			}
+		} else if term, ok := keywordTerm(v); ok {
+			terms = append(terms, term)
+		}
+	}
+	for _, term := range terms {
+		ids := x.postings[term]
+		if ids == nil {
+			ids = make(map[string]bool)
+			x.postings[term] = ids
+		}
+		ids[id] = true
+	}
+	if len(terms) > 0 {
+		x.terms[id] = terms
+	}
+}
+
+func (x *inverted) remove(id string) {
+	for _, term := range x.terms[id] {
+		if ids := x.postings[term]; ids != nil {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(x.postings, term)
+			}
+		}
+	}
+	delete(x.terms, id)
+}
+
+func (x *inverted) plan(f filter.Filter) (map[string]bool, bool) {
+	switch f := f.(type) {
+	case filter.Eq:
+		if !x.text {
+			return x.union([]interface{}{f.Value})
+		}
+	case filter.In:
+		if !x.text {
+			return x.union(f.Values)
+		}
+	case filter.Match:
+		if x.text {
+			return x.all(filter.Tokenize(f.Text))
+		}
+	}
+	return nil, false
+}
+
+// union 返回字段值等于 values 中任意一个的文档
+func (x *inverted) union(values []interface{}) (map[string]bool, bool) {
+	ids := make(map[string]bool)
+	for _, v := range values {
+		term, ok := keywordTerm(v)
+		if !ok {
+			return nil, false
+		}
+		for id := range x.postings[term] {
+			ids[id] = true
+		}
+	}
+	return ids, true
+}
+
+// all 返回包含全部词元的文档
+func (x *inverted) all(tokens []string) (map[string]bool, bool) {
+	if len(tokens) == 0 {
+		// 空查询匹配所有字符串值，倒排索引无法表示
+		return nil, false
+	}
+	ids := make(map[string]bool)
+	for id := range x.postings[tokens[0]] {
+		ids[id] = true
+	}
+	for _, tok := range tokens[1:] {
+		ids = intersect(ids, x.postings[tok])
+	}
+	return ids, true
+}
+
+// keywordTerm 把字段值转为词项，数字统一按 float64 格式化，与 filter 中数字按值比较的语义一致
+func keywordTerm(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return "s" + v, true
+	case bool:
+		return "b" + strconv.FormatBool(v), true
+	}
+	n, ok := filter.Number(v)
+	if !ok || n != n {
+		return "", false
+	}
+	if n == 0 {
+		n = 0 // 统一 -0 和 0
+	}
+	return "n" + strconv.FormatFloat(n, 'g', -1, 64), true
+}
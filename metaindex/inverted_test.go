@@ -0,0 +1,55 @@
+package metaindex
+
+import (
+	"reflect"
+	"testing"
+
+	"gvdb/filter"
+)
+
+func TestKeywordMatchesNumbersByValue(t *testing.T) {
+	x := newInverted(false)
+	x.add("a", []interface{}{int64(3)})
+	x.add("b", []interface{}{3.5, "3"})
+	x.add("c", []interface{}{true})
+
+	cases := []struct {
+		f    filter.Filter
+		want map[string]bool
+	}{
+		{filter.Eq{Field: "n", Value: 3.0}, map[string]bool{"a": true}},
+		{filter.Eq{Field: "n", Value: "3"}, map[string]bool{"b": true}},
+		{filter.In{Field: "n", Values: []interface{}{3.5, true}}, map[string]bool{"b": true, "c": true}},
+	}
+	for _, c := range cases {
+		got, exact := x.plan(c.f)
+		if !exact || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%#v: expected %v, got %v (exact %v)", c.f, c.want, got, exact)
+		}
+	}
+
+	x.remove("b")
+	if got, _ := x.plan(filter.Eq{Field: "n", Value: "3"}); len(got) != 0 {
+		t.Errorf("Expected removed document to disappear, got %v", got)
+	}
+	if len(x.postings) != 2 {
+		t.Errorf("Expected empty postings to be dropped, got %v", x.postings)
+	}
+}
+
+func TestTextRequiresAllTokens(t *testing.T) {
+	x := newInverted(true)
+	x.add("a", []interface{}{"Graph based ANN search"})
+	x.add("b", []interface{}{"Full-text search"})
+
+	got, _ := x.plan(filter.Match{Field: "t", Text: "SEARCH graph"})
+	if !reflect.DeepEqual(got, map[string]bool{"a": true}) {
+		t.Errorf("Expected only a, got %v", got)
+	}
+	if got, _ := x.plan(filter.Match{Field: "t", Text: "  "}); got != nil {
+		t.Errorf("Expected no plan for an empty query, got %v", got)
+	}
+	if got, _ := x.plan(filter.Eq{Field: "t", Value: "Full-text search"}); got != nil {
+		t.Errorf("Expected text index not to answer equality, got %v", got)
+	}
+}
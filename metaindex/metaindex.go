@@ -0,0 +1,194 @@
+// Package metaindex 在文档 Payload 上维护二级索引，为过滤条件生成候选 ID 集合
+package metaindex
+
+import (
+	"fmt"
+	"sync"
+
+	"gvdb/filter"
+)
+
+// Kind 为索引类型
+type Kind string
+
+const (
+	Keyword Kind = "keyword" // 倒排索引，支持等值和 IN
+	Int     Kind = "int"     // 有序索引，支持整数的范围查询
+	Float   Kind = "float"   // 有序索引，支持任意数字的范围查询
+	Text    Kind = "text"    // 分词倒排索引，支持全文匹配
+)
+
+// Field 声明一个需要索引的字段，Name 可以是 "a.b" 形式的嵌套路径
+type Field struct {
+	Name string
+	Kind Kind
+}
+
+type fieldIndex interface {
+	add(id string, values []interface{})
+	remove(id string)
+	// plan 与 Indexes.Plan 的约定相同：ids 为 nil 表示该索引无法回答 f
+	plan(f filter.Filter) (ids map[string]bool, exact bool)
+}
+
+// Indexes 是一组字段索引，可以并发使用
+type Indexes struct {
+	fields map[string]fieldIndex
+	ids    map[string]bool // 所有已加入的文档，用于计算 NOT 的补集
+	mutex  sync.RWMutex
+}
+
+func New(fields []Field) (*Indexes, error) {
+	x := &Indexes{fields: make(map[string]fieldIndex, len(fields)), ids: make(map[string]bool)}
+	for _, f := range fields {
+		if f.Name == "" {
+			return nil, fmt.Errorf("metadata index requires a field name")
+		}
+		if _, dup := x.fields[f.Name]; dup {
+			return nil, fmt.Errorf("duplicate metadata index on %s", f.Name)
+		}
+		switch f.Kind {
+		case Keyword:
+			x.fields[f.Name] = newInverted(false)
+		case Text:
+			x.fields[f.Name] = newInverted(true)
+		case Int:
+			x.fields[f.Name] = newNumeric(true)
+		case Float:
+			x.fields[f.Name] = newNumeric(false)
+		default:
+			return nil, fmt.Errorf("unknown metadata index type %q for %s", f.Kind, f.Name)
+		}
+	}
+	return x, nil
+}
+
+// Add 索引文档，id 已存在时替换旧值
+func (x *Indexes) Add(id string, doc filter.Document) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.ids[id] = true
+	for name, fi := range x.fields {
+		fi.remove(id)
+		if values := lookup(doc, name); len(values) > 0 {
+			fi.add(id, values)
+		}
+	}
+}
+
+func (x *Indexes) Remove(id string) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	delete(x.ids, id)
+	for _, fi := range x.fields {
+		fi.remove(id)
+	}
+}
+
+func (x *Indexes) Len() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return len(x.ids)
+}
+
+// Plan 用索引求出可能匹配 f 的文档 ID。ids 为 nil 表示没有可用的索引，调用方需要扫描全部文档；
+// exact 为 true 时 ids 恰好是匹配的文档，否则 ids 是候选超集，还需用 f.Match 逐个确认
+func (x *Indexes) Plan(f filter.Filter) (ids map[string]bool, exact bool) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return x.plan(f)
+}
+
+func (x *Indexes) plan(f filter.Filter) (map[string]bool, bool) {
+	switch f := f.(type) {
+	case filter.And:
+		var ids map[string]bool
+		exact := true
+		for _, sub := range f {
+			got, subExact := x.plan(sub)
+			if got == nil {
+				exact = false
+				continue
+			}
+			exact = exact && subExact
+			if ids == nil {
+				ids = got
+			} else {
+				ids = intersect(ids, got)
+			}
+		}
+		if ids == nil {
+			return nil, false
+		}
+		return ids, exact
+	case filter.Or:
+		ids := make(map[string]bool)
+		exact := true
+		for _, sub := range f {
+			got, subExact := x.plan(sub)
+			if got == nil {
+				return nil, false
+			}
+			exact = exact && subExact
+			for id := range got {
+				ids[id] = true
+			}
+		}
+		return ids, exact
+	case filter.Not:
+		// 候选超集的补集不再是超集，只能对精确结果取补
+		got, subExact := x.plan(f.Filter)
+		if got == nil || !subExact {
+			return nil, false
+		}
+		ids := make(map[string]bool, len(x.ids))
+		for id := range x.ids {
+			if !got[id] {
+				ids[id] = true
+			}
+		}
+		return ids, true
+	case filter.Eq:
+		return x.planField(f.Field, f)
+	case filter.In:
+		return x.planField(f.Field, f)
+	case filter.Range:
+		return x.planField(f.Field, f)
+	case filter.Match:
+		return x.planField(f.Field, f)
+	}
+	return nil, false
+}
+
+func (x *Indexes) planField(field string, f filter.Filter) (map[string]bool, bool) {
+	fi, ok := x.fields[field]
+	if !ok {
+		return nil, false
+	}
+	return fi.plan(f)
+}
+
+// lookup 取出字段的值，数组字段展开为各个元素，与 filter 中数组任一元素匹配的语义一致
+func lookup(doc filter.Document, field string) []interface{} {
+	v, ok := doc.Lookup(field)
+	if !ok {
+		return nil
+	}
+	if arr, ok := v.([]interface{}); ok {
+		return arr
+	}
+	return []interface{}{v}
+}
+
+func intersect(a, b map[string]bool) map[string]bool {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	ids := make(map[string]bool, len(a))
+	for id := range a {
+		if b[id] {
+			ids[id] = true
+		}
+	}
+	return ids
+}
@@ -0,0 +1,115 @@
+package metaindex
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"gvdb/filter"
+)
+
+func randomDoc(rng *rand.Rand) filter.Document {
+	langs := []string{"go", "rust", "python"}
+	words := []string{"vector", "search", "graph", "index", "数据"}
+	doc := filter.Document{
+		"lang":  langs[rng.Intn(len(langs))],
+		"year":  int64(2015 + rng.Intn(10)),
+		"score": rng.Float64(),
+		"title": words[rng.Intn(len(words))] + " " + words[rng.Intn(len(words))],
+		"tags":  []interface{}{langs[rng.Intn(len(langs))], langs[rng.Intn(len(langs))]},
+		"extra": rng.Intn(3),
+	}
+	if rng.Intn(10) == 0 {
+		delete(doc, "year")
+	}
+	if rng.Intn(20) == 0 {
+		doc["year"] = 2020.5 // int 索引无法精确表示，作为候选返回
+	}
+	return doc
+}
+
+func TestPlan(t *testing.T) {
+	x, err := New([]Field{
+		{Name: "lang", Kind: Keyword},
+		{Name: "tags", Kind: Keyword},
+		{Name: "year", Kind: Int},
+		{Name: "score", Kind: Float},
+		{Name: "title", Kind: Text},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(4))
+	docs := make(map[string]filter.Document)
+	// 超过 maxDelta 次写入，覆盖合并和删除后的过期条目
+	for i := 0; i < 3000; i++ {
+		id := fmt.Sprintf("id%d", rng.Intn(1500))
+		if rng.Intn(5) == 0 {
+			x.Remove(id)
+			delete(docs, id)
+			continue
+		}
+		doc := randomDoc(rng)
+		x.Add(id, doc)
+		docs[id] = doc
+	}
+	if x.Len() != len(docs) {
+		t.Fatalf("Expected %d documents, got %d", len(docs), x.Len())
+	}
+
+	cases := []struct {
+		f       filter.Filter
+		indexed bool
+	}{
+		{filter.Eq{Field: "lang", Value: "go"}, true},
+		{filter.In{Field: "tags", Values: []interface{}{"rust", "python"}}, true},
+		{filter.Between("year", 2017, 2019), true},
+		{filter.Gt("score", 0.9), true},
+		{filter.Eq{Field: "year", Value: 2020.0}, true},
+		{filter.Match{Field: "title", Text: "Vector GRAPH"}, true},
+		{filter.Match{Field: "title", Text: "数"}, true},
+		{filter.Not{Filter: filter.Eq{Field: "lang", Value: "go"}}, true},
+		{filter.Or{filter.Eq{Field: "lang", Value: "go"}, filter.Lt("score", 0.1)}, true},
+		{filter.And{filter.Eq{Field: "lang", Value: "rust"}, filter.Eq{Field: "extra", Value: 1}}, true},
+		{filter.Eq{Field: "extra", Value: 1}, false},
+		{filter.Or{filter.Eq{Field: "lang", Value: "go"}, filter.Eq{Field: "extra", Value: 1}}, false},
+		{filter.Gt("lang", "a"), false},
+	}
+	for _, c := range cases {
+		ids, exact := x.Plan(c.f)
+		if ids == nil {
+			if c.indexed {
+				t.Errorf("%#v: expected an index plan", c.f)
+			}
+			continue
+		}
+		if !c.indexed {
+			t.Errorf("%#v: expected no index plan", c.f)
+		}
+		for id, doc := range docs {
+			if c.f.Match(doc) && !ids[id] {
+				t.Errorf("%#v: missing matching document %s", c.f, id)
+			}
+			if exact && !c.f.Match(doc) && ids[id] {
+				t.Errorf("%#v: exact plan contains non-matching document %s", c.f, id)
+			}
+		}
+		for id := range ids {
+			if _, ok := docs[id]; !ok {
+				t.Errorf("%#v: plan contains removed document %s", c.f, id)
+			}
+		}
+	}
+}
+
+func TestNewRejectsInvalidFields(t *testing.T) {
+	for _, fields := range [][]Field{
+		{{Name: "", Kind: Keyword}},
+		{{Name: "a", Kind: "geo"}},
+		{{Name: "a", Kind: Keyword}, {Name: "a", Kind: Text}},
+	} {
+		if _, err := New(fields); err == nil {
+			t.Errorf("Expected error for %v", fields)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package metaindex
+
+import (
+	"math"
+	"sort"
+
+	"gvdb/filter"
+)
+
+// maxDelta 为增量缓冲区的上限，超过后与有序数组合并
+const maxDelta = 1024
+
+type numericEntry struct {
+	key float64
+	id  string
+}
+
+// numeric 是数字字段的有序索引：主体是按键排序的数组，范围查询用二分查找定位；
+// 新增条目先追加到未排序的增量缓冲区，删除只更新 keys，过期条目在查询时跳过并在合并时清理
+type numeric struct {
+	integer bool
+	keys    map[string][]float64 // 文档当前的键
+	stray   map[string]bool      // int 索引中含非整数值的文档，查询时作为候选返回
+	sorted  []numericEntry
+	delta   []numericEntry
+	stale   int
+}
+
+func newNumeric(integer bool) *numeric {
+	return &numeric{integer: integer, keys: make(map[string][]float64), stray: make(map[string]bool)}
+}
+
+func (x *numeric) add(id string, values []interface{}) {
+	var keys []float64
+	for _, v := range values {
+		n, ok := filter.Number(v)
+		if !ok || math.IsNaN(n) {
+			continue
+		}
+		if x.integer && n != math.Trunc(n) {
+			x.stray[id] = true
+			continue
+		}
+		keys = append(keys, n)
+		x.delta = append(x.delta, numericEntry{key: n, id: id})
+	}
+	if len(keys) > 0 {
+		x.keys[id] = keys
+	}
+	if len(x.delta) >= maxDelta {
+		x.compact()
+	}
+}
+
+func (x *numeric) remove(id string) {
+	x.stale += len(x.keys[id])
+	delete(x.keys, id)
+	delete(x.stray, id)
+	if x.stale >= maxDelta && x.stale*2 > len(x.sorted) {
+		x.compact()
+	}
+}
+
+// compact 用当前的键重建有序数组
+func (x *numeric) compact() {
+	sorted := make([]numericEntry, 0, len(x.sorted)+len(x.delta))
+	for id, keys := range x.keys {
+		for _, k := range keys {
+			sorted = append(sorted, numericEntry{key: k, id: id})
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].key != sorted[j].key {
+			return sorted[i].key < sorted[j].key
+		}
+		return sorted[i].id < sorted[j].id
+	})
+	x.sorted, x.delta, x.stale = sorted, nil, 0
+}
+
+func (x *numeric) plan(f filter.Filter) (map[string]bool, bool) {
+	switch f := f.(type) {
+	case filter.Eq:
+		if n, ok := filter.Number(f.Value); ok {
+			return x.between(n, n, false, false)
+		}
+	case filter.In:
+		ids := make(map[string]bool)
+		for _, v := range f.Values {
+			n, ok := filter.Number(v)
+			if !ok {
+				return nil, false
+			}
+			got, _ := x.between(n, n, false, false)
+			for id := range got {
+				ids[id] = true
+			}
+		}
+		return ids, len(x.stray) == 0
+	case filter.Range:
+		if f.Min == nil && f.Max == nil {
+			// 无界区间也匹配字符串等非数字值
+			return nil, false
+		}
+		lo, hi := math.Inf(-1), math.Inf(1)
+		if f.Min != nil {
+			n, ok := filter.Number(f.Min)
+			if !ok {
+				return nil, false
+			}
+			lo = n
+		}
+		if f.Max != nil {
+			n, ok := filter.Number(f.Max)
+			if !ok {
+				return nil, false
+			}
+			hi = n
+		}
+		return x.between(lo, hi, f.MinExclusive, f.MaxExclusive)
+	}
+	return nil, false
+}
+
+func (x *numeric) between(lo, hi float64, loExclusive, hiExclusive bool) (map[string]bool, bool) {
+	in := func(k float64) bool {
+		return (k > lo || (k == lo && !loExclusive)) && (k < hi || (k == hi && !hiExclusive))
+	}
+	ids := make(map[string]bool)
+	start := sort.Search(len(x.sorted), func(i int) bool {
+		k := x.sorted[i].key
+		return k > lo || (k == lo && !loExclusive)
+	})
+	for _, e := range x.sorted[start:] {
+		if !in(e.key) {
+			break
+		}
+		if x.valid(e) {
+			ids[e.id] = true
+		}
+	}
+	for _, e := range x.delta {
+		if in(e.key) && x.valid(e) {
+			ids[e.id] = true
+		}
+	}
+	for id := range x.stray {
+		ids[id] = true
+	}
+	return ids, len(x.stray) == 0
+}
+
+// valid 判断条目是否仍是文档的当前值
+func (x *numeric) valid(e numericEntry) bool {
+	for _, k := range x.keys[e.id] {
+		if k == e.key {
+			return true
+		}
+	}
+	return false
+}
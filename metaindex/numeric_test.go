@@ -0,0 +1,52 @@
+package metaindex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gvdb/filter"
+)
+
+func TestNumericRange(t *testing.T) {
+	x := newNumeric(true)
+	for i := 0; i < 3*maxDelta; i++ {
+		x.add(fmt.Sprintf("id%d", i), []interface{}{int64(i)})
+	}
+	if len(x.delta) >= maxDelta || len(x.sorted) < 2*maxDelta {
+		t.Fatalf("Expected delta to be merged, sorted=%d delta=%d", len(x.sorted), len(x.delta))
+	}
+
+	got, exact := x.plan(filter.Range{Field: "n", Min: 10, Max: 13, MinExclusive: true})
+	if !exact || len(got) != 3 {
+		t.Errorf("Expected 3 exact matches in (10, 13], got %v", got)
+	}
+	if got, _ := x.plan(filter.Gte("n", 3*maxDelta-1)); len(got) != 1 {
+		t.Errorf("Expected the last key only, got %v", got)
+	}
+	if got, _ := x.plan(filter.Range{Field: "n", Min: "a"}); got != nil {
+		t.Errorf("Expected no plan for string bounds, got %v", got)
+	}
+}
+
+func TestNumericUpdateAndStray(t *testing.T) {
+	x := newNumeric(true)
+	x.add("a", []interface{}{int64(1)})
+	x.add("b", []interface{}{int64(2), 2.5})
+	// 更新 a：旧键失效
+	x.remove("a")
+	x.add("a", []interface{}{int64(5)})
+	x.compact()
+	x.remove("a")
+	x.add("a", []interface{}{int64(7)})
+
+	got, exact := x.plan(filter.Lte("n", 5))
+	if exact || !reflect.DeepEqual(got, map[string]bool{"b": true}) {
+		t.Errorf("Expected inexact {b}, got %v (exact %v)", got, exact)
+	}
+	x.remove("b")
+	got, exact = x.plan(filter.Gt("n", 1))
+	if !exact || !reflect.DeepEqual(got, map[string]bool{"a": true}) {
+		t.Errorf("Expected exact {a}, got %v (exact %v)", got, exact)
+	}
+}
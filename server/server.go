@@ -0,0 +1,223 @@
+// Package server 以 REST/JSON 接口提供 service 层的功能，接口说明见 doc/rest-api.md
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gvdb/config"
+	"gvdb/service"
+)
+
+// MaxBodyBytes 为请求体的大小上限
+const MaxBodyBytes = 64 << 20
+
+type Server struct {
+	svc *service.Service
+	mux *http.ServeMux
+}
+
+func New(svc *service.Service) *Server {
+	s := &Server{svc: svc, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /healthz", s.health)
+	s.mux.HandleFunc("GET /collections", s.listCollections)
+	s.mux.HandleFunc("POST /collections", s.createCollection)
+	s.mux.HandleFunc("GET /collections/{collection}", s.describeCollection)
+	s.mux.HandleFunc("DELETE /collections/{collection}", s.dropCollection)
+	s.mux.HandleFunc("PUT /collections/{collection}/points", s.upsert)
+	s.mux.HandleFunc("POST /collections/{collection}/points/delete", s.deleteBatch)
+	s.mux.HandleFunc("GET /collections/{collection}/points/{id}", s.get)
+	s.mux.HandleFunc("DELETE /collections/{collection}/points/{id}", s.delete)
+	s.mux.HandleFunc("POST /collections/{collection}/search", s.search)
+	s.mux.HandleFunc("POST /collections/{collection}/search/batch", s.searchBatch)
+	s.mux.HandleFunc("POST /collections/{collection}/query", s.query)
+	s.mux.HandleFunc("GET /snapshot", s.snapshot)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) listCollections(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{"collections": s.svc.ListCollections()})
+}
+
+func (s *Server) createCollection(w http.ResponseWriter, r *http.Request) {
+	var cc config.CollectionConfig
+	if !decode(w, r, &cc) {
+		return
+	}
+	info, err := s.svc.CreateCollection(cc)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, info)
+}
+
+func (s *Server) describeCollection(w http.ResponseWriter, r *http.Request) {
+	info, err := s.svc.DescribeCollection(r.PathValue("collection"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) dropCollection(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.DropCollection(r.PathValue("collection")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) upsert(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Points []service.Point `json:"points"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	n, err := s.svc.Upsert(r.PathValue("collection"), req.Points)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"upserted": n})
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	p, err := s.svc.Get(r.PathValue("collection"), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	collection, id := r.PathValue("collection"), r.PathValue("id")
+	n, err := s.svc.Delete(collection, []string{id})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if n == 0 {
+		writeError(w, &service.Error{Code: service.NotFound, Message: fmt.Sprintf("point %s not found in collection %s", id, collection)})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	n, err := s.svc.Delete(r.PathValue("collection"), req.IDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": n})
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	var req service.SearchRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	hits, err := s.svc.Search(r.PathValue("collection"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]service.Hit{"hits": hits})
+}
+
+func (s *Server) searchBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Searches []service.SearchRequest `json:"searches"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	results, err := s.svc.SearchBatch(r.PathValue("collection"), req.Searches)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][][]service.Hit{"results": results})
+}
+
+func (s *Server) query(w http.ResponseWriter, r *http.Request) {
+	var req service.QueryRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	hits, err := s.svc.Query(r.PathValue("collection"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]service.Hit{"hits": hits})
+}
+
+// snapshot 以 zip 格式返回快照；开始写出后出错只能中断响应，客户端读取归档时会发现不完整
+func (s *Server) snapshot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="gvdb-snapshot.zip"`)
+	if _, err := s.svc.Snapshot(w); err != nil {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// decode 解析 JSON 请求体，拒绝未知字段；失败时已写入 400 响应
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorBody("invalid_argument", "request body too large"))
+			return false
+		}
+		writeJSON(w, http.StatusBadRequest, errorBody("invalid_argument", "invalid request body: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+var statusCodes = map[service.Code]int{
+	service.Internal:           http.StatusInternalServerError,
+	service.InvalidArgument:    http.StatusBadRequest,
+	service.NotFound:           http.StatusNotFound,
+	service.AlreadyExists:      http.StatusConflict,
+	service.FailedPrecondition: http.StatusConflict,
+	service.Unavailable:        http.StatusServiceUnavailable,
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := service.CodeOf(err)
+	writeJSON(w, statusCodes[code], errorBody(code.String(), err.Error()))
+}
+
+func errorBody(code, message string) map[string]interface{} {
+	return map[string]interface{}{"error": map[string]string{"code": code, "message": message}}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
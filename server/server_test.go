@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvdb/config"
+	"gvdb/service"
+	"gvdb/vectordb"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	dir := t.TempDir()
+	var cfg config.Config
+	cfg.Storage.Type = "file"
+	cfg.Storage.File.Enable = true
+	cfg.Storage.File.Path = filepath.Join(dir, "vectors.json")
+	cfg.HNSW = config.HNSWConfig{Dim: 3, M: 16, EF: 64, EFConstruction: 64, EFSearch: 64, Metric: "l2"}
+	cfg.Index.Type = "hnsw"
+	cfg.Search.BruteForceRatio = 0.01
+	cfg.Catalog = filepath.Join(dir, "collections.yaml")
+	db, err := vectordb.NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(New(service.New(db)))
+	t.Cleanup(func() {
+		ts.Close()
+		db.Close()
+	})
+	return ts
+}
+
+// do 发送请求并把响应体解码到 out，返回状态码
+func do(t *testing.T, ts *httptest.Server, method, path, body string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	ts := newTestServer(t)
+
+	var created service.CollectionInfo
+	status := do(t, ts, "POST", "/collections", `{"name": "images", "hnsw": {"dim": 2, "metric": "cosine"}, "index": {"type": "flat"}}`, &created)
+	if status != http.StatusCreated || created.Dim != 2 || created.IndexType != "flat" {
+		t.Fatalf("Create collection: %d %+v", status, created)
+	}
+	var list struct{ Collections []string }
+	do(t, ts, "GET", "/collections", "", &list)
+	if strings.Join(list.Collections, ",") != "default,images" {
+		t.Errorf("Unexpected collections: %v", list.Collections)
+	}
+
+	var upserted map[string]int
+	status = do(t, ts, "PUT", "/collections/images/points", `{"points": [
+		{"id": "a", "vector": [1, 0], "payload": {"tag": "x"}},
+		{"id": "b", "vector": [0, 1], "payload": {"tag": "y"}}
+	]}`, &upserted)
+	if status != http.StatusOK || upserted["upserted"] != 2 {
+		t.Fatalf("Upsert: %d %v", status, upserted)
+	}
+
+	var point service.Point
+	if status := do(t, ts, "GET", "/collections/images/points/a", "", &point); status != http.StatusOK || point.Payload["tag"] != "x" {
+		t.Errorf("Get: %d %+v", status, point)
+	}
+
+	var search struct{ Hits []service.Hit }
+	do(t, ts, "POST", "/collections/images/search", `{"vector": [0.2, 1], "limit": 1}`, &search)
+	if len(search.Hits) != 1 || search.Hits[0].ID != "b" {
+		t.Errorf("Unexpected search hits: %+v", search.Hits)
+	}
+	do(t, ts, "POST", "/collections/images/search", `{"vector": [0.2, 1], "filter": {"tag": "x"}}`, &search)
+	if len(search.Hits) != 1 || search.Hits[0].ID != "a" {
+		t.Errorf("Unexpected filtered hits: %+v", search.Hits)
+	}
+	var batch struct{ Results [][]service.Hit }
+	do(t, ts, "POST", "/collections/images/search/batch", `{"searches": [{"vector": [1, 0], "limit": 1}, {"vector": [0, 1], "limit": 1}]}`, &batch)
+	if len(batch.Results) != 2 || batch.Results[0][0].ID != "a" || batch.Results[1][0].ID != "b" {
+		t.Errorf("Unexpected batch results: %+v", batch.Results)
+	}
+	var query struct{ Hits []service.Hit }
+	do(t, ts, "POST", "/collections/images/query", `{"filter": {"tag": {"$in": ["x", "y"]}}}`, &query)
+	if len(query.Hits) != 2 {
+		t.Errorf("Unexpected query hits: %+v", query.Hits)
+	}
+
+	if status := do(t, ts, "DELETE", "/collections/images/points/a", "", nil); status != http.StatusNoContent {
+		t.Errorf("Delete: expected 204, got %d", status)
+	}
+	var deleted map[string]int
+	do(t, ts, "POST", "/collections/images/points/delete", `{"ids": ["a", "b"]}`, &deleted)
+	if deleted["deleted"] != 1 {
+		t.Errorf("Batch delete: %v", deleted)
+	}
+	if status := do(t, ts, "DELETE", "/collections/images", "", nil); status != http.StatusNoContent {
+		t.Errorf("Drop collection: expected 204, got %d", status)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	ts := newTestServer(t)
+	cases := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"GET", "/collections/missing", "", http.StatusNotFound, "not_found"},
+		{"GET", "/collections/default/points/missing", "", http.StatusNotFound, "not_found"},
+		{"DELETE", "/collections/default/points/missing", "", http.StatusNotFound, "not_found"},
+		{"PUT", "/collections/default/points", `{"points": [{"id": "a", "vector": [1]}]}`, http.StatusBadRequest, "invalid_argument"},
+		{"PUT", "/collections/default/points", `{"points": [`, http.StatusBadRequest, "invalid_argument"},
+		{"PUT", "/collections/default/points", `{"docs": []}`, http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/collections/default/search", `{"vector": [1, 2, 3], "filter": {"$xor": []}}`, http.StatusBadRequest, "invalid_argument"},
+		{"POST", "/collections", `{"name": "default", "hnsw": {"dim": 3}}`, http.StatusConflict, "already_exists"},
+		{"DELETE", "/collections/default", "", http.StatusConflict, "failed_precondition"},
+	}
+	for _, c := range cases {
+		var body struct {
+			Error struct{ Code, Message string }
+		}
+		status := do(t, ts, c.method, c.path, c.body, &body)
+		if status != c.status || body.Error.Code != c.code {
+			t.Errorf("%s %s: expected %d %s, got %d %+v", c.method, c.path, c.status, c.code, status, body.Error)
+		}
+	}
+	if status := do(t, ts, "PATCH", "/collections", "", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for unsupported method, got %d", status)
+	}
+}
+
+func TestServerSnapshot(t *testing.T) {
+	ts := newTestServer(t)
+	do(t, ts, "PUT", "/collections/default/points", `{"points": [{"id": "a", "vector": [1, 0, 0]}]}`, nil)
+	resp, err := http.Get(ts.URL + "/snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /snapshot: %d, %v", resp.StatusCode, err)
+	}
+	m, err := vectordb.ReadSnapshotManifest(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Collections) != 1 || m.Collections[0].Count != 1 {
+		t.Errorf("Unexpected snapshot manifest: %+v", m)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"gvdb/storage"
+	"gvdb/vectordb"
+)
+
+// Code 为与传输协议无关的错误类别，HTTP 和 gRPC 前端各自映射为状态码
+type Code int
+
+const (
+	Internal Code = iota
+	InvalidArgument
+	NotFound
+	AlreadyExists
+	FailedPrecondition
+	Unavailable
+)
+
+var codeNames = map[Code]string{
+	Internal:           "internal",
+	InvalidArgument:    "invalid_argument",
+	NotFound:           "not_found",
+	AlreadyExists:      "already_exists",
+	FailedPrecondition: "failed_precondition",
+	Unavailable:        "unavailable",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("code(%d)", int(c))
+}
+
+// ParseCode 是 Code.String 的逆操作，客户端用它还原响应中的错误类别
+func ParseCode(name string) (Code, bool) {
+	for code, n := range codeNames {
+		if n == name {
+			return code, true
+		}
+	}
+	return Internal, false
+}
+
+// Error 是服务层返回的错误
+type Error struct {
+	Code    Code
+	Message string
+	Err     error // 原始错误，可能为 nil
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func errorf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// CodeOf 返回错误的类别，非服务层错误按 wrap 前的原因分类
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return classify(err)
+}
+
+// wrap 把 vectordb 和存储层的错误转为服务层错误
+func wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Code: classify(err), Message: err.Error(), Err: err}
+}
+
+func classify(err error) Code {
+	switch {
+	case errors.Is(err, vectordb.ErrCollectionNotFound):
+		return NotFound
+	case errors.Is(err, vectordb.ErrCollectionExists), errors.Is(err, storage.ErrExists):
+		return AlreadyExists
+	case errors.Is(err, vectordb.ErrCollectionDeclared):
+		return FailedPrecondition
+	case errors.Is(err, vectordb.ErrDimensionMismatch):
+		return InvalidArgument
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		// 数据库连接断开，客户端可以重试
+		return Unavailable
+	}
+	return Internal
+}
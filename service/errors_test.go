@@ -0,0 +1,47 @@
+package service
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gvdb/vectordb"
+)
+
+func TestCodeOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		want Code
+	}{
+		{fmt.Errorf("%w: x", vectordb.ErrCollectionNotFound), NotFound},
+		{fmt.Errorf("%w: x", vectordb.ErrCollectionExists), AlreadyExists},
+		{fmt.Errorf("%w: x", vectordb.ErrCollectionDeclared), FailedPrecondition},
+		{fmt.Errorf("insert: %w", vectordb.ErrDimensionMismatch), InvalidArgument},
+		{fmt.Errorf("insert: %w", driver.ErrBadConn), Unavailable},
+		{errors.New("disk full"), Internal},
+		{errorf(NotFound, "point missing"), NotFound},
+	}
+	for _, c := range cases {
+		if got := CodeOf(c.err); got != c.want {
+			t.Errorf("CodeOf(%v) = %v, want %v", c.err, got, c.want)
+		}
+		if got := CodeOf(wrap(c.err)); got != c.want {
+			t.Errorf("CodeOf(wrap(%v)) = %v, want %v", c.err, got, c.want)
+		}
+	}
+	if wrap(nil) != nil {
+		t.Error("Expected wrap(nil) to be nil")
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	for code := range codeNames {
+		if got, ok := ParseCode(code.String()); !ok || got != code {
+			t.Errorf("ParseCode(%q) = %v, %v", code.String(), got, ok)
+		}
+	}
+	if _, ok := ParseCode("bogus"); ok {
+		t.Error("Expected ParseCode to reject unknown names")
+	}
+}
@@ -0,0 +1,263 @@
+// Package service 是 HTTP 等网络前端共用的业务层：校验请求、调用 vectordb，并把错误归类为 Code
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"path/filepath"
+
+	"gvdb/config"
+	"gvdb/filter"
+	"gvdb/storage"
+	"gvdb/vectordb"
+)
+
+const (
+	DefaultLimit = 10    // 搜索未指定 limit 时返回的结果数
+	MaxLimit     = 10000 // 单次搜索或查询最多返回的结果数
+)
+
+// Point 是网络接口中的一条向量文档
+type Point struct {
+	ID      string          `json:"id"`
+	Vector  []float32       `json:"vector"`
+	Meta    string          `json:"meta,omitempty"`
+	Payload storage.Payload `json:"payload,omitempty"`
+}
+
+// SearchRequest 为一次向量搜索，Filter 使用 filter.Parse 的 JSON 语法
+type SearchRequest struct {
+	Vector []float32       `json:"vector"`
+	Limit  int             `json:"limit,omitempty"`
+	Filter json.RawMessage `json:"filter,omitempty"`
+}
+
+// QueryRequest 为不带查询向量的过滤查询，Limit 为 0 时使用 MaxLimit
+type QueryRequest struct {
+	Filter json.RawMessage `json:"filter,omitempty"`
+	Limit  int             `json:"limit,omitempty"`
+}
+
+type Hit struct {
+	ID      string          `json:"id"`
+	Score   float32         `json:"score"`
+	Meta    string          `json:"meta,omitempty"`
+	Payload storage.Payload `json:"payload,omitempty"`
+}
+
+// CollectionInfo 是 vectordb.CollectionInfo 的 JSON 形式
+type CollectionInfo struct {
+	Name      string                  `json:"name"`
+	Dim       int                     `json:"dim"`
+	Metric    string                  `json:"metric"`
+	IndexType string                  `json:"index_type"`
+	Count     int                     `json:"count"`
+	Config    config.CollectionConfig `json:"config"`
+}
+
+type Service struct {
+	db *vectordb.VectorDB
+}
+
+func New(db *vectordb.VectorDB) *Service {
+	return &Service{db: db}
+}
+
+func (s *Service) ListCollections() []string {
+	return s.db.ListCollections()
+}
+
+// CreateCollection 创建集合；通过网络创建的集合只能使用相对路径保存索引文件
+func (s *Service) CreateCollection(cc config.CollectionConfig) (CollectionInfo, error) {
+	if err := cc.Normalize(); err != nil {
+		return CollectionInfo{}, errorf(InvalidArgument, "%v", err)
+	}
+	for _, path := range []string{cc.HNSW.IndexPath, cc.Index.Disk.Path} {
+		if path != "" && !filepath.IsLocal(path) {
+			return CollectionInfo{}, errorf(InvalidArgument, "index path %q must be relative to the server directory", path)
+		}
+	}
+	c, err := s.db.CreateCollection(cc)
+	if err != nil {
+		return CollectionInfo{}, wrap(err)
+	}
+	return infoOf(c.Info()), nil
+}
+
+func (s *Service) DropCollection(name string) error {
+	return wrap(s.db.DropCollection(name))
+}
+
+func (s *Service) DescribeCollection(name string) (CollectionInfo, error) {
+	info, err := s.db.DescribeCollection(name)
+	if err != nil {
+		return CollectionInfo{}, wrap(err)
+	}
+	return infoOf(info), nil
+}
+
+// Snapshot 把整个数据库的快照写入 w，格式见 vectordb.Snapshot
+func (s *Service) Snapshot(w io.Writer) (vectordb.SnapshotManifest, error) {
+	m, err := s.db.Snapshot(w)
+	return m, wrap(err)
+}
+
+// Upsert 先校验全部文档再整批原子写入，失败时不写入任何文档并返回 0
+func (s *Service) Upsert(collection string, points []Point) (int, error) {
+	c, err := s.db.GetCollection(collection)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	if len(points) == 0 {
+		return 0, errorf(InvalidArgument, "no points to upsert")
+	}
+	for i, p := range points {
+		if p.ID == "" {
+			return 0, errorf(InvalidArgument, "points[%d]: id is required", i)
+		}
+		if err := checkVector(p.Vector, c.Dim()); err != nil {
+			return 0, errorf(InvalidArgument, "points[%d]: %v", i, err.Message)
+		}
+	}
+	docs := make(map[string]storage.VectorDoc, len(points))
+	for _, p := range points {
+		docs[p.ID] = storage.VectorDoc{Vector: p.Vector, Meta: p.Meta, Payload: p.Payload}
+	}
+	if err := c.UpsertBatch(docs); err != nil {
+		return 0, wrap(err)
+	}
+	return len(points), nil
+}
+
+func (s *Service) Get(collection, id string) (Point, error) {
+	c, err := s.db.GetCollection(collection)
+	if err != nil {
+		return Point{}, wrap(err)
+	}
+	doc, ok := c.Get(id)
+	if !ok {
+		return Point{}, errorf(NotFound, "point %s not found in collection %s", id, collection)
+	}
+	return Point{ID: id, Vector: doc.Vector, Meta: doc.Meta, Payload: doc.Payload}, nil
+}
+
+// Delete 删除文档并返回实际删除的数量，不存在的 ID 会被忽略
+func (s *Service) Delete(collection string, ids []string) (int, error) {
+	c, err := s.db.GetCollection(collection)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	deleted, err := c.DeleteBatch(ids)
+	return deleted, wrap(err)
+}
+
+func (s *Service) Search(collection string, req SearchRequest) ([]Hit, error) {
+	c, err := s.db.GetCollection(collection)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return search(c, req)
+}
+
+// SearchBatch 在同一集合中执行多个搜索，任一请求无效时整体失败
+func (s *Service) SearchBatch(collection string, reqs []SearchRequest) ([][]Hit, error) {
+	c, err := s.db.GetCollection(collection)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	results := make([][]Hit, len(reqs))
+	for i, req := range reqs {
+		hits, err := search(c, req)
+		if err != nil {
+			if e, ok := err.(*Error); ok {
+				return nil, errorf(e.Code, "searches[%d]: %s", i, e.Message)
+			}
+			return nil, err
+		}
+		results[i] = hits
+	}
+	return results, nil
+}
+
+func (s *Service) Query(collection string, req QueryRequest) ([]Hit, error) {
+	c, err := s.db.GetCollection(collection)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if req.Limit < 0 || req.Limit > MaxLimit {
+		return nil, errorf(InvalidArgument, "limit must be between 1 and %d", MaxLimit)
+	}
+	if req.Limit == 0 {
+		req.Limit = MaxLimit
+	}
+	f, err := parseFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return hits(c.Query(f, req.Limit)), nil
+}
+
+func search(c *vectordb.Collection, req SearchRequest) ([]Hit, error) {
+	if err := checkVector(req.Vector, c.Dim()); err != nil {
+		return nil, err
+	}
+	if req.Limit < 0 || req.Limit > MaxLimit {
+		return nil, errorf(InvalidArgument, "limit must be between 1 and %d", MaxLimit)
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultLimit
+	}
+	f, err := parseFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	results, err := c.SearchVectorFilterErr(req.Vector, req.Limit, f)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return hits(results), nil
+}
+
+func checkVector(v []float32, dim int) *Error {
+	if len(v) != dim {
+		return errorf(InvalidArgument, "vector has dimension %d, collection expects %d", len(v), dim)
+	}
+	for _, x := range v {
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return errorf(InvalidArgument, "vector contains NaN or Inf")
+		}
+	}
+	return nil
+}
+
+// parseFilter 解析 JSON 过滤条件，空值和 null 表示不过滤
+func parseFilter(raw json.RawMessage) (filter.Filter, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	f, err := filter.Parse(raw)
+	if err != nil {
+		return nil, errorf(InvalidArgument, "invalid filter: %v", err)
+	}
+	return f, nil
+}
+
+func hits(results []vectordb.SearchResult) []Hit {
+	out := make([]Hit, len(results))
+	for i, r := range results {
+		out[i] = Hit{ID: r.ID, Score: r.Score, Meta: r.Meta, Payload: r.Payload}
+	}
+	return out
+}
+
+func infoOf(info vectordb.CollectionInfo) CollectionInfo {
+	return CollectionInfo{
+		Name:      info.Name,
+		Dim:       info.Dim,
+		Metric:    info.Metric,
+		IndexType: info.IndexType,
+		Count:     info.Count,
+		Config:    info.Config,
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"gvdb/config"
+	"gvdb/storage"
+	"gvdb/vectordb"
+)
+
+func newTestService(t *testing.T) *Service {
+	dir := t.TempDir()
+	var cfg config.Config
+	cfg.Storage.Type = "file"
+	cfg.Storage.File.Enable = true
+	cfg.Storage.File.Path = filepath.Join(dir, "vectors.json")
+	cfg.HNSW = config.HNSWConfig{Dim: 3, M: 16, EF: 64, EFConstruction: 64, EFSearch: 64, Metric: "l2"}
+	cfg.Index.Type = "hnsw"
+	cfg.Search.BruteForceRatio = 0.01
+	cfg.Catalog = filepath.Join(dir, "collections.yaml")
+	db, err := vectordb.NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db)
+}
+
+func TestServicePoints(t *testing.T) {
+	svc := newTestService(t)
+	points := []Point{
+		{ID: "a", Vector: []float32{1, 0, 0}, Payload: storage.Payload{"lang": "go"}},
+		{ID: "b", Vector: []float32{0, 1, 0}, Meta: "plain"},
+		{ID: "c", Vector: []float32{0, 0, 1}, Payload: storage.Payload{"lang": "rust"}},
+	}
+	if n, err := svc.Upsert("default", points); err != nil || n != 3 {
+		t.Fatalf("Upsert: %d, %v", n, err)
+	}
+
+	p, err := svc.Get("default", "b")
+	if err != nil || p.Meta != "plain" || p.Payload["meta"] != "plain" {
+		t.Errorf("Unexpected point: %+v, %v", p, err)
+	}
+
+	hits, err := svc.Search("default", SearchRequest{Vector: []float32{1, 0.1, 0}, Limit: 2})
+	if err != nil || len(hits) != 2 || hits[0].ID != "a" {
+		t.Errorf("Unexpected hits: %+v, %v", hits, err)
+	}
+	hits, err = svc.Search("default", SearchRequest{Vector: []float32{1, 0, 0}, Filter: json.RawMessage(`{"lang": "rust"}`)})
+	if err != nil || len(hits) != 1 || hits[0].ID != "c" {
+		t.Errorf("Unexpected filtered hits: %+v, %v", hits, err)
+	}
+	batch, err := svc.SearchBatch("default", []SearchRequest{{Vector: []float32{0, 1, 0}, Limit: 1}, {Vector: []float32{0, 0, 1}, Limit: 1}})
+	if err != nil || len(batch) != 2 || batch[0][0].ID != "b" || batch[1][0].ID != "c" {
+		t.Errorf("Unexpected batch results: %+v, %v", batch, err)
+	}
+	hits, err = svc.Query("default", QueryRequest{Filter: json.RawMessage(`{"lang": {"$in": ["go", "rust"]}}`)})
+	if err != nil || len(hits) != 2 {
+		t.Errorf("Unexpected query results: %+v, %v", hits, err)
+	}
+
+	if n, err := svc.Delete("default", []string{"a", "missing"}); err != nil || n != 1 {
+		t.Errorf("Delete: %d, %v", n, err)
+	}
+	if _, err := svc.Get("default", "a"); CodeOf(err) != NotFound {
+		t.Errorf("Expected NotFound after delete, got %v", err)
+	}
+}
+
+func TestServiceValidation(t *testing.T) {
+	svc := newTestService(t)
+	nan := float32(0)
+	nan = nan / nan
+	cases := []struct {
+		name string
+		call func() error
+		want Code
+	}{
+		{"missing collection", func() error { _, err := svc.Upsert("nope", []Point{{ID: "a", Vector: []float32{1, 2, 3}}}); return err }, NotFound},
+		{"empty upsert", func() error { _, err := svc.Upsert("default", nil); return err }, InvalidArgument},
+		{"missing id", func() error { _, err := svc.Upsert("default", []Point{{Vector: []float32{1, 2, 3}}}); return err }, InvalidArgument},
+		{"wrong dim", func() error { _, err := svc.Upsert("default", []Point{{ID: "a", Vector: []float32{1, 2}}}); return err }, InvalidArgument},
+		{"nan", func() error {
+			_, err := svc.Upsert("default", []Point{{ID: "a", Vector: []float32{1, nan, 3}}})
+			return err
+		}, InvalidArgument},
+		{"bad filter", func() error {
+			_, err := svc.Search("default", SearchRequest{Vector: []float32{1, 2, 3}, Filter: json.RawMessage(`{"$xor": []}`)})
+			return err
+		}, InvalidArgument},
+		{"bad limit", func() error {
+			_, err := svc.Search("default", SearchRequest{Vector: []float32{1, 2, 3}, Limit: MaxLimit + 1})
+			return err
+		}, InvalidArgument},
+		{"bad batch", func() error {
+			_, err := svc.SearchBatch("default", []SearchRequest{{Vector: []float32{1, 2, 3}}, {Vector: []float32{1}}})
+			return err
+		}, InvalidArgument},
+		{"bad collection", func() error { _, err := svc.CreateCollection(config.CollectionConfig{Name: "Bad"}); return err }, InvalidArgument},
+		{"absolute index path", func() error {
+			_, err := svc.CreateCollection(config.CollectionConfig{Name: "x", HNSW: config.HNSWConfig{Dim: 2, IndexPath: "/tmp/x.hnsw"}})
+			return err
+		}, InvalidArgument},
+		{"drop default", func() error { return svc.DropCollection("default") }, FailedPrecondition},
+	}
+	for _, c := range cases {
+		err := c.call()
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+			continue
+		}
+		if got := CodeOf(err); got != c.want {
+			t.Errorf("%s: expected %v, got %v (%v)", c.name, c.want, got, err)
+		}
+	}
+
+	if _, err := svc.CreateCollection(config.CollectionConfig{Name: "images", HNSW: config.HNSWConfig{Dim: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateCollection(config.CollectionConfig{Name: "images", HNSW: config.HNSWConfig{Dim: 2}}); CodeOf(err) != AlreadyExists {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+}
@@ -2,92 +2,227 @@ package storage
 
 import (
 	"database/sql"
-	"encoding/json"
+	"errors"
+	"fmt"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+
+	"gvdb/vector"
 )
 
 type DuckDBStorage struct {
-	db *sql.DB
+	db    *sql.DB
+	table string
 }
 
+// NewDuckDBStorage 打开数据库并使用 vectors 表；旧表会自动添加 payload 列，并把已有的 meta 迁移到 payload
 func NewDuckDBStorage(path string) (*DuckDBStorage, error) {
+	return NewDuckDBStorageTable(path, DefaultTable)
+}
+
+// NewDuckDBStorageTable 与 NewDuckDBStorage 相同，但使用指定的表，多个集合可以共用一个数据库文件
+func NewDuckDBStorageTable(path, table string) (*DuckDBStorage, error) {
+	if !validTable(table) {
+		return nil, fmt.Errorf("invalid table name: %q", table)
+	}
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, err
 	}
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS vectors (id TEXT PRIMARY KEY, vector BLOB, meta TEXT)")
-	return &DuckDBStorage{db: db}, err
+	s := &DuckDBStorage{db: db, table: table}
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + table + " (id TEXT PRIMARY KEY, vector BLOB, meta TEXT, payload JSON)"); err != nil {
+		return s, err
+	}
+	if err := s.addPayloadColumn(); err != nil {
+		return s, err
+	}
+	return s, migrateMetaColumn(db, table, "UPDATE "+table+" SET payload = ? WHERE id = ?")
+}
+
+func (s *DuckDBStorage) addPayloadColumn() error {
+	rows, err := s.db.Query("PRAGMA table_info(" + s.table + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == "payload" {
+			return nil
+		}
+	}
+	rows.Close()
+	_, err = s.db.Exec("ALTER TABLE " + s.table + " ADD COLUMN payload JSON")
+	return err
 }
 
 func (s *DuckDBStorage) Load() (map[string]VectorDoc, error) {
-	data := make(map[string]VectorDoc)
-	rows, err := s.db.Query("SELECT id, vector, meta FROM vectors")
+	_, data, err := s.query("SELECT id, vector, meta, payload FROM " + s.table)
+	return data, err
+}
+
+// Scan 按 ID 顺序返回 after 之后的至多 limit 个文档
+func (s *DuckDBStorage) Scan(after string, limit int) ([]string, map[string]VectorDoc, error) {
+	return s.query("SELECT id, vector, meta, payload FROM "+s.table+" WHERE id > ? ORDER BY id LIMIT ?", after, limit)
+}
+
+// query 执行返回 id、vector、meta、payload 列的查询，按结果顺序返回 ID
+func (s *DuckDBStorage) query(query string, args ...interface{}) ([]string, map[string]VectorDoc, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
+	var ids []string
+	data := make(map[string]VectorDoc)
 	for rows.Next() {
-		var id, meta string
-		var vectorBlob []byte
-		if err := rows.Scan(&id, &vectorBlob, &meta); err != nil {
-			return nil, err
+		var id string
+		var meta sql.NullString
+		var vectorBlob, payloadBlob []byte
+		if err := rows.Scan(&id, &vectorBlob, &meta, &payloadBlob); err != nil {
+			return nil, nil, err
 		}
-		var vector []float64
-		if err := json.Unmarshal(vectorBlob, &vector); err != nil {
-			return nil, err
+		doc, err := decodeRow(vectorBlob, meta.String, payloadBlob)
+		if err != nil {
+			return nil, nil, err
 		}
-		data[id] = VectorDoc{Vector: vector, Meta: meta}
+		ids = append(ids, id)
+		data[id] = doc
 	}
-	return data, nil
+	return ids, data, rows.Err()
 }
 
 func (s *DuckDBStorage) Save(data map[string]VectorDoc) error {
+	return s.UpsertBatch(data)
+}
+
+// Insert 以小端序 float32 字节写入向量，旧版 JSON 编码的数据在读取时仍可解析；Payload 以 JSON 文本保存
+func (s *DuckDBStorage) Insert(id string, doc VectorDoc) error {
+	payload, err := payloadArg(doc.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT OR REPLACE INTO "+s.table+" (id, vector, meta, payload) VALUES (?, ?, ?, ?)", id, vector.Encode(doc.Vector), doc.Meta, payload)
+	return err
+}
+
+func (s *DuckDBStorage) Get(id string) (VectorDoc, bool) {
+	var meta sql.NullString
+	var vectorBlob, payloadBlob []byte
+	err := s.db.QueryRow("SELECT vector, meta, payload FROM "+s.table+" WHERE id = ?", id).Scan(&vectorBlob, &meta, &payloadBlob)
+	if err != nil {
+		return VectorDoc{}, false
+	}
+	doc, err := decodeRow(vectorBlob, meta.String, payloadBlob)
+	if err != nil {
+		return VectorDoc{}, false
+	}
+	return doc, true
+}
+
+func (s *DuckDBStorage) Delete(id string) error {
+	_, err := s.db.Exec("DELETE FROM "+s.table+" WHERE id = ?", id)
+	return err
+}
+
+// InsertBatch 在一个事务中插入，主键冲突时回滚并返回 ErrExists
+func (s *DuckDBStorage) InsertBatch(docs map[string]VectorDoc) error {
+	return s.writeBatch("INSERT INTO", docs)
+}
+
+// UpsertBatch 在一个事务中用 INSERT OR REPLACE 写入
+func (s *DuckDBStorage) UpsertBatch(docs map[string]VectorDoc) error {
+	return s.writeBatch("INSERT OR REPLACE INTO", docs)
+}
+
+func (s *DuckDBStorage) writeBatch(insert string, docs map[string]VectorDoc) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
-	stmt, err := tx.Prepare("INSERT OR REPLACE INTO vectors (id, vector, meta) VALUES (?, ?, ?)")
+	stmt, err := tx.Prepare(insert + " " + s.table + " (id, vector, meta, payload) VALUES (?, ?, ?, ?)")
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
-	for id, doc := range data {
-		vectorBlob, err := json.Marshal(doc.Vector)
+	for id, doc := range docs {
+		payload, err := payloadArg(doc.Payload)
 		if err != nil {
+			tx.Rollback()
 			return err
 		}
-		if _, err := stmt.Exec(id, vectorBlob, doc.Meta); err != nil {
+		if _, err := stmt.Exec(id, vector.Encode(doc.Vector), doc.Meta, payload); err != nil {
+			tx.Rollback()
+			var se sqlite3.Error
+			if errors.As(err, &se) && se.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+				return fmt.Errorf("%w: %s", ErrExists, id)
+			}
 			return err
 		}
 	}
 	return tx.Commit()
 }
 
-func (s *DuckDBStorage) Insert(id string, doc VectorDoc) error {
-	vectorBlob, err := json.Marshal(doc.Vector)
+// DeleteBatch 在一个事务中逐个删除
+func (s *DuckDBStorage) DeleteBatch(ids []string) (int, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = s.db.Exec("INSERT OR REPLACE INTO vectors (id, vector, meta) VALUES (?, ?, ?)", id, vectorBlob, doc.Meta)
-	return err
-}
-
-func (s *DuckDBStorage) Get(id string) (VectorDoc, bool) {
-	var vectorBlob []byte
-	var meta string
-	err := s.db.QueryRow("SELECT vector, meta FROM vectors WHERE id = ?", id).Scan(&vectorBlob, &meta)
+	stmt, err := tx.Prepare("DELETE FROM " + s.table + " WHERE id = ?")
 	if err != nil {
-		return VectorDoc{}, false
+		tx.Rollback()
+		return 0, err
 	}
-	var vector []float64
-	json.Unmarshal(vectorBlob, &vector)
-	return VectorDoc{Vector: vector, Meta: meta}, true
+	defer stmt.Close()
+	deleted := 0
+	for _, id := range ids {
+		res, err := stmt.Exec(id)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		deleted += int(n)
+	}
+	return deleted, tx.Commit()
 }
 
-func (s *DuckDBStorage) Delete(id string) error {
-	_, err := s.db.Exec("DELETE FROM vectors WHERE id = ?", id)
+func (s *DuckDBStorage) Close() error { return s.db.Close() }
+
+// Compact 执行 VACUUM，回收已删除记录占用的空间
+func (s *DuckDBStorage) Compact() error {
+	_, err := s.db.Exec("VACUUM")
 	return err
 }
 
-func (s *DuckDBStorage) Close() error { return s.db.Close() }
+// Drop 删除表并关闭数据库
+func (s *DuckDBStorage) Drop() error {
+	if _, err := s.db.Exec("DROP TABLE IF EXISTS " + s.table); err != nil {
+		s.db.Close()
+		return err
+	}
+	return s.db.Close()
+}
+
+func decodeRow(vectorBlob []byte, meta string, payloadBlob []byte) (VectorDoc, error) {
+	vec, err := vector.Decode(vectorBlob)
+	if err != nil {
+		return VectorDoc{}, err
+	}
+	payload, err := DecodePayload(payloadBlob)
+	if err != nil {
+		return VectorDoc{}, err
+	}
+	return VectorDoc{Vector: vec, Meta: meta, Payload: payload}, nil
+}
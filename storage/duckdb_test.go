@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"database/sql"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -16,7 +18,7 @@ func TestDuckDBStorage(t *testing.T) {
 	defer s.Close()
 
 	// 测试插入
-	doc := VectorDoc{Vector: []float64{1.0, 2.0, 3.0}, Meta: "test"}
+	doc := VectorDoc{Vector: []float32{1.0, 2.0, 3.0}, Meta: "test"}
 	err = s.Insert("id1", doc)
 	if err != nil {
 		t.Fatalf("Insert failed: %v", err)
@@ -40,4 +42,121 @@ func TestDuckDBStorage(t *testing.T) {
 	if exists {
 		t.Error("Expected document to be deleted")
 	}
+
+	if err := s.Compact(); err != nil {
+		t.Errorf("Compact failed: %v", err)
+	}
+}
+
+func TestDuckDBStorageBatch(t *testing.T) {
+	s, err := NewDuckDBStorage(filepath.Join(t.TempDir(), "vectors.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testBatch(t, s)
+}
+
+func TestDuckDBStorageLegacyJSONVector(t *testing.T) {
+	s, err := NewDuckDBStorage("test_vectors_legacy.db")
+	if err != nil {
+		t.Fatalf("NewDuckDBStorage failed: %v", err)
+	}
+	defer os.Remove("test_vectors_legacy.db")
+	defer s.Close()
+
+	// 旧版本以 JSON 数组保存向量
+	_, err = s.db.Exec("INSERT INTO vectors (id, vector, meta) VALUES (?, ?, ?)", "old", []byte("[1.5,2,3]"), "legacy")
+	if err != nil {
+		t.Fatalf("Failed to insert legacy row: %v", err)
+	}
+	doc, exists := s.Get("old")
+	if !exists || !reflect.DeepEqual(doc.Vector, []float32{1.5, 2, 3}) {
+		t.Errorf("Expected legacy vector to decode, got %v", doc)
+	}
+	data, err := s.Load()
+	if err != nil || len(data) != 1 {
+		t.Errorf("Expected 1 legacy document, got %d (%v)", len(data), err)
+	}
+}
+
+func TestDuckDBStoragePayload(t *testing.T) {
+	s, err := NewDuckDBStorage("test_vectors_payload.db")
+	if err != nil {
+		t.Fatalf("NewDuckDBStorage failed: %v", err)
+	}
+	defer os.Remove("test_vectors_payload.db")
+	defer s.Close()
+
+	doc := VectorDoc{Vector: []float32{1, 2, 3}, Payload: Payload{
+		"lang": "go", "year": int64(2024), "score": 0.5, "tags": []interface{}{"db", "ann"},
+		"author": map[string]interface{}{"name": "ljq"},
+	}}
+	if err := s.Insert("id1", doc); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if got, _ := s.Get("id1"); !reflect.DeepEqual(got, doc) {
+		t.Errorf("Expected doc %v, got %v", doc, got)
+	}
+	data, err := s.Load()
+	if err != nil || !reflect.DeepEqual(data["id1"], doc) {
+		t.Errorf("Expected loaded doc %v, got %v (%v)", doc, data["id1"], err)
+	}
+}
+
+func TestDuckDBStorageMigratesMeta(t *testing.T) {
+	defer os.Remove("test_vectors_migrate.db")
+
+	// 旧版表没有 payload 列
+	db, err := sql.Open("sqlite3", "test_vectors_migrate.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("CREATE TABLE vectors (id TEXT PRIMARY KEY, vector BLOB, meta TEXT)")
+	db.Exec("INSERT INTO vectors VALUES ('plain', '[1,2]', 'Hello world'), ('json', '[3,4]', '{\"year\": 2021}')")
+	db.Close()
+
+	s, err := NewDuckDBStorage("test_vectors_migrate.db")
+	if err != nil {
+		t.Fatalf("NewDuckDBStorage failed: %v", err)
+	}
+	defer s.Close()
+	if doc, _ := s.Get("plain"); !reflect.DeepEqual(doc.Payload, Payload{"meta": "Hello world"}) || doc.Meta != "Hello world" {
+		t.Errorf("Expected plain meta to migrate, got %v", doc)
+	}
+	if doc, _ := s.Get("json"); !reflect.DeepEqual(doc.Payload, Payload{"year": int64(2021)}) {
+		t.Errorf("Expected JSON meta to migrate into fields, got %v", doc)
+	}
+}
+
+func TestDuckDBStorageTables(t *testing.T) {
+	defer os.Remove("test_vectors_tables.db")
+	a, err := NewDuckDBStorageTable("test_vectors_tables.db", "vectors_a")
+	if err != nil {
+		t.Fatalf("NewDuckDBStorageTable failed: %v", err)
+	}
+	b, err := NewDuckDBStorageTable("test_vectors_tables.db", "vectors_b")
+	if err != nil {
+		t.Fatalf("NewDuckDBStorageTable failed: %v", err)
+	}
+	defer b.Close()
+	if err := a.Insert("id1", VectorDoc{Vector: []float32{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := b.Get("id1"); exists {
+		t.Error("Expected tables to be independent")
+	}
+
+	if err := a.Drop(); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	var n int
+	b.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'vectors_a'").Scan(&n)
+	if n != 0 {
+		t.Error("Expected dropped table to be removed")
+	}
+
+	if _, err := NewDuckDBStorageTable("test_vectors_tables.db", "x; DROP TABLE vectors_b"); err == nil {
+		t.Error("Expected error for invalid table name")
+	}
 }
@@ -1,43 +1,380 @@
 package storage
 
 import (
+	"bufio"
 	"encoding/json"
-	"io/ioutil"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 )
 
+// compactMin 为自动压缩前至少需要的可回收记录数
+const compactMin = 1024
+
+// FileStorage 把文档保存在只追加的二进制段文件中，path 处为列出各段的 manifest（JSON），
+// 段文件与之位于同一目录，名为 <path 去掉扩展名>.<编号>.seg。全部文档常驻内存；
+// 每次写入向当前段追加一帧并 fsync，可回收的记录多于存活文档时自动压缩
 type FileStorage struct {
-	path string
-	data map[string]VectorDoc
+	path        string
+	data        map[string]VectorDoc
+	manifest    manifest
+	active      *os.File    // 当前追加的段，为 nil 时尚未创建 manifest（新存储或旧版 JSON 文件）
+	size        int64       // 当前段的长度
+	crc         hash.Hash32 // 当前段的 CRC32，封闭时写入 manifest
+	records     int         // 各段中的操作数，减去文档数即为可回收的记录
+	segmentSize int64
+	maintErr    error // 最近一次写入后封闭段或自动压缩的错误，之后成功时清除，由 Close 返回
 }
 
 func NewFileStorage(path string) *FileStorage {
-	return &FileStorage{path: path, data: make(map[string]VectorDoc)}
+	return &FileStorage{path: path, data: make(map[string]VectorDoc), segmentSize: segmentSize}
 }
 
+// Load 读取 manifest 和全部段：已封闭的段与 manifest 中的长度和校验和不符时返回 ErrCorrupt，
+// 当前段末尾写入中途崩溃留下的不完整帧会被截断。path 处为旧版 JSON 数据文件时按原格式读取，
+// 只有 Meta 的旧文档会生成 Payload，第一次写入时整体转换为段格式
 func (s *FileStorage) Load() (map[string]VectorDoc, error) {
-	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+	s.closeActive()
+	s.data = make(map[string]VectorDoc)
+	s.records = 0
+	s.maintErr = nil
+	raw, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
 		return s.data, nil
 	}
-	data, err := ioutil.ReadFile(s.path)
 	if err != nil {
 		return nil, err
 	}
-	return s.data, json.Unmarshal(data, &s.data)
+	m, ok := parseManifest(raw)
+	if !ok {
+		if err := json.Unmarshal(raw, &s.data); err != nil {
+			return s.data, err
+		}
+		for id, doc := range s.data {
+			s.data[id] = migrateDoc(doc)
+		}
+		return s.data, nil
+	}
+	if err := s.open(m); err != nil {
+		return nil, err
+	}
+	return s.data, nil
+}
+
+// open 依次重放 manifest 中的段，并打开最后一个段用于追加
+func (s *FileStorage) open(m manifest) error {
+	if len(m.Segments) == 0 {
+		return fmt.Errorf("%w: manifest %s lists no segments", ErrCorrupt, s.path)
+	}
+	for i, seg := range m.Segments {
+		data, err := os.ReadFile(s.segmentPath(seg.Name))
+		if err != nil {
+			return err
+		}
+		last := i == len(m.Segments)-1
+		if !last && (int64(len(data)) != seg.Size || crc32.ChecksumIEEE(data) != seg.Checksum) {
+			return fmt.Errorf("%w: segment %s does not match its checksum", ErrCorrupt, seg.Name)
+		}
+		if len(data) < len(segmentMagic) || string(data[:len(segmentMagic)]) != segmentMagic {
+			return fmt.Errorf("%w: %s is not a segment", ErrCorrupt, seg.Name)
+		}
+		n, err := decodeFrames(data[len(segmentMagic):], func(op segmentOp) {
+			op.doc = migrateDoc(op.doc)
+			s.apply(op)
+		})
+		n += len(segmentMagic)
+		if err != nil {
+			if !last {
+				return fmt.Errorf("%w: segment %s: %v", ErrCorrupt, seg.Name, err)
+			}
+			Logger.Printf("Truncating segment %s at offset %d: %v", seg.Name, n, err)
+		}
+		if !last {
+			continue
+		}
+		f, err := os.OpenFile(s.segmentPath(seg.Name), os.O_RDWR, 0644)
+		if err != nil {
+			return err
+		}
+		if n < len(data) {
+			if err := f.Truncate(int64(n)); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Sync(); err != nil {
+				f.Close()
+				return err
+			}
+		}
+		s.active = f
+		s.size = int64(n)
+		s.crc = crc32.NewIEEE()
+		s.crc.Write(data[:n])
+	}
+	s.manifest = m
+	s.removeOrphans()
+	return nil
 }
 
+// apply 把段中的一个操作应用到内存中的数据
+func (s *FileStorage) apply(op segmentOp) {
+	s.records++
+	if op.delete {
+		delete(s.data, op.id)
+	} else {
+		s.data[op.id] = op.doc
+	}
+}
+
+// Save 把 data 写入一个新段，再用只列出该段的 manifest 原子地替换旧的 manifest，之后删除旧段。
+// 在替换 manifest 之前崩溃时原有数据保持不变，新段在下次 Load 时删除
 func (s *FileStorage) Save(data map[string]VectorDoc) error {
+	next := max(s.manifest.Next, 1)
+	name := s.segmentName(next)
+	f, crc, err := createSegment(s.segmentPath(name))
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(s.segmentPath(name))
+		return err
+	}
+	size, err := writeDocs(f, crc, data)
+	if err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	m := manifest{Format: manifestFormat, Version: manifestVersion, Next: next + 1, Segments: []segmentInfo{{Name: name}}}
+	if err := s.writeManifest(m); err != nil {
+		return fail(err)
+	}
+
+	old := s.manifest.Segments
+	s.closeActive()
+	s.manifest = m
+	s.active, s.size, s.crc = f, size, crc
 	s.data = data
-	jsonData, err := json.MarshalIndent(data, "", "  ")
+	s.records = len(data)
+	for _, seg := range old {
+		os.Remove(s.segmentPath(seg.Name))
+	}
+	return nil
+}
+
+// writeDocs 把文档按每帧 1024 个写在段的 magic 之后，返回段的长度
+func writeDocs(f *os.File, crc hash.Hash32, data map[string]VectorDoc) (int64, error) {
+	bw := bufio.NewWriter(io.MultiWriter(f, crc))
+	size := int64(len(segmentMagic))
+	ops := make([]segmentOp, 0, 1024)
+	flush := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+		frame, err := encodeFrame(ops)
+		if err != nil {
+			return err
+		}
+		ops = ops[:0]
+		size += int64(len(frame))
+		_, err = bw.Write(frame)
+		return err
+	}
+	for id, doc := range data {
+		ops = append(ops, segmentOp{id: id, doc: doc})
+		if len(ops) == cap(ops) {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return size, bw.Flush()
+}
+
+// write 把一组操作作为一帧追加到当前段并 fsync，成功后才修改内存中的数据
+func (s *FileStorage) write(ops []segmentOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	if s.active == nil {
+		// 新存储或旧版 JSON 文件，先按段格式写入已有数据
+		if err := s.Save(s.data); err != nil {
+			return err
+		}
+	}
+	frame, err := encodeFrame(ops)
+	if err != nil {
+		return err
+	}
+	if _, err := s.active.WriteAt(frame, s.size); err != nil {
+		s.active.Truncate(s.size)
+		return err
+	}
+	if err := s.active.Sync(); err != nil {
+		s.active.Truncate(s.size)
+		return err
+	}
+	s.size += int64(len(frame))
+	s.crc.Write(frame)
+	for _, op := range ops {
+		s.apply(op)
+	}
+
+	s.maintain()
+	return nil
+}
+
+// maintain 在写入之后封闭写满的段并在需要时压缩。写入已经持久化，失败时不影响本次写入的结果：
+// 错误记录到 Logger 并保留在 maintErr 中由 Close 返回，下次写入时重试
+func (s *FileStorage) maintain() {
+	var err error
+	if s.size >= s.segmentSize {
+		if rerr := s.roll(); rerr != nil {
+			err = fmt.Errorf("seal segment of %s: %w", s.path, rerr)
+		}
+	}
+	if garbage := s.records - len(s.data); garbage >= compactMin && garbage > len(s.data) {
+		if cerr := s.Save(s.data); cerr != nil {
+			err = fmt.Errorf("compact %s: %w", s.path, cerr)
+		} else {
+			// 压缩把全部文档写入新段，之前未能封闭的段已被替换
+			err = nil
+		}
+	}
+	if err != nil && s.maintErr == nil {
+		Logger.Printf("File storage maintenance failed, retrying on the next write: %v", err)
+	}
+	s.maintErr = err
+}
+
+// roll 封闭当前段：把长度和 CRC32 写入 manifest，并开始一个新段
+func (s *FileStorage) roll() error {
+	next := s.manifest.Next
+	name := s.segmentName(next)
+	f, crc, err := createSegment(s.segmentPath(name))
+	if err != nil {
+		return err
+	}
+	m := s.manifest
+	m.Segments = append([]segmentInfo(nil), m.Segments...)
+	m.Segments[len(m.Segments)-1].Size = s.size
+	m.Segments[len(m.Segments)-1].Checksum = s.crc.Sum32()
+	m.Segments = append(m.Segments, segmentInfo{Name: name})
+	m.Next = next + 1
+	if err := s.writeManifest(m); err != nil {
+		f.Close()
+		os.Remove(s.segmentPath(name))
+		return err
+	}
+	s.closeActive()
+	s.manifest = m
+	s.active, s.size, s.crc = f, int64(len(segmentMagic)), crc
+	return nil
+}
+
+func (s *FileStorage) writeManifest(m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(s.path, jsonData, 0644)
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic 把 data 写入 path 旁的临时文件，fsync 后重命名为 path
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	// 同步目录使重命名和新建的段持久化，不支持的平台上忽略
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// segmentName 返回编号为 n 的段的文件名
+func (s *FileStorage) segmentName(n int) string {
+	return fmt.Sprintf("%s.%06d.seg", s.base(), n)
+}
+
+func (s *FileStorage) base() string {
+	return filepath.Base(strings.TrimSuffix(s.path, filepath.Ext(s.path)))
+}
+
+func (s *FileStorage) segmentPath(name string) string {
+	return filepath.Join(filepath.Dir(s.path), name)
+}
+
+// segments 返回目录中属于该存储的全部段文件，包括 manifest 中没有列出的
+func (s *FileStorage) segments() []string {
+	entries, err := os.ReadDir(filepath.Dir(s.path))
+	if err != nil {
+		return nil
+	}
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(s.base()) + `\.[0-9]{6,}\.seg$`)
+	var names []string
+	for _, e := range entries {
+		if pattern.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// removeOrphans 删除 manifest 中没有列出的段，它们来自在替换 manifest 前后崩溃的压缩或封闭
+func (s *FileStorage) removeOrphans() {
+	listed := make(map[string]bool, len(s.manifest.Segments))
+	for _, seg := range s.manifest.Segments {
+		listed[seg.Name] = true
+	}
+	for _, name := range s.segments() {
+		if !listed[name] {
+			os.Remove(s.segmentPath(name))
+		}
+	}
+}
+
+func (s *FileStorage) closeActive() error {
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
 }
 
 func (s *FileStorage) Insert(id string, doc VectorDoc) error {
-	s.data[id] = doc
-	return s.Save(s.data)
+	return s.write([]segmentOp{{id: id, doc: doc}})
 }
 
 func (s *FileStorage) Get(id string) (VectorDoc, bool) {
@@ -45,9 +382,79 @@ func (s *FileStorage) Get(id string) (VectorDoc, bool) {
 	return doc, exists
 }
 
+// Delete 删除文档，文档不存在时不写入
 func (s *FileStorage) Delete(id string) error {
-	delete(s.data, id)
-	return s.Save(s.data)
+	if _, exists := s.data[id]; !exists {
+		return nil
+	}
+	return s.write([]segmentOp{{id: id, delete: true}})
+}
+
+// InsertBatch 先检查全部 ID，再与 UpsertBatch 一样作为一帧写入
+func (s *FileStorage) InsertBatch(docs map[string]VectorDoc) error {
+	for id := range docs {
+		if _, exists := s.data[id]; exists {
+			return fmt.Errorf("%w: %s", ErrExists, id)
+		}
+	}
+	return s.UpsertBatch(docs)
+}
+
+// UpsertBatch 把整批文档作为一帧追加，只 fsync 一次
+func (s *FileStorage) UpsertBatch(docs map[string]VectorDoc) error {
+	ops := make([]segmentOp, 0, len(docs))
+	for id, doc := range docs {
+		ops = append(ops, segmentOp{id: id, doc: doc})
+	}
+	return s.write(ops)
 }
 
-func (s *FileStorage) Close() error { return nil }
+// DeleteBatch 与 UpsertBatch 相同，只为存在的文档写入删除记录
+func (s *FileStorage) DeleteBatch(ids []string) (int, error) {
+	var ops []segmentOp
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, exists := s.data[id]; exists && !seen[id] {
+			seen[id] = true
+			ops = append(ops, segmentOp{id: id, delete: true})
+		}
+	}
+	if err := s.write(ops); err != nil {
+		return 0, err
+	}
+	return len(ops), nil
+}
+
+// Close 关闭当前段，最近一次写入后封闭段或自动压缩失败时返回该错误
+func (s *FileStorage) Close() error {
+	if err := s.closeActive(); err != nil {
+		return err
+	}
+	return s.maintErr
+}
+
+// Compact 把存活的文档重写为一个新段并删除旧段，旧版 JSON 文件也会转换为段格式
+func (s *FileStorage) Compact() error {
+	if err := s.Save(s.data); err != nil {
+		return err
+	}
+	s.maintErr = nil
+	return nil
+}
+
+// Drop 删除 manifest 和全部段文件
+func (s *FileStorage) Drop() error {
+	s.closeActive()
+	s.data = make(map[string]VectorDoc)
+	s.manifest = manifest{}
+	s.records = 0
+	for _, name := range s.segments() {
+		if err := os.Remove(s.segmentPath(name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -1,7 +1,12 @@
 package storage
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -12,7 +17,7 @@ func TestFileStorage(t *testing.T) {
 	defer os.Remove("test_vectors.json")
 
 	// 测试插入
-	doc := VectorDoc{Vector: []float64{1.0, 2.0, 3.0}, Meta: "test"}
+	doc := VectorDoc{Vector: []float32{1.0, 2.0, 3.0}, Meta: "test"}
 	err := s.Insert("id1", doc)
 	if err != nil {
 		t.Fatalf("Insert failed: %v", err)
@@ -45,4 +50,209 @@ func TestFileStorage(t *testing.T) {
 	if len(data) != 0 {
 		t.Errorf("Expected empty data, got %v", data)
 	}
+
+	// 测试删除数据文件
+	if err := s.Drop(); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	if _, err := os.Stat("test_vectors.json"); !os.IsNotExist(err) {
+		t.Errorf("Expected data file to be removed, got %v", err)
+	}
+}
+
+func TestFileStorageBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	s := NewFileStorage(path)
+	testBatch(t, s)
+
+	// 批量写入只重写一次文件，重新加载后内容一致
+	data, err := NewFileStorage(path).Load()
+	if err != nil || len(data) != 1 {
+		t.Errorf("Expected 1 document after reload, got %v (%v)", data, err)
+	}
+	// 先写临时文件再重命名，完成后不留下临时文件
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected temporary file to be renamed, got %v", err)
+	}
+
+	// 写文件失败时不修改内存中的数据
+	s.active.Close()
+	if err := s.UpsertBatch(map[string]VectorDoc{"b3": {Vector: []float32{7, 7, 7}}, "x": {Vector: []float32{1, 1, 1}}}); err == nil {
+		t.Fatal("Expected write error")
+	}
+	if got, _ := s.Get("b3"); got.Vector[0] != 0 {
+		t.Errorf("Expected b3 unchanged, got %v", got)
+	}
+	if _, ok := s.Get("x"); ok {
+		t.Error("Expected x not to be written")
+	}
+}
+
+func TestFileStoragePayload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	// 旧版文件只有 Meta
+	legacy := `{"old": {"Vector": [1, 2], "Meta": "{\"lang\": \"go\"}"}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewFileStorage(path)
+	data, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(data["old"].Payload, Payload{"lang": "go"}) {
+		t.Errorf("Expected legacy meta to migrate, got %v", data["old"])
+	}
+
+	doc := VectorDoc{Vector: []float32{3, 4}, Payload: Payload{"n": int64(1), "nested": map[string]interface{}{"ok": true}}}
+	if err := s.Insert("new", doc); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	data, err = NewFileStorage(path).Load()
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !reflect.DeepEqual(data["new"], doc) {
+		t.Errorf("Expected %v after reload, got %v", doc, data["new"])
+	}
+}
+
+func TestFileStorageSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	s := NewFileStorage(path)
+	s.segmentSize = 256
+	want := make(map[string]VectorDoc)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("doc%02d", i)
+		doc := VectorDoc{Vector: []float32{float32(i), 1, 2}, Meta: "m", Payload: Payload{"i": int64(i)}}
+		if err := s.Insert(id, doc); err != nil {
+			t.Fatal(err)
+		}
+		want[id] = doc
+	}
+	if len(s.manifest.Segments) < 2 {
+		t.Fatalf("Expected segments to be sealed, got %+v", s.manifest)
+	}
+	s.Close()
+
+	reloaded := NewFileStorage(path)
+	data, err := reloaded.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Unexpected data after reload: %v", data)
+	}
+	reloaded.Close()
+
+	// 已封闭的段损坏时 Load 失败
+	sealed := s.segmentPath(s.manifest.Segments[0].Name)
+	raw, _ := os.ReadFile(sealed)
+	raw[len(raw)-1] ^= 0xff
+	os.WriteFile(sealed, raw, 0644)
+	if _, err := NewFileStorage(path).Load(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestFileStorageMaintenanceError(t *testing.T) {
+	var logged bytes.Buffer
+	defer func(l *log.Logger) { Logger = l }(Logger)
+	Logger = log.New(&logged, "", 0)
+
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	s := NewFileStorage(path)
+	s.segmentSize = 256
+	if err := s.Insert("doc00", VectorDoc{Vector: []float32{0, 1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	// 下一个段的位置被目录占用，封闭段失败
+	if err := os.Mkdir(s.segmentPath(s.segmentName(s.manifest.Next)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 20; i++ {
+		if err := s.Insert(fmt.Sprintf("doc%02d", i), VectorDoc{Vector: []float32{float32(i), 1, 2}}); err != nil {
+			t.Fatalf("Expected the write to succeed, got %v", err)
+		}
+	}
+	if n := bytes.Count(logged.Bytes(), []byte("\n")); n != 1 || !bytes.Contains(logged.Bytes(), []byte("seal segment")) {
+		t.Errorf("Expected the failure to be logged once, got %q", logged.String())
+	}
+	if err := s.Close(); err == nil {
+		t.Error("Expected Close to return the sealing error")
+	}
+
+	data, err := NewFileStorage(path).Load()
+	if err != nil || len(data) != 20 {
+		t.Errorf("Expected all writes to be kept, got %d documents, %v", len(data), err)
+	}
+}
+
+func TestFileStorageRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	s := NewFileStorage(path)
+	for _, id := range []string{"a", "b"} {
+		if err := s.Insert(id, VectorDoc{Vector: []float32{1, 2, 3}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	active := s.segmentPath(s.manifest.Segments[0].Name)
+	s.Close()
+
+	// 最后一帧只写入一半，另有压缩中途崩溃留下的段
+	info, _ := os.Stat(active)
+	os.Truncate(active, info.Size()-5)
+	orphan := s.segmentPath(s.segmentName(9))
+	os.WriteFile(orphan, []byte(segmentMagic), 0644)
+
+	s = NewFileStorage(path)
+	data, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := data["a"]; !ok || len(data) != 1 {
+		t.Errorf("Expected only the complete write to survive, got %v", data)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("Expected orphan segment to be removed, got %v", err)
+	}
+	if err := s.Insert("c", VectorDoc{Vector: []float32{4, 5, 6}}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if data, err := NewFileStorage(path).Load(); err != nil || len(data) != 2 {
+		t.Errorf("Expected a and c after reload, got %v, %v", data, err)
+	}
+}
+
+func TestFileStorageCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	s := NewFileStorage(path)
+	s.Insert("keep", VectorDoc{Vector: []float32{1, 1, 1}})
+	first := s.manifest.Segments[0].Name
+	// 反复覆盖同一文档，可回收的记录达到阈值后自动压缩
+	for i := 0; i <= compactMin; i++ {
+		if err := s.Insert("hot", VectorDoc{Vector: []float32{float32(i), 0, 0}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.records-len(s.data) >= compactMin {
+		t.Errorf("Expected automatic compaction, %d records for %d documents", s.records, len(s.data))
+	}
+	if _, err := os.Stat(s.segmentPath(first)); !os.IsNotExist(err) {
+		t.Errorf("Expected old segment to be removed after compaction, got %v", err)
+	}
+
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.segments()) != 1 || s.records != 2 {
+		t.Errorf("Expected a single segment with 2 records, got %v, %d", s.segments(), s.records)
+	}
+	s.Close()
+	data, err := NewFileStorage(path).Load()
+	if err != nil || len(data) != 2 || data["hot"].Vector[0] != float32(compactMin) {
+		t.Errorf("Unexpected data after compaction: %v, %v", data, err)
+	}
 }
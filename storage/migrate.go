@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"gvdb/vector"
+)
+
+// MigrateProgress 为迁移进度：已写入目标的最后一个 ID，以及到此为止源文档的数量和校验和，用于续传
+type MigrateProgress struct {
+	Last     string `json:"last"`
+	Count    int64  `json:"count"`
+	Checksum uint64 `json:"checksum"`
+}
+
+// MigrateOptions 为 Migrate 的参数，零值使用默认值
+type MigrateOptions struct {
+	BatchSize int              // 每批读取和写入的文档数，默认 1000
+	DryRun    bool             // 只读取源并计算文档数和校验和，不访问目标
+	Resume    *MigrateProgress // 从之前的进度继续；为 nil 时目标必须为空
+	// Progress 在每批写入后调用，返回错误时停止迁移
+	Progress func(MigrateProgress) error
+}
+
+func (o *MigrateOptions) normalize() {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 1000
+	}
+}
+
+// MigrateResult 为 Migrate 的结果，校验和与文档顺序无关
+type MigrateResult struct {
+	Copied         int64  `json:"copied"` // 本次写入的文档数，续传时不含之前写入的
+	Count          int64  `json:"count"`
+	Checksum       uint64 `json:"checksum"`
+	TargetCount    int64  `json:"target_count"` // DryRun 时为 0
+	TargetChecksum uint64 `json:"target_checksum"`
+}
+
+// ErrMigrationMismatch 表示迁移后目标的文档数或校验和与源不一致
+var ErrMigrationMismatch = errors.New("target does not match source after migration")
+
+// Migrate 按 ID 顺序分批把 src 的全部文档 upsert 到 dst，然后读取整个目标并核对文档数和校验和。
+// 实现 Scanner 的源逐页读取，其他存储一次加载。迁移期间不能写入源；
+// 中断后把最后一次 Progress 收到的进度作为 Resume 即可继续，最后一批可能被重复写入
+func Migrate(src, dst Storage, opts MigrateOptions) (MigrateResult, error) {
+	opts.normalize()
+	var res MigrateResult
+	var p MigrateProgress
+	if opts.Resume != nil {
+		p = *opts.Resume
+	}
+	if !opts.DryRun {
+		// 文件存储需要先加载才能写入，同时检查目标是否为空
+		ids, err := scanPage(dst, "", 1)
+		if err != nil {
+			return res, err
+		}
+		if len(ids) > 0 && opts.Resume == nil {
+			return res, errors.New("target storage is not empty")
+		}
+	}
+
+	err := ScanAll(src, p.Last, opts.BatchSize, func(ids []string, docs map[string]VectorDoc) error {
+		if !opts.DryRun {
+			if err := dst.UpsertBatch(docs); err != nil {
+				return fmt.Errorf("batch after %q: %v", p.Last, err)
+			}
+			res.Copied += int64(len(ids))
+		}
+		for _, id := range ids {
+			p.Checksum += docChecksum(id, docs[id])
+		}
+		p.Count += int64(len(ids))
+		p.Last = ids[len(ids)-1]
+		if opts.Progress != nil {
+			return opts.Progress(p)
+		}
+		return nil
+	})
+	res.Count, res.Checksum = p.Count, p.Checksum
+	if err != nil || opts.DryRun {
+		return res, err
+	}
+
+	err = ScanAll(dst, "", opts.BatchSize, func(ids []string, docs map[string]VectorDoc) error {
+		for _, id := range ids {
+			res.TargetChecksum += docChecksum(id, docs[id])
+		}
+		res.TargetCount += int64(len(ids))
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+	if res.TargetCount != res.Count || res.TargetChecksum != res.Checksum {
+		return res, fmt.Errorf("%w: target has %d documents (checksum %016x), source has %d (checksum %016x)",
+			ErrMigrationMismatch, res.TargetCount, res.TargetChecksum, res.Count, res.Checksum)
+	}
+	return res, nil
+}
+
+// ScanAll 按 ID 顺序对 after 之后的文档分批调用 fn；实现 Scanner 的存储逐页读取，其他存储一次加载后分批
+func ScanAll(s Storage, after string, batch int, fn func(ids []string, docs map[string]VectorDoc) error) error {
+	if sc, ok := s.(Scanner); ok {
+		for {
+			ids, docs, err := sc.Scan(after, batch)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			if err := fn(ids, docs); err != nil {
+				return err
+			}
+			after = ids[len(ids)-1]
+		}
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(data))
+	for id := range data {
+		if id > after {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for start := 0; start < len(ids); start += batch {
+		end := min(start+batch, len(ids))
+		docs := make(map[string]VectorDoc, end-start)
+		for _, id := range ids[start:end] {
+			docs[id] = data[id]
+		}
+		if err := fn(ids[start:end], docs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanPage 返回 after 之后的至多 limit 个 ID
+func scanPage(s Storage, after string, limit int) ([]string, error) {
+	var page []string
+	err := ScanAll(s, after, limit, func(ids []string, _ map[string]VectorDoc) error {
+		page = ids
+		return errStopScan
+	})
+	if err == errStopScan {
+		err = nil
+	}
+	return page, err
+}
+
+var errStopScan = errors.New("stop scan")
+
+// docChecksum 为单个文档的 FNV-64a 哈希，Payload 为空和为 nil 视为相同；
+// 迁移对各文档的哈希求和，因此与读取顺序无关
+func docChecksum(id string, doc VectorDoc) uint64 {
+	h := fnv.New64a()
+	buf := appendString(nil, id)
+	buf = binary.AppendUvarint(buf, uint64(len(doc.Vector)))
+	buf = append(buf, vector.Encode(doc.Vector)...)
+	buf = appendString(buf, doc.Meta)
+	if len(doc.Payload) > 0 {
+		payload, _ := EncodePayload(doc.Payload)
+		buf = appendString(buf, string(payload))
+	}
+	h.Write(buf)
+	return h.Sum64()
+}
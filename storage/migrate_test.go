@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// lossyStorage 在批量写入时丢掉一个文档
+type lossyStorage struct {
+	Storage
+}
+
+func (s lossyStorage) UpsertBatch(docs map[string]VectorDoc) error {
+	for id := range docs {
+		delete(docs, id)
+		break
+	}
+	return s.Storage.UpsertBatch(docs)
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	src := NewFileStorage(filepath.Join(dir, "vectors.json"))
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+	docs := make(map[string]VectorDoc)
+	for i := 0; i < 25; i++ {
+		docs[fmt.Sprintf("doc%02d", i)] = VectorDoc{Vector: []float32{float32(i), 1}, Payload: Payload{"n": int64(i)}}
+	}
+	docs["legacy"] = VectorDoc{Vector: []float32{0, 0}, Meta: "plain"}
+	if err := src.UpsertBatch(docs); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := Migrate(src, nil, MigrateOptions{DryRun: true})
+	if err != nil || dry.Count != 26 || dry.Copied != 0 {
+		t.Fatalf("Dry run: %+v, %v", dry, err)
+	}
+
+	// 第二批之后中断，再从进度继续
+	dst, err := NewDuckDBStorage(filepath.Join(dir, "vectors.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	var saved MigrateProgress
+	interrupted := errors.New("interrupted")
+	_, err = Migrate(src, dst, MigrateOptions{BatchSize: 10, Progress: func(p MigrateProgress) error {
+		saved = p
+		if p.Count == 20 {
+			return interrupted
+		}
+		return nil
+	}})
+	if !errors.Is(err, interrupted) || saved.Last != "doc19" {
+		t.Fatalf("Expected interruption after doc19, got %v at %+v", err, saved)
+	}
+	if _, err := Migrate(src, dst, MigrateOptions{}); err == nil {
+		t.Error("Expected a non-empty target to be rejected without Resume")
+	}
+	res, err := Migrate(src, dst, MigrateOptions{BatchSize: 10, Resume: &saved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Copied != 6 || res.Count != 26 || res.TargetCount != 26 || res.Checksum != dry.Checksum || res.TargetChecksum != dry.Checksum {
+		t.Errorf("Unexpected result after resume: %+v, dry run checksum %016x", res, dry.Checksum)
+	}
+	// 源和目标都在读取时为只有 Meta 的文档补上 Payload
+	want, _ := src.Load()
+	got, _ := dst.Load()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Target differs from source:\n%+v\nwant:\n%+v", got, want)
+	}
+
+	// 反方向迁移读取 SQL 存储的分页
+	back := NewFileStorage(filepath.Join(dir, "back.json"))
+	if res, err := Migrate(dst, back, MigrateOptions{BatchSize: 7}); err != nil || res.Checksum != dry.Checksum {
+		t.Errorf("Migrating back: %+v, %v", res, err)
+	}
+
+	lossy := lossyStorage{NewFileStorage(filepath.Join(dir, "lossy.json"))}
+	if _, err := Migrate(src, lossy, MigrateOptions{}); !errors.Is(err, ErrMigrationMismatch) {
+		t.Errorf("Expected ErrMigrationMismatch, got %v", err)
+	}
+}
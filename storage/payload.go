@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// Payload 为结构化元数据，值可以是 string、int64、float64、bool、[]interface{} 或嵌套的 map[string]interface{}
+type Payload map[string]interface{}
+
+// legacyMetaKey 为旧版非 JSON 的 Meta 字符串迁移到 Payload 后使用的键
+const legacyMetaKey = "meta"
+
+// UnmarshalJSON 保留整数类型：不带小数点和指数的数字解码为 int64，其余解码为 float64
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*p = nil
+		return nil
+	}
+	*p = Payload(normalize(raw).(map[string]interface{}))
+	return nil
+}
+
+// DecodePayload 解析 JSON 对象形式的 Payload，空输入和 null 返回 nil
+func DecodePayload(data []byte) (Payload, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var p Payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// EncodePayload 把 Payload 编码为 JSON，nil 编码为 nil 以便在数据库中存为 NULL
+func EncodePayload(p Payload) ([]byte, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// MetaToPayload 把旧版 Meta 字符串迁移为 Payload：JSON 对象按字段展开，其余字符串保存在 "meta" 键下
+func MetaToPayload(meta string) Payload {
+	if meta == "" {
+		return nil
+	}
+	if strings.HasPrefix(strings.TrimSpace(meta), "{") {
+		if p, err := DecodePayload([]byte(meta)); err == nil && p != nil {
+			return p
+		}
+	}
+	return Payload{legacyMetaKey: meta}
+}
+
+// migrateDoc 为只有 Meta 的旧文档补上 Payload
+func migrateDoc(doc VectorDoc) VectorDoc {
+	if doc.Payload == nil {
+		doc.Payload = MetaToPayload(doc.Meta)
+	}
+	return doc
+}
+
+// migrateMetaColumn 为 payload 列为 NULL 的旧记录根据 meta 列生成 payload，update 的参数依次为 payload 和 id
+func migrateMetaColumn(db *sql.DB, table, update string) error {
+	rows, err := db.Query("SELECT id, meta FROM " + table + " WHERE payload IS NULL AND meta IS NOT NULL AND meta <> ''")
+	if err != nil {
+		return err
+	}
+	pending := make(map[string]string)
+	for rows.Next() {
+		var id, meta string
+		if err := rows.Scan(&id, &meta); err != nil {
+			rows.Close()
+			return err
+		}
+		pending[id] = meta
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(pending) == 0 {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(update)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for id, meta := range pending {
+		payload, err := EncodePayload(MetaToPayload(meta))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := stmt.Exec(string(payload), id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// payloadArg 把 Payload 编码为 SQL 参数，nil 对应 NULL
+func payloadArg(p Payload) (interface{}, error) {
+	b, err := EncodePayload(p)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = normalize(e)
+		}
+		return x
+	case []interface{}:
+		for i, e := range x {
+			x[i] = normalize(e)
+		}
+		return x
+	}
+	return v
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodePayloadKeepsTypes(t *testing.T) {
+	p, err := DecodePayload([]byte(`{"s": "x", "i": 42, "f": 1.5, "e": 1e3, "b": true, "l": [1, "a"], "o": {"n": 7}, "z": null}`))
+	if err != nil {
+		t.Fatalf("DecodePayload: %v", err)
+	}
+	want := Payload{
+		"s": "x",
+		"i": int64(42),
+		"f": 1.5,
+		"e": 1000.0,
+		"b": true,
+		"l": []interface{}{int64(1), "a"},
+		"o": map[string]interface{}{"n": int64(7)},
+		"z": nil,
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Expected %#v, got %#v", want, p)
+	}
+
+	for _, empty := range []string{"", "null", "  "} {
+		if p, err := DecodePayload([]byte(empty)); err != nil || p != nil {
+			t.Errorf("Expected nil payload for %q, got %v (%v)", empty, p, err)
+		}
+	}
+	if _, err := DecodePayload([]byte(`[1]`)); err == nil {
+		t.Error("Expected error for non-object payload")
+	}
+}
+
+func TestMetaToPayload(t *testing.T) {
+	cases := []struct {
+		meta string
+		want Payload
+	}{
+		{"", nil},
+		{"Hello world", Payload{"meta": "Hello world"}},
+		{`{"lang": "go", "year": 2024}`, Payload{"lang": "go", "year": int64(2024)}},
+		// 以 { 开头但不是合法 JSON 时按普通字符串处理
+		{"{not json", Payload{"meta": "{not json"}},
+	}
+	for _, c := range cases {
+		if got := MetaToPayload(c.meta); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("MetaToPayload(%q) = %#v, want %#v", c.meta, got, c.want)
+		}
+	}
+}
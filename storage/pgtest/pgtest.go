@@ -0,0 +1,232 @@
+// Package pgtest 提供 PostgreSQL 的本地替身：一个基于 SQLite 的 database/sql 驱动，
+// 把 PostgresStorage 和 PgvectorStorage 使用的 PostgreSQL/pgvector 语句改写后在 SQLite 上执行，
+// 使存储和集合的测试不依赖 PostgreSQL 服务。只覆盖存储层用到的语句，向量索引按顺序扫描模拟。
+//
+// 使用时把 storage.PostgresDriver 设为 Driver，连接参数中的 database 为 SQLite 数据库文件路径。
+package pgtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Driver 为替身注册的驱动名
+const Driver = "pgtest"
+
+func init() {
+	sql.Register(Driver, &pgDriver{sqlite: &sqlite3.SQLiteDriver{ConnectHook: registerFuncs}})
+}
+
+var (
+	logMutex   sync.Mutex
+	statements = make(map[string][]string)
+)
+
+// Statements 返回对 database 执行过的原始语句（改写之前），用于检查建表、建索引和搜索参数
+func Statements(database string) []string {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	return append([]string(nil), statements[database]...)
+}
+
+type pgDriver struct {
+	sqlite *sqlite3.SQLiteDriver
+}
+
+// Open 从 lib/pq 格式的连接字符串中取出 dbname 作为 SQLite 文件路径
+func (d *pgDriver) Open(dsn string) (driver.Conn, error) {
+	var database string
+	for _, field := range strings.Fields(dsn) {
+		if v, ok := strings.CutPrefix(field, "dbname="); ok {
+			database = v
+		}
+	}
+	if database == "" {
+		return nil, errors.New("pgtest: dbname is required")
+	}
+	c, err := d.sqlite.Open("file:" + database + "?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, database: database}, nil
+}
+
+type conn struct {
+	driver.Conn
+	database string
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	logMutex.Lock()
+	statements[c.database] = append(statements[c.database], query)
+	logMutex.Unlock()
+
+	rewritten, addColumn := rewrite(query)
+	s, err := c.Conn.Prepare(rewritten)
+	if err != nil && addColumn && strings.Contains(err.Error(), "duplicate column name") {
+		// ADD COLUMN IF NOT EXISTS 遇到已有的列时什么也不做
+		s, err = c.Conn.Prepare("SELECT 1")
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &stmt{Stmt: s}, nil
+}
+
+type stmt struct {
+	driver.Stmt
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.Stmt.Exec(args)
+	return res, translate(err)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.Stmt.Query(args)
+	return rows, translate(err)
+}
+
+// translate 把 SQLite 的主键冲突转换为 PostgreSQL 的 unique_violation
+func translate(err error) error {
+	var se sqlite3.Error
+	if errors.As(err, &se) && (se.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || se.ExtendedCode == sqlite3.ErrConstraintUnique) {
+		return &pq.Error{Code: "23505", Message: se.Error()}
+	}
+	return err
+}
+
+var (
+	// 只影响 PostgreSQL 行为、在替身中没有对应物的语句，改为空操作
+	noop = regexp.MustCompile(`(?is)^\s*(CREATE EXTENSION|CREATE INDEX .* USING (hnsw|ivfflat)|SET LOCAL|ALTER TABLE \w+ ALTER COLUMN)`)
+	// SQLite 的列类型是宽松的，转换后的 vector 列仍然保存原来的 JSON 文本，只需要报告列的类型
+	columnType = regexp.MustCompile(`(?is)^\s*SELECT udt_name FROM information_schema\.columns\b.*`)
+	addColumn  = regexp.MustCompile(`(?i)ADD COLUMN IF NOT EXISTS`)
+	casts      = regexp.MustCompile(`::\w+(\(\d+\))?`)
+	anyArray   = regexp.MustCompile(`(?i)=\s*ANY\((\$\d+)\)`)
+	vacuum     = regexp.MustCompile(`(?i)^\s*VACUUM\s+\w+\s*$`)
+	distance   = regexp.MustCompile(`(\w+) (<->|<=>|<#>|<\+>) (\$\d+)`)
+)
+
+// distanceFuncs 为 pgvector 操作符对应的 SQLite 函数
+var distanceFuncs = map[string]string{
+	"<->": "l2_distance",
+	"<=>": "cosine_distance",
+	"<#>": "negative_inner_product",
+	"<+>": "l1_distance",
+}
+
+// rewrite 把 PostgreSQL 语句改写为 SQLite 语句，第二个返回值表示是否为 ADD COLUMN IF NOT EXISTS
+func rewrite(query string) (string, bool) {
+	if noop.MatchString(query) {
+		return "SELECT 1", false
+	}
+	if columnType.MatchString(query) {
+		return "SELECT type FROM pragma_table_info($1) WHERE name = 'vector'", false
+	}
+	if vacuum.MatchString(query) {
+		return "VACUUM", false
+	}
+	add := addColumn.MatchString(query)
+	if add {
+		query = addColumn.ReplaceAllString(query, "ADD COLUMN")
+	}
+	query = casts.ReplaceAllString(query, "")
+	query = anyArray.ReplaceAllString(query, "IN (SELECT value FROM json_each(pg_array(${1})))")
+	query = distance.ReplaceAllStringFunc(query, func(m string) string {
+		p := distance.FindStringSubmatch(m)
+		return fmt.Sprintf("%s(%s, %s)", distanceFuncs[p[2]], p[1], p[3])
+	})
+	return query, add
+}
+
+func registerFuncs(c *sqlite3.SQLiteConn) error {
+	funcs := map[string]interface{}{
+		"pg_array":               pgArray,
+		"l2_distance":            vectorFunc(l2Distance),
+		"cosine_distance":        vectorFunc(cosineDistance),
+		"negative_inner_product": vectorFunc(negativeInnerProduct),
+		"l1_distance":            vectorFunc(l1Distance),
+	}
+	for name, impl := range funcs {
+		if err := c.RegisterFunc(name, impl, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pgArray 把 lib/pq 编码的文本数组（如 {a,b}）转换为 JSON 数组，供 json_each 展开
+func pgArray(s string) (string, error) {
+	var arr pq.StringArray
+	if err := arr.Scan(s); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal([]string(arr))
+	return string(data), err
+}
+
+// vectorFunc 把两个以 JSON 文本保存的向量解码后计算距离，与 pgvector 一样要求维度相同
+func vectorFunc(dist func(a, b []float64) float64) func(a, b string) (float64, error) {
+	return func(a, b string) (float64, error) {
+		var va, vb []float64
+		if err := json.Unmarshal([]byte(a), &va); err != nil {
+			return 0, err
+		}
+		if err := json.Unmarshal([]byte(b), &vb); err != nil {
+			return 0, err
+		}
+		if len(va) != len(vb) {
+			return 0, fmt.Errorf("different vector dimensions %d and %d", len(va), len(vb))
+		}
+		return dist(va, vb), nil
+	}
+}
+
+func l2Distance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+func cosineDistance(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return math.NaN()
+	}
+	return 1 - dot/math.Sqrt(na*nb)
+}
+
+func negativeInnerProduct(a, b []float64) float64 {
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return -dot
+}
+
+func l1Distance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += math.Abs(a[i] - b[i])
+	}
+	return sum
+}
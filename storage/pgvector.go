@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PgvectorOptions 为 pgvector 存储的参数，为 0 的字段使用默认值
+type PgvectorOptions struct {
+	Dim            int    // 向量维度，决定 vector(dim) 列的类型
+	Metric         string // cosine、l2、ip 或 manhattan，决定索引的操作符类和搜索使用的距离
+	Index          string // 在数据库中建立的向量索引：hnsw（默认）、ivfflat 或 none
+	M              int    // hnsw 每层最大连接数，默认 16
+	EFConstruction int    // hnsw 构建时的候选集大小，默认 64
+	EFSearch       int    // hnsw 搜索时的候选集大小，默认 40，小于 k 时使用 k
+	Lists          int    // ivfflat 的簇数量，默认 100
+	Probes         int    // ivfflat 搜索时扫描的簇数量，默认 10
+}
+
+func (o *PgvectorOptions) normalize() error {
+	if o.Dim <= 0 {
+		return errors.New("pgvector requires a positive dimension")
+	}
+	if _, ok := pgvectorMetrics[o.Metric]; !ok {
+		return fmt.Errorf("pgvector does not support metric %q", o.Metric)
+	}
+	switch o.Index {
+	case "":
+		o.Index = "hnsw"
+	case "hnsw", "none":
+	case "ivfflat":
+		if o.Metric == "manhattan" {
+			return errors.New("pgvector ivfflat indexes do not support metric manhattan, use hnsw")
+		}
+	default:
+		return fmt.Errorf("unknown pgvector index type: %s", o.Index)
+	}
+	if o.M == 0 {
+		o.M = 16
+	}
+	if o.EFConstruction == 0 {
+		o.EFConstruction = 64
+	}
+	if o.EFSearch == 0 {
+		o.EFSearch = 40
+	}
+	if o.Lists == 0 {
+		o.Lists = 100
+	}
+	if o.Probes == 0 {
+		o.Probes = 10
+	}
+	return nil
+}
+
+// pgvectorMetric 描述一种度量在 pgvector 中的距离操作符、索引操作符类，以及把距离换算为得分的方式
+type pgvectorMetric struct {
+	op      string
+	opclass string
+	score   func(distance float64) float32
+}
+
+var pgvectorMetrics = map[string]pgvectorMetric{
+	"cosine":    {"<=>", "vector_cosine_ops", func(d float64) float32 { return float32(1 - d) }},
+	"l2":        {"<->", "vector_l2_ops", func(d float64) float32 { return float32(d) }},
+	"ip":        {"<#>", "vector_ip_ops", func(d float64) float32 { return float32(-d) }}, // <#> 返回内积的相反数
+	"manhattan": {"<+>", "vector_l1_ops", func(d float64) float32 { return float32(d) }},
+}
+
+// hnswMaxEFSearch 为 pgvector 允许的 hnsw.ef_search 上限，一次 hnsw 搜索最多返回这么多行
+const hnswMaxEFSearch = 1000
+
+// PgvectorStorage 为使用 pgvector 扩展的 PostgreSQL 存储：向量保存在 vector(dim) 列中，
+// 并可以在数据库中建立 hnsw 或 ivfflat 索引、由数据库执行近邻搜索
+type PgvectorStorage struct {
+	*PostgresStorage
+	opts   PgvectorOptions
+	metric pgvectorMetric
+}
+
+// NewPgvectorStorage 连接数据库，启用 vector 扩展并使用指定的表。
+// 旧表 JSONB 类型的 vector 列会转换为 vector(dim)；向量索引只在不存在时创建，修改索引参数后需要先删除 <表名>_vector_<索引类型> 索引
+func NewPgvectorStorage(host string, port int, user, password, database, table string, opts PgvectorOptions) (*PgvectorStorage, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	ps, err := openPostgres(host, port, user, password, database, table)
+	if err != nil {
+		return nil, err
+	}
+	ps.vectorColumn = fmt.Sprintf("vector(%d)", opts.Dim)
+	ps.vectorCast = "vector"
+	s := &PgvectorStorage{PostgresStorage: ps, opts: opts, metric: pgvectorMetrics[opts.Metric]}
+	if _, err := s.db.Exec("CREATE EXTENSION IF NOT EXISTS vector"); err != nil {
+		return s, fmt.Errorf("enable pgvector: %v", err)
+	}
+	if err := s.createTable(); err != nil {
+		return s, err
+	}
+	if err := s.convertColumn(); err != nil {
+		return s, err
+	}
+	return s, s.createIndex()
+}
+
+// convertColumn 把 vector 列从 JSONB 就地转换为 vector(dim)，维度与配置不一致的文档会使转换失败
+func (s *PgvectorStorage) convertColumn() error {
+	var typ string
+	err := s.db.QueryRow(`SELECT udt_name FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'vector'`, strings.ToLower(s.table)).Scan(&typ)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(typ, "jsonb") {
+		return nil
+	}
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN vector TYPE %s USING vector::text::%s", s.table, s.vectorColumn, s.vectorColumn))
+	if err != nil {
+		return fmt.Errorf("convert vector column to %s: %v", s.vectorColumn, err)
+	}
+	return nil
+}
+
+func (s *PgvectorStorage) createIndex() error {
+	var with string
+	switch s.opts.Index {
+	case "hnsw":
+		with = fmt.Sprintf("m = %d, ef_construction = %d", s.opts.M, s.opts.EFConstruction)
+	case "ivfflat":
+		with = fmt.Sprintf("lists = %d", s.opts.Lists)
+	default:
+		return nil
+	}
+	_, err := s.db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_vector_%s ON %s USING %s (vector %s) WITH (%s)",
+		s.table, s.opts.Index, s.table, s.opts.Index, s.metric.opclass, with))
+	return err
+}
+
+// SearchVectors 在数据库中按配置的度量搜索，使用向量索引时结果是近似的。
+// 搜索参数通过 SET LOCAL 设置在单独的事务中，不影响连接上的其他查询
+func (s *PgvectorStorage) SearchVectors(query []float32, k int) ([]VectorMatch, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	q, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	switch s.opts.Index {
+	case "hnsw":
+		_, err = tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", min(max(s.opts.EFSearch, k), hnswMaxEFSearch)))
+	case "ivfflat":
+		_, err = tx.Exec(fmt.Sprintf("SET LOCAL ivfflat.probes = %d", s.opts.Probes))
+	}
+	if err != nil {
+		return nil, err
+	}
+	distance := "vector " + s.metric.op + " $1::vector"
+	rows, err := tx.Query("SELECT id, "+distance+" FROM "+s.table+" ORDER BY "+distance+" LIMIT $2", string(q), k)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	matches := make([]VectorMatch, 0, k)
+	for rows.Next() {
+		var id string
+		var d float64
+		if err := rows.Scan(&id, &d); err != nil {
+			return nil, err
+		}
+		matches = append(matches, VectorMatch{ID: id, Score: s.metric.score(d)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return matches, tx.Commit()
+}
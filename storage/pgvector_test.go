@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gvdb/storage/pgtest"
+)
+
+func TestPgvectorStorage(t *testing.T) {
+	database := postgresDatabase(t)
+	const table = "pgvector_test"
+
+	// 旧表的 JSONB 列在启用 pgvector 时转换为 vector(3)
+	legacy, err := NewPostgresStorageTable("localhost", 5432, "postgres", "your_password", database, table)
+	if err != nil {
+		t.Fatalf("NewPostgresStorageTable failed: %v", err)
+	}
+	if err := legacy.Insert("x", VectorDoc{Vector: []float32{1, 0, 0}, Payload: Payload{"n": int64(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	legacy.Close()
+
+	s, err := NewPgvectorStorage("localhost", 5432, "postgres", "your_password", database, table, PgvectorOptions{Dim: 3, Metric: "cosine"})
+	if err != nil {
+		t.Fatalf("NewPgvectorStorage failed: %v", err)
+	}
+	defer s.Drop()
+	if doc, ok := s.Get("x"); !ok || !reflect.DeepEqual(doc.Vector, []float32{1, 0, 0}) || doc.Payload["n"] != int64(1) {
+		t.Errorf("Unexpected converted document: %+v", doc)
+	}
+	if err := s.UpsertBatch(map[string]VectorDoc{
+		"y": {Vector: []float32{0.9, 0.1, 0}},
+		"z": {Vector: []float32{0, 0, 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := s.SearchVectors([]float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].ID != "x" || matches[1].ID != "y" || matches[0].Score < 0.999 {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+	if os.Getenv("POSTGRES_TEST") != "true" {
+		log := strings.Join(pgtest.Statements(database), "\n")
+		for _, want := range []string{
+			"ALTER TABLE pgvector_test ALTER COLUMN vector TYPE vector(3)",
+			"USING hnsw (vector vector_cosine_ops) WITH (m = 16, ef_construction = 64)",
+			"SET LOCAL hnsw.ef_search = 40",
+		} {
+			if !strings.Contains(log, want) {
+				t.Errorf("Expected a statement containing %q", want)
+			}
+		}
+	}
+
+	testBatch(t, s)
+}
+
+func TestPgvectorSearchMetrics(t *testing.T) {
+	database := postgresDatabase(t)
+	docs := map[string]VectorDoc{
+		"a": {Vector: []float32{1, 1}},
+		"b": {Vector: []float32{4, 4}},
+	}
+	// 得分与进程内索引的含义相同：l2 和 manhattan 为距离，ip 为内积
+	for metric, want := range map[string][]VectorMatch{
+		"l2":        {{"a", 1}, {"b", 5}},
+		"manhattan": {{"a", 1}, {"b", 7}},
+		"ip":        {{"b", 4}, {"a", 1}},
+	} {
+		s, err := NewPgvectorStorage("localhost", 5432, "postgres", "your_password", database, "pgvector_"+metric, PgvectorOptions{Dim: 2, Metric: metric, Index: "none"})
+		if err != nil {
+			t.Fatalf("%s: %v", metric, err)
+		}
+		if err := s.UpsertBatch(docs); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.SearchVectors([]float32{1, 0}, 5)
+		s.Drop()
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, %v; want %+v", metric, got, err, want)
+		}
+	}
+
+	for _, opts := range []PgvectorOptions{
+		{Dim: 0, Metric: "l2"},
+		{Dim: 2, Metric: "hamming"},
+		{Dim: 2, Metric: "manhattan", Index: "ivfflat"},
+		{Dim: 2, Metric: "l2", Index: "btree"},
+	} {
+		if _, err := NewPgvectorStorage("localhost", 5432, "postgres", "your_password", database, "invalid", opts); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+}
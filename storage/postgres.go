@@ -3,98 +3,219 @@ package storage
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresStorage struct {
-	db *sql.DB
+	db    *sql.DB
+	table string
+	// vectorColumn 为 vector 列的类型，vectorCast 为写入时 JSON 文本转换的目标类型；pgvector 存储使用 vector(dim) 和 vector
+	vectorColumn string
+	vectorCast   string
 }
 
+// PostgresDriver 为 PostgreSQL 存储使用的 database/sql 驱动名，测试中可以换成 storage/pgtest 提供的替身
+var PostgresDriver = "postgres"
+
+// NewPostgresStorage 连接数据库并使用 vectors 表；旧表会自动添加 JSONB 类型的 payload 列，并把已有的 meta 迁移到 payload
 func NewPostgresStorage(host string, port int, user, password, database string) (*PostgresStorage, error) {
+	return NewPostgresStorageTable(host, port, user, password, database, DefaultTable)
+}
+
+// NewPostgresStorageTable 与 NewPostgresStorage 相同，但使用指定的表
+func NewPostgresStorageTable(host string, port int, user, password, database, table string) (*PostgresStorage, error) {
+	s, err := openPostgres(host, port, user, password, database, table)
+	if err != nil {
+		return nil, err
+	}
+	return s, s.createTable()
+}
+
+func openPostgres(host string, port int, user, password, database, table string) (*PostgresStorage, error) {
+	if !validTable(table) {
+		return nil, fmt.Errorf("invalid table name: %q", table)
+	}
 	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		host, port, user, password, database)
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open(PostgresDriver, connStr)
 	if err != nil {
 		return nil, err
 	}
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS vectors (
+	return &PostgresStorage{db: db, table: table, vectorColumn: "JSONB", vectorCast: "jsonb"}, nil
+}
+
+// createTable 建表，并为旧表补上 payload 列、迁移 meta
+func (s *PostgresStorage) createTable() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
         id TEXT PRIMARY KEY,
-        vector JSONB,
-        meta TEXT
+        vector ` + s.vectorColumn + `,
+        meta TEXT,
+        payload JSONB
     )`)
-	return &PostgresStorage{db: db}, err
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("ALTER TABLE " + s.table + " ADD COLUMN IF NOT EXISTS payload JSONB"); err != nil {
+		return err
+	}
+	return migrateMetaColumn(s.db, s.table, "UPDATE "+s.table+" SET payload = $1::jsonb WHERE id = $2")
 }
 
 func (s *PostgresStorage) Load() (map[string]VectorDoc, error) {
-	data := make(map[string]VectorDoc)
-	rows, err := s.db.Query("SELECT id, vector, meta FROM vectors")
+	_, data, err := s.query("SELECT id, vector, meta, payload FROM " + s.table)
+	return data, err
+}
+
+// Scan 按 ID 顺序（数据库的排序规则）返回 after 之后的至多 limit 个文档
+func (s *PostgresStorage) Scan(after string, limit int) ([]string, map[string]VectorDoc, error) {
+	return s.query("SELECT id, vector, meta, payload FROM "+s.table+" WHERE id > $1 ORDER BY id LIMIT $2", after, limit)
+}
+
+// query 执行返回 id、vector、meta、payload 列的查询，按结果顺序返回 ID
+func (s *PostgresStorage) query(query string, args ...interface{}) ([]string, map[string]VectorDoc, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
+	var ids []string
+	data := make(map[string]VectorDoc)
 	for rows.Next() {
-		var id, meta string
-		var vectorBlob []byte
-		if err := rows.Scan(&id, &vectorBlob, &meta); err != nil {
-			return nil, err
+		var id string
+		var meta sql.NullString
+		var vectorBlob, payloadBlob []byte
+		if err := rows.Scan(&id, &vectorBlob, &meta, &payloadBlob); err != nil {
+			return nil, nil, err
 		}
-		var vector []float64
+		var vector []float32
 		if err := json.Unmarshal(vectorBlob, &vector); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		payload, err := DecodePayload(payloadBlob)
+		if err != nil {
+			return nil, nil, err
 		}
-		data[id] = VectorDoc{Vector: vector, Meta: meta}
+		ids = append(ids, id)
+		data[id] = VectorDoc{Vector: vector, Meta: meta.String, Payload: payload}
 	}
-	return data, nil
+	return ids, data, rows.Err()
 }
 
 func (s *PostgresStorage) Save(data map[string]VectorDoc) error {
+	return s.UpsertBatch(data)
+}
+
+// postgresUpsert 中 JSON 以文本参数传入，由 Postgres 转换为 JSONB 或 pgvector 的 vector；
+// %[1]s 为表名，%[2]s 为向量的类型
+const postgresUpsert = postgresInsert + `
+ON CONFLICT (id) DO UPDATE SET vector = $2::%[2]s, meta = $3, payload = $4::jsonb`
+
+const postgresInsert = `INSERT INTO %[1]s (id, vector, meta, payload) VALUES ($1, $2::%[2]s, $3, $4::jsonb)`
+
+// postgresUniqueViolation 为主键冲突的 SQLSTATE
+const postgresUniqueViolation = "23505"
+
+func (s *PostgresStorage) Insert(id string, doc VectorDoc) error {
+	vectorBlob, err := json.Marshal(doc.Vector)
+	if err != nil {
+		return err
+	}
+	payload, err := payloadArg(doc.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(fmt.Sprintf(postgresUpsert, s.table, s.vectorCast), id, string(vectorBlob), doc.Meta, payload)
+	return err
+}
+
+func (s *PostgresStorage) Get(id string) (VectorDoc, bool) {
+	var vectorBlob, payloadBlob []byte
+	var meta sql.NullString
+	err := s.db.QueryRow("SELECT vector, meta, payload FROM "+s.table+" WHERE id = $1", id).Scan(&vectorBlob, &meta, &payloadBlob)
+	if err != nil {
+		return VectorDoc{}, false
+	}
+	var vector []float32
+	json.Unmarshal(vectorBlob, &vector)
+	payload, _ := DecodePayload(payloadBlob)
+	return VectorDoc{Vector: vector, Meta: meta.String, Payload: payload}, true
+}
+
+func (s *PostgresStorage) Delete(id string) error {
+	_, err := s.db.Exec("DELETE FROM "+s.table+" WHERE id = $1", id)
+	return err
+}
+
+// InsertBatch 在一个事务中插入，主键冲突时回滚并返回 ErrExists
+func (s *PostgresStorage) InsertBatch(docs map[string]VectorDoc) error {
+	return s.writeBatch(postgresInsert, docs)
+}
+
+// UpsertBatch 在一个事务中用 ON CONFLICT DO UPDATE 写入
+func (s *PostgresStorage) UpsertBatch(docs map[string]VectorDoc) error {
+	return s.writeBatch(postgresUpsert, docs)
+}
+
+func (s *PostgresStorage) writeBatch(query string, docs map[string]VectorDoc) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
-	stmt, err := tx.Prepare("INSERT INTO vectors (id, vector, meta) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET vector = $2, meta = $3")
+	stmt, err := tx.Prepare(fmt.Sprintf(query, s.table, s.vectorCast))
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
-	for id, doc := range data {
+	for id, doc := range docs {
 		vectorBlob, err := json.Marshal(doc.Vector)
 		if err != nil {
+			tx.Rollback()
 			return err
 		}
-		if _, err := stmt.Exec(id, vectorBlob, doc.Meta); err != nil {
+		payload, err := payloadArg(doc.Payload)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := stmt.Exec(id, string(vectorBlob), doc.Meta, payload); err != nil {
+			tx.Rollback()
+			var pe *pq.Error
+			if errors.As(err, &pe) && pe.Code == postgresUniqueViolation {
+				return fmt.Errorf("%w: %s", ErrExists, id)
+			}
 			return err
 		}
 	}
 	return tx.Commit()
 }
 
-func (s *PostgresStorage) Insert(id string, doc VectorDoc) error {
-	vectorBlob, err := json.Marshal(doc.Vector)
+// DeleteBatch 用一条 DELETE ... WHERE id = ANY($1) 删除
+func (s *PostgresStorage) DeleteBatch(ids []string) (int, error) {
+	res, err := s.db.Exec("DELETE FROM "+s.table+" WHERE id = ANY($1)", pq.Array(ids))
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = s.db.Exec("INSERT INTO vectors (id, vector, meta) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET vector = $2, meta = $3", id, vectorBlob, doc.Meta)
-	return err
+	n, err := res.RowsAffected()
+	return int(n), err
 }
 
-func (s *PostgresStorage) Get(id string) (VectorDoc, bool) {
-	var vectorBlob []byte
-	var meta string
-	err := s.db.QueryRow("SELECT vector, meta FROM vectors WHERE id = $1", id).Scan(&vectorBlob, &meta)
-	if err != nil {
-		return VectorDoc{}, false
-	}
-	var vector []float64
-	json.Unmarshal(vectorBlob, &vector)
-	return VectorDoc{Vector: vector, Meta: meta}, true
-}
+func (s *PostgresStorage) Close() error { return s.db.Close() }
 
-func (s *PostgresStorage) Delete(id string) error {
-	_, err := s.db.Exec("DELETE FROM vectors WHERE id = $1", id)
+// Compact 对表执行 VACUUM，回收已删除记录占用的空间
+func (s *PostgresStorage) Compact() error {
+	_, err := s.db.Exec("VACUUM " + s.table)
 	return err
 }
 
-func (s *PostgresStorage) Close() error { return s.db.Close() }
+// Drop 删除表并关闭连接
+func (s *PostgresStorage) Drop() error {
+	if _, err := s.db.Exec("DROP TABLE IF EXISTS " + s.table); err != nil {
+		s.db.Close()
+		return err
+	}
+	return s.db.Close()
+}
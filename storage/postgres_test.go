@@ -2,25 +2,36 @@ package storage
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"gvdb/storage/pgtest"
 )
 
-func TestPostgresStorage(t *testing.T) {
-	// 跳过测试，除非明确启用 PostgreSQL 测试环境
-	if os.Getenv("POSTGRES_TEST") != "true" {
-		t.Skip("Skipping PostgreSQL test; set POSTGRES_TEST=true to run")
+// postgresDatabase 返回测试使用的数据库名：设置 POSTGRES_TEST=true 时连接本机的 test_db，
+// 否则换用 pgtest 的替身驱动，数据库为临时目录中的 SQLite 文件
+func postgresDatabase(t *testing.T) string {
+	t.Helper()
+	if os.Getenv("POSTGRES_TEST") == "true" {
+		return "test_db"
 	}
+	driver := PostgresDriver
+	PostgresDriver = pgtest.Driver
+	t.Cleanup(func() { PostgresDriver = driver })
+	return filepath.Join(t.TempDir(), "test_db")
+}
 
+func TestPostgresStorage(t *testing.T) {
 	// 初始化 PostgreSQL 存储
-	s, err := NewPostgresStorage("localhost", 5432, "postgres", "your_password", "test_db")
+	s, err := NewPostgresStorage("localhost", 5432, "postgres", "your_password", postgresDatabase(t))
 	if err != nil {
 		t.Fatalf("NewPostgresStorage failed: %v", err)
 	}
 	defer s.Close()
 
 	// 测试插入
-	doc := VectorDoc{Vector: []float64{1.0, 2.0, 3.0}, Meta: "test"}
+	doc := VectorDoc{Vector: []float32{1.0, 2.0, 3.0}, Meta: "test"}
 	err = s.Insert("id1", doc)
 	if err != nil {
 		t.Fatalf("Insert failed: %v", err)
@@ -35,6 +46,18 @@ func TestPostgresStorage(t *testing.T) {
 		t.Errorf("Expected doc %v, got %v", doc, gotDoc)
 	}
 
+	// 测试 JSONB payload
+	withPayload := VectorDoc{Vector: []float32{4, 5, 6}, Payload: Payload{"year": int64(2024), "tags": []interface{}{"a"}}}
+	if err := s.Insert("id2", withPayload); err != nil {
+		t.Fatalf("Insert with payload failed: %v", err)
+	}
+	defer s.Delete("id2")
+	if got, _ := s.Get("id2"); !reflect.DeepEqual(got, withPayload) {
+		t.Errorf("Expected doc %v, got %v", withPayload, got)
+	}
+
+	testBatch(t, s)
+
 	// 测试删除
 	err = s.Delete("id1")
 	if err != nil {
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"math"
+	"os"
+
+	"gvdb/vector"
+)
+
+// 段文件格式：8 字节 magic，之后为若干帧。每帧为长度（uint32）、CRC32（uint32）和内容，
+// 内容为操作数和各个操作：类型、ID，写入操作还有向量（维度加小端序 float32）、Meta 和 JSON 编码的 Payload，
+// 变长整数均为 uvarint。一次写入的全部操作放在同一帧中，崩溃后要么全部可见，要么全部丢弃
+const (
+	segmentMagic    = "GVDBSEG1"
+	segmentSize     = 64 << 20 // 当前段超过该长度后封闭并开始新段
+	maxFrameSize    = 1 << 30
+	manifestFormat  = "gvdb-segments"
+	manifestVersion = 1
+)
+
+// ErrCorrupt 表示文件存储的段与 manifest 中的校验和不符或无法解析
+var ErrCorrupt = errors.New("file storage is corrupt")
+
+const (
+	opPut    byte = 1
+	opDelete byte = 2
+)
+
+// segmentOp 为段中的一个写入或删除操作
+type segmentOp struct {
+	id     string
+	delete bool
+	doc    VectorDoc
+}
+
+// manifest 保存在 FileStorage 的 path 处，按写入顺序列出段；最后一个段为当前追加的段，其余段已封闭
+type manifest struct {
+	Format   string        `json:"format"`
+	Version  int           `json:"version"`
+	Next     int           `json:"next"` // 下一个新段的编号
+	Segments []segmentInfo `json:"segments"`
+}
+
+// segmentInfo 描述一个段，封闭时记录长度和整个文件的 CRC32，打开时校验
+type segmentInfo struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size,omitempty"`
+	Checksum uint32 `json:"checksum,omitempty"`
+}
+
+// parseManifest 解析 manifest；旧版的 JSON 数据文件以文档 ID 为键，返回 false
+func parseManifest(raw []byte) (manifest, bool) {
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil || m.Format != manifestFormat {
+		return manifest{}, false
+	}
+	return m, true
+}
+
+// encodeFrame 把一组操作编码为一帧
+func encodeFrame(ops []segmentOp) ([]byte, error) {
+	body := make([]byte, 8, 64)
+	body = binary.AppendUvarint(body, uint64(len(ops)))
+	for _, op := range ops {
+		if op.delete {
+			body = append(body, opDelete)
+			body = appendString(body, op.id)
+			continue
+		}
+		payload, err := EncodePayload(op.doc.Payload)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, opPut)
+		body = appendString(body, op.id)
+		body = binary.AppendUvarint(body, uint64(len(op.doc.Vector)))
+		body = append(body, vector.Encode(op.doc.Vector)...)
+		body = appendString(body, op.doc.Meta)
+		body = appendString(body, string(payload))
+	}
+	if len(body)-8 > maxFrameSize {
+		return nil, fmt.Errorf("write of %d bytes is too large", len(body)-8)
+	}
+	binary.LittleEndian.PutUint32(body[:4], uint32(len(body)-8))
+	binary.LittleEndian.PutUint32(body[4:8], crc32.ChecksumIEEE(body[8:]))
+	return body, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// decodeFrames 依次解析 data 中的帧并对每个操作调用 apply，返回完整帧的总长度。
+// 遇到不完整、校验失败或无法解析的帧时停止并返回原因，该帧的操作都不会交给 apply
+func decodeFrames(data []byte, apply func(segmentOp)) (int, error) {
+	off := 0
+	for off < len(data) {
+		if len(data)-off < 8 {
+			return off, errors.New("incomplete frame header")
+		}
+		length := int(binary.LittleEndian.Uint32(data[off:]))
+		if length > maxFrameSize || length > len(data)-off-8 {
+			return off, errors.New("incomplete frame")
+		}
+		body := data[off+8 : off+8+length]
+		if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[off+4:]) {
+			return off, errors.New("checksum mismatch")
+		}
+		ops, err := decodeOps(body)
+		if err != nil {
+			return off, err
+		}
+		for _, op := range ops {
+			apply(op)
+		}
+		off += 8 + length
+	}
+	return off, nil
+}
+
+func decodeOps(body []byte) ([]segmentOp, error) {
+	r := frameReader{buf: body}
+	n := r.uvarint()
+	if n > uint64(len(body)) {
+		return nil, errors.New("invalid operation count")
+	}
+	ops := make([]segmentOp, 0, n)
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		kind := r.byte()
+		op := segmentOp{id: r.string()}
+		switch kind {
+		case opDelete:
+			op.delete = true
+		case opPut:
+			dim := r.uvarint()
+			if dim > uint64(len(r.buf))/4 {
+				r.fail()
+				break
+			}
+			raw := r.bytes(4 * dim)
+			op.doc.Vector = make([]float32, dim)
+			for j := range op.doc.Vector {
+				op.doc.Vector[j] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*j:]))
+			}
+			op.doc.Meta = r.string()
+			payload, err := DecodePayload(r.bytes(r.uvarint()))
+			if err != nil && r.err == nil {
+				r.err = err
+			}
+			op.doc.Payload = payload
+		default:
+			r.fail()
+		}
+		ops = append(ops, op)
+	}
+	if r.err == nil && len(r.buf) != 0 {
+		r.fail()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return ops, nil
+}
+
+// frameReader 从帧内容中读取字段，越界时记录错误并返回零值
+type frameReader struct {
+	buf []byte
+	err error
+}
+
+func (r *frameReader) fail() {
+	if r.err == nil {
+		r.err = errors.New("malformed frame")
+	}
+	r.buf = nil
+}
+
+func (r *frameReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *frameReader) byte() byte {
+	if len(r.buf) < 1 {
+		r.fail()
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *frameReader) bytes(n uint64) []byte {
+	if n > uint64(len(r.buf)) {
+		r.fail()
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *frameReader) string() string {
+	return string(r.bytes(r.uvarint()))
+}
+
+// createSegment 创建只含 magic 的新段并 fsync，返回的 CRC32 已包含 magic
+func createSegment(path string) (*os.File, hash.Hash32, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := f.Write([]byte(segmentMagic)); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(segmentMagic))
+	return f, crc, nil
+}
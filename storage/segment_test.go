@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegmentFrames(t *testing.T) {
+	ops := []segmentOp{
+		{id: "a", doc: VectorDoc{Vector: []float32{1.5, -2}, Meta: "m", Payload: Payload{"n": int64(1)}}},
+		{id: "b", doc: VectorDoc{Vector: []float32{0, 0}}},
+		{id: "a", delete: true},
+	}
+	frame, err := encodeFrame(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(append([]byte(nil), frame...), frame...)
+	var got []segmentOp
+	n, err := decodeFrames(data, func(op segmentOp) { got = append(got, op) })
+	if err != nil || n != len(data) {
+		t.Fatalf("decodeFrames = %d, %v", n, err)
+	}
+	if !reflect.DeepEqual(got, append(ops, ops...)) {
+		t.Errorf("Unexpected operations %+v", got)
+	}
+
+	// 不完整或被修改的帧不会应用任何操作
+	for cut := 1; cut < len(frame); cut++ {
+		applied := 0
+		n, err := decodeFrames(data[:len(frame)+cut], func(segmentOp) { applied++ })
+		if err == nil || n != len(frame) || applied != len(ops) {
+			t.Fatalf("Truncated at %d: n=%d applied=%d err=%v", cut, n, applied, err)
+		}
+	}
+	frame[len(frame)-1] ^= 1
+	if n, err := decodeFrames(frame, func(segmentOp) { t.Error("Expected corrupt frame to be skipped") }); err == nil || n != 0 {
+		t.Errorf("Expected checksum mismatch, got %d, %v", n, err)
+	}
+}
@@ -1,9 +1,21 @@
 package storage
 
-// VectorDoc 表示存储的向量文档
+import (
+	"errors"
+	"log"
+	"os"
+	"regexp"
+)
+
+// Logger 接收不影响调用结果的诊断信息，例如截断写入中途崩溃留下的不完整帧、自动封闭段或压缩失败，默认写到标准错误
+var Logger = log.New(os.Stderr, "", log.LstdFlags)
+
+// VectorDoc 表示存储的向量文档，向量以 float32 存储。
+// Meta 为旧版的字符串元数据，仍会原样保存；新代码应使用 Payload。打开存储时只有 Meta 的旧数据会通过 MetaToPayload 迁移
 type VectorDoc struct {
-	Vector []float64
-	Meta   string
+	Vector  []float32
+	Meta    string
+	Payload Payload `json:",omitempty"`
 }
 
 // Storage 定义存储接口
@@ -14,4 +26,61 @@ type Storage interface {
 	Get(id string) (VectorDoc, bool)
 	Delete(id string) error
 	Close() error
+	// Drop 删除全部数据（文件或表）并释放资源，之后不能再使用
+	Drop() error
+	// InsertBatch 原子地写入一批新文档，任一 ID 已存在时返回 ErrExists，不写入任何文档
+	InsertBatch(docs map[string]VectorDoc) error
+	// UpsertBatch 原子地写入或替换一批文档
+	UpsertBatch(docs map[string]VectorDoc) error
+	// DeleteBatch 原子地删除一批文档并返回实际删除的数量，不存在的 ID 会被忽略
+	DeleteBatch(ids []string) (int, error)
+}
+
+// ErrExists 表示 InsertBatch 写入的 ID 已经存在
+var ErrExists = errors.New("document already exists")
+
+// Compacter 由能够回收已删除数据所占空间的存储实现
+type Compacter interface {
+	Compact() error
+}
+
+var (
+	_ Compacter = (*FileStorage)(nil)
+	_ Compacter = (*DuckDBStorage)(nil)
+	_ Compacter = (*PostgresStorage)(nil)
+)
+
+// Scanner 由能够按 ID 顺序分页读取、不必一次加载全部文档的存储实现
+type Scanner interface {
+	// Scan 按 ID 升序返回 after 之后的至多 limit 个文档的 ID 及文档，没有更多文档时返回空列表
+	Scan(after string, limit int) ([]string, map[string]VectorDoc, error)
+}
+
+var (
+	_ Scanner = (*DuckDBStorage)(nil)
+	_ Scanner = (*PostgresStorage)(nil)
+)
+
+// VectorMatch 为存储端向量搜索的一个结果
+type VectorMatch struct {
+	ID    string
+	Score float32 // 与进程内索引的得分含义相同：cosine 和 ip 为相似度，l2 和 manhattan 为距离
+}
+
+// VectorSearcher 由能够在数据库中执行近邻搜索的存储实现（如 pgvector），集合可以不在进程内建立向量索引
+type VectorSearcher interface {
+	// SearchVectors 返回与 query 最相似的至多 k 个文档，按相似程度从高到低排列
+	SearchVectors(query []float32, k int) ([]VectorMatch, error)
+}
+
+var _ VectorSearcher = (*PgvectorStorage)(nil)
+
+// DefaultTable 为 SQL 存储默认使用的表名
+const DefaultTable = "vectors"
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validTable 检查表名，表名会直接拼接进 SQL 语句
+func validTable(table string) bool {
+	return tableName.MatchString(table) && len(table) <= 63
 }
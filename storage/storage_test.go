@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testBatch 检查批量接口的语义，各存储实现共用
+func testBatch(t *testing.T, s Storage) {
+	t.Helper()
+	docs := map[string]VectorDoc{
+		"b1": {Vector: []float32{1, 0, 0}, Payload: Payload{"n": int64(1)}},
+		"b2": {Vector: []float32{0, 1, 0}, Meta: "two"},
+	}
+	if err := s.InsertBatch(docs); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	// 有一个 ID 已存在时整批都不写入
+	err := s.InsertBatch(map[string]VectorDoc{"b3": {Vector: []float32{0, 0, 1}}, "b1": {Vector: []float32{9, 9, 9}}})
+	if !errors.Is(err, ErrExists) {
+		t.Fatalf("Expected ErrExists, got %v", err)
+	}
+	if _, ok := s.Get("b3"); ok {
+		t.Error("Expected failed InsertBatch to write nothing")
+	}
+	if got, _ := s.Get("b1"); !reflect.DeepEqual(got, docs["b1"]) {
+		t.Errorf("Expected b1 unchanged, got %v", got)
+	}
+
+	replaced := VectorDoc{Vector: []float32{2, 2, 2}, Meta: "new"}
+	if err := s.UpsertBatch(map[string]VectorDoc{"b1": replaced, "b3": {Vector: []float32{0, 0, 1}}}); err != nil {
+		t.Fatalf("UpsertBatch failed: %v", err)
+	}
+	if got, _ := s.Get("b1"); !reflect.DeepEqual(got, replaced) {
+		t.Errorf("Expected b1 replaced, got %v", got)
+	}
+
+	n, err := s.DeleteBatch([]string{"b1", "b2", "missing"})
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteBatch = %d, %v; want 2", n, err)
+	}
+	data, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, b1 := data["b1"]
+	_, b3 := data["b3"]
+	if b1 || !b3 {
+		t.Errorf("Expected b1 deleted and b3 kept, got %v", data)
+	}
+}
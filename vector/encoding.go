@@ -0,0 +1,40 @@
+package vector
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Encode 把向量编码为小端序 float32 字节序列
+func Encode(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+// Decode 解码 Encode 生成的字节序列；以 '[' 开头且能解析为 JSON 数组的数据按旧版格式解析，
+// 首字节恰好为 0x5B 的二进制数据仍按 float32 解码
+func Decode(buf []byte) ([]float32, error) {
+	if len(buf) > 0 && buf[0] == '[' {
+		var v []float32
+		err := json.Unmarshal(buf, &v)
+		if err == nil {
+			return v, nil
+		}
+		if len(buf)%4 != 0 {
+			return nil, err
+		}
+	}
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("vector: invalid encoded length %d", len(buf))
+	}
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v, nil
+}
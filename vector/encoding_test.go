@@ -0,0 +1,38 @@
+package vector
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	v := []float32{1.5, -2, 3.25, 0}
+	buf := Encode(v)
+	if len(buf) != 16 {
+		t.Fatalf("Expected 4 bytes per component, got %d", len(buf))
+	}
+	got, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("Expected %v, got %v", v, got)
+	}
+
+	// 兼容旧版 JSON 编码
+	got, err = Decode([]byte("[1,2.5,3]"))
+	if err != nil || !reflect.DeepEqual(got, []float32{1, 2.5, 3}) {
+		t.Errorf("Expected legacy JSON to decode, got %v (%v)", got, err)
+	}
+
+	// 二进制编码的首字节可能是 '['
+	v = []float32{math.Float32frombits(0x3f00005b), 2}
+	if got, err := Decode(Encode(v)); err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("Expected binary data starting with '[' to decode, got %v (%v)", got, err)
+	}
+
+	if _, err := Decode([]byte{1, 2, 3}); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+}
@@ -0,0 +1,36 @@
+package vector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKernels(t *testing.T) {
+	a := []float32{1, 2, 3, 4, 5}
+	b := []float32{5, 1, 0, 2, 2}
+	if got := Dot(a, b); got != 25 {
+		t.Errorf("Expected dot 25, got %f", got)
+	}
+	if got := L2Squared(a, b); got != 16+1+9+4+9 {
+		t.Errorf("Expected squared distance 39, got %f", got)
+	}
+	if got := L1(a, b); got != 4+1+3+2+3 {
+		t.Errorf("Expected manhattan distance 13, got %f", got)
+	}
+
+	v := []float32{3, 4}
+	Normalize(v)
+	if v[0] != 0.6 || v[1] != 0.8 {
+		t.Errorf("Expected normalized [0.6 0.8], got %v", v)
+	}
+}
+
+func TestConversion(t *testing.T) {
+	v := []float64{1.5, -2, 3.25}
+	if got := ToFloat64(FromFloat64(v)); !reflect.DeepEqual(got, v) {
+		t.Errorf("Expected %v, got %v", v, got)
+	}
+	if FromFloat64(nil) != nil {
+		t.Error("Expected nil for nil input")
+	}
+}
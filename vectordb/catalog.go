@@ -0,0 +1,137 @@
+package vectordb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"gvdb/config"
+)
+
+// catalogFile 是目录文件的格式，与 config.yaml 的 collections 段相同
+type catalogFile struct {
+	Collections []config.CollectionConfig `yaml:"collections"`
+}
+
+// loadCatalog 读取通过 API 创建的集合，文件不存在时返回空列表
+func loadCatalog(path string) ([]config.CollectionConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f catalogFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return f.Collections, nil
+}
+
+// saveCatalog 先写临时文件再重命名，避免中途失败留下不完整的目录
+func saveCatalog(path string, collections []config.CollectionConfig) error {
+	data, err := yaml.Marshal(catalogFile{Collections: collections})
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// collectionConfigs 返回 cfg 中的全部集合：默认集合、配置中声明的集合和目录文件中记录的集合，
+// declared 标记前两类
+func collectionConfigs(cfg config.Config) (all []config.CollectionConfig, declared map[string]bool, err error) {
+	created, err := loadCatalog(cfg.Catalog)
+	if err != nil {
+		return nil, nil, err
+	}
+	all = append([]config.CollectionConfig{cfg.Default()}, cfg.Collections...)
+	declared = make(map[string]bool, len(all))
+	for _, cc := range all {
+		declared[cc.Name] = true
+	}
+	for _, cc := range created {
+		if declared[cc.Name] {
+			return nil, nil, fmt.Errorf("collection %s is defined in both config and %s", cc.Name, cfg.Catalog)
+		}
+		if err := cc.Normalize(); err != nil {
+			return nil, nil, err
+		}
+		all = append(all, cc)
+	}
+	return all, declared, nil
+}
+
+// targetCatalog 为恢复或迁移确定目标数据库中各集合的配置：目标已有的集合须与源的维度和度量一致，
+// 其余集合沿用源的定义并加入目标的目录文件
+type targetCatalog struct {
+	path     string
+	existing map[string]config.CollectionConfig
+	created  []config.CollectionConfig
+	changed  bool
+}
+
+func newTargetCatalog(cfg config.Config) (*targetCatalog, error) {
+	all, declared, err := collectionConfigs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	t := &targetCatalog{path: cfg.Catalog, existing: make(map[string]config.CollectionConfig, len(all))}
+	for _, cc := range all {
+		t.existing[cc.Name] = cc
+		if !declared[cc.Name] {
+			t.created = append(t.created, cc)
+		}
+	}
+	return t, nil
+}
+
+// resolve 返回源集合 src 在目标中的配置
+func (t *targetCatalog) resolve(src config.CollectionConfig) (config.CollectionConfig, error) {
+	cc, ok := t.existing[src.Name]
+	if !ok {
+		cc = src
+		if err := cc.Normalize(); err != nil {
+			return cc, fmt.Errorf("collection %s: %w", src.Name, err)
+		}
+		t.existing[cc.Name] = cc
+		t.created = append(t.created, cc)
+		t.changed = true
+	}
+	if cc.HNSW.Dim != src.HNSW.Dim || cc.HNSW.Metric != src.HNSW.Metric {
+		return cc, fmt.Errorf("collection %s: source has dim %d and metric %s, target has dim %d and metric %s",
+			src.Name, src.HNSW.Dim, src.HNSW.Metric, cc.HNSW.Dim, cc.HNSW.Metric)
+	}
+	return cc, nil
+}
+
+// save 把新加入的集合写入目标的目录文件
+func (t *targetCatalog) save() error {
+	if !t.changed {
+		return nil
+	}
+	sort.Slice(t.created, func(i, j int) bool { return t.created[i].Name < t.created[j].Name })
+	return saveCatalog(t.path, t.created)
+}
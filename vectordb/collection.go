@@ -0,0 +1,705 @@
+package vectordb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"gvdb/config"
+	"gvdb/filter"
+	"gvdb/hnsw"
+	"gvdb/index"
+	"gvdb/metaindex"
+	"gvdb/storage"
+	"gvdb/vector"
+	"gvdb/wal"
+)
+
+// ErrDimensionMismatch 表示插入的向量维度与集合不一致
+var ErrDimensionMismatch = errors.New("vector dimension does not match collection")
+
+// scanBatchSize 为由数据库搜索和使用磁盘索引的集合打开时分页读取存储的每页文档数
+const scanBatchSize = 1000
+
+// Collection 是一个独立的向量集合，拥有自己的存储表/文件、索引和二级索引
+type Collection struct {
+	name            string
+	cfg             config.CollectionConfig
+	storage         storage.Storage
+	index           index.Index
+	indexPath       string                     // HNSW 图快照路径，为空时不持久化索引
+	fields          map[string]filter.Document // 文档的 Payload，供过滤条件使用
+	meta            *metaindex.Indexes         // Payload 字段上的二级索引
+	wal             *wal.Log                   // 预写日志，未启用时为 nil
+	snapshotErr     error                      // 打开时读取 HNSW 快照的错误，快照被忽略、索引已重建
+	bruteForceRatio float64
+	mutex           sync.RWMutex
+}
+
+// openCollection 从存储加载文档并建立索引，cfg 需已经过 Normalize
+func openCollection(cfg config.CollectionConfig, s storage.Storage, bruteForceRatio float64) (*Collection, error) {
+	// 由数据库搜索和使用磁盘索引的集合不把全部向量载入内存，之后分页读取
+	var data map[string]storage.VectorDoc
+	if cfg.Index.Type != "pgvector" && cfg.Index.Type != "disk" {
+		var err error
+		if data, err = s.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	idx, err := newIndex(cfg, s)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := newMetaIndexes(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Collection{
+		name:            cfg.Name,
+		cfg:             cfg,
+		storage:         s,
+		index:           idx,
+		fields:          make(map[string]filter.Document, len(data)),
+		meta:            meta,
+		bruteForceRatio: bruteForceRatio,
+	}
+	if ext, ok := idx.(*index.ExternalIndex); ok {
+		err := storage.ScanAll(s, "", scanBatchSize, func(ids []string, docs map[string]storage.VectorDoc) error {
+			for _, id := range ids {
+				c.fields[id] = filter.Document(payloadOf(docs[id]))
+				meta.Add(id, c.fields[id])
+				ext.Add(id, nil)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	if d, ok := idx.(*index.DiskIndex); ok {
+		if err := c.fillDisk(d); err != nil {
+			d.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+	for id, doc := range data {
+		c.fields[id] = filter.Document(payloadOf(doc))
+		meta.Add(id, c.fields[id])
+	}
+	if h, ok := idx.(*hnsw.HNSWIndex); ok && cfg.HNSW.IndexPath != "" {
+		c.indexPath = cfg.HNSW.IndexPath
+		loaded, err := loadSnapshot(cfg, h, s, data)
+		if err != nil {
+			c.snapshotErr = fmt.Errorf("load HNSW snapshot %s: %w", cfg.HNSW.IndexPath, err)
+			Logger.Printf("Collection %s: ignoring HNSW snapshot and rebuilding the index: %v", cfg.Name, err)
+		}
+		if loaded != nil {
+			c.index = loaded
+			return c, nil
+		}
+	}
+	fill(cfg.Name, idx, data)
+	return c, nil
+}
+
+// newMetaIndexes 按配置创建空的二级索引
+func newMetaIndexes(cfg config.CollectionConfig) (*metaindex.Indexes, error) {
+	fields := make([]metaindex.Field, 0, len(cfg.Metadata.Indexes))
+	for _, mi := range cfg.Metadata.Indexes {
+		fields = append(fields, metaindex.Field{Name: mi.Field, Kind: metaindex.Kind(mi.Type)})
+	}
+	return metaindex.New(fields)
+}
+
+// fill 用存储中的文档训练并填充新建的索引，索引拒绝的文档（如维度不一致）记录到 Logger 后跳过
+func fill(name string, idx index.Index, data map[string]storage.VectorDoc) {
+	if t, ok := idx.(index.Trainer); ok && !t.Trained() {
+		samples := make([][]float32, 0, len(data))
+		for _, doc := range data {
+			samples = append(samples, doc.Vector)
+		}
+		// 样本不足时索引会在插入足够向量后自动训练
+		t.Train(samples)
+	}
+	ids := make([]string, 0, len(data))
+	vectors := make([][]float32, 0, len(data))
+	for id, doc := range data {
+		ids = append(ids, id)
+		vectors = append(vectors, doc.Vector)
+	}
+	if err := index.AddBatch(idx, ids, vectors); err != nil {
+		Logger.Printf("Collection %s: documents left out of the index: %v", name, err)
+	}
+}
+
+// fillDisk 分页读取存储，填充 Payload、二级索引和在进程间保留的磁盘索引。文件包含上次的全部修改时跳过其中已有的 ID，
+// 不读取映射文件中的向量；否则逐个比较，只把变化的向量放入增量缓冲区。最后去掉存储中已不存在的向量
+func (c *Collection) fillDisk(d *index.DiskIndex) error {
+	clean := d.Clean()
+	err := storage.ScanAll(c.storage, "", scanBatchSize, func(ids []string, docs map[string]storage.VectorDoc) error {
+		added := make([]string, 0, len(ids))
+		vectors := make([][]float32, 0, len(ids))
+		for _, id := range ids {
+			c.fields[id] = filter.Document(payloadOf(docs[id]))
+			c.meta.Add(id, c.fields[id])
+			if clean && d.Contains(id) {
+				continue
+			}
+			added = append(added, id)
+			vectors = append(vectors, docs[id].Vector)
+		}
+		if err := index.AddBatch(d, added, vectors); err != nil {
+			Logger.Printf("Collection %s: documents left out of the index: %v", c.name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range d.IDs() {
+		if _, exists := c.fields[id]; !exists {
+			d.Remove(id)
+		}
+	}
+	return nil
+}
+
+func (c *Collection) Name() string { return c.name }
+
+func (c *Collection) Metric() hnsw.Metric { return c.index.Metric() }
+
+func (c *Collection) Dim() int { return c.cfg.HNSW.Dim }
+
+// SnapshotErr 返回打开集合时读取 HNSW 快照的错误，例如快照损坏（hnsw.ErrSnapshotCorrupt）。
+// 出错时快照被忽略、索引由存储重建，集合仍然可用；下次保存快照时会覆盖损坏的文件
+func (c *Collection) SnapshotErr() error { return c.snapshotErr }
+
+// CollectionInfo 描述集合的参数和文档数量
+type CollectionInfo struct {
+	Name      string
+	Dim       int
+	Metric    string
+	IndexType string
+	Count     int
+	Config    config.CollectionConfig
+}
+
+func (c *Collection) Info() CollectionInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return CollectionInfo{
+		Name:      c.name,
+		Dim:       c.cfg.HNSW.Dim,
+		Metric:    c.index.Metric().Name(),
+		IndexType: c.cfg.Index.Type,
+		Count:     len(c.fields),
+		Config:    c.cfg,
+	}
+}
+
+// InsertFromModel 接收模型输出的 float64 向量，内部转换为 float32 存储
+func (c *Collection) InsertFromModel(id string, embedding []float64, meta string) error {
+	return c.InsertVector(id, vector.FromFloat64(embedding), meta)
+}
+
+// InsertVector 以字符串元数据插入向量，Payload 由 storage.MetaToPayload 生成
+func (c *Collection) InsertVector(id string, vec []float32, meta string) error {
+	return c.insert(id, storage.VectorDoc{Vector: vec, Meta: meta, Payload: storage.MetaToPayload(meta)})
+}
+
+// InsertFromModelPayload 接收模型输出的 float64 向量和结构化元数据
+func (c *Collection) InsertFromModelPayload(id string, embedding []float64, payload storage.Payload) error {
+	return c.InsertVectorPayload(id, vector.FromFloat64(embedding), payload)
+}
+
+// InsertVectorPayload 以结构化元数据插入向量
+func (c *Collection) InsertVectorPayload(id string, vec []float32, payload storage.Payload) error {
+	return c.insert(id, storage.VectorDoc{Vector: vec, Payload: payload})
+}
+
+// InsertDoc 同时写入 Meta 和 Payload，Payload 为空时由 Meta 生成
+func (c *Collection) InsertDoc(id string, doc storage.VectorDoc) error {
+	if doc.Payload == nil {
+		doc.Payload = storage.MetaToPayload(doc.Meta)
+	}
+	return c.insert(id, doc)
+}
+
+func (c *Collection) insert(id string, doc storage.VectorDoc) error {
+	if len(doc.Vector) != c.cfg.HNSW.Dim {
+		return fmt.Errorf("%w: got %d, want %d", ErrDimensionMismatch, len(doc.Vector), c.cfg.HNSW.Dim)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.markIndexDirty(); err != nil {
+		return err
+	}
+	seq, err := c.logWrite(wal.Entry{Op: wal.OpUpsert, Docs: map[string]storage.VectorDoc{id: doc}})
+	if err != nil {
+		return err
+	}
+	if err := c.storage.Insert(id, doc); err != nil {
+		return c.undoWrite(seq, err)
+	}
+	c.index.Add(id, doc.Vector)
+	c.fields[id] = filter.Document(payloadOf(doc))
+	c.meta.Add(id, c.fields[id])
+	c.maybeCheckpoint()
+	return nil
+}
+
+// InsertBatch 原子地插入一批新文档，任一 ID 已存在时返回 storage.ErrExists，不写入任何文档。
+// Payload 为空的文档与 InsertDoc 一样由 Meta 生成；向量并行加入索引（HNSW）
+func (c *Collection) InsertBatch(docs map[string]storage.VectorDoc) error {
+	return c.writeBatch(docs, false)
+}
+
+// UpsertBatch 原子地写入或替换一批文档，其余与 InsertBatch 相同
+func (c *Collection) UpsertBatch(docs map[string]storage.VectorDoc) error {
+	return c.writeBatch(docs, true)
+}
+
+func (c *Collection) writeBatch(docs map[string]storage.VectorDoc, upsert bool) error {
+	batch := make(map[string]storage.VectorDoc, len(docs))
+	for id, doc := range docs {
+		if len(doc.Vector) != c.cfg.HNSW.Dim {
+			return fmt.Errorf("%w: %s: got %d, want %d", ErrDimensionMismatch, id, len(doc.Vector), c.cfg.HNSW.Dim)
+		}
+		if doc.Payload == nil {
+			doc.Payload = storage.MetaToPayload(doc.Meta)
+		}
+		batch[id] = doc
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !upsert && c.wal != nil {
+		// 日志中只记录会成功的插入，重放时按写入或替换处理
+		for id := range batch {
+			if _, exists := c.fields[id]; exists {
+				return fmt.Errorf("%w: %s", storage.ErrExists, id)
+			}
+		}
+	}
+	if err := c.markIndexDirty(); err != nil {
+		return err
+	}
+	seq, err := c.logWrite(wal.Entry{Op: wal.OpUpsert, Docs: batch})
+	if err != nil {
+		return err
+	}
+	if upsert {
+		err = c.storage.UpsertBatch(batch)
+	} else {
+		err = c.storage.InsertBatch(batch)
+	}
+	if err != nil {
+		return c.undoWrite(seq, err)
+	}
+	c.addDocs(batch)
+	c.maybeCheckpoint()
+	return nil
+}
+
+// markIndexDirty 在写入存储之前为磁盘索引写入 .dirty 标记，调用方需持有写锁
+func (c *Collection) markIndexDirty() error {
+	if d, ok := c.index.(*index.DiskIndex); ok {
+		return d.MarkDirty()
+	}
+	return nil
+}
+
+// addDocs 把已写入存储的文档加入向量索引和二级索引
+func (c *Collection) addDocs(docs map[string]storage.VectorDoc) {
+	ids := make([]string, 0, len(docs))
+	vectors := make([][]float32, 0, len(docs))
+	for id, doc := range docs {
+		ids = append(ids, id)
+		vectors = append(vectors, doc.Vector)
+		c.fields[id] = filter.Document(payloadOf(doc))
+		c.meta.Add(id, c.fields[id])
+	}
+	index.AddBatch(c.index, ids, vectors)
+}
+
+// removeDocs 从向量索引和二级索引中删除文档
+func (c *Collection) removeDocs(ids []string) {
+	index.RemoveBatch(c.index, ids)
+	for _, id := range ids {
+		delete(c.fields, id)
+		c.meta.Remove(id)
+	}
+}
+
+// payloadOf 返回文档的 Payload，只有 Meta 的文档按旧格式迁移
+func payloadOf(doc storage.VectorDoc) storage.Payload {
+	if doc.Payload == nil {
+		return storage.MetaToPayload(doc.Meta)
+	}
+	return doc.Payload
+}
+
+// SaveIndex 把 HNSW 图写入配置的快照文件，下次启动时可跳过重建
+func (c *Collection) SaveIndex() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.saveIndex()
+}
+
+func (c *Collection) saveIndex() error {
+	h, ok := c.index.(*hnsw.HNSWIndex)
+	if !ok || c.indexPath == "" {
+		return nil
+	}
+	return h.SaveFile(c.indexPath)
+}
+
+// Close 保存索引快照、关闭磁盘索引并关闭存储
+func (c *Collection) Close() error {
+	saveErr := c.SaveIndex()
+	if c.wal != nil {
+		// 存储在每次写入后已持久化，快照保存成功后日志中的记录不再需要
+		if saveErr == nil {
+			saveErr = c.wal.Checkpoint()
+		}
+		if err := c.wal.Close(); err != nil && saveErr == nil {
+			saveErr = err
+		}
+	}
+	if c, ok := c.index.(io.Closer); ok {
+		if err := c.Close(); err != nil && saveErr == nil {
+			saveErr = err
+		}
+	}
+	if err := c.storage.Close(); err != nil {
+		return err
+	}
+	return saveErr
+}
+
+// RebuildIndex 丢弃现有的向量索引（包括 HNSW 快照和磁盘索引文件），按当前配置从存储重新构建，
+// 同时重建二级索引并保存快照。失败时集合不再可用，需要重新打开
+func (c *Collection) RebuildIndex() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	meta, err := newMetaIndexes(c.cfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := c.index.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	if c.cfg.Index.Type == "disk" {
+		if err := removeDiskIndex(c.cfg.Index.Disk.Path); err != nil {
+			return err
+		}
+	}
+	idx, err := newIndex(c.cfg, c.storage)
+	if err != nil {
+		return err
+	}
+
+	// 分页读取存储；需要训练的索引先收集向量，训练后再一起插入
+	var data map[string]storage.VectorDoc
+	if t, ok := idx.(index.Trainer); ok && !t.Trained() {
+		data = make(map[string]storage.VectorDoc)
+	}
+	fields := make(map[string]filter.Document)
+	err = storage.ScanAll(c.storage, "", scanBatchSize, func(ids []string, docs map[string]storage.VectorDoc) error {
+		vectors := make([][]float32, 0, len(ids))
+		for _, id := range ids {
+			fields[id] = filter.Document(payloadOf(docs[id]))
+			meta.Add(id, fields[id])
+			vectors = append(vectors, docs[id].Vector)
+			if data != nil {
+				data[id] = storage.VectorDoc{Vector: docs[id].Vector}
+			}
+		}
+		if data != nil {
+			return nil
+		}
+		if err := index.AddBatch(idx, ids, vectors); err != nil {
+			Logger.Printf("Collection %s: documents left out of the index: %v", c.name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		fill(c.name, idx, data)
+	}
+	c.index = idx
+	if d, ok := idx.(*index.DiskIndex); ok {
+		if err := d.Merge(); err != nil {
+			return err
+		}
+	}
+	c.fields = fields
+	c.meta = meta
+	return c.saveIndex()
+}
+
+// removeDiskIndex 删除磁盘索引文件和它的 .dirty 标记
+func removeDiskIndex(path string) error {
+	for _, p := range []string{path, path + ".dirty"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compact 回收存储中已删除数据占用的空间，并把磁盘索引的增量缓冲区合并进索引文件
+func (c *Collection) Compact() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if cs, ok := c.storage.(storage.Compacter); ok {
+		if err := cs.Compact(); err != nil {
+			return err
+		}
+	}
+	if d, ok := c.index.(*index.DiskIndex); ok {
+		return d.Merge()
+	}
+	return nil
+}
+
+// Migrate 按当前格式重写存储中的全部文档：只有 Meta 的旧文档写入生成的 Payload，旧版 JSON 编码的向量改为二进制编码。
+// 返回重写的文档数
+func (c *Collection) Migrate() (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	data, err := c.storage.Load()
+	if err != nil {
+		return 0, err
+	}
+	return len(data), c.storage.Save(data)
+}
+
+// drop 关闭索引并删除集合的存储和索引文件
+func (c *Collection) drop() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if closer, ok := c.index.(io.Closer); ok {
+		closer.Close()
+	}
+	if c.wal != nil {
+		if err := c.wal.Remove(); err != nil {
+			return err
+		}
+	}
+	if err := c.storage.Drop(); err != nil {
+		return err
+	}
+	if c.indexPath != "" {
+		if err := os.Remove(c.indexPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if c.cfg.Index.Disk.Path != "" {
+		return removeDiskIndex(c.cfg.Index.Disk.Path)
+	}
+	return nil
+}
+
+func (c *Collection) Get(id string) (storage.VectorDoc, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.storage.Get(id)
+}
+
+func (c *Collection) Delete(id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.markIndexDirty(); err != nil {
+		return err
+	}
+	seq, err := c.logWrite(wal.Entry{Op: wal.OpDelete, IDs: []string{id}})
+	if err != nil {
+		return err
+	}
+	if err := c.storage.Delete(id); err != nil {
+		return c.undoWrite(seq, err)
+	}
+	c.index.Remove(id)
+	delete(c.fields, id)
+	c.meta.Remove(id)
+	c.maybeCheckpoint()
+	return nil
+}
+
+// DeleteBatch 原子地删除一批文档并返回实际删除的数量，不存在的 ID 会被忽略
+func (c *Collection) DeleteBatch(ids []string) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.markIndexDirty(); err != nil {
+		return 0, err
+	}
+	seq, err := c.logWrite(wal.Entry{Op: wal.OpDelete, IDs: ids})
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.storage.DeleteBatch(ids)
+	if err != nil {
+		return 0, c.undoWrite(seq, err)
+	}
+	c.removeDocs(ids)
+	c.maybeCheckpoint()
+	return n, nil
+}
+
+type SearchResult struct {
+	ID      string
+	Score   float32
+	Meta    string
+	Payload storage.Payload
+}
+
+// SearchFromModel 接收模型输出的 float64 查询向量
+func (c *Collection) SearchFromModel(queryEmbedding []float64, limit int) []SearchResult {
+	return c.SearchVector(vector.FromFloat64(queryEmbedding), limit)
+}
+
+func (c *Collection) SearchVector(query []float32, limit int) []SearchResult {
+	return c.SearchVectorFilter(query, limit, nil)
+}
+
+// SearchFromModelFilter 接收模型输出的 float64 查询向量，只返回元数据匹配 f 的结果
+func (c *Collection) SearchFromModelFilter(queryEmbedding []float64, limit int, f filter.Filter) []SearchResult {
+	return c.SearchVectorFilter(vector.FromFloat64(queryEmbedding), limit, f)
+}
+
+// SearchVectorFilter 只返回元数据匹配 f 的结果，f 为 nil 时不过滤。
+// 匹配的文档优先由二级索引求出；占比不超过 brute_force_ratio 时直接对这些文档暴力搜索，否则在索引遍历中过滤。
+// 由数据库执行的搜索失败时返回空结果，需要错误时使用 SearchVectorFilterErr
+func (c *Collection) SearchVectorFilter(query []float32, limit int, f filter.Filter) []SearchResult {
+	results, _ := c.SearchVectorFilterErr(query, limit, f)
+	return results
+}
+
+// SearchVectorFilterErr 与 SearchVectorFilter 相同，但返回搜索的错误，例如 pgvector 查询失败
+func (c *Collection) SearchVectorFilterErr(query []float32, limit int, f filter.Filter) ([]SearchResult, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var neighbors []hnsw.Neighbor
+	var err error
+	if f == nil {
+		neighbors, err = index.SearchFilter(c.index, query, limit, nil)
+	} else {
+		matches := c.matching(f)
+		if float64(len(matches)) <= c.bruteForceRatio*float64(len(c.fields)) {
+			neighbors = c.bruteForce(query, limit, matches)
+		} else {
+			neighbors, err = index.SearchFilter(c.index, query, limit, func(id string) bool { return matches[id] })
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("search collection %s: %w", c.name, err)
+	}
+	return c.results(neighbors), nil
+}
+
+// Query 不带查询向量，按 ID 顺序返回元数据匹配 f 的文档，limit 不大于 0 时返回全部；结果的 Score 为 0
+func (c *Collection) Query(f filter.Filter, limit int) []SearchResult {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var ids []string
+	if f == nil {
+		ids = make([]string, 0, len(c.fields))
+		for id := range c.fields {
+			ids = append(ids, id)
+		}
+	} else {
+		matches := c.matching(f)
+		ids = make([]string, 0, len(matches))
+		for id := range matches {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	neighbors := make([]hnsw.Neighbor, len(ids))
+	for i, id := range ids {
+		neighbors[i] = hnsw.Neighbor{ID: id}
+	}
+	return c.results(neighbors)
+}
+
+// matching 返回元数据匹配 f 的文档 ID：二级索引能精确回答时直接使用其结果，
+// 只能给出候选时逐个确认，没有可用索引时扫描全部文档
+func (c *Collection) matching(f filter.Filter) map[string]bool {
+	candidates, exact := c.meta.Plan(f)
+	if exact {
+		return candidates
+	}
+	matches := make(map[string]bool)
+	if candidates != nil {
+		for id := range candidates {
+			if doc, ok := c.fields[id]; ok && f.Match(doc) {
+				matches[id] = true
+			}
+		}
+		return matches
+	}
+	for id, doc := range c.fields {
+		if f.Match(doc) {
+			matches[id] = true
+		}
+	}
+	return matches
+}
+
+// results 从存储读取结果文档的元数据
+func (c *Collection) results(neighbors []hnsw.Neighbor) []SearchResult {
+	results := make([]SearchResult, 0, len(neighbors))
+	for _, n := range neighbors {
+		if doc, exists := c.storage.Get(n.ID); exists {
+			results = append(results, SearchResult{
+				ID:      n.ID,
+				Score:   n.Score,
+				Meta:    doc.Meta,
+				Payload: payloadOf(doc),
+			})
+		}
+	}
+	return results
+}
+
+// bruteForce 从存储读取候选文档的原始向量并精确计算得分
+func (c *Collection) bruteForce(query []float32, limit int, ids map[string]bool) []hnsw.Neighbor {
+	if limit <= 0 {
+		return nil
+	}
+	metric := c.index.Metric()
+	neighbors := make([]hnsw.Neighbor, 0, len(ids))
+	for id := range ids {
+		if doc, ok := c.storage.Get(id); ok {
+			neighbors = append(neighbors, hnsw.Neighbor{ID: id, Score: metric.Score(query, doc.Vector)})
+		}
+	}
+	sort.Slice(neighbors, func(i, j int) bool { return hnsw.Better(metric, neighbors[i].Score, neighbors[j].Score) })
+	if len(neighbors) > limit {
+		neighbors = neighbors[:limit]
+	}
+	return neighbors
+}
@@ -0,0 +1,536 @@
+package vectordb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvdb/config"
+	"gvdb/filter"
+	"gvdb/hnsw"
+	"gvdb/index"
+	"gvdb/storage"
+	"gvdb/storage/pgtest"
+)
+
+func TestCollectionFilteredSearchAndQuery(t *testing.T) {
+	cfg := testConfig(t.TempDir())
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c, err := db.CreateCollection(config.CollectionConfig{
+		Name: "docs",
+		HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"},
+		Metadata: config.MetadataConfig{Indexes: []config.MetadataIndex{
+			{Field: "lang", Type: "keyword"},
+			{Field: "year", Type: "int"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		lang := "go"
+		if i%4 == 0 {
+			lang = "rust"
+		}
+		payload := storage.Payload{"lang": lang, "year": int64(2000 + i%20), "rank": int64(i)}
+		if err := c.InsertVectorPayload(fmt.Sprintf("doc%03d", i), []float32{float32(i), 0}, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		f    filter.Filter
+		want int
+	}{
+		{filter.Eq{Field: "lang", Value: "rust"}, 50},
+		{filter.And{filter.Eq{Field: "lang", Value: "go"}, filter.Gte("year", 2015)}, 40},
+		// rank 没有二级索引，需要逐个确认
+		{filter.And{filter.Eq{Field: "lang", Value: "rust"}, filter.Lt("rank", 20)}, 5},
+		{filter.Lt("rank", 3), 3},
+	}
+	for _, tc := range cases {
+		got := c.Query(tc.f, 0)
+		if len(got) != tc.want {
+			t.Errorf("Query(%#v): expected %d results, got %d", tc.f, tc.want, len(got))
+		}
+		for i, r := range got {
+			if !tc.f.Match(filter.Document(r.Payload)) {
+				t.Errorf("Query(%#v) returned non-matching %s", tc.f, r.ID)
+			}
+			if i > 0 && got[i-1].ID >= r.ID {
+				t.Errorf("Query(%#v) results not in ID order", tc.f)
+			}
+		}
+		search := c.SearchVectorFilter([]float32{100, 0}, 3, tc.f)
+		if len(search) != 3 && len(search) != tc.want {
+			t.Errorf("SearchVectorFilter(%#v): expected 3 results, got %d", tc.f, len(search))
+		}
+		for _, r := range search {
+			if !tc.f.Match(filter.Document(r.Payload)) {
+				t.Errorf("SearchVectorFilter(%#v) returned non-matching %s", tc.f, r.ID)
+			}
+		}
+	}
+
+	// 只匹配两个文档，走暴力搜索；limit 不大于 0 时与索引搜索一样返回空结果
+	selective := filter.And{filter.Eq{Field: "lang", Value: "rust"}, filter.Lt("rank", 5)}
+	if got := c.SearchVectorFilter([]float32{100, 0}, 5, selective); len(got) != 2 {
+		t.Errorf("Expected 2 brute-force results, got %+v", got)
+	}
+	for _, limit := range []int{0, -1} {
+		if got := c.SearchVectorFilter([]float32{100, 0}, limit, selective); len(got) != 0 {
+			t.Errorf("Expected no results with limit %d, got %+v", limit, got)
+		}
+	}
+
+	if got := c.Query(nil, 5); len(got) != 5 || got[0].ID != "doc000" {
+		t.Errorf("Expected the first 5 documents, got %v", got)
+	}
+	c.Delete("doc000")
+	if got := c.Query(filter.Eq{Field: "lang", Value: "rust"}, 0); len(got) != 49 {
+		t.Errorf("Expected deleted document to leave the metadata index, got %d results", len(got))
+	}
+}
+
+func TestCollectionMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.HNSW.IndexPath = filepath.Join(dir, "vectors.hnsw")
+	// 旧版数据文件只有 Meta
+	legacy := `{"old": {"Vector": [1, 0, 0], "Meta": "{\"lang\": \"go\"}"}}`
+	if err := os.WriteFile(cfg.Storage.File.Path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for i := 0; i < 50; i++ {
+		if err := db.InsertVector(fmt.Sprintf("doc%02d", i), []float32{float32(i), 1, 0}, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := db.Migrate()
+	if err != nil || n != 51 {
+		t.Fatalf("Migrate: %d, %v", n, err)
+	}
+	// 旧版 JSON 文件已转换为段格式
+	data, _ := os.ReadFile(cfg.Storage.File.Path)
+	if !strings.Contains(string(data), `"segments"`) {
+		t.Errorf("Expected the legacy file to be replaced by a manifest, got %s", data)
+	}
+	if doc, _ := storage.NewFileStorage(cfg.Storage.File.Path).Load(); doc["old"].Payload["lang"] != "go" {
+		t.Errorf("Expected migrated documents to be written with a payload, got %+v", doc["old"])
+	}
+
+	if err := db.RebuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SearchVector([]float32{1, 0, 0}, 1); len(got) != 1 || got[0].ID != "old" {
+		t.Errorf("Unexpected search result after rebuild: %+v", got)
+	}
+	if got := db.Query(filter.Eq{Field: "lang", Value: "go"}, 0); len(got) != 1 {
+		t.Errorf("Expected payload filter to work after rebuild, got %+v", got)
+	}
+	if _, err := os.Stat(cfg.HNSW.IndexPath); err != nil {
+		t.Errorf("Expected snapshot to be saved after rebuild: %v", err)
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	cc := config.CollectionConfig{Name: "disk", HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"}}
+	cc.Index.Type = "disk"
+	cc.Index.Disk.Path = filepath.Join(dir, "vectors.disk")
+	disk, err := db.CreateCollection(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := disk.InsertVector(fmt.Sprintf("d%02d", i), []float32{float32(i), 0}, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := disk.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	// 重建时连同残留的标记一起删除，新文件合并后被视为最新
+	if err := os.WriteFile(cc.Index.Disk.Path+".dirty", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := disk.RebuildIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if got := disk.SearchVector([]float32{5, 0}, 1); len(got) != 1 || got[0].ID != "d05" {
+		t.Errorf("Unexpected disk search result after rebuild: %+v", got)
+	}
+	if _, err := os.Stat(cc.Index.Disk.Path + ".dirty"); !os.IsNotExist(err) {
+		t.Errorf("Expected no dirty marker after rebuild, got %v", err)
+	}
+}
+
+func TestCollectionCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.HNSW.IndexPath = filepath.Join(dir, "vectors.hnsw")
+	if err := os.WriteFile(cfg.HNSW.IndexPath, []byte("not a snapshot"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	defer func(l *log.Logger) { Logger = l }(Logger)
+	Logger = log.New(&logged, "", 0)
+
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// 损坏的快照被忽略并重建索引，错误保留给调用方查看
+	if err := db.SnapshotErr(); !errors.Is(err, hnsw.ErrSnapshotCorrupt) {
+		t.Errorf("Expected a corrupt snapshot error, got %v", err)
+	}
+	if !strings.Contains(logged.String(), "ignoring HNSW snapshot") {
+		t.Errorf("Expected the corrupt snapshot to be logged, got %q", logged.String())
+	}
+	if err := db.InsertVector("a", []float32{1, 0, 0}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SearchVector([]float32{1, 0, 0}, 1); len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("Unexpected search result: %+v", got)
+	}
+}
+
+func TestCollectionDiskReopen(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := config.CollectionConfig{Name: "disk", HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"}}
+	cc.Index.Type = "disk"
+	cc.Index.Disk.Path = filepath.Join(dir, "vectors.disk")
+	disk, err := db.CreateCollection(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := make(map[string]storage.VectorDoc)
+	for i := 0; i < 20; i++ {
+		docs[fmt.Sprintf("d%02d", i)] = storage.VectorDoc{Vector: []float32{float32(i), 0}, Payload: storage.Payload{"i": int64(i)}}
+	}
+	if err := disk.UpsertBatch(docs); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// 正常关闭后重新打开，磁盘索引中已有的文档不再重新插入
+	db, err = NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	disk, err = db.GetCollection("disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := disk.index.(*index.DiskIndex); !d.Clean() || d.Len() != 20 {
+		t.Fatalf("Expected a clean disk index with 20 vectors, got clean=%v len=%d", d.Clean(), d.Len())
+	}
+	if got := disk.Query(filter.Eq{Field: "i", Value: int64(5)}, 0); len(got) != 1 || got[0].ID != "d05" {
+		t.Errorf("Unexpected query after reopening: %+v", got)
+	}
+
+	// 修改尚未合并进文件时另一个实例打开同一集合（相当于崩溃后重启），变化的向量仍会被发现
+	if err := disk.UpsertBatch(map[string]storage.VectorDoc{"d05": {Vector: []float32{100, 0}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := disk.Delete("d06"); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	reopened, err := other.GetCollection("disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.SearchVector([]float32{100, 0}, 1); len(got) != 1 || got[0].ID != "d05" || got[0].Score != 0 {
+		t.Errorf("Expected the updated vector after an unclean shutdown, got %+v", got)
+	}
+	if got := reopened.SearchVector([]float32{6, 0}, 1); len(got) != 1 || got[0].ID == "d06" {
+		t.Errorf("Expected the deleted vector to be removed, got %+v", got)
+	}
+}
+
+// markerCheck 在每次写入存储时记录磁盘索引的 .dirty 标记是否已经存在
+type markerCheck struct {
+	storage.Storage
+	marker  string
+	missing []string
+}
+
+func (m *markerCheck) check(op string) {
+	if _, err := os.Stat(m.marker); err != nil {
+		m.missing = append(m.missing, op)
+	}
+}
+
+func (m *markerCheck) Insert(id string, doc storage.VectorDoc) error {
+	m.check("Insert")
+	return m.Storage.Insert(id, doc)
+}
+
+func (m *markerCheck) InsertBatch(docs map[string]storage.VectorDoc) error {
+	m.check("InsertBatch")
+	return m.Storage.InsertBatch(docs)
+}
+
+func (m *markerCheck) UpsertBatch(docs map[string]storage.VectorDoc) error {
+	m.check("UpsertBatch")
+	return m.Storage.UpsertBatch(docs)
+}
+
+func (m *markerCheck) Delete(id string) error {
+	m.check("Delete")
+	return m.Storage.Delete(id)
+}
+
+func (m *markerCheck) DeleteBatch(ids []string) (int, error) {
+	m.check("DeleteBatch")
+	return m.Storage.DeleteBatch(ids)
+}
+
+func TestCollectionDiskMarkerBeforeWrite(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := config.CollectionConfig{Name: "disk", HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"}}
+	cc.Index.Type = "disk"
+	cc.Index.Disk.Path = filepath.Join(dir, "vectors.disk")
+	disk, err := db.CreateCollection(cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := &markerCheck{Storage: disk.storage, marker: cc.Index.Disk.Path + ".dirty"}
+	disk.storage = check
+
+	// 每次写入存储之前标记都已落盘：写入后、更新索引之前崩溃时，文件不会被当作最新
+	writes := []func() error{
+		func() error { return disk.InsertVector("a", []float32{1, 0}, "") },
+		func() error { return disk.InsertBatch(map[string]storage.VectorDoc{"b": {Vector: []float32{2, 0}}}) },
+		func() error { return disk.UpsertBatch(map[string]storage.VectorDoc{"a": {Vector: []float32{3, 0}}}) },
+		func() error { return disk.Delete("a") },
+		func() error { _, err := disk.DeleteBatch([]string{"b"}); return err },
+	}
+	for i, write := range writes {
+		// 合并后标记被删除，文件包含全部修改
+		if err := disk.Compact(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(check.marker); !os.IsNotExist(err) {
+			t.Fatalf("Expected no marker after compacting, got %v", err)
+		}
+		if err := write(); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if len(check.missing) > 0 {
+		t.Errorf("Expected the marker to exist before every storage write, missing for %v", check.missing)
+	}
+
+	// 模拟写入存储后、更新索引前崩溃：重新打开时不把文件当作最新，存储中的文档会进入索引
+	if err := disk.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if err := disk.markIndexDirty(); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.Storage.Insert("c", storage.VectorDoc{Vector: []float32{5, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	reopened, err := other.GetCollection("disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.index.(*index.DiskIndex).Clean() {
+		t.Error("Expected the disk index not to be clean after a crash between marking and indexing")
+	}
+	if got := reopened.SearchVector([]float32{5, 0}, 1); len(got) != 1 || got[0].ID != "c" {
+		t.Errorf("Expected the document written before the crash to be indexed, got %+v", got)
+	}
+	db.Close()
+}
+
+func TestCollectionBatch(t *testing.T) {
+	db, err := NewVectorDB(testConfig(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c, err := db.CreateCollection(config.CollectionConfig{
+		Name:     "batch",
+		HNSW:     config.HNSWConfig{Dim: 2, Metric: "l2"},
+		Metadata: config.MetadataConfig{Indexes: []config.MetadataIndex{{Field: "n", Type: "int"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := make(map[string]storage.VectorDoc)
+	for i := 0; i < 100; i++ {
+		docs[fmt.Sprintf("doc%03d", i)] = storage.VectorDoc{Vector: []float32{float32(i), 0}, Payload: storage.Payload{"n": int64(i)}}
+	}
+	if err := c.InsertBatch(docs); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.SearchVector([]float32{42, 0}, 1); len(got) != 1 || got[0].ID != "doc042" {
+		t.Errorf("Unexpected search result after InsertBatch: %+v", got)
+	}
+	if got := c.Query(filter.Lt("n", 10), 0); len(got) != 10 {
+		t.Errorf("Expected 10 results, got %d", len(got))
+	}
+
+	// 任一 ID 已存在时整批失败
+	err = c.InsertBatch(map[string]storage.VectorDoc{
+		"new":    {Vector: []float32{1, 1}},
+		"doc000": {Vector: []float32{1, 1}},
+	})
+	if !errors.Is(err, storage.ErrExists) {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+	if _, ok := c.Get("new"); ok {
+		t.Error("Expected failed InsertBatch to write nothing")
+	}
+	if err := c.UpsertBatch(map[string]storage.VectorDoc{"doc000": {Vector: []float32{1}}}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+
+	// Upsert 替换向量，Meta 生成 Payload
+	if err := c.UpsertBatch(map[string]storage.VectorDoc{"doc000": {Vector: []float32{500, 0}, Meta: `{"n": 500}`}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.SearchVector([]float32{500, 0}, 1); len(got) != 1 || got[0].ID != "doc000" {
+		t.Errorf("Unexpected search result after UpsertBatch: %+v", got)
+	}
+	if got := c.Query(filter.Lt("n", 10), 0); len(got) != 9 {
+		t.Errorf("Expected 9 results after upsert, got %d", len(got))
+	}
+
+	n, err := c.DeleteBatch([]string{"doc001", "doc002", "missing"})
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteBatch: %d, %v", n, err)
+	}
+	if got := c.SearchVector([]float32{1, 0}, 1); len(got) != 1 || got[0].ID != "doc003" {
+		t.Errorf("Unexpected search result after DeleteBatch: %+v", got)
+	}
+	if got := c.Query(filter.Lt("n", 10), 0); len(got) != 7 {
+		t.Errorf("Expected 7 results after delete, got %d", len(got))
+	}
+}
+
+func TestCollectionPgvector(t *testing.T) {
+	driver := storage.PostgresDriver
+	storage.PostgresDriver = pgtest.Driver
+	defer func() { storage.PostgresDriver = driver }()
+
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.Storage.Type = "postgres"
+	cfg.Storage.File.Enable = false
+	cfg.Storage.Postgres.Enable = true
+	cfg.Storage.Postgres.Database = filepath.Join(dir, "pg.db")
+	cfg.Storage.Postgres.Pgvector.Enable = true
+	cfg.Index.Type = "pgvector"
+	cfg.Metadata.Indexes = []config.MetadataIndex{{Field: "lang", Type: "keyword"}}
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertBatch(map[string]storage.VectorDoc{
+		"a": {Vector: []float32{1, 0, 0}, Payload: storage.Payload{"lang": "go"}},
+		"b": {Vector: []float32{0.8, 0.2, 0}, Payload: storage.Payload{"lang": "rust"}},
+		"c": {Vector: []float32{0, 0, 1}, Payload: storage.Payload{"lang": "go"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SearchVector([]float32{1, 0, 0}, 2); len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" || got[0].Payload["lang"] != "go" {
+		t.Errorf("Unexpected search results: %+v", got)
+	}
+	if got := db.SearchVectorFilter([]float32{1, 0, 0}, 2, filter.Eq{Field: "lang", Value: "go"}); len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Errorf("Unexpected filtered results: %+v", got)
+	}
+	if err := db.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.SearchVector([]float32{1, 0, 0}, 1); len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("Expected deleted document to be gone: %+v", got)
+	}
+
+	// 同一存储上的其他集合仍可使用进程内索引
+	images, err := db.CreateCollection(config.CollectionConfig{Name: "images", HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := images.InsertVector("img", []float32{3, 4}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := images.SearchVector([]float32{0, 0}, 1); len(got) != 1 || got[0].Score != 5 {
+		t.Errorf("Unexpected in-process search: %+v", got)
+	}
+	db.Close()
+
+	// 重新打开时分页读取 ID 和 Payload，搜索仍由数据库执行
+	db, err = NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if info := db.Info(); info.Count != 2 {
+		t.Errorf("Expected 2 documents after reopening, got %d", info.Count)
+	}
+	if got := db.Query(filter.Eq{Field: "lang", Value: "go"}, 0); len(got) != 1 || got[0].ID != "c" {
+		t.Errorf("Unexpected query after reopening: %+v", got)
+	}
+	if got := db.SearchVector([]float32{0, 0, 1}, 1); len(got) != 1 || got[0].ID != "c" || got[0].Score < 0.999 {
+		t.Errorf("Unexpected search after reopening: %+v", got)
+	}
+
+	// 数据库搜索失败时返回错误，而不是空结果
+	db.storage.Close()
+	if _, err := db.SearchVectorFilterErr([]float32{0, 0, 1}, 1, nil); err == nil {
+		t.Error("Expected a failed database search to return an error")
+	}
+	if got := db.SearchVector([]float32{0, 0, 1}, 1); len(got) != 0 {
+		t.Errorf("Expected no results from a failed search, got %+v", got)
+	}
+
+	other, err := NewVectorDB(testConfig(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err := other.CreateCollection(config.CollectionConfig{Name: "remote", HNSW: config.HNSWConfig{Dim: 2}, Index: config.IndexConfig{Type: "pgvector"}}); err == nil {
+		t.Error("Expected index type pgvector to require pgvector storage")
+	}
+}
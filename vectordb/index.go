@@ -0,0 +1,106 @@
+package vectordb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gvdb/config"
+	"gvdb/hnsw"
+	"gvdb/index"
+	"gvdb/storage"
+)
+
+// loadSnapshot 加载 HNSW 图快照，快照不存在、参数或数据与存储不一致时返回 nil，由调用方重建索引。
+// 快照无法读取或已损坏时同样返回 nil，并返回读取错误
+func loadSnapshot(cfg config.CollectionConfig, fresh *hnsw.HNSWIndex, s storage.Storage, data map[string]storage.VectorDoc) (*hnsw.HNSWIndex, error) {
+	loaded, err := hnsw.LoadFile(cfg.HNSW.IndexPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	q, _ := hnsw.NewQuantizer(cfg.HNSW.Quantization, fresh.Metric())
+	if !loaded.Compatible(cfg.HNSW.Dim, cfg.HNSW.M, fresh.Metric(), q) || loaded.Len() != len(data) {
+		return nil, nil
+	}
+	for id, doc := range data {
+		if !loaded.Contains(id, doc.Vector) {
+			return nil, nil
+		}
+	}
+	// 搜索参数和重新打分的数据源不属于快照，沿用当前配置
+	loaded.SetEfSearch(cfg.HNSW.EFSearch)
+	if q != nil {
+		loaded.SetRescore(storageSource(s), cfg.HNSW.Rescore)
+	}
+	return loaded, nil
+}
+
+func newIndex(cfg config.CollectionConfig, s storage.Storage) (index.Index, error) {
+	metric, err := hnsw.MetricByName(cfg.HNSW.Metric)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Index.Type {
+	case "", "hnsw":
+		idx := hnsw.NewHNSWIndex(cfg.HNSW.Dim, cfg.HNSW.M, cfg.HNSW.EFConstruction, cfg.HNSW.EFSearch, metric)
+		q, err := hnsw.NewQuantizer(cfg.HNSW.Quantization, metric)
+		if err != nil {
+			return nil, err
+		}
+		if q != nil {
+			idx.SetQuantizer(q, cfg.HNSW.TrainSize)
+			idx.SetRescore(storageSource(s), cfg.HNSW.Rescore)
+		}
+		return idx, nil
+	case "flat":
+		return index.NewFlatIndex(cfg.HNSW.Dim, metric, cfg.Index.Flat.Workers), nil
+	case "ivf":
+		ivf := cfg.Index.IVF
+		return index.NewIVFIndex(cfg.HNSW.Dim, ivf.Nlist, ivf.Nprobe, ivf.TrainSize, metric), nil
+	case "ivfpq":
+		ivf, pq := cfg.Index.IVF, cfg.Index.PQ
+		idx, err := index.NewIVFPQIndex(cfg.HNSW.Dim, ivf.Nlist, ivf.Nprobe, pq.M, pq.Nbits, ivf.TrainSize, metric)
+		if err != nil {
+			return nil, err
+		}
+		idx.SetRerank(storageSource(s), pq.Rerank)
+		return idx, nil
+	case "disk":
+		d := cfg.Index.Disk
+		return index.NewDiskIndex(d.Path, cfg.HNSW.Dim, d.MaxDegree, d.BuildList, d.SearchList, float32(d.Alpha), d.MergeThreshold, metric)
+	case "pgvector":
+		searcher, ok := s.(storage.VectorSearcher)
+		if !ok {
+			return nil, errors.New("index type pgvector requires postgres storage with storage.postgres.pgvector.enable")
+		}
+		return index.NewExternalIndex(metric, storageSearch(searcher)), nil
+	default:
+		return nil, fmt.Errorf("unknown index type: %s", cfg.Index.Type)
+	}
+}
+
+// storageSearch 由存储在数据库中执行搜索
+func storageSearch(s storage.VectorSearcher) index.SearchFunc {
+	return func(query []float32, k int) ([]hnsw.Neighbor, error) {
+		matches, err := s.SearchVectors(query, k)
+		if err != nil {
+			return nil, err
+		}
+		neighbors := make([]hnsw.Neighbor, len(matches))
+		for i, m := range matches {
+			neighbors[i] = hnsw.Neighbor{ID: m.ID, Score: m.Score}
+		}
+		return neighbors, nil
+	}
+}
+
+// storageSource 从存储读取原始向量，供量化索引重新打分
+func storageSource(s storage.Storage) hnsw.VectorSource {
+	return func(id string) ([]float32, bool) {
+		doc, ok := s.Get(id)
+		return doc.Vector, ok
+	}
+}
@@ -0,0 +1,210 @@
+package vectordb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"gvdb/config"
+	"gvdb/storage"
+)
+
+// MigrateOptions 为 MigrateStorage 的参数
+type MigrateOptions struct {
+	Collections []string // 只迁移列出的集合，为空时迁移全部
+	BatchSize   int      // 每批复制的文档数，默认 1000
+	DryRun      bool     // 只读取源，报告文档数和校验和，不写入目标
+	Checkpoint  string   // 进度文件，每批写入后更新，全部完成后删除；为空时不能续传
+	Resume      bool     // 从进度文件继续，没有进度文件时从头开始
+	// Progress 在每批写入后调用
+	Progress func(collection string, p storage.MigrateProgress)
+}
+
+// MigrateResult 为一个集合的迁移结果
+type MigrateResult struct {
+	Collection string `json:"collection"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	storage.MigrateResult
+}
+
+// migrateCheckpoint 为进度文件的内容，From 和 To 用于确认续传的是同一次迁移
+type migrateCheckpoint struct {
+	From        string                             `json:"from"`
+	To          string                             `json:"to"`
+	Collections map[string]storage.MigrateProgress `json:"collections"`
+}
+
+// MigrateStorage 把 from 中各集合的文档复制到 to 配置的存储，例如从文件存储迁移到 DuckDB 或 PostgreSQL，
+// 并逐个集合核对文档数和校验和。to 中不存在的集合沿用源的定义写入 to 的目录文件。
+// 迁移期间不能有服务使用源或目标；索引不复制，用 to 打开数据库时从存储重建
+func MigrateStorage(from, to config.Config, opts MigrateOptions) ([]MigrateResult, error) {
+	all, _, err := collectionConfigs(from)
+	if err != nil {
+		return nil, err
+	}
+	collections := all
+	if len(opts.Collections) > 0 {
+		collections = nil
+		for _, name := range opts.Collections {
+			cc, ok := findCollection(all, name)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+			}
+			collections = append(collections, cc)
+		}
+	}
+	target, err := newTargetCatalog(to)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := migrateCheckpoint{From: describeStorage(from, ""), To: describeStorage(to, ""), Collections: make(map[string]storage.MigrateProgress)}
+	if cp.From == cp.To {
+		return nil, errors.New("source and target use the same storage")
+	}
+	if opts.Resume && opts.Checkpoint != "" && !opts.DryRun {
+		saved, err := loadMigrateCheckpoint(opts.Checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		if saved != nil {
+			if saved.From != cp.From || saved.To != cp.To {
+				return nil, fmt.Errorf("checkpoint %s is for a migration from %s to %s, remove it to start over", opts.Checkpoint, saved.From, saved.To)
+			}
+			cp = *saved
+		}
+	}
+
+	var results []MigrateResult
+	for _, cc := range collections {
+		dc, err := target.resolve(cc)
+		if err != nil {
+			return results, err
+		}
+		res, err := migrateCollection(from, to, cc, dc, &cp, opts)
+		results = append(results, res)
+		if err != nil {
+			return results, fmt.Errorf("collection %s: %w", cc.Name, err)
+		}
+	}
+	if opts.DryRun {
+		return results, nil
+	}
+	if err := target.save(); err != nil {
+		return results, err
+	}
+	if opts.Checkpoint != "" {
+		if err := os.Remove(opts.Checkpoint); err != nil && !os.IsNotExist(err) {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func findCollection(all []config.CollectionConfig, name string) (config.CollectionConfig, bool) {
+	for _, cc := range all {
+		if cc.Name == name {
+			return cc, true
+		}
+	}
+	return config.CollectionConfig{}, false
+}
+
+// migrateCollection 把集合从 from 的存储复制到 to，sc 和 dc 分别为集合在源和目标中的定义
+func migrateCollection(from, to config.Config, sc, dc config.CollectionConfig, cp *migrateCheckpoint, opts MigrateOptions) (MigrateResult, error) {
+	name := sc.Name
+	res := MigrateResult{Collection: name, From: describeStorage(from, name), To: describeStorage(to, name)}
+	src, err := openStorage(from, sc)
+	if err != nil {
+		return res, err
+	}
+	defer src.Close()
+	var dst storage.Storage
+	if !opts.DryRun {
+		if dst, err = openStorage(to, dc); err != nil {
+			return res, err
+		}
+		defer dst.Close()
+	}
+
+	sopts := storage.MigrateOptions{BatchSize: opts.BatchSize, DryRun: opts.DryRun}
+	if p, ok := cp.Collections[name]; ok {
+		sopts.Resume = &p
+	}
+	sopts.Progress = func(p storage.MigrateProgress) error {
+		if !opts.DryRun && opts.Checkpoint != "" {
+			cp.Collections[name] = p
+			if err := cp.save(opts.Checkpoint); err != nil {
+				return err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(name, p)
+		}
+		return nil
+	}
+	res.MigrateResult, err = storage.Migrate(src, dst, sopts)
+	return res, err
+}
+
+// describeStorage 返回集合的存储位置，用于输出和校验进度文件；name 为空时描述整个存储
+func describeStorage(cfg config.Config, name string) string {
+	table := storage.DefaultTable
+	if name != "" && name != config.DefaultCollection {
+		table += "_" + name
+	}
+	switch cfg.Storage.Type {
+	case "file":
+		path := cfg.Storage.File.Path
+		if name != "" {
+			path = collectionFile(path, name)
+		}
+		return "file:" + path
+	case "duckdb":
+		if name == "" {
+			return "duckdb:" + cfg.Storage.DuckDB.Path
+		}
+		return "duckdb:" + cfg.Storage.DuckDB.Path + "#" + table
+	case "postgres":
+		pg := cfg.Storage.Postgres
+		s := fmt.Sprintf("postgres://%s@%s:%d/%s", pg.User, pg.Host, pg.Port, pg.Database)
+		if name == "" {
+			return s
+		}
+		return s + "#" + table
+	}
+	return cfg.Storage.Type
+}
+
+func loadMigrateCheckpoint(path string) (*migrateCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp migrateCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %v", path, err)
+	}
+	if cp.Collections == nil {
+		cp.Collections = make(map[string]storage.MigrateProgress)
+	}
+	return &cp, nil
+}
+
+// save 先写临时文件再重命名，中途崩溃不会留下损坏的进度文件
+func (cp *migrateCheckpoint) save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
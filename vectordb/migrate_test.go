@@ -0,0 +1,105 @@
+package vectordb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gvdb/config"
+	"gvdb/storage"
+)
+
+func TestMigrateStorage(t *testing.T) {
+	dir := t.TempDir()
+	from := testConfig(dir)
+	db, err := NewVectorDB(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertBatch(map[string]storage.VectorDoc{
+		"a": {Vector: []float32{1, 0, 0}, Payload: storage.Payload{"lang": "go"}},
+		"b": {Vector: []float32{0, 1, 0}},
+		"c": {Vector: []float32{0, 0, 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	images, err := db.CreateCollection(config.CollectionConfig{Name: "images", HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := images.InsertVector("img", []float32{1, 2}, ""); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	to := testConfig(dir)
+	to.Storage.Type = "duckdb"
+	to.Storage.File.Enable = false
+	to.Storage.DuckDB.Enable = true
+	to.Storage.DuckDB.Path = filepath.Join(dir, "vectors.db")
+	to.Catalog = filepath.Join(dir, "target.yaml")
+	checkpoint := filepath.Join(dir, "migrate.checkpoint")
+
+	dry, err := MigrateStorage(from, to, MigrateOptions{DryRun: true, Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry) != 2 || dry[0].Count != 3 || dry[0].Copied != 0 || dry[1].Collection != "images" {
+		t.Fatalf("Unexpected dry run: %+v", dry)
+	}
+	if _, err := os.Stat(to.Catalog); !os.IsNotExist(err) {
+		t.Error("Expected dry run to leave the target catalog alone")
+	}
+
+	var batches int
+	results, err := MigrateStorage(from, to, MigrateOptions{BatchSize: 2, Checkpoint: checkpoint, Progress: func(string, storage.MigrateProgress) {
+		batches++
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batches != 3 || results[0].Copied != 3 || results[0].TargetChecksum != dry[0].Checksum || results[1].TargetCount != 1 {
+		t.Errorf("Unexpected migration: %d batches, %+v", batches, results)
+	}
+	if !strings.HasSuffix(results[1].To, "#vectors_images") {
+		t.Errorf("Unexpected target description: %s", results[1].To)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Error("Expected the checkpoint to be removed after a complete migration")
+	}
+
+	// 目标非空时只能续传
+	if _, err := MigrateStorage(from, to, MigrateOptions{Checkpoint: checkpoint}); err == nil {
+		t.Error("Expected a non-empty target to be rejected")
+	}
+	// 进度文件中有记录的集合从记录的位置继续，这里从头重新写入
+	cp := migrateCheckpoint{From: describeStorage(from, ""), To: describeStorage(to, ""), Collections: map[string]storage.MigrateProgress{"default": {}, "images": {}}}
+	cp.save(checkpoint)
+	if _, err := MigrateStorage(from, to, MigrateOptions{Checkpoint: checkpoint, Resume: true}); err != nil {
+		t.Errorf("Resume failed: %v", err)
+	}
+	cp.From = "file:elsewhere.json"
+	cp.save(checkpoint)
+	if _, err := MigrateStorage(from, to, MigrateOptions{Checkpoint: checkpoint, Resume: true}); err == nil {
+		t.Error("Expected a checkpoint of another migration to be rejected")
+	}
+	if _, err := MigrateStorage(from, from, MigrateOptions{}); err == nil {
+		t.Error("Expected migrating a storage onto itself to fail")
+	}
+
+	out, err := NewVectorDB(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if doc, ok := out.Get("a"); !ok || doc.Payload["lang"] != "go" {
+		t.Errorf("Unexpected migrated document: %+v", doc)
+	}
+	if got := out.SearchVector([]float32{0, 0, 1}, 1); len(got) != 1 || got[0].ID != "c" {
+		t.Errorf("Unexpected search after migration: %+v", got)
+	}
+	if info, err := out.DescribeCollection("images"); err != nil || info.Count != 1 {
+		t.Errorf("Expected images to be migrated: %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,399 @@
+package vectordb
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"gvdb/config"
+	"gvdb/dataio"
+	"gvdb/hnsw"
+	"gvdb/storage"
+)
+
+// 快照为 zip 归档，与存储类型无关：
+//
+//	gvdb-snapshot.json    SnapshotManifest：格式版本、源存储类型和各集合的定义与文档数
+//	config.yaml           源数据库的配置，PostgreSQL 密码已清除
+//	<集合名>/points.jsonl  集合的全部文档，格式与 export 子命令相同
+//	<集合名>/graph.hnsw    HNSW 图快照，仅 HNSW 索引的集合有
+const (
+	SnapshotFormat        = "gvdb-snapshot"
+	SnapshotSchemaVersion = 1
+	snapshotManifest      = "gvdb-snapshot.json"
+	snapshotConfig        = "config.yaml"
+	restoreBatchSize      = 1000
+)
+
+// ErrSnapshotInvalid 表示归档不是 gvdb 快照、版本不受支持或内容与 manifest 不符
+var ErrSnapshotInvalid = errors.New("invalid snapshot")
+
+// SnapshotManifest 描述快照的内容
+type SnapshotManifest struct {
+	Format        string               `json:"format"`
+	SchemaVersion int                  `json:"schema_version"`
+	Created       time.Time            `json:"created"`
+	Storage       string               `json:"storage"` // 源数据库的存储类型
+	Collections   []SnapshotCollection `json:"collections"`
+}
+
+// SnapshotCollection 描述快照中的一个集合
+type SnapshotCollection struct {
+	Name   string                  `json:"name"`
+	Config config.CollectionConfig `json:"config"`
+	Count  int                     `json:"count"`
+	Graph  bool                    `json:"graph"` // 是否包含 HNSW 图快照
+}
+
+// Snapshot 在数据库运行期间把所有集合写入 w。归档先在锁内写入临时文件：每个集合在读锁下复制，内容为同一时刻的状态，
+// 复制期间该集合的写入等待，创建和删除集合等待全部集合复制完成。释放锁之后才把临时文件复制到 w，
+// 因此 w 很慢（如 HTTP 客户端）时不会阻塞写入和搜索
+func (db *VectorDB) Snapshot(w io.Writer) (SnapshotManifest, error) {
+	tmp, err := os.CreateTemp("", "gvdb-snapshot-*.zip")
+	if err != nil {
+		return SnapshotManifest{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	m, err := db.writeSnapshot(tmp)
+	if err != nil {
+		return SnapshotManifest{}, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return SnapshotManifest{}, err
+	}
+	if _, err := io.Copy(w, tmp); err != nil {
+		return SnapshotManifest{}, err
+	}
+	return m, nil
+}
+
+// writeSnapshot 在读锁下把所有集合写入 w
+func (db *VectorDB) writeSnapshot(w io.Writer) (SnapshotManifest, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	m := SnapshotManifest{
+		Format:        SnapshotFormat,
+		SchemaVersion: SnapshotSchemaVersion,
+		Created:       time.Now().UTC(),
+		Storage:       db.cfg.Storage.Type,
+	}
+	names := make([]string, 0, len(db.collections))
+	for name := range db.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		sc, err := db.collections[name].snapshot(zw, m.Created)
+		if err != nil {
+			return SnapshotManifest{}, fmt.Errorf("collection %s: %w", name, err)
+		}
+		m.Collections = append(m.Collections, sc)
+	}
+	cfg := db.cfg
+	cfg.Storage.Postgres.Password = ""
+	raw, err := yaml.Marshal(cfg)
+	if err != nil {
+		return SnapshotManifest{}, err
+	}
+	if err := writeZipFile(zw, snapshotConfig, m.Created, raw); err != nil {
+		return SnapshotManifest{}, err
+	}
+	raw, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return SnapshotManifest{}, err
+	}
+	if err := writeZipFile(zw, snapshotManifest, m.Created, raw); err != nil {
+		return SnapshotManifest{}, err
+	}
+	return m, zw.Close()
+}
+
+// snapshot 在读锁下把集合的文档和 HNSW 图写入 zw
+func (c *Collection) snapshot(zw *zip.Writer, created time.Time) (SnapshotCollection, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	sc := SnapshotCollection{Name: c.name, Config: c.cfg}
+	ids := make([]string, 0, len(c.fields))
+	for id := range c.fields {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	f, err := createZipFile(zw, c.name+"/points.jsonl", created)
+	if err != nil {
+		return sc, err
+	}
+	out := dataio.NewJSONLWriter(f)
+	for _, id := range ids {
+		doc, ok := c.storage.Get(id)
+		if !ok {
+			return sc, fmt.Errorf("document %s is missing from storage", id)
+		}
+		if err := out.Write(dataio.Record{ID: id, Vector: doc.Vector, Meta: doc.Meta, Payload: doc.Payload}); err != nil {
+			return sc, err
+		}
+		sc.Count++
+	}
+	if err := out.Close(); err != nil {
+		return sc, err
+	}
+
+	if h, ok := c.index.(*hnsw.HNSWIndex); ok {
+		f, err := createZipFile(zw, c.name+"/graph.hnsw", created)
+		if err != nil {
+			return sc, err
+		}
+		if err := h.Save(f); err != nil {
+			return sc, err
+		}
+		sc.Graph = true
+	}
+	return sc, nil
+}
+
+// createZipFile 在归档中创建压缩的文件，修改时间为快照时间
+func createZipFile(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	f, err := createZipFile(zw, name, modified)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// ReadSnapshotManifest 读取并校验快照的 manifest
+func ReadSnapshotManifest(r io.ReaderAt, size int64) (SnapshotManifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return SnapshotManifest{}, fmt.Errorf("%w: %v", ErrSnapshotInvalid, err)
+	}
+	return readManifest(zr)
+}
+
+func readManifest(zr *zip.Reader) (SnapshotManifest, error) {
+	var m SnapshotManifest
+	f, err := zr.Open(snapshotManifest)
+	if err != nil {
+		return m, fmt.Errorf("%w: %v", ErrSnapshotInvalid, err)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return m, fmt.Errorf("%w: %v", ErrSnapshotInvalid, err)
+	}
+	if m.Format != SnapshotFormat {
+		return m, fmt.Errorf("%w: unknown format %q", ErrSnapshotInvalid, m.Format)
+	}
+	if m.SchemaVersion < 1 || m.SchemaVersion > SnapshotSchemaVersion {
+		return m, fmt.Errorf("%w: unsupported schema version %d", ErrSnapshotInvalid, m.SchemaVersion)
+	}
+	return m, nil
+}
+
+func (m SnapshotManifest) collection(name string) (SnapshotCollection, bool) {
+	for _, sc := range m.Collections {
+		if sc.Name == name {
+			return sc, true
+		}
+	}
+	return SnapshotCollection{}, false
+}
+
+// RestoreOptions 控制 Restore 恢复哪些集合以及如何处理目标中已有的数据
+type RestoreOptions struct {
+	Collections []string // 只恢复列出的集合，为空时恢复全部
+	Overwrite   bool     // 清除目标集合中已有的文档；否则目标集合必须为空
+}
+
+// Restore 把快照恢复到 cfg 描述的数据库，目标可以使用与源不同的存储类型，恢复期间目标数据库不能在运行。
+// 配置中声明的集合维度和度量须与快照一致，其余集合按快照中的定义写入目录文件。
+// 返回的 manifest 只包含恢复的集合
+func Restore(cfg config.Config, r io.ReaderAt, size int64, opts RestoreOptions) (SnapshotManifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return SnapshotManifest{}, fmt.Errorf("%w: %v", ErrSnapshotInvalid, err)
+	}
+	m, err := readManifest(zr)
+	if err != nil {
+		return m, err
+	}
+	collections := m.Collections
+	if len(opts.Collections) > 0 {
+		collections = nil
+		for _, name := range opts.Collections {
+			sc, ok := m.collection(name)
+			if !ok {
+				return m, fmt.Errorf("%w in snapshot: %s", ErrCollectionNotFound, name)
+			}
+			collections = append(collections, sc)
+		}
+	}
+
+	target, err := newTargetCatalog(cfg)
+	if err != nil {
+		return m, err
+	}
+	var restored []SnapshotCollection
+	for _, sc := range collections {
+		cc, err := target.resolve(sc.Config)
+		if err != nil {
+			return m, err
+		}
+		n, err := restoreCollection(cfg, cc, zr, sc, opts.Overwrite)
+		if err != nil {
+			return m, fmt.Errorf("collection %s: %w", sc.Name, err)
+		}
+		sc.Count = n
+		restored = append(restored, sc)
+	}
+	if err := target.save(); err != nil {
+		return m, err
+	}
+	m.Collections = restored
+	return m, nil
+}
+
+// restoreCollection 把快照中一个集合的文档写入目标存储，并替换其 HNSW 图快照，返回写入的文档数
+func restoreCollection(cfg config.Config, cc config.CollectionConfig, zr *zip.Reader, sc SnapshotCollection, overwrite bool) (int, error) {
+	s, err := openStorage(cfg, cc)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := s.Load()
+	if err != nil {
+		s.Close()
+		return 0, err
+	}
+	if len(existing) > 0 {
+		if !overwrite {
+			s.Close()
+			return 0, fmt.Errorf("target already has %d documents", len(existing))
+		}
+		if err := s.Drop(); err != nil {
+			return 0, err
+		}
+		if s, err = openStorage(cfg, cc); err != nil {
+			return 0, err
+		}
+	}
+	defer s.Close()
+
+	f, err := zr.Open(sc.Name + "/points.jsonl")
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSnapshotInvalid, err)
+	}
+	defer f.Close()
+	in := dataio.NewJSONLReader(f, 0)
+	n := 0
+	batch := make(map[string]storage.VectorDoc, restoreBatchSize)
+	for {
+		rec, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, fmt.Errorf("%w: %v", ErrSnapshotInvalid, err)
+		}
+		if len(rec.Vector) != cc.HNSW.Dim {
+			return n, fmt.Errorf("%w: document %s has %d dimensions, want %d", ErrDimensionMismatch, rec.ID, len(rec.Vector), cc.HNSW.Dim)
+		}
+		batch[rec.ID] = storage.VectorDoc{Vector: rec.Vector, Meta: rec.Meta, Payload: rec.Payload}
+		if len(batch) == restoreBatchSize {
+			if err := s.UpsertBatch(batch); err != nil {
+				return n, err
+			}
+			n += len(batch)
+			batch = make(map[string]storage.VectorDoc, restoreBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.UpsertBatch(batch); err != nil {
+			return n, err
+		}
+		n += len(batch)
+	}
+	if n != sc.Count {
+		return n, fmt.Errorf("%w: %d documents, manifest says %d", ErrSnapshotInvalid, n, sc.Count)
+	}
+
+	// 目标原有的预写日志和索引文件描述的是旧数据，打开时磁盘索引从存储重建
+	stale := []string{cc.HNSW.IndexPath}
+	if cc.Index.Disk.Path != "" {
+		stale = append(stale, cc.Index.Disk.Path, cc.Index.Disk.Path+".dirty")
+	}
+	if cfg.WAL.Enable {
+		stale = append(stale, walPath(cfg, cc.Name))
+	}
+	for _, path := range stale {
+		if err := removeFile(path); err != nil {
+			return n, err
+		}
+	}
+	if sc.Graph && cc.HNSW.IndexPath != "" {
+		// 参数与目标配置不一致的图快照在打开集合时被忽略并重建
+		if err := restoreGraph(zr, sc.Name+"/graph.hnsw", cc.HNSW.IndexPath); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func removeFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// restoreGraph 把归档中的 HNSW 图快照写入临时文件后重命名为 path
+func restoreGraph(zr *zip.Reader, name, path string) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotInvalid, err)
+	}
+	defer f.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, f); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
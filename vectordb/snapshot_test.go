@@ -0,0 +1,180 @@
+package vectordb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gvdb/config"
+	"gvdb/filter"
+	"gvdb/storage"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	src := t.TempDir()
+	cfg := testConfig(src)
+	cfg.HNSW.IndexPath = filepath.Join(src, "vectors.hnsw")
+	cfg.Storage.Postgres.Password = "secret"
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.UpsertBatch(map[string]storage.VectorDoc{
+		"a": {Vector: []float32{1, 0, 0}, Payload: storage.Payload{"lang": "go", "stars": int64(3)}},
+		"b": {Vector: []float32{0, 1, 0}, Meta: "legacy"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	images, err := db.CreateCollection(config.CollectionConfig{Name: "images", HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"}, Index: config.IndexConfig{Type: "flat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := images.InsertVector("img", []float32{1, 2}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	m, err := db.Snapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Collections) != 2 || m.Collections[0].Name != "default" || m.Collections[0].Count != 2 || !m.Collections[0].Graph || m.Collections[1].Graph {
+		t.Fatalf("Unexpected manifest: %+v", m)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret")) {
+		t.Error("Expected the Postgres password to be left out of the snapshot")
+	}
+	archive := bytes.NewReader(buf.Bytes())
+	if got, err := ReadSnapshotManifest(archive, archive.Size()); err != nil || got.SchemaVersion != SnapshotSchemaVersion {
+		t.Fatalf("ReadSnapshotManifest: %+v, %v", got, err)
+	}
+
+	// 恢复到使用 DuckDB 的另一个数据库
+	dst := t.TempDir()
+	target := testConfig(dst)
+	target.HNSW.IndexPath = filepath.Join(dst, "vectors.hnsw")
+	target.Storage.Type = "duckdb"
+	target.Storage.File.Enable = false
+	target.Storage.DuckDB.Enable = true
+	target.Storage.DuckDB.Path = filepath.Join(dst, "vectors.db")
+	restored, err := Restore(target, archive, archive.Size(), RestoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Collections) != 2 || restored.Collections[1].Count != 1 {
+		t.Errorf("Unexpected restore result: %+v", restored)
+	}
+	if _, err := os.Stat(target.HNSW.IndexPath); err != nil {
+		t.Errorf("Expected the HNSW graph to be restored: %v", err)
+	}
+
+	out, err := NewVectorDB(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, ok := out.Get("a"); !ok || doc.Payload["stars"] != int64(3) {
+		t.Errorf("Unexpected restored document: %+v", doc)
+	}
+	if doc, ok := out.Get("b"); !ok || doc.Meta != "legacy" {
+		t.Errorf("Unexpected restored document: %+v", doc)
+	}
+	if got := out.Query(filter.Eq{Field: "lang", Value: "go"}, 0); len(got) != 1 {
+		t.Errorf("Expected restored payload to be indexed, got %+v", got)
+	}
+	if got := out.SearchVector([]float32{0, 1, 0}, 1); len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("Unexpected search after restore: %+v", got)
+	}
+	c, err := out.GetCollection("images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := c.Info(); info.Count != 1 || info.IndexType != "flat" {
+		t.Errorf("Unexpected restored collection: %+v", info)
+	}
+	out.Close()
+
+	// 目标非空时需要 Overwrite
+	if _, err := Restore(target, archive, archive.Size(), RestoreOptions{Collections: []string{"default"}}); err == nil || !strings.Contains(err.Error(), "already has") {
+		t.Errorf("Expected restore into a non-empty collection to fail, got %v", err)
+	}
+	if _, err := Restore(target, archive, archive.Size(), RestoreOptions{Collections: []string{"default"}, Overwrite: true}); err != nil {
+		t.Errorf("Overwrite failed: %v", err)
+	}
+	if _, err := Restore(target, archive, archive.Size(), RestoreOptions{Collections: []string{"missing"}}); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound, got %v", err)
+	}
+
+	// 维度不一致的目标
+	target.HNSW.Dim = 4
+	if _, err := Restore(target, archive, archive.Size(), RestoreOptions{Overwrite: true}); err == nil {
+		t.Error("Expected dimension mismatch")
+	}
+
+	garbage := bytes.NewReader([]byte("not a snapshot"))
+	if _, err := Restore(target, garbage, garbage.Size(), RestoreOptions{}); !errors.Is(err, ErrSnapshotInvalid) {
+		t.Errorf("Expected ErrSnapshotInvalid, got %v", err)
+	}
+}
+
+// stalledWriter 在第一次 Write 时通知 started，然后一直等到 release 关闭
+type stalledWriter struct {
+	started chan struct{}
+	release chan struct{}
+	once    bool
+}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	if !w.once {
+		w.once = true
+		close(w.started)
+	}
+	<-w.release
+	return len(p), nil
+}
+
+func TestSnapshotSlowWriter(t *testing.T) {
+	db, err := NewVectorDB(testConfig(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.InsertVector("a", []float32{1, 0, 0}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &stalledWriter{started: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		_, err := db.Snapshot(w)
+		done <- err
+	}()
+	<-w.started
+
+	// 写入客户端停滞时锁已经释放，写入和创建集合不需要等待
+	inserted := make(chan error)
+	go func() {
+		if err := db.InsertVector("b", []float32{0, 1, 0}, ""); err != nil {
+			inserted <- err
+			return
+		}
+		_, err := db.CreateCollection(config.CollectionConfig{Name: "late", HNSW: config.HNSWConfig{Dim: 2}})
+		inserted <- err
+	}()
+	select {
+	case err := <-inserted:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected writes not to wait for a stalled snapshot writer")
+	}
+	close(w.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
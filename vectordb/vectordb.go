@@ -0,0 +1,255 @@
+// Package vectordb 管理一组集合：每个集合有独立的维度、度量、索引参数和存储表/文件
+package vectordb
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gvdb/config"
+	"gvdb/hnsw"
+	"gvdb/storage"
+)
+
+var (
+	ErrCollectionExists   = errors.New("collection already exists")
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrCollectionDeclared 表示集合在配置文件中声明，只能通过修改配置删除
+	ErrCollectionDeclared = errors.New("collection is defined in config")
+)
+
+// Logger 接收不影响调用结果的诊断信息，例如忽略损坏的快照、后台检查点失败，默认写到标准错误
+var Logger = log.New(os.Stderr, "", log.LstdFlags)
+
+// VectorDB 内嵌默认集合，单集合的用法与之前相同；其他集合通过 GetCollection 获取
+type VectorDB struct {
+	*Collection
+	cfg         config.Config
+	collections map[string]*Collection
+	declared    map[string]bool // 默认集合和 config.yaml 中声明的集合
+	mutex       sync.RWMutex
+}
+
+// NewVectorDB 打开默认集合、配置中声明的集合以及目录文件中记录的集合，cfg 需由 config.LoadConfig 读取
+func NewVectorDB(cfg config.Config) (*VectorDB, error) {
+	db := &VectorDB{
+		cfg:         cfg,
+		collections: make(map[string]*Collection),
+	}
+	all, declared, err := collectionConfigs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db.declared = declared
+	for _, cc := range all {
+		c, err := db.open(cc)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("collection %s: %w", cc.Name, err)
+		}
+		db.collections[cc.Name] = c
+	}
+	db.Collection = db.collections[config.DefaultCollection]
+	return db, nil
+}
+
+func (db *VectorDB) open(cc config.CollectionConfig) (*Collection, error) {
+	s, err := openStorage(db.cfg, cc)
+	if err != nil {
+		return nil, err
+	}
+	c, err := openCollection(cc, s, db.cfg.Search.BruteForceRatio)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	if db.cfg.WAL.Enable {
+		if err := c.openWAL(walPath(db.cfg, cc.Name), walOptions(db.cfg)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// openStorage 打开集合的存储：默认集合使用配置的文件或 vectors 表，
+// 其他集合使用 <文件名>.<集合名>.json 或 vectors_<集合名> 表。启用 pgvector 时按集合的维度和度量建立 vector 列和索引
+func openStorage(cfg config.Config, cc config.CollectionConfig) (storage.Storage, error) {
+	name := cc.Name
+	table := storage.DefaultTable
+	if name != config.DefaultCollection {
+		table += "_" + name
+	}
+	switch cfg.Storage.Type {
+	case "file":
+		if cfg.Storage.File.Enable {
+			return storage.NewFileStorage(collectionFile(cfg.Storage.File.Path, name)), nil
+		}
+	case "duckdb":
+		if cfg.Storage.DuckDB.Enable {
+			return storage.NewDuckDBStorageTable(cfg.Storage.DuckDB.Path, table)
+		}
+	case "postgres":
+		if cfg.Storage.Postgres.Enable && cfg.Storage.Postgres.Pgvector.Enable {
+			return openPgvector(cfg, cc, table)
+		}
+		if cfg.Storage.Postgres.Enable {
+			return storage.NewPostgresStorageTable(
+				cfg.Storage.Postgres.Host,
+				cfg.Storage.Postgres.Port,
+				cfg.Storage.Postgres.User,
+				cfg.Storage.Postgres.Password,
+				cfg.Storage.Postgres.Database,
+				table,
+			)
+		}
+	default:
+		return nil, fmt.Errorf("unknown or disabled storage type: %s", cfg.Storage.Type)
+	}
+	return nil, fmt.Errorf("no enabled storage backend selected")
+}
+
+func openPgvector(cfg config.Config, cc config.CollectionConfig, table string) (storage.Storage, error) {
+	metric, err := hnsw.MetricByName(cc.HNSW.Metric)
+	if err != nil {
+		return nil, err
+	}
+	pg, pv := cfg.Storage.Postgres, cfg.Storage.Postgres.Pgvector
+	return storage.NewPgvectorStorage(pg.Host, pg.Port, pg.User, pg.Password, pg.Database, table, storage.PgvectorOptions{
+		Dim:            cc.HNSW.Dim,
+		Metric:         metric.Name(),
+		Index:          pv.Index,
+		M:              pv.M,
+		EFConstruction: pv.EFConstruction,
+		EFSearch:       pv.EFSearch,
+		Lists:          pv.Lists,
+		Probes:         pv.Probes,
+	})
+}
+
+// collectionFile 把 vectors.json 变为 vectors.<name>.json，默认集合保持原路径
+func collectionFile(path, name string) string {
+	if name == config.DefaultCollection {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
+
+// GetCollection 返回指定名称的集合
+func (db *VectorDB) GetCollection(name string) (*Collection, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	c, ok := db.collections[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return c, nil
+}
+
+// CreateCollection 创建集合并记录到目录文件，重启后仍然存在
+func (db *VectorDB) CreateCollection(cc config.CollectionConfig) (*Collection, error) {
+	if err := cc.Normalize(); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.collections[cc.Name]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionExists, cc.Name)
+	}
+	c, err := db.open(cc)
+	if err != nil {
+		return nil, err
+	}
+	db.collections[cc.Name] = c
+	if err := db.saveCatalog(); err != nil {
+		delete(db.collections, cc.Name)
+		c.drop()
+		return nil, err
+	}
+	return c, nil
+}
+
+// DropCollection 删除集合及其存储和索引文件；默认集合和配置中声明的集合不能删除
+func (db *VectorDB) DropCollection(name string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	c, ok := db.collections[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	if db.declared[name] {
+		return fmt.Errorf("%w: %s", ErrCollectionDeclared, name)
+	}
+	delete(db.collections, name)
+	if err := db.saveCatalog(); err != nil {
+		db.collections[name] = c
+		return err
+	}
+	return c.drop()
+}
+
+// ListCollections 按名称顺序返回所有集合
+func (db *VectorDB) ListCollections() []string {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	names := make([]string, 0, len(db.collections))
+	for name := range db.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (db *VectorDB) DescribeCollection(name string) (CollectionInfo, error) {
+	c, err := db.GetCollection(name)
+	if err != nil {
+		return CollectionInfo{}, err
+	}
+	return c.Info(), nil
+}
+
+// saveCatalog 把通过 API 创建的集合写入目录文件，调用方需持有写锁
+func (db *VectorDB) saveCatalog() error {
+	var created []config.CollectionConfig
+	for name, c := range db.collections {
+		if !db.declared[name] {
+			created = append(created, c.cfg)
+		}
+	}
+	sort.Slice(created, func(i, j int) bool { return created[i].Name < created[j].Name })
+	return saveCatalog(db.cfg.Catalog, created)
+}
+
+// SaveIndex 保存所有集合的 HNSW 图快照
+func (db *VectorDB) SaveIndex() error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	var firstErr error
+	for _, c := range db.collections {
+		if err := c.SaveIndex(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close 关闭所有集合
+func (db *VectorDB) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	var firstErr error
+	for _, c := range db.collections {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,145 @@
+package vectordb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gvdb/config"
+)
+
+func testConfig(dir string) config.Config {
+	var cfg config.Config
+	cfg.Storage.Type = "file"
+	cfg.Storage.File.Enable = true
+	cfg.Storage.File.Path = filepath.Join(dir, "vectors.json")
+	cfg.HNSW = config.HNSWConfig{Dim: 3, M: 16, EF: 64, EFConstruction: 64, EFSearch: 64, Metric: "cosine"}
+	cfg.Index.Type = "hnsw"
+	cfg.Search.BruteForceRatio = 0.01
+	cfg.Catalog = filepath.Join(dir, "collections.yaml")
+	return cfg
+}
+
+func TestCollections(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatalf("NewVectorDB failed: %v", err)
+	}
+
+	images := config.CollectionConfig{Name: "images", HNSW: config.HNSWConfig{Dim: 2, Metric: "l2"}, Index: config.IndexConfig{Type: "flat"}}
+	c, err := db.CreateCollection(images)
+	if err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+	if _, err := db.CreateCollection(images); !errors.Is(err, ErrCollectionExists) {
+		t.Errorf("Expected ErrCollectionExists, got %v", err)
+	}
+	if err := c.InsertVector("img1", []float32{1, 0}, ""); err != nil {
+		t.Fatalf("Insert into collection failed: %v", err)
+	}
+	if err := c.InsertVector("img2", []float32{1, 0, 0}, ""); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+	// 默认集合与 images 的 ID 空间相互独立
+	if err := db.InsertVector("img1", []float32{1, 2, 3}, ""); err != nil {
+		t.Fatalf("Insert into default collection failed: %v", err)
+	}
+	if got := c.SearchVector([]float32{0.9, 0.1}, 5); len(got) != 1 || got[0].ID != "img1" {
+		t.Errorf("Unexpected search results in images: %v", got)
+	}
+
+	if got := db.ListCollections(); !reflect.DeepEqual(got, []string{"default", "images"}) {
+		t.Errorf("Unexpected collections: %v", got)
+	}
+	info, err := db.DescribeCollection("images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Dim != 2 || info.Metric != "l2" || info.IndexType != "flat" || info.Count != 1 {
+		t.Errorf("Unexpected collection info: %+v", info)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后从目录文件恢复集合
+	db, err = NewVectorDB(cfg)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer db.Close()
+	c, err = db.GetCollection("images")
+	if err != nil {
+		t.Fatalf("Expected images to survive a restart: %v", err)
+	}
+	if doc, ok := c.Get("img1"); !ok || !reflect.DeepEqual(doc.Vector, []float32{1, 0}) {
+		t.Errorf("Unexpected document after reopen: %v", doc)
+	}
+
+	if err := db.DropCollection(config.DefaultCollection); !errors.Is(err, ErrCollectionDeclared) {
+		t.Errorf("Expected ErrCollectionDeclared, got %v", err)
+	}
+	if err := db.DropCollection("images"); err != nil {
+		t.Fatalf("DropCollection failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vectors.images.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected collection file to be removed, got %v", err)
+	}
+	if _, err := db.DescribeCollection("images"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound, got %v", err)
+	}
+	if _, ok := db.Get("img1"); !ok {
+		t.Error("Expected default collection to be unaffected")
+	}
+}
+
+func TestDeclaredCollections(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.Storage.Type = "duckdb"
+	cfg.Storage.File.Enable = false
+	cfg.Storage.DuckDB.Enable = true
+	cfg.Storage.DuckDB.Path = filepath.Join(dir, "vectors.db")
+	docs := config.CollectionConfig{Name: "docs", HNSW: config.HNSWConfig{Dim: 4}}
+	if err := docs.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Collections = []config.CollectionConfig{docs}
+
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatalf("NewVectorDB failed: %v", err)
+	}
+	defer db.Close()
+	c, err := db.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.InsertVector("a", []float32{1, 2, 3, 4}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.Get("a"); ok {
+		t.Error("Expected declared collection to use its own table")
+	}
+	if err := db.DropCollection("docs"); !errors.Is(err, ErrCollectionDeclared) {
+		t.Errorf("Expected ErrCollectionDeclared, got %v", err)
+	}
+}
+
+func TestCollectionFile(t *testing.T) {
+	cases := map[[2]string]string{
+		{"vectors.json", "default"}:     "vectors.json",
+		{"vectors.json", "images"}:      "vectors.images.json",
+		{"data/store", "images"}:        "data/store.images",
+		{"data/v1.0/vectors.json", "a"}: "data/v1.0/vectors.a.json",
+	}
+	for in, want := range cases {
+		if got := collectionFile(in[0], in[1]); got != want {
+			t.Errorf("collectionFile(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
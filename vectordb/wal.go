@@ -0,0 +1,125 @@
+package vectordb
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gvdb/config"
+	"gvdb/wal"
+)
+
+// walPath 返回集合的预写日志文件 <wal.dir>/<集合名>.wal
+func walPath(cfg config.Config, name string) string {
+	return filepath.Join(cfg.WAL.Dir, name+".wal")
+}
+
+func walOptions(cfg config.Config) wal.Options {
+	return wal.Options{
+		Sync:           cfg.WAL.Sync,
+		SyncInterval:   time.Duration(cfg.WAL.SyncInterval) * time.Millisecond,
+		CheckpointSize: cfg.WAL.CheckpointSize,
+		Logger:         Logger,
+	}
+}
+
+// openWAL 打开预写日志，把上次检查点之后的记录重放到存储和索引中；重放了记录时随后做一次检查点
+func (c *Collection) openWAL(path string, opts wal.Options) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	replayed := 0
+	log, err := wal.Open(path, opts, func(e wal.Entry) error {
+		replayed++
+		return c.replay(e)
+	})
+	if err != nil {
+		return err
+	}
+	c.wal = log
+	if replayed > 0 {
+		Logger.Printf("Replayed %d WAL records into collection %s", replayed, c.name)
+		return c.checkpoint()
+	}
+	return nil
+}
+
+// replay 重新执行一条日志记录。写入或删除整个文档的操作是幂等的，存储中已包含该记录的修改时结果不变
+func (c *Collection) replay(e wal.Entry) error {
+	if err := c.markIndexDirty(); err != nil {
+		return err
+	}
+	switch e.Op {
+	case wal.OpUpsert:
+		if err := c.storage.UpsertBatch(e.Docs); err != nil {
+			return err
+		}
+		c.addDocs(e.Docs)
+	case wal.OpDelete:
+		if _, err := c.storage.DeleteBatch(e.IDs); err != nil {
+			return err
+		}
+		c.removeDocs(e.IDs)
+	}
+	return nil
+}
+
+// logWrite 在写入存储之前把操作追加到预写日志，调用方需持有写锁；未启用日志时返回 0
+func (c *Collection) logWrite(e wal.Entry) (uint64, error) {
+	if c.wal == nil {
+		return 0, nil
+	}
+	return c.wal.Append(e)
+}
+
+// undoWrite 在存储写入失败后撤销 logWrite 追加的记录，避免重启时重放一次失败的写入
+func (c *Collection) undoWrite(seq uint64, err error) error {
+	if c.wal == nil {
+		return err
+	}
+	if uerr := c.wal.Undo(seq); uerr != nil {
+		return fmt.Errorf("%w (undoing WAL record %d: %v)", err, seq, uerr)
+	}
+	return err
+}
+
+// maybeCheckpoint 在日志超过 checkpoint_size 时做检查点。写入本身已经成功，检查点失败只记录到 Logger，下次写入时重试
+func (c *Collection) maybeCheckpoint() {
+	if c.wal == nil || !c.wal.NeedsCheckpoint() {
+		return
+	}
+	if err := c.checkpoint(); err != nil {
+		Logger.Printf("WAL checkpoint of collection %s failed: %v", c.name, err)
+	}
+}
+
+// Checkpoint 保存 HNSW 图快照并截断预写日志，未启用日志时不做任何事
+func (c *Collection) Checkpoint() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.checkpoint()
+}
+
+// checkpoint 调用方需持有写锁。存储在每次写入后已经持久化，快照让重启时不必重建索引
+func (c *Collection) checkpoint() error {
+	if c.wal == nil {
+		return nil
+	}
+	if err := c.saveIndex(); err != nil {
+		return err
+	}
+	return c.wal.Checkpoint()
+}
+
+// Checkpoint 对所有集合做检查点
+func (db *VectorDB) Checkpoint() error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+	var firstErr error
+	for _, c := range db.collections {
+		if err := c.Checkpoint(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,95 @@
+package vectordb
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gvdb/filter"
+	"gvdb/storage"
+	"gvdb/wal"
+)
+
+// failingStorage 的批量写入总是失败
+type failingStorage struct {
+	storage.Storage
+}
+
+func (failingStorage) UpsertBatch(map[string]storage.VectorDoc) error {
+	return errors.New("disk full")
+}
+
+func TestWALRecovery(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(dir)
+	cfg.HNSW.IndexPath = filepath.Join(dir, "vectors.hnsw")
+	cfg.WAL.Enable = true
+	cfg.WAL.Dir = filepath.Join(dir, "wal")
+	cfg.WAL.Sync = wal.SyncAlways
+
+	db, err := NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := db.InsertVector(id, []float32{1, 0, 0}, `{"lang": "go"}`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if db.wal.Size() == 0 {
+		t.Error("Expected inserts to be logged")
+	}
+	if err := db.InsertBatch(map[string]storage.VectorDoc{"a": {Vector: []float32{0, 1, 0}}}); !errors.Is(err, storage.ErrExists) {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+
+	// 存储写入失败时撤销日志记录
+	size := db.wal.Size()
+	s := db.storage
+	db.storage = failingStorage{s}
+	if err := db.UpsertBatch(map[string]storage.VectorDoc{"x": {Vector: []float32{0, 0, 1}}}); err == nil {
+		t.Fatal("Expected storage error")
+	}
+	db.storage = s
+	if db.wal.Size() != size {
+		t.Errorf("Expected failed write to be undone, log has %d bytes, want %d", db.wal.Size(), size)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟崩溃：记录已写入日志，但存储和索引没有修改
+	log, err := wal.Open(walPath(cfg, "default"), wal.Options{}, func(wal.Entry) error {
+		t.Error("Expected log to be empty after Close")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Append(wal.Entry{Op: wal.OpUpsert, Docs: map[string]storage.VectorDoc{
+		"lost": {Vector: []float32{0, 1, 0}, Payload: storage.Payload{"lang": "rust"}},
+	}})
+	log.Append(wal.Entry{Op: wal.OpDelete, IDs: []string{"a"}})
+	log.Close()
+
+	db, err = NewVectorDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, ok := db.Get("lost"); !ok {
+		t.Error("Expected logged upsert to be replayed into storage")
+	}
+	if _, ok := db.Get("a"); ok {
+		t.Error("Expected logged delete to be replayed into storage")
+	}
+	if got := db.SearchVector([]float32{0, 1, 0}, 1); len(got) != 1 || got[0].ID != "lost" {
+		t.Errorf("Expected replayed document in the index, got %+v", got)
+	}
+	if got := db.Query(filter.Eq{Field: "lang", Value: "go"}, 0); len(got) != 2 {
+		t.Errorf("Expected 2 go documents after replay, got %+v", got)
+	}
+	if db.wal.Size() != 0 {
+		t.Errorf("Expected checkpoint after replay, log has %d bytes", db.wal.Size())
+	}
+}
@@ -0,0 +1,373 @@
+// Package wal 实现集合写操作的预写日志：写入存储之前先追加一条带序号和 CRC32 校验的记录，
+// 启动时重放上次检查点之后的记录，检查点之后截断日志
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gvdb/storage"
+)
+
+// 日志文件格式：magic 和下一条记录的序号，之后为若干记录：
+// 长度（uint32）、CRC32（uint32）、序号（uint64）、操作（1 字节）、JSON 编码的内容，长度和 CRC32 覆盖序号之后的部分
+const (
+	magic         = "GVDBWAL1"
+	headerSize    = len(magic) + 8
+	maxRecordSize = 1 << 30
+)
+
+// ErrNotLog 表示文件不是预写日志
+var ErrNotLog = errors.New("wal: not a write-ahead log")
+
+// Op 为日志记录的操作类型
+type Op byte
+
+const (
+	OpUpsert Op = 1 // 写入或替换 Docs
+	OpDelete Op = 2 // 删除 IDs
+)
+
+// Entry 为一条日志记录，Seq 由 Append 分配，在日志中连续递增
+type Entry struct {
+	Seq  uint64
+	Op   Op
+	Docs map[string]storage.VectorDoc
+	IDs  []string
+}
+
+// 同步策略
+const (
+	SyncAlways   = "always"   // 每条记录追加后 fsync
+	SyncInterval = "interval" // 后台按 SyncInterval 定期 fsync，崩溃时可能丢失最后一个间隔内的写入
+	SyncNone     = "none"     // 只在检查点和关闭时 fsync，由操作系统决定何时落盘
+)
+
+// Options 为日志参数，零值使用默认值
+type Options struct {
+	Sync           string        // SyncAlways（默认）、SyncInterval 或 SyncNone
+	SyncInterval   time.Duration // 默认 1 秒
+	CheckpointSize int64         // 记录总字节数达到该值时 NeedsCheckpoint 返回 true，默认 64MB
+	Logger         *log.Logger   // 接收截断损坏记录、后台 fsync 失败等诊断信息，默认写到标准错误
+}
+
+func (o *Options) normalize() error {
+	switch o.Sync {
+	case "":
+		o.Sync = SyncAlways
+	case SyncAlways, SyncInterval, SyncNone:
+	default:
+		return fmt.Errorf("wal: unknown sync policy %q", o.Sync)
+	}
+	if o.SyncInterval <= 0 {
+		o.SyncInterval = time.Second
+	}
+	if o.CheckpointSize <= 0 {
+		o.CheckpointSize = 64 << 20
+	}
+	if o.Logger == nil {
+		o.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	return nil
+}
+
+// Log 是一个只追加的日志文件，可以被多个 goroutine 同时使用
+type Log struct {
+	path    string
+	opts    Options
+	f       *os.File
+	nextSeq uint64
+	size    int64 // 文件中有效数据的长度，新记录写在此处
+	last    int64 // 最后追加的记录的偏移，供 Undo 使用，-1 表示不能撤销
+	dirty   bool  // 有未 fsync 的记录
+	syncErr error // fsync 失败后未同步的记录可能已经丢失，之后的 Append 和 Sync 都返回该错误，直到检查点丢弃这些记录
+	stop    chan struct{}
+	done    chan struct{}
+	mutex   sync.Mutex
+}
+
+// Open 打开或创建日志文件，按顺序把上次检查点之后的记录交给 replay，replay 返回错误时打开失败。
+// 文件末尾不完整或校验失败的记录（写入中途崩溃）会被截断
+func Open(path string, opts Options, replay func(Entry) error) (*Log, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{path: path, opts: opts, f: f, last: -1}
+	if err := l.recover(replay); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if opts.Sync == SyncInterval {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop()
+	}
+	return l, nil
+}
+
+// recover 读取头部和全部记录，截断第一条无效记录及其之后的内容
+func (l *Log) recover(replay func(Entry) error) error {
+	info, err := l.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < int64(headerSize) {
+		// 新文件，或者写入头部之前崩溃
+		return l.reset(1)
+	}
+	r := bufio.NewReader(io.NewSectionReader(l.f, 0, info.Size()))
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if string(header[:len(magic)]) != magic {
+		return fmt.Errorf("%w: %s", ErrNotLog, l.path)
+	}
+	l.nextSeq = binary.LittleEndian.Uint64(header[len(magic):])
+	l.size = int64(headerSize)
+	for {
+		e, n, err := readRecord(r, l.nextSeq)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			l.opts.Logger.Printf("Truncating WAL %s at offset %d: %v", l.path, l.size, err)
+			break
+		}
+		if err := replay(e); err != nil {
+			return fmt.Errorf("replay WAL record %d: %w", e.Seq, err)
+		}
+		l.nextSeq++
+		l.size += n
+	}
+	if l.size < info.Size() {
+		if err := l.f.Truncate(l.size); err != nil {
+			return err
+		}
+		return l.f.Sync()
+	}
+	return nil
+}
+
+// readRecord 读取序号应为 seq 的下一条记录并返回其长度，正好在文件末尾时返回 io.EOF
+func readRecord(r io.Reader, seq uint64) (Entry, int64, error) {
+	var head [8]byte
+	if n, err := io.ReadFull(r, head[:]); err != nil {
+		if n == 0 {
+			return Entry{}, 0, io.EOF
+		}
+		return Entry{}, 0, errors.New("incomplete record header")
+	}
+	length := binary.LittleEndian.Uint32(head[:4])
+	if length < 9 || length > maxRecordSize {
+		return Entry{}, 0, fmt.Errorf("invalid record length %d", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Entry{}, 0, errors.New("incomplete record")
+	}
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(head[4:]) {
+		return Entry{}, 0, errors.New("checksum mismatch")
+	}
+	e := Entry{Seq: binary.LittleEndian.Uint64(body[:8]), Op: Op(body[8])}
+	if e.Seq != seq {
+		// 检查点重写头部之后、截断之前崩溃时，旧记录的序号小于头部中的序号
+		return Entry{}, 0, fmt.Errorf("sequence number %d, want %d", e.Seq, seq)
+	}
+	if err := json.Unmarshal(body[9:], &e); err != nil {
+		return Entry{}, 0, fmt.Errorf("record %d: %v", seq, err)
+	}
+	if e.Op != OpUpsert && e.Op != OpDelete {
+		return Entry{}, 0, fmt.Errorf("record %d: unknown operation %d", seq, e.Op)
+	}
+	return e, int64(len(head) + len(body)), nil
+}
+
+// Append 追加一条记录并返回分配的序号，同步策略为 always 时在返回前 fsync。
+// 之前的 fsync 失败过时返回该错误，不再追加
+func (l *Log) Append(e Entry) (uint64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.syncErr != nil {
+		return 0, l.syncErr
+	}
+
+	e.Seq = l.nextSeq
+	content, err := json.Marshal(struct {
+		Docs map[string]storage.VectorDoc `json:",omitempty"`
+		IDs  []string                     `json:",omitempty"`
+	}{e.Docs, e.IDs})
+	if err != nil {
+		return 0, err
+	}
+	if len(content)+9 > maxRecordSize {
+		return 0, fmt.Errorf("wal: record of %d bytes is too large", len(content))
+	}
+	buf := make([]byte, 8+9+len(content))
+	body := buf[8:]
+	binary.LittleEndian.PutUint64(body[:8], e.Seq)
+	body[8] = byte(e.Op)
+	copy(body[9:], content)
+	binary.LittleEndian.PutUint32(buf[:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(body))
+
+	if _, err := l.f.WriteAt(buf, l.size); err != nil {
+		l.f.Truncate(l.size)
+		return 0, err
+	}
+	if l.opts.Sync == SyncAlways {
+		if err := l.f.Sync(); err != nil {
+			l.f.Truncate(l.size)
+			return 0, err
+		}
+	} else {
+		l.dirty = true
+	}
+	l.last = l.size
+	l.size += int64(len(buf))
+	l.nextSeq++
+	return e.Seq, nil
+}
+
+// Undo 删除刚追加的记录，用于记录对应的写入失败的情况；seq 必须是最后一条记录
+func (l *Log) Undo(seq uint64) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.last < 0 || seq != l.nextSeq-1 {
+		return fmt.Errorf("wal: record %d is not the last record", seq)
+	}
+	if err := l.f.Truncate(l.last); err != nil {
+		return err
+	}
+	if l.opts.Sync == SyncAlways {
+		if err := l.f.Sync(); err != nil {
+			return err
+		}
+	}
+	l.size = l.last
+	l.last = -1
+	l.nextSeq--
+	return nil
+}
+
+// Sync 把已追加的记录 fsync 到磁盘。fsync 失败后无法确定哪些记录已经落盘，
+// 错误会保留下来，之后的 Append、Sync 和 Close 都返回它，直到 Checkpoint 成功
+func (l *Log) Sync() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.syncErr != nil {
+		return l.syncErr
+	}
+	if !l.dirty {
+		return nil
+	}
+	if err := l.f.Sync(); err != nil {
+		l.syncErr = fmt.Errorf("wal: sync %s: %w", l.path, err)
+		return l.syncErr
+	}
+	l.dirty = false
+	return nil
+}
+
+// Size 返回检查点之后追加的记录的总字节数
+func (l *Log) Size() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.size - int64(headerSize)
+}
+
+// NeedsCheckpoint 在记录总字节数达到 CheckpointSize 时返回 true
+func (l *Log) NeedsCheckpoint() bool {
+	return l.Size() >= l.opts.CheckpointSize
+}
+
+// Checkpoint 丢弃全部记录，调用方需保证记录中的写入都已持久化到存储。序号在检查点之后继续递增，
+// 成功后清除之前的 fsync 错误
+func (l *Log) Checkpoint() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.reset(l.nextSeq)
+}
+
+// reset 先重写头部再截断：两步之间崩溃时，旧记录的序号与头部不符，打开时会被丢弃
+func (l *Log) reset(seq uint64) error {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.LittleEndian.PutUint64(header[len(magic):], seq)
+	if _, err := l.f.WriteAt(header, 0); err != nil {
+		return err
+	}
+	if err := l.f.Truncate(int64(headerSize)); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.nextSeq = seq
+	l.size = int64(headerSize)
+	l.last = -1
+	l.dirty = false
+	l.syncErr = nil
+	return nil
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mutex.Lock()
+			failed := l.syncErr != nil
+			l.mutex.Unlock()
+			// 错误由之后的 Append 返回，这里只在第一次失败时记录
+			if err := l.Sync(); err != nil && !failed {
+				l.opts.Logger.Printf("WAL sync failed, rejecting writes until the next checkpoint: %v", err)
+			}
+		}
+	}
+}
+
+// Close fsync 未同步的记录并关闭文件，之前的 fsync 失败过时返回该错误
+func (l *Log) Close() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop = nil
+	}
+	syncErr := l.Sync()
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// Remove 关闭并删除日志文件
+func (l *Log) Remove() error {
+	l.Close()
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
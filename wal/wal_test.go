@@ -0,0 +1,189 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gvdb/storage"
+)
+
+// collect 打开日志并返回重放的全部记录
+func collect(t *testing.T, path string, opts Options) (*Log, []Entry) {
+	t.Helper()
+	var entries []Entry
+	l, err := Open(path, opts, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, entries
+}
+
+func TestLogReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal", "default.wal")
+	l, entries := collect(t, path, Options{})
+	if len(entries) != 0 {
+		t.Fatalf("Expected empty log, got %+v", entries)
+	}
+	docs := map[string]storage.VectorDoc{
+		"a": {Vector: []float32{1, 2}, Meta: "m", Payload: storage.Payload{"n": int64(1)}},
+	}
+	want := []Entry{
+		{Seq: 1, Op: OpUpsert, Docs: docs},
+		{Seq: 2, Op: OpDelete, IDs: []string{"a", "b"}},
+	}
+	for _, e := range want {
+		seq, err := l.Append(Entry{Op: e.Op, Docs: e.Docs, IDs: e.IDs})
+		if err != nil || seq != e.Seq {
+			t.Fatalf("Append: %d, %v", seq, err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, entries = collect(t, path, Options{})
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Unexpected replay:\n%+v\nwant:\n%+v", entries, want)
+	}
+
+	// 检查点之后日志为空，序号继续递增
+	if err := l.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if l.Size() != 0 {
+		t.Errorf("Expected empty log after checkpoint, got %d bytes", l.Size())
+	}
+	if seq, _ := l.Append(Entry{Op: OpDelete, IDs: []string{"c"}}); seq != 3 {
+		t.Errorf("Expected sequence 3 after checkpoint, got %d", seq)
+	}
+	l.Close()
+	l, entries = collect(t, path, Options{})
+	defer l.Close()
+	if len(entries) != 1 || entries[0].Seq != 3 {
+		t.Errorf("Unexpected replay after checkpoint: %+v", entries)
+	}
+
+	// 重放失败时打开失败
+	if _, err := Open(path, Options{}, func(Entry) error { return errors.New("boom") }); err == nil {
+		t.Error("Expected replay error")
+	}
+}
+
+func TestLogTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.wal")
+	l, _ := collect(t, path, Options{Sync: SyncNone})
+	for _, id := range []string{"a", "b", "c"} {
+		l.Append(Entry{Op: OpDelete, IDs: []string{id}})
+	}
+	l.Close()
+	info, _ := os.Stat(path)
+
+	// 最后一条记录只写入一半
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	l, entries := collect(t, path, Options{Logger: log.New(&logged, "", 0)})
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 complete records, got %+v", entries)
+	}
+	if !bytes.Contains(logged.Bytes(), []byte("Truncating WAL")) {
+		t.Errorf("Expected the truncation to be logged, got %q", logged.String())
+	}
+	// 截断后新记录紧接在完整记录之后
+	if seq, err := l.Append(Entry{Op: OpDelete, IDs: []string{"d"}}); err != nil || seq != 3 {
+		t.Fatalf("Append after truncation: %d, %v", seq, err)
+	}
+	l.Close()
+
+	// 校验失败的记录及其之后的内容被丢弃
+	data, _ := os.ReadFile(path)
+	data[len(data)-2] ^= 0xff
+	os.WriteFile(path, data, 0644)
+	l, entries = collect(t, path, Options{})
+	l.Close()
+	if len(entries) != 2 || entries[1].IDs[0] != "b" {
+		t.Errorf("Expected corrupt record to be dropped, got %+v", entries)
+	}
+
+	os.WriteFile(path, []byte("not a write-ahead log"), 0644)
+	if _, err := Open(path, Options{}, func(Entry) error { return nil }); !errors.Is(err, ErrNotLog) {
+		t.Errorf("Expected ErrNotLog, got %v", err)
+	}
+}
+
+func TestLogSyncError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.wal")
+	l, _ := collect(t, path, Options{Sync: SyncNone})
+	if _, err := l.Append(Entry{Op: OpDelete, IDs: []string{"a"}}); err != nil {
+		t.Fatal(err)
+	}
+	// 用已关闭的文件模拟 fsync 失败
+	f := l.f
+	closed, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	l.f = closed
+	if err := l.Sync(); err == nil {
+		t.Fatal("Expected Sync to fail")
+	}
+	l.f = f
+
+	// 错误保留下来，之后的写入不会被当作成功
+	if _, err := l.Append(Entry{Op: OpDelete, IDs: []string{"b"}}); err == nil {
+		t.Error("Expected Append to return the earlier sync error")
+	}
+	if err := l.Sync(); err == nil {
+		t.Error("Expected Sync to keep returning the error")
+	}
+	if err := l.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(Entry{Op: OpDelete, IDs: []string{"c"}}); err != nil {
+		t.Errorf("Expected Append to succeed after a checkpoint, got %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLogUndo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.wal")
+	l, _ := collect(t, path, Options{Sync: SyncInterval, SyncInterval: time.Millisecond})
+	l.Append(Entry{Op: OpDelete, IDs: []string{"a"}})
+	seq, _ := l.Append(Entry{Op: OpDelete, IDs: []string{"b"}})
+	if err := l.Undo(seq - 1); err == nil {
+		t.Error("Expected error when undoing a record that is not the last")
+	}
+	if err := l.Undo(seq); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Undo(seq - 1); err == nil {
+		t.Error("Expected error when undoing twice")
+	}
+	if next, _ := l.Append(Entry{Op: OpDelete, IDs: []string{"c"}}); next != seq {
+		t.Errorf("Expected undone sequence %d to be reused, got %d", seq, next)
+	}
+	time.Sleep(10 * time.Millisecond)
+	l.Close()
+
+	l, entries := collect(t, path, Options{})
+	defer l.Close()
+	if len(entries) != 2 || entries[1].IDs[0] != "c" {
+		t.Errorf("Unexpected replay after undo: %+v", entries)
+	}
+	if _, err := Open(path, Options{Sync: "sometimes"}, nil); err == nil {
+		t.Error("Expected error for unknown sync policy")
+	}
+}